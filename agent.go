@@ -0,0 +1,298 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/smithy-go"
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+)
+
+// AgentInput identifies a Bedrock Agent and the text to send it.
+type AgentInput struct {
+	AgentID      string // Agent ID (required)
+	AgentAliasID string // Agent alias ID, e.g. "TSTALIASID" for the draft (required)
+	// SessionID groups calls into one conversation; reuse it for follow-up
+	// turns (required).
+	SessionID string
+	InputText string // The user's message (required)
+	// EndSession closes the session after this call.
+	EndSession bool
+}
+
+// AgentTraceStep is one step of an agent's trace: its reasoning, action
+// group and knowledge base invocations, their observations, and guardrail
+// or pre/post-processing checks.
+type AgentTraceStep struct {
+	// Type is the trace part, e.g. "orchestrationTrace", "preProcessingTrace"
+	// or "guardrailTrace".
+	Type string `json:"type"`
+	// Step is the kind of step within Type, e.g. "rationale",
+	// "invocationInput" or "observation" for orchestration traces.
+	Step string `json:"step,omitempty"`
+	// Detail is the step as Bedrock reported it. For an invocationInput,
+	// Detail["invocationType"] is "ACTION_GROUP" or "KNOWLEDGE_BASE", with the
+	// call under "actionGroupInvocationInput" or "knowledgeBaseLookupInput".
+	Detail map[string]any `json:"detail,omitempty"`
+}
+
+// AgentEvent is one event of an InvokeAgent response stream: Type is the
+// event type ("chunk", "trace", ...) and Payload its JSON body.
+type AgentEvent struct {
+	Type    string
+	Payload []byte
+}
+
+// AgentRuntimeClient is the subset of the Bedrock Agents runtime used by
+// [InvokeAgent]. The plugin creates one at Init from its AWS config.
+type AgentRuntimeClient interface {
+	// InvokeAgent sends in to the agent and calls handle for each event of
+	// the response stream, in order. Exceptions in the stream are returned
+	// as errors.
+	InvokeAgent(ctx context.Context, in *AgentInput, handle func(AgentEvent) error) error
+}
+
+// InvokeAgent sends in.InputText to a Bedrock Agent and returns its answer.
+// Agents plan, call their action groups and query knowledge bases on the
+// service side; the steps they take are returned in the response metadata
+// (see [AgentTrace]). When cb is non-nil, the answer is streamed to it as it
+// arrives.
+func InvokeAgent(ctx context.Context, g *genkit.Genkit, in AgentInput, cb func(context.Context, *ai.ModelResponseChunk) error) (*ai.ModelResponse, error) {
+	client, timeout, err := agentClientFor(g)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := withRequestTimeout(ctx, timeout)
+	defer cancel()
+	return invokeAgent(ctx, client, in, cb)
+}
+
+func agentClientFor(g *genkit.Genkit) (AgentRuntimeClient, time.Duration, error) {
+	if g == nil {
+		return nil, 0, errors.New("bedrock.InvokeAgent: Genkit instance required")
+	}
+	p, _ := genkit.LookupPlugin(g, provider).(*Bedrock)
+	if p == nil {
+		return nil, 0, errors.New("bedrock.InvokeAgent: bedrock plugin not registered")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.initted {
+		return nil, 0, errors.New("bedrock.InvokeAgent: plugin not initialized")
+	}
+	return p.agents, p.RequestTimeout, nil
+}
+
+func invokeAgent(ctx context.Context, client AgentRuntimeClient, in AgentInput, cb func(context.Context, *ai.ModelResponseChunk) error) (*ai.ModelResponse, error) {
+	if client == nil {
+		return nil, errors.New("bedrock.InvokeAgent: agent runtime client required")
+	}
+	var missing []string
+	for _, f := range []struct{ name, value string }{
+		{"AgentID", in.AgentID},
+		{"AgentAliasID", in.AgentAliasID},
+		{"SessionID", in.SessionID},
+		{"InputText", in.InputText},
+	} {
+		if strings.TrimSpace(f.value) == "" {
+			missing = append(missing, f.name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("bedrock.InvokeAgent: missing %s", strings.Join(missing, ", "))
+	}
+
+	var text strings.Builder
+	var pending utf8Tail
+	var steps []AgentTraceStep
+	emit := func(s string) error {
+		if s == "" {
+			return nil
+		}
+		text.WriteString(s)
+		if cb == nil {
+			return nil
+		}
+		if err := cb(ctx, &ai.ModelResponseChunk{Index: 0, Content: []*ai.Part{ai.NewTextPart(s)}}); err != nil {
+			return fmt.Errorf("callback error: %w", err)
+		}
+		return nil
+	}
+	err := client.InvokeAgent(ctx, &in, func(event AgentEvent) error {
+		switch event.Type {
+		case "chunk":
+			var chunk struct {
+				Bytes []byte `json:"bytes"`
+			}
+			if err := json.Unmarshal(event.Payload, &chunk); err != nil {
+				return fmt.Errorf("decode chunk event: %w", err)
+			}
+			return emit(pending.complete(string(chunk.Bytes)))
+		case "trace":
+			traced, err := agentTraceStepsFrom(event.Payload)
+			if err != nil {
+				return err
+			}
+			steps = append(steps, traced...)
+		case "returnControl":
+			return errors.New("agent returned control to the caller, which InvokeAgent does not support; configure the action group with a Lambda function")
+		default:
+			// Other events (e.g. "files") carry nothing for the text answer.
+		}
+		return nil
+	})
+	if err == nil {
+		err = emit(pending.flush())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("bedrock.InvokeAgent: %w", err)
+	}
+
+	msg := &ai.Message{Role: ai.RoleModel, Content: []*ai.Part{ai.NewTextPart(text.String())}}
+	if len(steps) > 0 {
+		msg.Metadata = map[string]any{agentTraceMetadataKey: steps}
+	}
+	return &ai.ModelResponse{Message: msg, FinishReason: ai.FinishReasonStop}, nil
+}
+
+// agentTraceStepsFrom decodes a trace event into its steps. A trace part
+// made only of objects, like an orchestration trace, gives one step per
+// object, in key order; any other part, like a guardrail trace with its
+// "action", is a single step.
+func agentTraceStepsFrom(payload []byte) ([]AgentTraceStep, error) {
+	var event struct {
+		Trace map[string]map[string]any `json:"trace"`
+	}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("decode trace event: %w", err)
+	}
+	var steps []AgentTraceStep
+	for _, typ := range slices.Sorted(maps.Keys(event.Trace)) {
+		part := event.Trace[typ]
+		var partSteps []AgentTraceStep
+		for _, name := range slices.Sorted(maps.Keys(part)) {
+			detail, ok := part[name].(map[string]any)
+			if !ok {
+				partSteps = []AgentTraceStep{{Type: typ, Detail: part}}
+				break
+			}
+			partSteps = append(partSteps, AgentTraceStep{Type: typ, Step: name, Detail: detail})
+		}
+		steps = append(steps, partSteps...)
+	}
+	return steps, nil
+}
+
+// agentRuntimeClient calls the Bedrock Agents runtime with the control-plane
+// client's signing, endpoint and retry handling.
+type agentRuntimeClient struct {
+	rest *controlPlaneClient
+}
+
+func newAgentRuntimeClient(cfg aws.Config) *agentRuntimeClient {
+	return &agentRuntimeClient{rest: &controlPlaneClient{
+		cfg:      cfg,
+		endpoint: serviceEndpoint(context.Background(), cfg, "Bedrock Agent Runtime", "bedrock-agent-runtime"),
+		signer:   v4.NewSigner(),
+	}}
+}
+
+// InvokeAgent implements [AgentRuntimeClient].
+func (c *agentRuntimeClient) InvokeAgent(ctx context.Context, in *AgentInput, handle func(AgentEvent) error) error {
+	body, err := json.Marshal(struct {
+		InputText   string `json:"inputText"`
+		EnableTrace bool   `json:"enableTrace"`
+		EndSession  bool   `json:"endSession,omitempty"`
+	}{in.InputText, true, in.EndSession})
+	if err != nil {
+		return fmt.Errorf("marshal request: %w", err)
+	}
+	if c.rest.cfg.Credentials == nil {
+		return errors.New("no AWS credentials configured")
+	}
+	path := "/agents/" + url.PathEscape(in.AgentID) +
+		"/agentAliases/" + url.PathEscape(in.AgentAliasID) +
+		"/sessions/" + url.PathEscape(in.SessionID) + "/text"
+
+	var stream io.ReadCloser
+	err = c.rest.withRetries(ctx, func() error {
+		resp, err := c.rest.open(ctx, "POST", path, "application/vnd.amazon.eventstream", true, body)
+		if err != nil {
+			return err
+		}
+		stream = resp.Body
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	return decodeAgentEvents(stream, handle)
+}
+
+// decodeAgentEvents reads AWS event stream messages from r until it ends.
+func decodeAgentEvents(r io.Reader, handle func(AgentEvent) error) error {
+	decoder := eventstream.NewDecoder()
+	for {
+		msg, err := decoder.Decode(r, nil)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read event stream: %w", err)
+		}
+
+		switch headerString(msg.Headers, ":message-type") {
+		case "event":
+			if err := handle(AgentEvent{Type: headerString(msg.Headers, ":event-type"), Payload: msg.Payload}); err != nil {
+				return err
+			}
+		case "exception":
+			var payload struct {
+				Message string `json:"message"`
+			}
+			_ = json.Unmarshal(msg.Payload, &payload)
+			return &smithy.GenericAPIError{Code: headerString(msg.Headers, ":exception-type"), Message: payload.Message}
+		case "error":
+			return &smithy.GenericAPIError{Code: headerString(msg.Headers, ":error-code"), Message: headerString(msg.Headers, ":error-message")}
+		}
+	}
+}
+
+func headerString(headers eventstream.Headers, name string) string {
+	v := headers.Get(name)
+	if v == nil {
+		return ""
+	}
+	return v.String()
+}
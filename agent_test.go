@@ -0,0 +1,201 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream"
+	"github.com/firebase/genkit/go/ai"
+)
+
+// fakeAgentClient replays events, recording the input it was called with.
+type fakeAgentClient struct {
+	events []AgentEvent
+	err    error
+	got    *AgentInput
+}
+
+func (f *fakeAgentClient) InvokeAgent(_ context.Context, in *AgentInput, handle func(AgentEvent) error) error {
+	f.got = in
+	for _, event := range f.events {
+		if err := handle(event); err != nil {
+			return err
+		}
+	}
+	return f.err
+}
+
+func agentChunk(t *testing.T, text string) AgentEvent {
+	t.Helper()
+	payload, err := json.Marshal(map[string]any{"bytes": []byte(text)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return AgentEvent{Type: "chunk", Payload: payload}
+}
+
+func agentTraceEvent(trace string) AgentEvent {
+	return AgentEvent{Type: "trace", Payload: []byte(`{"agentId":"AGENT12345","sessionId":"s1","trace":` + trace + `}`)}
+}
+
+func TestInvokeAgent_StreamsCompletionAndTrace(t *testing.T) {
+	emoji := "\U0001F600"
+	client := &fakeAgentClient{events: []AgentEvent{
+		agentTraceEvent(`{"orchestrationTrace":{"rationale":{"text":"Look up the refund policy."}}}`),
+		agentTraceEvent(`{"orchestrationTrace":{"invocationInput":{"invocationType":"KNOWLEDGE_BASE","knowledgeBaseLookupInput":{"knowledgeBaseId":"KB1","text":"refund policy"}}}}`),
+		agentTraceEvent(`{"orchestrationTrace":{"observation":{"type":"KNOWLEDGE_BASE"}}}`),
+		agentTraceEvent(`{"guardrailTrace":{"action":"NONE"}}`),
+		agentChunk(t, "Refunds take 5 days "+emoji[:2]),
+		agentChunk(t, emoji[2:]+"."),
+	}}
+
+	var streamed []string
+	resp, err := invokeAgent(context.Background(), client, AgentInput{
+		AgentID: "AGENT12345", AgentAliasID: "TSTALIASID", SessionID: "s1", InputText: "How long do refunds take?",
+	}, func(_ context.Context, chunk *ai.ModelResponseChunk) error {
+		streamed = append(streamed, chunk.Text())
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Refunds take 5 days " + emoji + "."
+	if resp.Text() != want || strings.Join(streamed, "") != want {
+		t.Errorf("text = %q, streamed %q; want %q", resp.Text(), streamed, want)
+	}
+	if client.got == nil || client.got.InputText != "How long do refunds take?" {
+		t.Errorf("client input = %+v", client.got)
+	}
+
+	steps := AgentTrace(resp)
+	gotSteps := make([]string, len(steps))
+	for i, s := range steps {
+		gotSteps[i] = s.Type + "/" + s.Step
+	}
+	wantSteps := []string{"orchestrationTrace/rationale", "orchestrationTrace/invocationInput", "orchestrationTrace/observation", "guardrailTrace/"}
+	if !reflect.DeepEqual(gotSteps, wantSteps) {
+		t.Fatalf("steps = %v, want %v", gotSteps, wantSteps)
+	}
+	if steps[1].Detail["invocationType"] != "KNOWLEDGE_BASE" {
+		t.Errorf("invocationInput detail = %v", steps[1].Detail)
+	}
+	if steps[3].Detail["action"] != "NONE" {
+		t.Errorf("guardrail detail = %v", steps[3].Detail)
+	}
+
+	// The trace survives a JSON round trip of the response.
+	raw, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded ai.ModelResponse
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if got := AgentTrace(&decoded); len(got) != 4 || got[0].Detail["text"] != "Look up the refund policy." {
+		t.Errorf("AgentTrace after round trip = %+v", got)
+	}
+}
+
+func TestInvokeAgent_Errors(t *testing.T) {
+	valid := AgentInput{AgentID: "AGENT12345", AgentAliasID: "TSTALIASID", SessionID: "s1", InputText: "hi"}
+	tests := []struct {
+		name    string
+		client  AgentRuntimeClient
+		in      AgentInput
+		wantErr string
+	}{
+		{"nil client", nil, valid, "client required"},
+		{"missing fields", &fakeAgentClient{}, AgentInput{AgentID: "AGENT12345", InputText: " "}, "missing AgentAliasID, SessionID, InputText"},
+		{"return control", &fakeAgentClient{events: []AgentEvent{{Type: "returnControl", Payload: []byte(`{}`)}}}, valid, "returned control"},
+		{"bad chunk", &fakeAgentClient{events: []AgentEvent{{Type: "chunk", Payload: []byte(`{`)}}}, valid, "decode chunk event"},
+		{"stream error", &fakeAgentClient{err: errors.New("boom")}, valid, "boom"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := invokeAgent(context.Background(), tt.client, tt.in, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) || !strings.HasPrefix(err.Error(), "bedrock.InvokeAgent: ") {
+				t.Errorf("error = %v, want bedrock.InvokeAgent error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAgentRuntimeClient_InvokeAgent(t *testing.T) {
+	var gotPath, gotAuth string
+	var gotBody map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.EscapedPath()
+		gotAuth = r.Header.Get("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+		w.Header().Set("Content-Type", "application/vnd.amazon.eventstream")
+		writeStreamEvent(t, w, "trace", `{"trace":{"orchestrationTrace":{"rationale":{"text":"thinking"}}}}`)
+		writeStreamEvent(t, w, "chunk", `{"bytes":"SGVsbG8="}`)
+	}))
+	defer server.Close()
+
+	client := newAgentRuntimeClient(testControlPlaneConfig(server))
+	var events []string
+	err := client.InvokeAgent(context.Background(), &AgentInput{
+		AgentID: "AGENT12345", AgentAliasID: "TSTALIASID", SessionID: "user/42", InputText: "hi", EndSession: true,
+	}, func(event AgentEvent) error {
+		events = append(events, event.Type+":"+string(event.Payload))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "/agents/AGENT12345/agentAliases/TSTALIASID/sessions/user%2F42/text"; gotPath != want {
+		t.Errorf("path = %q, want %q", gotPath, want)
+	}
+	if !strings.Contains(gotAuth, "/bedrock/aws4_request") {
+		t.Errorf("Authorization = %q, want a SigV4 signature for bedrock", gotAuth)
+	}
+	if want := map[string]any{"inputText": "hi", "enableTrace": true, "endSession": true}; !reflect.DeepEqual(gotBody, want) {
+		t.Errorf("body = %v, want %v", gotBody, want)
+	}
+	if len(events) != 2 || !strings.HasPrefix(events[0], "trace:") || events[1] != `chunk:{"bytes":"SGVsbG8="}` {
+		t.Errorf("events = %q", events)
+	}
+}
+
+func TestAgentRuntimeClient_StreamException(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeStreamEvent(t, w, "chunk", `{"bytes":"SGk="}`)
+		var headers eventstream.Headers
+		headers.Set(":message-type", eventstream.StringValue("exception"))
+		headers.Set(":exception-type", eventstream.StringValue("dependencyFailedException"))
+		_ = eventstream.NewEncoder().Encode(w, eventstream.Message{Headers: headers, Payload: []byte(`{"message":"Lambda failed"}`)})
+	}))
+	defer server.Close()
+
+	_, err := invokeAgent(context.Background(), newAgentRuntimeClient(testControlPlaneConfig(server)), AgentInput{
+		AgentID: "AGENT12345", AgentAliasID: "TSTALIASID", SessionID: "s1", InputText: "hi",
+	}, nil)
+	if err == nil || !strings.Contains(err.Error(), "dependencyFailedException") || !strings.Contains(err.Error(), "Lambda failed") {
+		t.Errorf("error = %v, want the stream exception", err)
+	}
+}
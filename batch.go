@@ -0,0 +1,375 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/firebase/genkit/go/genkit"
+)
+
+// BatchJobInput describes a batch inference job. Bedrock reads JSONL records
+// (one {"recordId", "modelInput"} object per line) from InputS3URI and writes
+// results under OutputS3URI.
+type BatchJobInput struct {
+	JobName     string // Unique job name (required)
+	ModelID     string // Model ID or inference profile to run (required)
+	RoleARN     string // IAM service role Bedrock assumes to access S3 (required)
+	InputS3URI  string // s3:// URI of the JSONL input file or prefix (required)
+	OutputS3URI string // s3:// URI prefix for results (required)
+	// TimeoutHours bounds how long the job may run (0: Bedrock's default).
+	TimeoutHours int
+	// ClientRequestToken makes submission idempotent: Bedrock creates at most
+	// one job per token. Reuse the same token when resubmitting after an
+	// ambiguous failure. When empty, SubmitBatchJob generates one, which is
+	// reused for the client's own retries of that call.
+	ClientRequestToken string
+}
+
+// Batch job statuses reported by Bedrock.
+const (
+	BatchJobSubmitted          = "Submitted"
+	BatchJobValidating         = "Validating"
+	BatchJobScheduled          = "Scheduled"
+	BatchJobInProgress         = "InProgress"
+	BatchJobStopping           = "Stopping"
+	BatchJobCompleted          = "Completed"
+	BatchJobPartiallyCompleted = "PartiallyCompleted"
+	BatchJobFailed             = "Failed"
+	BatchJobStopped            = "Stopped"
+	BatchJobExpired            = "Expired"
+)
+
+// BatchJob is the current state of a batch inference job.
+type BatchJob struct {
+	JobARN           string    `json:"jobArn"`
+	JobName          string    `json:"jobName"`
+	ModelID          string    `json:"modelId"`
+	Status           string    `json:"status"`
+	Message          string    `json:"message,omitempty"` // Failure or status detail
+	SubmitTime       time.Time `json:"submitTime"`
+	LastModifiedTime time.Time `json:"lastModifiedTime"`
+	EndTime          time.Time `json:"endTime"`
+}
+
+// Done reports whether the job has reached a terminal status.
+func (j *BatchJob) Done() bool {
+	switch j.Status {
+	case BatchJobCompleted, BatchJobPartiallyCompleted, BatchJobFailed, BatchJobStopped, BatchJobExpired:
+		return true
+	}
+	return false
+}
+
+// BatchClient is the subset of the Bedrock control plane used for batch
+// inference. The plugin creates one at Init from its AWS config.
+type BatchClient interface {
+	CreateModelInvocationJob(ctx context.Context, in *BatchJobInput) (jobARN string, err error)
+	GetModelInvocationJob(ctx context.Context, jobARN string) (*BatchJob, error)
+}
+
+type createModelInvocationJobRequest struct {
+	JobName                string            `json:"jobName"`
+	ModelID                string            `json:"modelId"`
+	RoleARN                string            `json:"roleArn"`
+	InputDataConfig        batchInputConfig  `json:"inputDataConfig"`
+	OutputDataConfig       batchOutputConfig `json:"outputDataConfig"`
+	TimeoutDurationInHours int               `json:"timeoutDurationInHours,omitempty"`
+	ClientRequestToken     string            `json:"clientRequestToken,omitempty"`
+}
+
+type batchInputConfig struct {
+	S3InputDataConfig struct {
+		S3URI         string `json:"s3Uri"`
+		S3InputFormat string `json:"s3InputFormat"`
+	} `json:"s3InputDataConfig"`
+}
+
+type batchOutputConfig struct {
+	S3OutputDataConfig struct {
+		S3URI string `json:"s3Uri"`
+	} `json:"s3OutputDataConfig"`
+}
+
+// CreateModelInvocationJob implements [BatchClient].
+func (c *controlPlaneClient) CreateModelInvocationJob(ctx context.Context, in *BatchJobInput) (string, error) {
+	req := createModelInvocationJobRequest{
+		JobName:                in.JobName,
+		ModelID:                in.ModelID,
+		RoleARN:                in.RoleARN,
+		TimeoutDurationInHours: in.TimeoutHours,
+		ClientRequestToken:     in.ClientRequestToken,
+	}
+	req.InputDataConfig.S3InputDataConfig.S3URI = in.InputS3URI
+	req.InputDataConfig.S3InputDataConfig.S3InputFormat = "JSONL"
+	req.OutputDataConfig.S3OutputDataConfig.S3URI = in.OutputS3URI
+
+	var resp struct {
+		JobARN string `json:"jobArn"`
+	}
+	if err := c.do(ctx, "POST", "/model-invocation-job", req, &resp); err != nil {
+		return "", err
+	}
+	return resp.JobARN, nil
+}
+
+// GetModelInvocationJob implements [BatchClient].
+func (c *controlPlaneClient) GetModelInvocationJob(ctx context.Context, jobARN string) (*BatchJob, error) {
+	var job BatchJob
+	if err := c.do(ctx, "GET", "/model-invocation-job/"+url.PathEscape(jobARN), nil, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// SubmitBatchJob submits a batch inference job (CreateModelInvocationJob) and
+// returns its ARN. Batch jobs are billed at a discount to on-demand calls and
+// suit large offline workloads; poll them with [GetBatchJob] or
+// [WaitForBatchJob].
+func SubmitBatchJob(ctx context.Context, g *genkit.Genkit, in BatchJobInput) (string, error) {
+	client, timeout, err := batchClientFor(g, "SubmitBatchJob")
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := withRequestTimeout(ctx, timeout)
+	defer cancel()
+	return submitBatchJob(ctx, client, in)
+}
+
+// GetBatchJob returns the current state of the batch job jobARN.
+func GetBatchJob(ctx context.Context, g *genkit.Genkit, jobARN string) (*BatchJob, error) {
+	client, timeout, err := batchClientFor(g, "GetBatchJob")
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := withRequestTimeout(ctx, timeout)
+	defer cancel()
+	return getBatchJob(ctx, client, jobARN)
+}
+
+// WaitForBatchJob polls the batch job jobARN every interval (default 30s)
+// until it reaches a terminal status or ctx is done. A job that ends Failed,
+// Stopped, or Expired is returned without error; check [BatchJob.Status].
+func WaitForBatchJob(ctx context.Context, g *genkit.Genkit, jobARN string, interval time.Duration) (*BatchJob, error) {
+	client, timeout, err := batchClientFor(g, "WaitForBatchJob")
+	if err != nil {
+		return nil, err
+	}
+	return waitForBatchJob(ctx, client, timeout, jobARN, interval)
+}
+
+func batchClientFor(g *genkit.Genkit, op string) (BatchClient, time.Duration, error) {
+	if g == nil {
+		return nil, 0, fmt.Errorf("bedrock.%s: Genkit instance required", op)
+	}
+	p, _ := genkit.LookupPlugin(g, provider).(*Bedrock)
+	if p == nil {
+		return nil, 0, fmt.Errorf("bedrock.%s: bedrock plugin not registered", op)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.initted {
+		return nil, 0, fmt.Errorf("bedrock.%s: plugin not initialized", op)
+	}
+	return p.batch, p.RequestTimeout, nil
+}
+
+func submitBatchJob(ctx context.Context, client BatchClient, in BatchJobInput) (string, error) {
+	if client == nil {
+		return "", errors.New("bedrock.SubmitBatchJob: batch client required")
+	}
+	var missing []string
+	for _, f := range []struct{ name, value string }{
+		{"JobName", in.JobName},
+		{"ModelID", in.ModelID},
+		{"RoleARN", in.RoleARN},
+		{"InputS3URI", in.InputS3URI},
+		{"OutputS3URI", in.OutputS3URI},
+	} {
+		if f.value == "" {
+			missing = append(missing, f.name)
+		}
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("bedrock.SubmitBatchJob: missing %s", strings.Join(missing, ", "))
+	}
+	for name, uri := range map[string]string{"InputS3URI": in.InputS3URI, "OutputS3URI": in.OutputS3URI} {
+		if !strings.HasPrefix(uri, "s3://") {
+			return "", fmt.Errorf("bedrock.SubmitBatchJob: %s %q must be an s3:// URI", name, uri)
+		}
+	}
+
+	if in.ClientRequestToken == "" {
+		token, err := newClientRequestToken()
+		if err != nil {
+			return "", fmt.Errorf("bedrock.SubmitBatchJob: %w", err)
+		}
+		in.ClientRequestToken = token
+	} else if len(in.ClientRequestToken) > 256 || !clientRequestTokenPattern.MatchString(in.ClientRequestToken) {
+		return "", fmt.Errorf("bedrock.SubmitBatchJob: ClientRequestToken %q must be 1-256 alphanumeric characters, optionally separated by hyphens", in.ClientRequestToken)
+	}
+
+	jobARN, err := client.CreateModelInvocationJob(ctx, &in)
+	if err != nil {
+		return "", fmt.Errorf("bedrock.SubmitBatchJob: create model invocation job: %w", err)
+	}
+	if jobARN == "" {
+		return "", errors.New("bedrock.SubmitBatchJob: response has no job ARN")
+	}
+	return jobARN, nil
+}
+
+// clientRequestTokenPattern is the token format Bedrock accepts.
+var clientRequestTokenPattern = regexp.MustCompile(`^[a-zA-Z0-9](-*[a-zA-Z0-9]){0,255}$`)
+
+// newClientRequestToken returns a random idempotency token.
+func newClientRequestToken() (string, error) {
+	var buf [16]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return "", fmt.Errorf("generate client request token: %w", err)
+	}
+	return hex.EncodeToString(buf[:]), nil
+}
+
+func getBatchJob(ctx context.Context, client BatchClient, jobARN string) (*BatchJob, error) {
+	if client == nil {
+		return nil, errors.New("bedrock.GetBatchJob: batch client required")
+	}
+	if jobARN == "" {
+		return nil, errors.New("bedrock.GetBatchJob: job ARN required")
+	}
+	job, err := client.GetModelInvocationJob(ctx, jobARN)
+	if err != nil {
+		return nil, fmt.Errorf("bedrock.GetBatchJob: get model invocation job: %w", err)
+	}
+	return job, nil
+}
+
+func waitForBatchJob(ctx context.Context, client BatchClient, requestTimeout time.Duration, jobARN string, interval time.Duration) (*BatchJob, error) {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		callCtx, cancel := withRequestTimeout(ctx, requestTimeout)
+		job, err := getBatchJob(callCtx, client, jobARN)
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+		if job.Done() {
+			return job, nil
+		}
+		select {
+		case <-ctx.Done():
+			return job, fmt.Errorf("bedrock.WaitForBatchJob: %w (last status %s)", ctx.Err(), job.Status)
+		case <-ticker.C:
+		}
+	}
+}
+
+// BatchRecordResult is one record of a batch job's output. Exactly one of
+// Output and Error is set.
+type BatchRecordResult struct {
+	RecordID string          // The record's recordId from the input
+	Input    json.RawMessage // The record's modelInput, e.g. to resubmit failures
+	Output   json.RawMessage // The model's response body, as InvokeModel returns it
+	Error    *BatchRecordError
+}
+
+// BatchRecordError is a per-record failure in batch output, such as a
+// ModelErrorException for one malformed input.
+type BatchRecordError struct {
+	Code    string // Bedrock's errorCode, e.g. "400"
+	Message string
+}
+
+func (e *BatchRecordError) Error() string {
+	return fmt.Sprintf("bedrock: batch record failed (%s): %s", e.Code, e.Message)
+}
+
+// ReadBatchResults parses a batch job's output JSONL (the .jsonl.out files
+// Bedrock writes under OutputS3URI) into one result per record. Records
+// that failed are returned with their error rather than failing the whole
+// read, so a PartiallyCompleted job's successes stay usable; only
+// malformed lines are errors. Blank lines are skipped.
+func ReadBatchResults(r io.Reader) ([]BatchRecordResult, error) {
+	var results []BatchRecordResult
+	reader := bufio.NewReader(r)
+	for lineNo := 1; ; lineNo++ {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return nil, fmt.Errorf("bedrock.ReadBatchResults: read line %d: %w", lineNo, readErr)
+		}
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			result, err := parseBatchRecord(line)
+			if err != nil {
+				return nil, fmt.Errorf("bedrock.ReadBatchResults: line %d: %w", lineNo, err)
+			}
+			results = append(results, result)
+		}
+		if readErr == io.EOF {
+			return results, nil
+		}
+	}
+}
+
+func parseBatchRecord(line []byte) (BatchRecordResult, error) {
+	var record struct {
+		RecordID    string          `json:"recordId"`
+		ModelInput  json.RawMessage `json:"modelInput"`
+		ModelOutput json.RawMessage `json:"modelOutput"`
+		Error       *struct {
+			ErrorCode    json.RawMessage `json:"errorCode"`
+			ErrorMessage string          `json:"errorMessage"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(line, &record); err != nil {
+		return BatchRecordResult{}, err
+	}
+	result := BatchRecordResult{RecordID: record.RecordID, Input: record.ModelInput}
+	switch {
+	case record.Error != nil:
+		// errorCode is a number in Bedrock's output; accept a string too.
+		code := string(record.Error.ErrorCode)
+		if unquoted, err := strconv.Unquote(code); err == nil {
+			code = unquoted
+		}
+		result.Error = &BatchRecordError{Code: code, Message: record.Error.ErrorMessage}
+	case len(record.ModelOutput) > 0 && string(record.ModelOutput) != "null":
+		result.Output = record.ModelOutput
+	default:
+		return BatchRecordResult{}, fmt.Errorf("record %q has neither modelOutput nor error", record.RecordID)
+	}
+	return result, nil
+}
@@ -0,0 +1,372 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+type fakeBatchClient struct {
+	created  []*BatchJobInput
+	statuses []string // Returned in order by GetModelInvocationJob; the last repeats
+	gets     int
+	err      error
+}
+
+func (f *fakeBatchClient) CreateModelInvocationJob(ctx context.Context, in *BatchJobInput) (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	f.created = append(f.created, in)
+	return "arn:aws:bedrock:us-east-1:123456789012:model-invocation-job/abc123", nil
+}
+
+func (f *fakeBatchClient) GetModelInvocationJob(ctx context.Context, jobARN string) (*BatchJob, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	status := f.statuses[min(f.gets, len(f.statuses)-1)]
+	f.gets++
+	return &BatchJob{JobARN: jobARN, Status: status}, nil
+}
+
+func validBatchJobInput() BatchJobInput {
+	return BatchJobInput{
+		JobName:     "nightly-summaries",
+		ModelID:     "anthropic.claude-3-haiku-20240307-v1:0",
+		RoleARN:     "arn:aws:iam::123456789012:role/BedrockBatch",
+		InputS3URI:  "s3://bucket/input/records.jsonl",
+		OutputS3URI: "s3://bucket/output/",
+	}
+}
+
+func TestSubmitBatchJob(t *testing.T) {
+	fake := &fakeBatchClient{}
+	arn, err := submitBatchJob(context.Background(), fake, validBatchJobInput())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(arn, "model-invocation-job/abc123") {
+		t.Errorf("job ARN = %q", arn)
+	}
+	if len(fake.created) != 1 || fake.created[0].JobName != "nightly-summaries" {
+		t.Fatalf("created = %+v, want one nightly-summaries job", fake.created)
+	}
+}
+
+func TestSubmitBatchJob_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(*BatchJobInput)
+		wantErr string
+	}{
+		{name: "missing fields", mutate: func(in *BatchJobInput) { in.RoleARN, in.ModelID = "", "" }, wantErr: "missing ModelID, RoleARN"},
+		{name: "non-S3 input", mutate: func(in *BatchJobInput) { in.InputS3URI = "/tmp/records.jsonl" }, wantErr: "InputS3URI"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := validBatchJobInput()
+			tt.mutate(&in)
+			fake := &fakeBatchClient{}
+			_, err := submitBatchJob(context.Background(), fake, in)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want containing %q", err, tt.wantErr)
+			}
+			if len(fake.created) != 0 {
+				t.Error("invalid input must not reach the control plane")
+			}
+		})
+	}
+}
+
+func TestSubmitBatchJob_ClientRequestToken(t *testing.T) {
+	fake := &fakeBatchClient{}
+	in := validBatchJobInput()
+	in.ClientRequestToken = "nightly-2025-01-02"
+	if _, err := submitBatchJob(context.Background(), fake, in); err != nil {
+		t.Fatal(err)
+	}
+	if got := fake.created[0].ClientRequestToken; got != "nightly-2025-01-02" {
+		t.Errorf("ClientRequestToken = %q, want the caller's token", got)
+	}
+
+	if _, err := submitBatchJob(context.Background(), fake, validBatchJobInput()); err != nil {
+		t.Fatal(err)
+	}
+	if got := fake.created[1].ClientRequestToken; !clientRequestTokenPattern.MatchString(got) {
+		t.Errorf("generated ClientRequestToken = %q, want a valid token", got)
+	}
+
+	in.ClientRequestToken = "not a token!"
+	if _, err := submitBatchJob(context.Background(), fake, in); err == nil || !strings.Contains(err.Error(), "ClientRequestToken") {
+		t.Fatalf("error = %v, want invalid ClientRequestToken", err)
+	}
+}
+
+func TestSubmitBatchJob_ClientError(t *testing.T) {
+	_, err := submitBatchJob(context.Background(), &fakeBatchClient{err: errors.New("AccessDenied")}, validBatchJobInput())
+	if err == nil || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Fatalf("error = %v, want wrapped AccessDenied", err)
+	}
+}
+
+func TestWaitForBatchJob_PollsUntilTerminal(t *testing.T) {
+	fake := &fakeBatchClient{statuses: []string{BatchJobSubmitted, BatchJobInProgress, BatchJobCompleted}}
+	job, err := waitForBatchJob(context.Background(), fake, 0, "arn:job", time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != BatchJobCompleted || fake.gets != 3 {
+		t.Errorf("status = %s after %d polls, want Completed after 3", job.Status, fake.gets)
+	}
+}
+
+func TestWaitForBatchJob_ContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	fake := &fakeBatchClient{statuses: []string{BatchJobInProgress}}
+	job, err := waitForBatchJob(ctx, fake, 0, "arn:job", time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("error = %v, want deadline exceeded", err)
+	}
+	if job == nil || job.Status != BatchJobInProgress {
+		t.Errorf("job = %+v, want last known InProgress status", job)
+	}
+}
+
+func TestControlPlaneClient_ModelInvocationJobWireFormat(t *testing.T) {
+	const jobARN = "arn:aws:bedrock:us-east-1:123456789012:model-invocation-job/abc123"
+	var createBody map[string]any
+	var getPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256") || !strings.Contains(r.Header.Get("Authorization"), "/bedrock/aws4_request") {
+			http.Error(w, "unsigned", http.StatusForbidden)
+			return
+		}
+		switch r.Method {
+		case http.MethodPost:
+			body, _ := io.ReadAll(r.Body)
+			_ = json.Unmarshal(body, &createBody)
+			_, _ = w.Write([]byte(`{"jobArn":"` + jobARN + `"}`))
+		case http.MethodGet:
+			getPath = r.URL.EscapedPath()
+			_, _ = w.Write([]byte(`{"jobArn":"` + jobARN + `","status":"Failed","message":"bad input","submitTime":"2025-01-02T03:04:05Z"}`))
+		}
+	}))
+	defer server.Close()
+
+	client := newControlPlaneClient(testControlPlaneConfig(server))
+
+	arn, err := client.CreateModelInvocationJob(context.Background(), &BatchJobInput{
+		JobName:      "job",
+		ModelID:      "model",
+		RoleARN:      "role",
+		InputS3URI:   "s3://bucket/in.jsonl",
+		OutputS3URI:  "s3://bucket/out/",
+		TimeoutHours: 24,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if arn != jobARN {
+		t.Errorf("job ARN = %q, want %q", arn, jobARN)
+	}
+	input := createBody["inputDataConfig"].(map[string]any)["s3InputDataConfig"].(map[string]any)
+	if input["s3Uri"] != "s3://bucket/in.jsonl" || input["s3InputFormat"] != "JSONL" {
+		t.Errorf("inputDataConfig = %v", input)
+	}
+	if createBody["roleArn"] != "role" || createBody["timeoutDurationInHours"] != float64(24) {
+		t.Errorf("create body = %v", createBody)
+	}
+
+	job, err := client.GetModelInvocationJob(context.Background(), jobARN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if getPath != "/model-invocation-job/arn:aws:bedrock:us-east-1:123456789012:model-invocation-job%2Fabc123" {
+		t.Errorf("GET path = %q", getPath)
+	}
+	if !job.Done() || job.Status != BatchJobFailed || job.Message != "bad input" || job.SubmitTime.Year() != 2025 {
+		t.Errorf("job = %+v", job)
+	}
+}
+
+func TestControlPlaneClient_ErrorResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Amzn-Errortype", "ValidationException:http://internal.amazon.com/")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"message":"roleArn is invalid"}`))
+	}))
+	defer server.Close()
+
+	client := newControlPlaneClient(testControlPlaneConfig(server))
+
+	_, err := client.GetModelInvocationJob(context.Background(), "arn:job")
+	if err == nil || !strings.Contains(err.Error(), "ValidationException") || !strings.Contains(err.Error(), "roleArn is invalid") {
+		t.Fatalf("error = %v, want ValidationException with message", err)
+	}
+}
+
+func testControlPlaneConfig(server *httptest.Server) aws.Config {
+	return aws.Config{
+		Region:       "us-east-1",
+		Credentials:  credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+		HTTPClient:   server.Client(),
+		BaseEndpoint: aws.String(server.URL),
+		Retryer: func() aws.Retryer {
+			return retry.NewStandard(func(o *retry.StandardOptions) {
+				o.Backoff = retry.BackoffDelayerFunc(func(int, error) (time.Duration, error) { return 0, nil })
+			})
+		},
+	}
+}
+
+// fipsSource is a config source that enables FIPS endpoints, like
+// AWS_USE_FIPS_ENDPOINT=true does through config.LoadDefaultConfig.
+type fipsSource struct{}
+
+func (fipsSource) GetUseFIPSEndpoint(context.Context) (aws.FIPSEndpointState, bool, error) {
+	return aws.FIPSEndpointStateEnabled, true, nil
+}
+
+func TestControlPlaneClient_RetriesReuseClientRequestToken(t *testing.T) {
+	var tokens []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			ClientRequestToken string `json:"clientRequestToken"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		tokens = append(tokens, body.ClientRequestToken)
+		if len(tokens) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"message":"try again"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"jobArn":"arn:job"}`))
+	}))
+	defer server.Close()
+
+	client := newControlPlaneClient(testControlPlaneConfig(server))
+	if _, err := submitBatchJob(context.Background(), client, validBatchJobInput()); err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 2 || tokens[0] == "" || tokens[0] != tokens[1] {
+		t.Errorf("clientRequestToken per attempt = %q, want the same non-empty token on the retry", tokens)
+	}
+}
+
+func TestControlPlaneEndpoint(t *testing.T) {
+	t.Setenv("AWS_ENDPOINT_URL", "")
+	t.Setenv("AWS_ENDPOINT_URL_BEDROCK", "")
+	tests := []struct {
+		name string
+		cfg  aws.Config
+		want string
+	}{
+		{"regional", aws.Config{Region: "us-west-2"}, "https://bedrock.us-west-2.amazonaws.com"},
+		{"china", aws.Config{Region: "cn-north-1"}, "https://bedrock.cn-north-1.amazonaws.com.cn"},
+		{"fips", aws.Config{Region: "us-east-1", ConfigSources: []any{fipsSource{}}}, "https://bedrock-fips.us-east-1.amazonaws.com"},
+		{"base endpoint", aws.Config{Region: "us-east-1", BaseEndpoint: aws.String("https://vpce-123.bedrock.us-east-1.vpce.amazonaws.com/")}, "https://vpce-123.bedrock.us-east-1.vpce.amazonaws.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := controlPlaneEndpoint(context.Background(), tt.cfg); got != tt.want {
+				t.Errorf("controlPlaneEndpoint() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestControlPlaneClient_RetriesThrottling(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("X-Amzn-Errortype", "ThrottlingException")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"message":"slow down"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"jobArn":"arn:job","status":"InProgress"}`))
+	}))
+	defer server.Close()
+
+	job, err := newControlPlaneClient(testControlPlaneConfig(server)).GetModelInvocationJob(context.Background(), "arn:job")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 || job.Status != BatchJobInProgress {
+		t.Errorf("calls = %d, job = %+v; want a retried success", calls, job)
+	}
+}
+
+func TestReadBatchResults_MixedSuccessAndErrors(t *testing.T) {
+	output := `{"recordId":"r1","modelInput":{"prompt":"a"},"modelOutput":{"content":[{"type":"text","text":"ok"}]}}
+{"recordId":"r2","modelInput":{"prompt":"b"},"error":{"errorCode":400,"errorMessage":"ModelErrorException: malformed input"}}
+
+{"recordId":"r3","modelInput":{"prompt":"c"},"error":{"errorCode":"ThrottlingException","errorMessage":"retry later"}}
+{"recordId":"r4","modelInput":{"prompt":"d"},"modelOutput":{"content":[]}}`
+
+	results, err := ReadBatchResults(strings.NewReader(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("got %d results, want 4", len(results))
+	}
+	for i, want := range []string{"r1", "r2", "r3", "r4"} {
+		if results[i].RecordID != want {
+			t.Errorf("results[%d].RecordID = %q, want %q", i, results[i].RecordID, want)
+		}
+	}
+	if r := results[0]; r.Error != nil || string(r.Output) != `{"content":[{"type":"text","text":"ok"}]}` || string(r.Input) != `{"prompt":"a"}` {
+		t.Errorf("success record = %+v", r)
+	}
+	if r := results[1]; r.Output != nil || r.Error == nil || r.Error.Code != "400" || !strings.Contains(r.Error.Error(), "ModelErrorException: malformed input") {
+		t.Errorf("error record = %+v", r)
+	}
+	if r := results[2]; r.Error == nil || r.Error.Code != "ThrottlingException" || string(r.Input) != `{"prompt":"c"}` {
+		t.Errorf("string error code record = %+v", r)
+	}
+}
+
+func TestReadBatchResults_MalformedLine(t *testing.T) {
+	for name, output := range map[string]string{
+		"invalid JSON":       "{\"recordId\":\"r1\",\"modelOutput\":{}}\n{not json}\n",
+		"no output or error": `{"recordId":"r1","modelInput":{}}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ReadBatchResults(strings.NewReader(output))
+			if err == nil || !strings.Contains(err.Error(), "bedrock.ReadBatchResults: line") {
+				t.Fatalf("err = %v, want a line error", err)
+			}
+		})
+	}
+}
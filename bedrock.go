@@ -26,11 +26,21 @@ package bedrock
 
 import (
 	"context"
+	"encoding/json"
+	"expvar"
 	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
@@ -41,14 +51,210 @@ import (
 
 // Bedrock provides configuration options for the AWS Bedrock plugin.
 type Bedrock struct {
-	Region         string        // AWS region override (optional; otherwise resolved by the AWS SDK)
-	MaxRetries     int           // Maximum number of retries (default: 3)
+	Region         string        // AWS region override, over AWSConfig's (optional; otherwise resolved from the environment)
+	MaxRetries     int           // AWS SDK maximum attempts per call, the first included (default: 3)
 	RequestTimeout time.Duration // Request timeout (default: 30s)
 	AWSConfig      *aws.Config   // Custom AWS config (optional)
+	ClampMaxTokens bool          // Clamp maxTokens to the model maximum instead of failing (default: false)
+	DefaultModel   string        // Chat model ID registered at Init as the plugin's primary model (optional)
+	CacheTools     bool          // Add a prompt cache point after tool definitions on models that support it (default: false)
+	// Credentials supplies AWS credentials from a custom source, such as
+	// Vault or an in-house SSO, for every client the plugin creates. It takes
+	// precedence over the default credential chain and over AWSConfig's
+	// credentials, and is wrapped in an aws.CredentialsCache so it is only
+	// called when the cached credentials near expiry. Default: nil.
+	Credentials aws.CredentialsProvider
+	// HTTPMaxIdleConnsPerHost caps the idle keep-alive connections kept per
+	// host by the plugin's HTTP client, so bursts of calls reuse connections
+	// instead of opening new ones. It and the other HTTP settings apply unless
+	// AWSConfig supplies an HTTPClient. Default: 100 (the AWS SDK's is 10).
+	HTTPMaxIdleConnsPerHost int
+	// HTTPIdleConnTimeout closes connections idle for this long. Default: 90s.
+	HTTPIdleConnTimeout time.Duration
+	// HTTPKeepAlive is the TCP keep-alive probe interval of the plugin's
+	// connections. Default: 30s.
+	HTTPKeepAlive time.Duration
+	// RetryMode selects the AWS SDK retryer: aws.RetryModeStandard, or
+	// aws.RetryModeAdaptive, which also rate-limits calls client-side after
+	// throttling. Like MaxRetries it is ignored when AWSConfig is set.
+	// Default: "" (the SDK's choice, standard unless AWS_RETRY_MODE or the
+	// shared config file says otherwise).
+	RetryMode aws.RetryMode
+	// ResolveModelVersions maps versionless model IDs passed to DefineModel
+	// or DefaultModel to the latest known version (see [ResolveModelID]).
+	// Exact IDs are always used as given. Default: false.
+	ResolveModelVersions bool
+	// ValidateJSONOutput checks responses to requests with JSON output and a
+	// schema against that schema, failing with *JSONValidationError.
+	ValidateJSONOutput bool
+	// RepromptInvalidJSON implies ValidateJSONOutput and, for non-streaming
+	// calls, retries once with the validation errors before failing. The
+	// retry is a second billed model call.
+	RepromptInvalidJSON bool
+	// StripUnsupportedTools drops tools (with a warning) from requests to
+	// models whose capabilities have Tools: false, instead of failing.
+	StripUnsupportedTools bool
+	// MaxToolRounds caps the consecutive tool-use rounds in one generation:
+	// a request whose history already holds that many model turns calling
+	// tools since the last user message fails with *ToolRoundLimitError
+	// before Bedrock is called. It backs up the agent loop's own limit.
+	// Default: 0 (no limit).
+	MaxToolRounds int
+	// DefaultProfilePrefix, e.g. "us.", is prepended to base model IDs
+	// passed to DefineModel or DefaultModel when the model is offered through
+	// cross-region inference profiles, so calls use the profile. IDs that
+	// already carry a profile prefix, and models without profiles, are called
+	// directly. Models stay registered under the name given. Default: "".
+	DefaultProfilePrefix string
+	// ExplicitProfileModels turns off capability inference for inference
+	// profile IDs such as "us.anthropic.claude-3-haiku-20240307-v1:0", which
+	// otherwise take the capabilities of their base model. Such models must
+	// be defined with DefineModel and a ModelInfo that sets Supports, used as
+	// given; DefaultModel, GenerateText and Warmup reject profile IDs not
+	// defined that way. Default: false.
+	ExplicitProfileModels bool
+	// APIModes forces the API used for the listed chat models, keyed by model
+	// ID (exact, or without its inference profile prefix), overriding the
+	// default routing: APIModeConverse skips the model's ProviderCodec, and
+	// APIModeInvoke calls InvokeModel with the registered codec (see
+	// [RegisterProviderCodec]) or a built-in one, such as [MistralChatCodec]
+	// for Mistral Large; other models fail. Default: models with a
+	// registered codec use InvokeModel, others Converse.
+	APIModes map[string]APIMode
+	// IncludeRoutingMetadata records on each generated response the AWS
+	// region that served it and, when the model ID is an inference profile,
+	// the profile (see [ServedBy]). Default: false.
+	IncludeRoutingMetadata bool
+	// StreamUsage sends the callback of a streaming call a chunk carrying the
+	// running token usage each time the stream reports it (see
+	// [ChunkUsage]). The response's Usage holds the final numbers.
+	// Default: false.
+	StreamUsage bool
+	// MaxToolResultBytes truncates each tool response sent to the model to
+	// this many bytes of text, marking the cut, so oversized results do not
+	// overflow the context. Its Output and text parts share the limit; media
+	// is kept. [Config.MaxToolResultBytes] overrides it per request.
+	// Default: 0 (no limit).
+	MaxToolResultBytes int
+	// StreamPartialToolInput sends the callback of a streaming call the input
+	// of each tool request as it arrives, completed best-effort into valid
+	// JSON and marked Partial, e.g. to render structured output forced
+	// through a tool progressively. The response holds the complete input.
+	// Default: false (tool requests are streamed once complete).
+	StreamPartialToolInput bool
+	// FamilyInferenceDefaults fills in inference settings a request leaves
+	// unset with defaults tuned per model family: temperature 1.0 for
+	// Anthropic models and 0.7 for others. A request's own values win.
+	// Default: false (unset fields are left to the model).
+	FamilyInferenceDefaults bool
+	// EmptyPromptUserTurn is sent as the user message of requests that have
+	// none, such as empty requests or ones with only a system prompt.
+	// Default: "" (such requests fail before calling Bedrock).
+	EmptyPromptUserTurn string
+	// ImagePreprocessing converts image inputs in formats Converse does not
+	// accept (such as BMP) to PNG, and scales down images over Converse's
+	// size limits (3.75 MB, 8000 pixels a side), before sending them.
+	// Default: false.
+	ImagePreprocessing bool
+	// RemoteMediaFetch downloads media parts given as https:// URLs and
+	// sends the bytes inline, since Converse accepts only inline media.
+	// Downloads run server-side, so restrict them with RemoteMediaHosts when
+	// URLs come from users. Default: false (URLs are rejected).
+	RemoteMediaFetch bool
+	// RemoteMediaHosts lists the hosts RemoteMediaFetch may download from,
+	// e.g. "cdn.example.com", including redirect targets. Default: any host.
+	RemoteMediaHosts []string
+	// RemoteMediaTimeout bounds each media download. Default: 10s.
+	RemoteMediaTimeout time.Duration
+	// RemoteMediaMaxBytes caps the size of each media download. Default: 25 MiB.
+	RemoteMediaMaxBytes int64
+	// MaxResponseBytes caps the body size of non-streaming Bedrock Runtime
+	// responses, such as generated images, failing larger ones with a
+	// [ResponseTooLargeError] before they are held in memory. Streaming
+	// responses are not capped. Default: 64 MiB; negative for no limit.
+	MaxResponseBytes int64
+	// ImageOutputS3 writes images from image models to this S3 location and
+	// returns their s3:// URIs as media parts instead of inline base64 data.
+	// Bedrock returns images inline, so the plugin uploads them with its
+	// own AWS credentials, which need s3:PutObject on the bucket. Modern
+	// Stability models (sd3, stable-image) keep returning images inline.
+	// Default: nil (images are returned inline).
+	ImageOutputS3 *S3Output
+	// ProvisionedModels routes chat models called through Converse to
+	// provisioned throughput, mapping model IDs (exact, or without their
+	// inference profile prefix) to provisioned model ARNs. Calls go to the
+	// ARN first and fall back to the on-demand model when the provisioned
+	// capacity is throttled, not ready, or not found, unless part of the
+	// response was already streamed. Init panics on empty ARNs.
+	// Default: nil (on-demand only).
+	ProvisionedModels map[string]string
+	// DiscoverModels lists the foundation models available in the region at
+	// Init (see [Bedrock.RefreshModels]). Models defined later that are
+	// outside the plugin's capability map then take their media support
+	// from the listing, and legacy chat models are marked as such. A failed
+	// listing is logged and leaves discovery empty. Default: false.
+	DiscoverModels bool
+	// ContentFilterAsError makes a chat response that the model's content
+	// filters or a guardrail blocked (finish reason "blocked") fail with a
+	// *[ContentFilteredError] carrying the response, instead of returning
+	// it. Default: false (blocked responses are returned as finished).
+	ContentFilterAsError bool
+	// CapabilitiesFile is the path of a JSON file of model capabilities that
+	// this plugin checks before the built-in capability map, so new models
+	// can be added, or known ones corrected, without a plugin release. The
+	// file is {"models": {"<base model ID>": {"tools": true,
+	// "maxOutputTokens": 8192, ...}}} with the fields of [ModelCapability] in
+	// camelCase; an entry replaces the built-in one for that model, as
+	// [Bedrock.Capabilities] reports. Other plugin instances and the
+	// package-level [Capabilities] are not affected. Init panics on an
+	// unreadable or invalid file. Default: "".
+	CapabilitiesFile string
+	// RequestHeaders are sent on every HTTP request the plugin makes to
+	// Bedrock (runtime, control plane and agent calls), e.g. for an API
+	// gateway or proxy in front of it. They are signed with the request.
+	// Init panics on invalid names and on headers the SDK or signer set
+	// (Authorization, Host, Content-Type, Content-Length, X-Amz-*).
+	// Default: nil.
+	RequestHeaders map[string]string
+	// EmbedDimensions sets the size of the vectors returned by embedding
+	// models, keyed by model ID. Only Titan Text Embeddings V2 is
+	// configurable (256, 512 or 1024); DefineEmbedder panics on other
+	// entries. The size is reported in the embedder's info (see
+	// [Bedrock.EmbedderDimensions]). Default: each model's own size.
+	EmbedDimensions map[string]int
+	// ClientNormalize scales embedding vectors to unit length in the plugin,
+	// so cosine similarity is consistent across models that do not normalize
+	// server-side (Titan V1, Titan multimodal, Cohere, Nova). Titan Text
+	// Embeddings V2 vectors are already normalized unless a request turns
+	// that off. [EmbedOptions.ClientNormalize] overrides it per request.
+	// Default: false.
+	ClientNormalize bool
+	// Metrics, when set, receives per-model counters for text generation
+	// calls: "requests", "errors", "input_tokens" and "output_tokens" maps
+	// keyed by model ID, and a "latency_ms" map of [LatencyHistogram]s.
+	// Publish it with expvar.NewMap to serve it on /debug/vars.
+	// Default: nil (no metrics).
+	Metrics *expvar.Map
 
-	mu      sync.Mutex // Mutex to control access
-	client  BedrockClient
-	initted bool // Whether the plugin has been initialized
+	mu             sync.Mutex // Mutex to control access
+	client         BedrockClient
+	batch          BatchClient
+	agents         AgentRuntimeClient
+	knowledgeBases knowledgeBaseClient
+	objects        objectUploader
+	lister         modelLister
+	profiles       profileLister
+	awsConfig      aws.Config               // Resolved at Init; the base for per-region clients
+	regionClients  map[string]BedrockClient // Per-request region overrides, built on first use
+	initted        bool                     // Whether the plugin has been initialized
+
+	mediaHTTPClient *http.Client // Client for RemoteMediaFetch; nil uses a default client
+
+	explicitModels map[string]bool // Profile IDs defined with a ModelInfo, for ExplicitProfileModels
+
+	capabilityOverrides map[string]ModelCapability // From CapabilitiesFile, checked before the built-in map
+
+	discovered atomic.Pointer[map[string]FoundationModel] // Last RefreshModels result, by model ID
 }
 
 // Name returns the provider name.
@@ -73,10 +279,55 @@ func (b *Bedrock) Init(ctx context.Context) []api.Action {
 	if b.RequestTimeout == 0 {
 		b.RequestTimeout = 30 * time.Second
 	}
+	if b.HTTPMaxIdleConnsPerHost == 0 {
+		b.HTTPMaxIdleConnsPerHost = defaultHTTPMaxIdleConnsPerHost
+	}
+	if b.HTTPIdleConnTimeout == 0 {
+		b.HTTPIdleConnTimeout = awshttp.DefaultHTTPTransportIdleConnTimeout
+	}
+	if b.HTTPKeepAlive == 0 {
+		b.HTTPKeepAlive = defaultHTTPKeepAlive
+	}
+	switch b.RetryMode {
+	case "", aws.RetryModeStandard, aws.RetryModeAdaptive:
+	default:
+		panic(fmt.Sprintf("bedrock: unknown RetryMode %q; use %q or %q", b.RetryMode, aws.RetryModeStandard, aws.RetryModeAdaptive))
+	}
+	for id, mode := range b.APIModes {
+		if mode != APIModeConverse && mode != APIModeInvoke {
+			panic(fmt.Sprintf("bedrock: unknown APIModes[%q] %q; use %q or %q", id, mode, APIModeConverse, APIModeInvoke))
+		}
+	}
+	for id, arn := range b.ProvisionedModels {
+		if arn == "" {
+			panic(fmt.Sprintf("bedrock: ProvisionedModels[%q] is empty; set a provisioned model ARN or remove the entry", id))
+		}
+	}
+	if err := checkRequestHeaders(b.RequestHeaders); err != nil {
+		panic(err.Error())
+	}
+	if b.ImageOutputS3 != nil && b.ImageOutputS3.Bucket == "" {
+		panic("bedrock: ImageOutputS3 requires a Bucket")
+	}
+	if b.CapabilitiesFile != "" {
+		caps, err := loadCapabilitiesFile(b.CapabilitiesFile)
+		if err != nil {
+			panic(err.Error())
+		}
+		b.capabilityOverrides = caps
+	}
+	if b.DefaultProfilePrefix != "" {
+		prefix := strings.TrimSuffix(b.DefaultProfilePrefix, ".") + "."
+		if known := profilePrefixes(); !slices.Contains(known, prefix) {
+			panic(fmt.Sprintf("bedrock: unknown DefaultProfilePrefix %q; use one of %s, or register it with RegisterProfilePrefix", b.DefaultProfilePrefix, strings.Join(known, ", ")))
+		}
+		b.DefaultProfilePrefix = prefix
+	}
 
 	// Load AWS configuration
 	var awsConfig aws.Config
 	var err error
+	var sharedRegion string
 
 	if b.AWSConfig != nil {
 		awsConfig = *b.AWSConfig
@@ -84,6 +335,9 @@ func (b *Bedrock) Init(ctx context.Context) []api.Action {
 		loadOptions := []func(*config.LoadOptions) error{
 			config.WithRetryMaxAttempts(b.MaxRetries),
 		}
+		if b.RetryMode != "" {
+			loadOptions = append(loadOptions, config.WithRetryMode(b.RetryMode))
+		}
 		if b.Region != "" {
 			loadOptions = append(loadOptions, config.WithRegion(b.Region))
 		}
@@ -93,6 +347,15 @@ func (b *Bedrock) Init(ctx context.Context) []api.Action {
 		if err != nil {
 			panic(fmt.Sprintf("bedrock: failed to load AWS config: %v", err))
 		}
+		sharedRegion = awsConfig.Region
+	}
+	awsConfig.Region, _ = resolveRegion(b.pluginRegionLayers(sharedRegion)...)
+
+	if b.Credentials != nil {
+		awsConfig.Credentials = cachedCredentials(b.Credentials)
+	}
+	if b.AWSConfig == nil || b.AWSConfig.HTTPClient == nil {
+		awsConfig.HTTPClient = b.httpClient()
 	}
 
 	if awsConfig.Region == "" {
@@ -100,11 +363,120 @@ func (b *Bedrock) Init(ctx context.Context) []api.Action {
 	}
 
 	// Create Bedrock Runtime client
-	b.client = bedrockruntime.NewFromConfig(awsConfig)
+	b.client = bedrockruntime.NewFromConfig(awsConfig, b.limitResponses, b.addRequestHeaders)
+	b.awsConfig = awsConfig
+	control := newControlPlaneClient(awsConfig)
+	control.headers = b.RequestHeaders
+	b.batch = control
+	b.lister = control
+	b.profiles = control
+	agents := newAgentRuntimeClient(awsConfig)
+	agents.rest.headers = b.RequestHeaders
+	b.agents = agents
+	b.knowledgeBases = agents
+	b.objects = newS3Client(awsConfig)
 
 	b.initted = true
 
-	return []api.Action{}
+	if b.DiscoverModels {
+		if err := b.refreshModels(ctx, b.lister, b.RequestTimeout); err != nil {
+			slog.Warn("bedrock: model discovery failed", "error", err)
+		}
+	}
+
+	actions := []api.Action{}
+	if b.DefaultModel != "" {
+		// Init has no *genkit.Genkit, so the default model is returned as an
+		// action for Genkit to register. Genkit applies its own default model
+		// after plugin Init; pair this with genkit.WithDefaultModel(b.DefaultModelName()).
+		model := ModelDefinition{Name: b.DefaultModel, Type: "chat"}
+		target := b.resolveModel(model)
+		if err := b.checkExplicitProfile(target.Name, nil); err != nil {
+			panic(err.Error())
+		}
+		m := ai.NewModel(api.NewName(provider, model.Name), b.modelOptions(target, nil), b.modelFunc(target))
+		actions = append(actions, m.(api.Action))
+	}
+	return actions
+}
+
+// Defaults for the plugin's HTTP client.
+const (
+	defaultHTTPMaxIdleConnsPerHost = 100
+	defaultHTTPKeepAlive           = 30 * time.Second
+)
+
+// httpClient returns the AWS SDK's HTTP client tuned with the plugin's HTTP
+// settings.
+func (b *Bedrock) httpClient() *awshttp.BuildableClient {
+	return awshttp.NewBuildableClient().
+		WithTransportOptions(func(tr *http.Transport) {
+			tr.MaxIdleConnsPerHost = b.HTTPMaxIdleConnsPerHost
+			tr.MaxIdleConns = max(tr.MaxIdleConns, b.HTTPMaxIdleConnsPerHost)
+			tr.IdleConnTimeout = b.HTTPIdleConnTimeout
+		}).
+		WithDialerOptions(func(d *net.Dialer) {
+			d.KeepAlive = b.HTTPKeepAlive
+		})
+}
+
+// cachedCredentials wraps provider in an aws.CredentialsCache unless it
+// already is one.
+func cachedCredentials(provider aws.CredentialsProvider) aws.CredentialsProvider {
+	if _, ok := provider.(*aws.CredentialsCache); ok {
+		return provider
+	}
+	return aws.NewCredentialsCache(provider)
+}
+
+// clientForRequest returns the Bedrock Runtime client for input: the plugin
+// client, or a cached client for [Config.Region] when it names another
+// region.
+func (b *Bedrock) clientForRequest(input *ai.ModelRequest) (BedrockClient, error) {
+	cfg, err := configFromRequest(input)
+	if err != nil {
+		return nil, err
+	}
+	if cfg == nil || cfg.Region == "" {
+		return b.client, nil
+	}
+	return b.clientForRegion(cfg.Region)
+}
+
+// regionPattern matches AWS region names such as "us-east-1",
+// "ap-southeast-2" or "us-gov-west-1".
+var regionPattern = regexp.MustCompile(`^[a-z]{2}(?:-[a-z]+)+-\d{1,2}$`)
+
+// maxRegionClients bounds the per-region client cache; real deployments use
+// a handful of regions.
+const maxRegionClients = 32
+
+// clientForRegion returns a client for region, building and caching one
+// from the plugin's AWS config on first use. Region comes from request
+// config, so it is validated before a client is built and kept.
+func (b *Bedrock) clientForRegion(region string) (BedrockClient, error) {
+	if !regionPattern.MatchString(region) {
+		return nil, fmt.Errorf("bedrock: invalid region %q in request config", region)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if region == b.awsConfig.Region && b.client != nil {
+		return b.client, nil
+	}
+	if client, ok := b.regionClients[region]; ok {
+		return client, nil
+	}
+	if len(b.regionClients) >= maxRegionClients {
+		return nil, fmt.Errorf("bedrock: region %q exceeds the limit of %d regional clients", region, maxRegionClients)
+	}
+	client := bedrockruntime.NewFromConfig(b.awsConfig, b.limitResponses, b.addRequestHeaders, func(o *bedrockruntime.Options) {
+		o.Region = region
+	})
+	if b.regionClients == nil {
+		b.regionClients = map[string]BedrockClient{}
+	}
+	b.regionClients[region] = client
+	return client, nil
 }
 
 func (b *Bedrock) withRequestTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
@@ -123,6 +495,8 @@ func withRequestTimeout(ctx context.Context, timeout time.Duration) (context.Con
 
 // DefineModel defines a model in the registry.
 // This follows the same pattern as the Anthropic plugin's DefineModel method.
+// If the model is already registered, e.g. as [Bedrock.DefaultModel], the
+// existing model is returned and info is ignored.
 func (b *Bedrock) DefineModel(g *genkit.Genkit, model ModelDefinition, info *ai.ModelInfo) ai.Model {
 	b.mu.Lock()
 	defer b.mu.Unlock()
@@ -131,12 +505,78 @@ func (b *Bedrock) DefineModel(g *genkit.Genkit, model ModelDefinition, info *ai.
 		panic("bedrock: Init not called")
 	}
 
+	if existing := genkit.LookupModel(g, api.NewName(provider, model.Name)); existing != nil {
+		return existing
+	}
+	target := b.resolveModel(model)
+	if err := b.checkExplicitProfile(target.Name, info); err != nil {
+		panic(err.Error())
+	}
+	if b.ExplicitProfileModels && isProfileModelID(target.Name) {
+		if b.explicitModels == nil {
+			b.explicitModels = map[string]bool{}
+		}
+		b.explicitModels[target.Name] = true
+	}
+	return genkit.DefineModel(g, api.NewName(provider, model.Name), b.modelOptions(target, info), b.modelFunc(target))
+}
+
+// checkExplicitProfile enforces ExplicitProfileModels for a model being
+// defined with info: a profile ID needs a ModelInfo that sets Supports.
+func (b *Bedrock) checkExplicitProfile(name string, info *ai.ModelInfo) error {
+	if !b.ExplicitProfileModels || !isProfileModelID(name) || (info != nil && info.Supports != nil) {
+		return nil
+	}
+	return fmt.Errorf("bedrock: model %q is an inference profile and ExplicitProfileModels is set; it must be registered with DefineModel and a ModelInfo that sets Supports", name)
+}
+
+// resolveModel returns the definition to invoke for model: the same one, or
+// with a versionless ID resolved when ResolveModelVersions is set and the
+// DefaultProfilePrefix applied. The model stays registered under the name the
+// caller gave.
+func (b *Bedrock) resolveModel(model ModelDefinition) ModelDefinition {
+	if model.Type != "" && model.Type != "chat" && model.Type != "text" {
+		return model
+	}
+	name, err := b.resolveModelID(model.Name)
+	if err != nil {
+		panic(err.Error())
+	}
+	model.Name = name
+	return model
+}
+
+// resolveModelID applies ResolveModelVersions and DefaultProfilePrefix to a
+// chat or text model ID.
+func (b *Bedrock) resolveModelID(name string) (string, error) {
+	if b.ResolveModelVersions {
+		id, err := resolveModelVersion(name, b.capabilityModelIDs())
+		if err != nil {
+			return "", err
+		}
+		name = id
+	}
+	if b.DefaultProfilePrefix != "" && baseModelID(name) == name {
+		if caps, ok := b.modelCapability(name); ok && caps.Profile {
+			name = b.DefaultProfilePrefix + name
+		}
+	}
+	return name, nil
+}
+
+// modelOptions builds the registered model metadata, inferring capabilities
+// for anything info leaves unset.
+func (b *Bedrock) modelOptions(model ModelDefinition, info *ai.ModelInfo) *ai.ModelOptions {
 	providedInfo := info != nil
 
-	// Auto-detect model capabilities if not provided
-	if info == nil {
+	// Auto-detect model capabilities if not provided. With
+	// ExplicitProfileModels, a profile model's info, which
+	// checkExplicitProfile requires, is used exactly as given.
+	switch {
+	case b.ExplicitProfileModels && isProfileModelID(model.Name):
+	case info == nil:
 		info = b.inferModelCapabilities(model.Name, model.Type)
-	} else {
+	default:
 		inferred := b.inferModelCapabilities(model.Name, model.Type)
 		copyInfo := *info
 		if copyInfo.Supports == nil {
@@ -157,35 +597,115 @@ func (b *Bedrock) DefineModel(g *genkit.Genkit, model ModelDefinition, info *ai.
 	}
 
 	// Create model metadata
-	meta := &ai.ModelOptions{
+	return &ai.ModelOptions{
 		Label:        label,
 		Supports:     info.Supports,
 		Stage:        info.Stage,
 		Versions:     info.Versions,
 		ConfigSchema: configSchemaMap,
 	}
+}
 
-	// Create the model function based on model type
+// modelFunc returns the generation function for the model's type.
+func (b *Bedrock) modelFunc(model ModelDefinition) ai.ModelFunc {
 	switch model.Type {
 	case "image":
-		return genkit.DefineModel(g, api.NewName(provider, model.Name), meta, func(
+		return func(
 			ctx context.Context,
 			input *ai.ModelRequest,
 			cb func(context.Context, *ai.ModelResponseChunk) error,
 		) (*ai.ModelResponse, error) {
 			return b.generateImage(ctx, model.Name, input, cb)
-		})
+		}
 	default:
-		return genkit.DefineModel(g, api.NewName(provider, model.Name), meta, func(
+		return func(
 			ctx context.Context,
 			input *ai.ModelRequest,
 			cb func(context.Context, *ai.ModelResponseChunk) error,
 		) (*ai.ModelResponse, error) {
 			return b.generateText(ctx, model.Name, input, cb)
-		})
+		}
 	}
 }
 
+// DefaultModelName returns the fully qualified Genkit name ("bedrock/<id>") of
+// [Bedrock.DefaultModel], suitable for [genkit.WithDefaultModel], or "" when no
+// default model is configured.
+func (b *Bedrock) DefaultModelName() string {
+	if b == nil || b.DefaultModel == "" {
+		return ""
+	}
+	return api.NewName(provider, b.DefaultModel)
+}
+
+// GenerateText sends prompt to modelID as a single user message and returns
+// the response text. It is a shortcut for scripts that need neither a Genkit
+// registry nor message building; cfg optionally sets generation options such
+// as Temperature and MaxTokens (pass at most one). The model ID is resolved
+// like a defined model's, and the call does not run Genkit middleware.
+func (b *Bedrock) GenerateText(ctx context.Context, modelID, prompt string, cfg ...*Config) (string, error) {
+	if len(cfg) > 1 {
+		return "", fmt.Errorf("bedrock.GenerateText: got %d configs, want at most one", len(cfg))
+	}
+	var c *Config
+	if len(cfg) == 1 {
+		c = cfg[0]
+	}
+	resp, err := b.generatePrompt(ctx, "bedrock.GenerateText", modelID, prompt, c)
+	if err != nil {
+		return "", err
+	}
+	return resp.Text(), nil
+}
+
+// Warmup primes modelID, typically a provisioned throughput model ARN, with a
+// one-token generation so the first real request does not pay the cold-start
+// latency. It is safe to call repeatedly, e.g. from deployment hooks.
+func (b *Bedrock) Warmup(ctx context.Context, modelID string) error {
+	_, err := b.generatePrompt(ctx, "bedrock.Warmup", modelID, "Hi", &Config{MaxTokens: 1})
+	return err
+}
+
+// generatePrompt sends prompt to modelID as a single user message. op
+// prefixes validation errors.
+func (b *Bedrock) generatePrompt(ctx context.Context, op, modelID, prompt string, cfg *Config) (*ai.ModelResponse, error) {
+	req := &ai.ModelRequest{Messages: []*ai.Message{ai.NewUserTextMessage(prompt)}}
+	if cfg != nil {
+		req.Config = cfg
+	}
+	return b.generateRequest(ctx, op, modelID, req)
+}
+
+// generateRequest sends req to modelID, resolved like a defined model's. op
+// prefixes validation errors.
+func (b *Bedrock) generateRequest(ctx context.Context, op, modelID string, req *ai.ModelRequest) (*ai.ModelResponse, error) {
+	if b == nil {
+		return nil, fmt.Errorf("%s: plugin required", op)
+	}
+	b.mu.Lock()
+	initted := b.initted
+	b.mu.Unlock()
+	if !initted {
+		return nil, fmt.Errorf("%s: plugin not initialized", op)
+	}
+	if modelID == "" {
+		return nil, fmt.Errorf("%s: model ID required", op)
+	}
+	name, err := b.resolveModelID(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if b.ExplicitProfileModels && isProfileModelID(name) {
+		b.mu.Lock()
+		defined := b.explicitModels[name]
+		b.mu.Unlock()
+		if !defined {
+			return nil, fmt.Errorf("%s: model %q is an inference profile and ExplicitProfileModels is set; it must be registered with DefineModel and a ModelInfo first", op, name)
+		}
+	}
+	return b.generateText(ctx, name, req, nil)
+}
+
 // DefineEmbedder defines an embedder in the registry.
 func (b *Bedrock) DefineEmbedder(g *genkit.Genkit, modelName string) ai.Embedder {
 	b.mu.Lock()
@@ -194,8 +714,13 @@ func (b *Bedrock) DefineEmbedder(g *genkit.Genkit, modelName string) ai.Embedder
 	if !b.initted {
 		panic("bedrock: Init not called")
 	}
+	if err := b.checkEmbedDimensions(modelName); err != nil {
+		panic(err.Error())
+	}
 
-	return genkit.DefineEmbedder(g, api.NewName(provider, modelName), nil, func(
+	name := api.NewName(provider, modelName)
+	opts := &ai.EmbedderOptions{Label: name, Dimensions: b.EmbedderDimensions(modelName)}
+	return genkit.DefineEmbedder(g, name, opts, func(
 		ctx context.Context,
 		req *ai.EmbedRequest,
 	) (*ai.EmbedResponse, error) {
@@ -213,6 +738,22 @@ func Model(g *genkit.Genkit, name string) ai.Model {
 	return genkit.LookupModel(g, api.NewName(provider, name))
 }
 
+// Capabilities returns the curated capabilities of a Bedrock model ID, which
+// may carry an inference profile prefix. ok is false for models the plugin
+// has no capability entry for. Entries from a plugin's
+// [Bedrock.CapabilitiesFile] are not included; use [Bedrock.Capabilities] to
+// see the capabilities that plugin enforces.
+func Capabilities(modelID string) (caps ModelCapability, ok bool) {
+	return lookupModelCapability(modelID)
+}
+
+// Capabilities returns the capabilities the plugin enforces for a Bedrock
+// model ID: its CapabilitiesFile entry when it has one, else the built-in
+// entry reported by the package-level [Capabilities].
+func (b *Bedrock) Capabilities(modelID string) (caps ModelCapability, ok bool) {
+	return b.modelCapability(modelID)
+}
+
 // DefineCommonModels is a helper to define commonly used models
 func DefineCommonModels(b *Bedrock, g *genkit.Genkit) map[string]ai.Model {
 	models := make(map[string]ai.Model)
@@ -275,6 +816,16 @@ func DefineCommonModels(b *Bedrock, g *genkit.Genkit) map[string]ai.Model {
 		Type: "chat",
 	}, nil)
 	models["titan-text"] = titanText
+	titanTextExpress := b.DefineModel(g, ModelDefinition{
+		Name: "amazon.titan-text-express-v1",
+		Type: "chat",
+	}, nil)
+	models["titan-text-express"] = titanTextExpress
+	titanTextLite := b.DefineModel(g, ModelDefinition{
+		Name: "amazon.titan-text-lite-v1",
+		Type: "chat",
+	}, nil)
+	models["titan-text-lite"] = titanTextLite
 
 	// Meta Llama models
 	llama3_8b := b.DefineModel(g, ModelDefinition{
@@ -378,3 +929,205 @@ func CachePointType(part *ai.Part) (types.CachePointType, bool) {
 	cpt, ok := cachePointTypeVal.(types.CachePointType)
 	return cpt, ok
 }
+
+// NewToolErrorPart returns a tool response part reporting that the tool call
+// identified by ref failed with err. Converse receives it as a tool result
+// with status "error", so the model knows the tool did not run successfully
+// and can retry or explain instead of trusting the output.
+func NewToolErrorPart(ref, name string, err error) *ai.Part {
+	part := ai.NewToolResponsePart(&ai.ToolResponse{
+		Ref:    ref,
+		Name:   name,
+		Output: map[string]any{"error": err.Error()},
+	})
+	part.Metadata = map[string]any{toolErrorMetadataKey: true}
+	return part
+}
+
+// IsToolError reports whether part is a tool response flagged as a failed
+// tool call, either by [NewToolErrorPart] or by setting Metadata["toolError"]
+// to true.
+func IsToolError(part *ai.Part) bool {
+	if part == nil || !part.IsToolResponse() {
+		return false
+	}
+	failed, _ := part.Metadata[toolErrorMetadataKey].(bool)
+	return failed
+}
+
+// ToolUseBlock returns the position of the toolUse block a tool request part
+// came from in the Converse response content, and its toolUseId. ok is false
+// for parts without that metadata. The metadata survives JSON round trips.
+func ToolUseBlock(part *ai.Part) (index int, toolUseID string, ok bool) {
+	if part == nil || !part.IsToolRequest() {
+		return 0, "", false
+	}
+	switch v := part.Metadata[toolUseIndexMetadataKey].(type) {
+	case int:
+		index = v
+	case float64:
+		index = int(v)
+	default:
+		return 0, "", false
+	}
+	toolUseID, _ = part.Metadata[toolUseIDMetadataKey].(string)
+	return index, toolUseID, true
+}
+
+// ServedBy returns the AWS region and inference profile recorded on resp when
+// [Bedrock.IncludeRoutingMetadata] is set. profile is "" for calls made with
+// a plain model ID. ok is false when resp carries no routing metadata.
+func ServedBy(resp *ai.ModelResponse) (region, profile string, ok bool) {
+	if resp == nil || resp.Message == nil {
+		return "", "", false
+	}
+	region, ok = resp.Message.Metadata[regionMetadataKey].(string)
+	profile, _ = resp.Message.Metadata[inferenceProfileMetadataKey].(string)
+	return region, profile, ok
+}
+
+// ServerLatency returns the server-side latency Bedrock reported for resp
+// (Converse metrics.latencyMs), as distinct from the client-measured
+// [ai.ModelResponse.LatencyMs]. ok is false when the response carries none.
+func ServerLatency(resp *ai.ModelResponse) (latency time.Duration, ok bool) {
+	if resp == nil || resp.Message == nil {
+		return 0, false
+	}
+	switch v := resp.Message.Metadata[serverLatencyMetadataKey].(type) {
+	case int64:
+		return time.Duration(v) * time.Millisecond, true
+	case float64: // JSON round-trip
+		return time.Duration(v * float64(time.Millisecond)), true
+	}
+	return 0, false
+}
+
+// RawStopReason returns the Converse stopReason of resp when it is one this
+// plugin does not recognize, such as a reason Bedrock added after this
+// release. The finish reason of such responses is "other". ok is false for
+// known stop reasons.
+func RawStopReason(resp *ai.ModelResponse) (stopReason string, ok bool) {
+	if resp == nil || resp.Message == nil {
+		return "", false
+	}
+	stopReason, ok = resp.Message.Metadata[rawStopReasonMetadataKey].(string)
+	return stopReason, ok
+}
+
+// Truncated reports whether resp stopped because it reached the output token
+// limit, so the caller may want to continue it with [ContinueGeneration].
+func Truncated(resp *ai.ModelResponse) bool {
+	if resp == nil || resp.Message == nil {
+		return false
+	}
+	truncated, _ := resp.Message.Metadata[truncatedMetadataKey].(bool)
+	return truncated
+}
+
+// CacheHit reports whether part of resp's input was read from the prompt
+// cache (cacheReadInputTokens > 0), for tracking cache effectiveness.
+func CacheHit(resp *ai.ModelResponse) bool {
+	if resp == nil || resp.Message == nil {
+		return false
+	}
+	hit, _ := resp.Message.Metadata[cacheHitMetadataKey].(bool)
+	return hit
+}
+
+// StopReason returns Bedrock's stop reason for resp, such as "end_turn",
+// "tool_use" or "max_tokens", which the response's FinishReason generalizes.
+// It is "" when Bedrock reported none.
+func StopReason(resp *ai.ModelResponse) string {
+	if resp == nil || resp.Message == nil {
+		return ""
+	}
+	stopReason, _ := resp.Message.Metadata[stopReasonMetadataKey].(string)
+	return stopReason
+}
+
+// StoppedForToolUse reports whether the model stopped to call tools, so the
+// caller should run the requested tools and continue the conversation, rather
+// than because it finished. Both finish with [ai.FinishReasonStop].
+func StoppedForToolUse(resp *ai.ModelResponse) bool {
+	return StopReason(resp) == string(types.StopReasonToolUse)
+}
+
+// Logprobs returns the generated tokens and their log probabilities, recorded
+// when the request set [Config.Logprobs], or nil. It also reads metadata that
+// went through a JSON round trip.
+func Logprobs(resp *ai.ModelResponse) []TokenLogprob {
+	if resp == nil || resp.Message == nil {
+		return nil
+	}
+	switch v := resp.Message.Metadata[logprobsMetadataKey].(type) {
+	case []TokenLogprob:
+		return v
+	case []any:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil
+		}
+		var out []TokenLogprob
+		if err := json.Unmarshal(raw, &out); err != nil {
+			return nil
+		}
+		return out
+	}
+	return nil
+}
+
+// ChunkUsage returns the running token usage a stream chunk carries when
+// [Bedrock.StreamUsage] is set. ok is false for chunks without usage.
+func ChunkUsage(chunk *ai.ModelResponseChunk) (usage *ai.GenerationUsage, ok bool) {
+	if chunk == nil {
+		return nil, false
+	}
+	custom, _ := chunk.Custom.(map[string]any)
+	switch v := custom[usageChunkKey].(type) {
+	case *ai.GenerationUsage:
+		return v, v != nil
+	case map[string]any:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil, false
+		}
+		var out ai.GenerationUsage
+		if err := json.Unmarshal(raw, &out); err != nil {
+			return nil, false
+		}
+		return &out, true
+	}
+	return nil, false
+}
+
+// AgentTrace returns the trace steps of an [InvokeAgent] response, or nil.
+func AgentTrace(resp *ai.ModelResponse) []AgentTraceStep {
+	if resp == nil || resp.Message == nil {
+		return nil
+	}
+	switch v := resp.Message.Metadata[agentTraceMetadataKey].(type) {
+	case []AgentTraceStep:
+		return v
+	case []any:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil
+		}
+		var out []AgentTraceStep
+		if err := json.Unmarshal(raw, &out); err != nil {
+			return nil
+		}
+		return out
+	}
+	return nil
+}
+
+// AdditionalResponseFields returns the provider-specific fields the model
+// returned in Converse additionalModelResponseFields, decoded as JSON, or nil.
+func AdditionalResponseFields(resp *ai.ModelResponse) map[string]any {
+	if resp == nil || resp.Message == nil {
+		return nil
+	}
+	fields, _ := resp.Message.Metadata[additionalFieldsMetadataKey].(map[string]any)
+	return fields
+}
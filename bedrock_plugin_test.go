@@ -20,7 +20,10 @@ package bedrock
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -29,6 +32,8 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
 	"github.com/firebase/genkit/go/ai"
@@ -240,6 +245,50 @@ func TestInferenceProfilePrefixes_Coverage(t *testing.T) {
 	}
 }
 
+func TestRegisterProfilePrefix(t *testing.T) {
+	saved := profilePrefixes()
+	t.Cleanup(func() {
+		inferenceProfilePrefixesMu.Lock()
+		inferenceProfilePrefixes = saved
+		inferenceProfilePrefixesMu.Unlock()
+	})
+
+	const model = "ca.anthropic.claude-3-5-sonnet-20241022-v2:0"
+	if got := baseModelID(model); got != model {
+		t.Fatalf("baseModelID before registration = %q, want unchanged", got)
+	}
+
+	RegisterProfilePrefix("ca")
+	RegisterProfilePrefix("ca.") // no-op
+	if n := len(profilePrefixes()); n != len(saved)+1 {
+		t.Errorf("registered %d prefixes, want %d", n-len(saved), 1)
+	}
+	if got := baseModelID(model); got != "anthropic.claude-3-5-sonnet-20241022-v2:0" {
+		t.Errorf("baseModelID = %q, want the prefix stripped", got)
+	}
+	info := (&Bedrock{}).inferModelCapabilities(model, "chat")
+	if info.Supports == nil || !info.Supports.Media || !info.Supports.Tools {
+		t.Errorf("inferModelCapabilities(%q).Supports = %+v, want media and tools", model, info.Supports)
+	}
+	if info.Label != model {
+		t.Errorf("Label = %q, want %q", info.Label, model)
+	}
+	if got := inferenceProfileID(model); got != model {
+		t.Errorf("inferenceProfileID = %q, want %q", got, model)
+	}
+
+	b := testInitializedBedrock()
+	b.DefaultProfilePrefix = "ca"
+	b.Init(context.Background())
+	if b.DefaultProfilePrefix != "ca." {
+		t.Errorf("DefaultProfilePrefix = %q, want ca.", b.DefaultProfilePrefix)
+	}
+
+	for _, bad := range []string{"", ".", "CA.", "c a.", "ca..", "1ca."} {
+		assertPanicsContains(t, "invalid inference profile prefix", func() { RegisterProfilePrefix(bad) })
+	}
+}
+
 func TestDefineModelRequiresInitializedPluginInstance(t *testing.T) {
 	ctx := context.Background()
 	b := &Bedrock{
@@ -290,6 +339,32 @@ func TestDefineEmbedderRequiresInitializedPluginInstance(t *testing.T) {
 	})
 }
 
+func TestDefineEmbedderReportsDimensions(t *testing.T) {
+	ctx := context.Background()
+	b := testInitializedBedrock()
+	b.EmbedDimensions = map[string]int{"amazon.titan-embed-text-v2:0": 512}
+	g := genkit.Init(ctx, genkit.WithPlugins(b))
+
+	for model, want := range map[string]int{
+		"amazon.titan-embed-text-v2:0": 512,
+		"cohere.embed-english-v3":      1024,
+	} {
+		action, ok := b.DefineEmbedder(g, model).(api.Action)
+		if !ok {
+			t.Fatalf("embedder for %s is not an api.Action", model)
+		}
+		info, _ := action.Desc().Metadata["info"].(map[string]any)
+		if got := info["dimensions"]; got != want {
+			t.Errorf("%s: info dimensions = %v, want %d", model, got, want)
+		}
+	}
+
+	b.EmbedDimensions["cohere.embed-multilingual-v3"] = 256
+	assertPanicsContains(t, "not configurable", func() {
+		b.DefineEmbedder(g, "cohere.embed-multilingual-v3")
+	})
+}
+
 func TestModelLookupHelpers(t *testing.T) {
 	ctx := context.Background()
 	b := testInitializedBedrock()
@@ -315,6 +390,66 @@ func TestModelLookupHelpers(t *testing.T) {
 	}
 }
 
+func TestInitRegistersDefaultModelUsedWithoutExplicitModel(t *testing.T) {
+	var gotPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.EscapedPath()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"output":{"message":{"role":"assistant","content":[{"text":"hi"}]}},"stopReason":"end_turn"}`)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	b := testInitializedBedrock()
+	b.AWSConfig.HTTPClient = server.Client()
+	b.AWSConfig.BaseEndpoint = aws.String(server.URL)
+	b.DefaultModel = "amazon.nova-lite-v1:0"
+	g := genkit.Init(ctx, genkit.WithPlugins(b), genkit.WithDefaultModel(b.DefaultModelName()))
+
+	if !IsDefinedModel(g, "amazon.nova-lite-v1:0") {
+		t.Fatal("default model was not registered at Init")
+	}
+	resp, err := genkit.Generate(ctx, g, ai.WithPrompt("hello"))
+	if err != nil {
+		t.Fatalf("Generate without model: %v", err)
+	}
+	if resp.Text() != "hi" {
+		t.Fatalf("Text() = %q, want hi", resp.Text())
+	}
+	if !strings.Contains(gotPath, "/model/amazon.nova-lite-v1%3A0/converse") {
+		t.Fatalf("path = %q, want default model converse path", gotPath)
+	}
+}
+
+func TestDefineModelReturnsRegisteredDefaultModel(t *testing.T) {
+	ctx := context.Background()
+	b := testInitializedBedrock()
+	b.DefaultModel = "amazon.nova-lite-v1:0"
+	g := genkit.Init(ctx, genkit.WithPlugins(b), genkit.WithDefaultModel(b.DefaultModelName()))
+
+	if Model(g, "amazon.nova-lite-v1:0") == nil {
+		t.Fatal("default model was not registered at Init")
+	}
+	// Defining the same ID again must not panic with "already registered".
+	got := b.DefineModel(g, ModelDefinition{Name: "amazon.nova-lite-v1:0", Type: "chat"}, nil)
+	if got == nil || got.Name() != b.DefaultModelName() {
+		t.Fatalf("DefineModel returned %v, want the registered default model", got)
+	}
+	models := DefineCommonModels(b, g)
+	if m := models["nova-lite"]; m == nil || m.Name() != b.DefaultModelName() {
+		t.Fatalf("DefineCommonModels nova-lite = %v, want the registered default model", m)
+	}
+}
+
+func TestDefaultModelNameUnset(t *testing.T) {
+	if got := (&Bedrock{}).DefaultModelName(); got != "" {
+		t.Fatalf("DefaultModelName() = %q, want empty", got)
+	}
+	if got := (&Bedrock{DefaultModel: "amazon.nova-micro-v1:0"}).DefaultModelName(); got != "bedrock/amazon.nova-micro-v1:0" {
+		t.Fatalf("DefaultModelName() = %q", got)
+	}
+}
+
 func TestDefineCommonModelsRegistersExpectedAliases(t *testing.T) {
 	ctx := context.Background()
 	b := testInitializedBedrock()
@@ -331,6 +466,8 @@ func TestDefineCommonModelsRegistersExpectedAliases(t *testing.T) {
 		"nova-lite",
 		"nova-pro",
 		"titan-text",
+		"titan-text-express",
+		"titan-text-lite",
 		"llama3-8b",
 		"llama3-1-8b",
 		"llama3-2-3b",
@@ -426,6 +563,160 @@ func TestInitUsesSDKRegionChain(t *testing.T) {
 	}
 }
 
+func TestInitAppliesSDKRetryer(t *testing.T) {
+	isolateAWSConfig(t)
+
+	b := &Bedrock{Region: "us-east-1", RetryMode: aws.RetryModeAdaptive, MaxRetries: 5}
+	b.Init(context.Background())
+
+	if b.awsConfig.RetryMode != aws.RetryModeAdaptive || b.awsConfig.RetryMaxAttempts != 5 {
+		t.Errorf("AWS config retry mode = %q, max attempts = %d; want adaptive, 5", b.awsConfig.RetryMode, b.awsConfig.RetryMaxAttempts)
+	}
+	opts := b.client.Options()
+	if opts.RetryMode != aws.RetryModeAdaptive {
+		t.Errorf("runtime client retry mode = %q, want adaptive", opts.RetryMode)
+	}
+	if got := opts.Retryer.MaxAttempts(); got != 5 {
+		t.Errorf("runtime client max attempts = %d, want 5", got)
+	}
+	control := b.batch.(*controlPlaneClient).retryer()
+	if _, ok := control.(*retry.AdaptiveMode); !ok || control.MaxAttempts() != 5 {
+		t.Errorf("control-plane retryer = %T with %d attempts, want adaptive with 5", control, control.MaxAttempts())
+	}
+}
+
+func TestInitPanicsOnUnknownRetryMode(t *testing.T) {
+	isolateAWSConfig(t)
+
+	assertPanicsContains(t, `unknown RetryMode "fast"`, func() {
+		(&Bedrock{Region: "us-east-1", RetryMode: "fast"}).Init(context.Background())
+	})
+}
+
+func TestInitTunesHTTPClient(t *testing.T) {
+	isolateAWSConfig(t)
+
+	transport := func(t *testing.T, b *Bedrock) (*http.Transport, *awshttp.BuildableClient) {
+		t.Helper()
+		client, ok := b.awsConfig.HTTPClient.(*awshttp.BuildableClient)
+		if !ok {
+			t.Fatalf("HTTP client = %T, want *awshttp.BuildableClient", b.awsConfig.HTTPClient)
+		}
+		return client.GetTransport(), client
+	}
+
+	b := &Bedrock{Region: "us-east-1"}
+	b.Init(context.Background())
+	tr, client := transport(t, b)
+	if tr.MaxIdleConnsPerHost != 100 || tr.IdleConnTimeout != 90*time.Second || client.GetDialer().KeepAlive != 30*time.Second {
+		t.Errorf("default transport: max idle per host %d, idle timeout %v, keep-alive %v; want 100, 90s, 30s",
+			tr.MaxIdleConnsPerHost, tr.IdleConnTimeout, client.GetDialer().KeepAlive)
+	}
+	if b.client.Options().HTTPClient != b.awsConfig.HTTPClient {
+		t.Error("runtime client does not use the tuned HTTP client")
+	}
+
+	b = &Bedrock{
+		AWSConfig:               testInitializedBedrock().AWSConfig,
+		HTTPMaxIdleConnsPerHost: 256,
+		HTTPIdleConnTimeout:     time.Minute,
+		HTTPKeepAlive:           15 * time.Second,
+	}
+	b.Init(context.Background())
+	tr, client = transport(t, b)
+	if tr.MaxIdleConnsPerHost != 256 || tr.MaxIdleConns < 256 || tr.IdleConnTimeout != time.Minute || client.GetDialer().KeepAlive != 15*time.Second {
+		t.Errorf("configured transport: max idle per host %d (total %d), idle timeout %v, keep-alive %v; want 256, 1m, 15s",
+			tr.MaxIdleConnsPerHost, tr.MaxIdleConns, tr.IdleConnTimeout, client.GetDialer().KeepAlive)
+	}
+
+	custom := &http.Client{}
+	cfg := testInitializedBedrock().AWSConfig
+	cfg.HTTPClient = custom
+	b = &Bedrock{AWSConfig: cfg, HTTPMaxIdleConnsPerHost: 256}
+	b.Init(context.Background())
+	if b.awsConfig.HTTPClient != custom {
+		t.Errorf("HTTP client = %T, want the AWSConfig's own client", b.awsConfig.HTTPClient)
+	}
+}
+
+// countingCredentials returns fixed credentials valid for an hour and counts
+// how often it is asked for them.
+type countingCredentials struct {
+	calls int
+}
+
+func (c *countingCredentials) Retrieve(context.Context) (aws.Credentials, error) {
+	c.calls++
+	return aws.Credentials{
+		AccessKeyID:     "AKIDVAULT",
+		SecretAccessKey: "secret",
+		CanExpire:       true,
+		Expires:         time.Now().Add(time.Hour),
+	}, nil
+}
+
+func TestInitUsesCredentialsProvider(t *testing.T) {
+	var auths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auths = append(auths, r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"output":{"message":{"role":"assistant","content":[{"text":"ok"}]}},"stopReason":"end_turn"}`))
+	}))
+	defer server.Close()
+
+	provider := &countingCredentials{}
+	b := &Bedrock{
+		Credentials: provider,
+		AWSConfig: &aws.Config{
+			Region:       "us-east-1",
+			Credentials:  credentials.NewStaticCredentialsProvider("AKIDCONFIG", "SECRET", ""),
+			HTTPClient:   server.Client(),
+			BaseEndpoint: aws.String(server.URL),
+		},
+	}
+	b.Init(context.Background())
+
+	req := &ai.ModelRequest{Messages: []*ai.Message{ai.NewUserTextMessage("hi")}}
+	for range 2 {
+		if _, err := b.generateText(context.Background(), "amazon.nova-lite-v1:0", req, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, auth := range auths {
+		if !strings.Contains(auth, "Credential=AKIDVAULT/") {
+			t.Errorf("Authorization = %q, want the provider's access key", auth)
+		}
+	}
+	if provider.calls != 1 {
+		t.Errorf("provider called %d times, want 1 (cached)", provider.calls)
+	}
+	if _, ok := b.awsConfig.Credentials.(*aws.CredentialsCache); !ok {
+		t.Errorf("credentials = %T, want *aws.CredentialsCache", b.awsConfig.Credentials)
+	}
+	if b.batch.(*controlPlaneClient).cfg.Credentials != b.awsConfig.Credentials ||
+		b.agents.(*agentRuntimeClient).rest.cfg.Credentials != b.awsConfig.Credentials {
+		t.Error("control-plane and agent clients do not share the provider")
+	}
+}
+
+func TestInitCredentialsProviderOverridesDefaultChain(t *testing.T) {
+	isolateAWSConfig(t)
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDENV")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "SECRET")
+
+	cache := aws.NewCredentialsCache(&countingCredentials{})
+	b := &Bedrock{Region: "us-east-1", Credentials: cache}
+	b.Init(context.Background())
+
+	if b.awsConfig.Credentials != aws.CredentialsProvider(cache) {
+		t.Errorf("credentials = %T, want the given cache used as is", b.awsConfig.Credentials)
+	}
+	creds, err := b.client.Options().Credentials.Retrieve(context.Background())
+	if err != nil || creds.AccessKeyID != "AKIDVAULT" {
+		t.Errorf("client credentials = %q, %v; want AKIDVAULT", creds.AccessKeyID, err)
+	}
+}
+
 func TestInitPanicsWhenNoRegionResolved(t *testing.T) {
 	isolateAWSConfig(t)
 
@@ -435,6 +726,7 @@ func TestInitPanicsWhenNoRegionResolved(t *testing.T) {
 }
 
 func TestInitPanicsWhenAWSConfigHasNoRegion(t *testing.T) {
+	isolateAWSConfig(t)
 	b := &Bedrock{
 		AWSConfig: &aws.Config{
 			Credentials: aws.NewCredentialsCache(credentials.NewStaticCredentialsProvider(
@@ -532,6 +824,59 @@ func TestDefineModelRegistersProvidedMetadata(t *testing.T) {
 	}
 }
 
+func TestExplicitProfileModels(t *testing.T) {
+	const profile = "us.anthropic.claude-3-haiku-20240307-v1:0"
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"output":{"message":{"role":"assistant","content":[{"text":"ok"}]}},"stopReason":"end_turn"}`)
+	}))
+	defer server.Close()
+
+	b := testInitializedBedrock()
+	b.ExplicitProfileModels = true
+	b.AWSConfig.HTTPClient = server.Client()
+	b.AWSConfig.BaseEndpoint = aws.String(server.URL)
+	g := genkit.Init(context.Background(), genkit.WithPlugins(b))
+
+	// Unregistered profile models are rejected instead of inferring the base
+	// model's capabilities.
+	_, err := b.GenerateText(context.Background(), profile, "hi")
+	if err == nil || !strings.Contains(err.Error(), "must be registered with DefineModel") {
+		t.Fatalf("GenerateText error = %v, want a must-register error", err)
+	}
+	if calls != 0 {
+		t.Errorf("Bedrock calls = %d, want 0", calls)
+	}
+	assertPanicsContains(t, "must be registered with DefineModel and a ModelInfo that sets Supports", func() {
+		b.DefineModel(g, ModelDefinition{Name: profile, Type: "chat"}, nil)
+	})
+
+	// Registered with a ModelInfo, the model uses exactly those capabilities.
+	supports := &ai.ModelSupports{Multiturn: true, Tools: false, SystemRole: true}
+	m := b.DefineModel(g, ModelDefinition{Name: profile, Type: "chat"}, &ai.ModelInfo{Supports: supports})
+	meta := modelMetadata(t, m)
+	got, ok := meta["supports"].(map[string]any)
+	if !ok {
+		t.Fatalf("supports = %T, want map[string]any", meta["supports"])
+	}
+	if got["tools"] != false || got["media"] != false || got["multiturn"] != true {
+		t.Errorf("supports = %v, want exactly the registered capabilities", got)
+	}
+	if stage := meta["stage"]; stage == ai.ModelStageStable {
+		t.Errorf("stage = %v, want none inferred from the base model", stage)
+	}
+	if _, err := b.GenerateText(context.Background(), profile, "hi"); err != nil {
+		t.Errorf("GenerateText after DefineModel: %v", err)
+	}
+
+	// Base model IDs keep inferred capabilities.
+	if _, err := b.GenerateText(context.Background(), "anthropic.claude-3-haiku-20240307-v1:0", "hi"); err != nil {
+		t.Errorf("GenerateText for a base model: %v", err)
+	}
+}
+
 func testInitializedBedrock() *Bedrock {
 	return &Bedrock{
 		Region: "us-east-1",
@@ -665,6 +1010,127 @@ func TestModelCapabilities_KnownModels(t *testing.T) {
 	}
 }
 
+func TestCapabilities_ParallelTools(t *testing.T) {
+	tests := []struct {
+		modelID string
+		want    bool
+	}{
+		{"anthropic.claude-3-5-sonnet-20241022-v2:0", true},
+		{"us.anthropic.claude-sonnet-4-20250514-v1:0", true},
+		{"amazon.nova-pro-v1:0", true},
+		{"cohere.command-r-plus-v1:0", true},
+		{"mistral.mistral-large-2407-v1:0", true},
+		{"mistral.mistral-large-2402-v1:0", false},
+		{"meta.llama3-1-70b-instruct-v1:0", false},
+		{"ai21.jamba-1-5-large-v1:0", false},
+		{"amazon.titan-text-express-v1", false},
+	}
+	for _, tt := range tests {
+		caps, ok := Capabilities(tt.modelID)
+		if !ok {
+			t.Errorf("Capabilities(%q) not found", tt.modelID)
+			continue
+		}
+		if caps.ParallelTools != tt.want {
+			t.Errorf("Capabilities(%q).ParallelTools = %v, want %v", tt.modelID, caps.ParallelTools, tt.want)
+		}
+	}
+	if _, ok := Capabilities("example.unknown-model-v1:0"); ok {
+		t.Error("Capabilities(unknown) ok = true, want false")
+	}
+}
+
+func TestResolveModelID(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"anthropic.claude-3-5-sonnet", "anthropic.claude-3-5-sonnet-20241022-v2:0"},
+		{"us.anthropic.claude-3-5-sonnet", "us.anthropic.claude-3-5-sonnet-20241022-v2:0"},
+		{"anthropic.claude-opus-4", "anthropic.claude-opus-4-20250514-v1:0"}, // exact family wins over opus-4-1/4-5
+		{"amazon.nova-lite", "amazon.nova-lite-v1:0"},
+		{"mistral.mistral-large", "mistral.mistral-large-2407-v1:0"},
+		// Exact, versioned, and ARN IDs pass through untouched.
+		{"anthropic.claude-3-haiku-20240307-v1:0", "anthropic.claude-3-haiku-20240307-v1:0"},
+		{"anthropic.claude-sonnet-4-6", "anthropic.claude-sonnet-4-6"},
+		{"acme.future-model-v3:0", "acme.future-model-v3:0"},
+		{"arn:aws:bedrock:us-east-1:123456789012:provisioned-model/abc", "arn:aws:bedrock:us-east-1:123456789012:provisioned-model/abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ResolveModelID(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveModelID(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveModelID_Errors(t *testing.T) {
+	_, err := ResolveModelID("anthropic.claude-3-5")
+	if err == nil || !strings.Contains(err.Error(), "ambiguous") ||
+		!strings.Contains(err.Error(), "anthropic.claude-3-5-haiku") || !strings.Contains(err.Error(), "anthropic.claude-3-5-sonnet") {
+		t.Errorf("ambiguous error = %v, want both families listed", err)
+	}
+	_, err = ResolveModelID("acme.unknown-model")
+	if err == nil || !strings.Contains(err.Error(), "unknown model ID") {
+		t.Errorf("unknown error = %v, want unknown model ID", err)
+	}
+}
+
+func TestDefineModelResolvesVersionlessIDWhenEnabled(t *testing.T) {
+	b := &Bedrock{ResolveModelVersions: true}
+	if got := b.resolveModel(ModelDefinition{Name: "anthropic.claude-3-5-sonnet", Type: "chat"}); got.Name != "anthropic.claude-3-5-sonnet-20241022-v2:0" {
+		t.Errorf("resolved name = %q", got.Name)
+	}
+	if got := (&Bedrock{}).resolveModel(ModelDefinition{Name: "anthropic.claude-3-5-sonnet", Type: "chat"}); got.Name != "anthropic.claude-3-5-sonnet" {
+		t.Errorf("resolution must be opt-in, got %q", got.Name)
+	}
+	assertPanicsContains(t, "is ambiguous", func() {
+		b.resolveModel(ModelDefinition{Name: "anthropic.claude-3-5", Type: "chat"})
+	})
+}
+
+func TestResolveModel_DefaultProfilePrefix(t *testing.T) {
+	b := testInitializedBedrock()
+	b.DefaultProfilePrefix = "us"
+	b.ResolveModelVersions = true
+	b.Init(context.Background())
+	if b.DefaultProfilePrefix != "us." {
+		t.Fatalf("DefaultProfilePrefix = %q, want normalized to us.", b.DefaultProfilePrefix)
+	}
+
+	tests := []struct {
+		name, in, want string
+	}{
+		{"base ID with profile", "anthropic.claude-3-5-haiku-20241022-v1:0", "us.anthropic.claude-3-5-haiku-20241022-v1:0"},
+		{"versionless ID", "amazon.nova-lite", "us.amazon.nova-lite-v1:0"},
+		{"already prefixed", "eu.anthropic.claude-3-haiku-20240307-v1:0", "eu.anthropic.claude-3-haiku-20240307-v1:0"},
+		{"global profile", "global.anthropic.claude-haiku-4-5-20251001-v1:0", "global.anthropic.claude-haiku-4-5-20251001-v1:0"},
+		{"no profile", "cohere.command-r-plus-v1:0", "cohere.command-r-plus-v1:0"},
+		{"provisioned variant", "anthropic.claude-3-haiku-20240307-v1:0:200k", "anthropic.claude-3-haiku-20240307-v1:0:200k"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := b.resolveModel(ModelDefinition{Name: tt.in, Type: "chat"}); got.Name != tt.want {
+				t.Errorf("resolveModel(%q) = %q, want %q", tt.in, got.Name, tt.want)
+			}
+		})
+	}
+	if got := b.resolveModel(ModelDefinition{Name: "amazon.titan-embed-text-v2:0", Type: "embedding"}); got.Name != "amazon.titan-embed-text-v2:0" {
+		t.Errorf("embedder resolved to %q, want unchanged", got.Name)
+	}
+}
+
+func TestInit_RejectsUnknownDefaultProfilePrefix(t *testing.T) {
+	b := testInitializedBedrock()
+	b.DefaultProfilePrefix = "mars."
+	assertPanicsContains(t, "unknown DefaultProfilePrefix", func() { b.Init(context.Background()) })
+}
+
 func TestBuildConverseInput_MediaContentBlocks(t *testing.T) {
 	b := &Bedrock{initted: true}
 
@@ -816,3 +1282,112 @@ func TestBuildConverseInput_MediaContentBlocks(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateTextHelper(t *testing.T) {
+	var gotPath string
+	var gotBody map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		if err := json.NewDecoder(r.Body).Decode(&gotBody); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{
+			"output": {"message":{"role":"assistant","content":[{"text":"Paris"}]}},
+			"stopReason": "end_turn"
+		}`)
+	}))
+	defer server.Close()
+
+	b := newTestBedrock(server)
+	b.DefaultProfilePrefix = "us."
+	temperature := float32(0.5)
+	text, err := b.GenerateText(context.Background(), "amazon.nova-lite-v1:0", "Capital of France?", &Config{
+		MaxTokens:   64,
+		Temperature: &temperature,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "Paris" {
+		t.Errorf("text = %q, want Paris", text)
+	}
+	if !strings.Contains(gotPath, "us.amazon.nova-lite-v1:0") {
+		t.Errorf("path = %q, want the profile-prefixed model", gotPath)
+	}
+	wantMessages := []any{map[string]any{
+		"role":    "user",
+		"content": []any{map[string]any{"text": "Capital of France?"}},
+	}}
+	if !reflect.DeepEqual(gotBody["messages"], wantMessages) {
+		t.Errorf("messages = %#v, want %#v", gotBody["messages"], wantMessages)
+	}
+	if _, ok := gotBody["system"]; ok {
+		t.Errorf("unexpected system prompt: %v", gotBody["system"])
+	}
+	wantInference := map[string]any{"maxTokens": float64(64), "temperature": 0.5}
+	if !reflect.DeepEqual(gotBody["inferenceConfig"], wantInference) {
+		t.Errorf("inferenceConfig = %#v, want %#v", gotBody["inferenceConfig"], wantInference)
+	}
+}
+
+func TestGenerateTextHelper_Errors(t *testing.T) {
+	ctx := context.Background()
+	if _, err := (&Bedrock{}).GenerateText(ctx, "amazon.nova-lite-v1:0", "hi"); err == nil || !strings.Contains(err.Error(), "not initialized") {
+		t.Errorf("uninitialized: error = %v", err)
+	}
+	b := &Bedrock{initted: true}
+	if _, err := b.GenerateText(ctx, "", "hi"); err == nil || !strings.Contains(err.Error(), "model ID required") {
+		t.Errorf("empty model: error = %v", err)
+	}
+	if _, err := b.GenerateText(ctx, "amazon.nova-lite-v1:0", "hi", &Config{}, &Config{}); err == nil || !strings.Contains(err.Error(), "at most one") {
+		t.Errorf("two configs: error = %v", err)
+	}
+	b.ResolveModelVersions = true
+	if _, err := b.GenerateText(ctx, "anthropic.claude-unknown", "hi"); err == nil || !strings.HasPrefix(err.Error(), "bedrock.GenerateText:") {
+		t.Errorf("unresolvable model: error = %v", err)
+	}
+}
+
+func TestWarmup(t *testing.T) {
+	const arn = "arn:aws:bedrock:us-east-1:123456789012:provisioned-model/abc123"
+	var paths []string
+	var bodies []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		bodies = append(bodies, body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{
+			"output": {"message":{"role":"assistant","content":[{"text":"Hello"}]}},
+			"stopReason": "max_tokens"
+		}`)
+	}))
+	defer server.Close()
+
+	b := newTestBedrock(server)
+	for range 2 {
+		if err := b.Warmup(context.Background(), arn); err != nil {
+			t.Fatalf("Warmup: %v", err)
+		}
+	}
+	if len(paths) != 2 {
+		t.Fatalf("requests = %d, want one per call", len(paths))
+	}
+	if want := "/model/" + arn + "/converse"; paths[0] != want {
+		t.Errorf("path = %q, want %q", paths[0], want)
+	}
+	if got := bodies[0]["inferenceConfig"]; !reflect.DeepEqual(got, map[string]any{"maxTokens": float64(1)}) {
+		t.Errorf("inferenceConfig = %#v, want maxTokens 1", got)
+	}
+	if msgs, _ := bodies[0]["messages"].([]any); len(msgs) != 1 {
+		t.Errorf("messages = %#v, want a single user message", bodies[0]["messages"])
+	}
+
+	if err := (&Bedrock{}).Warmup(context.Background(), arn); err == nil || !strings.Contains(err.Error(), "bedrock.Warmup: plugin not initialized") {
+		t.Errorf("uninitialized: error = %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// capabilitiesFile is the JSON layout of [Bedrock.CapabilitiesFile]:
+//
+//	{"models": {"acme.model-v1:0": {"tools": true, "systemPrompt": true, "maxOutputTokens": 8192}}}
+type capabilitiesFile struct {
+	Models map[string]capabilityEntry `json:"models"`
+}
+
+// capabilityEntry is one model's capabilities in a capabilities file. Omitted
+// fields are false or 0.
+type capabilityEntry struct {
+	Multimodal       bool `json:"multimodal"`
+	Documents        bool `json:"documents"`
+	Video            bool `json:"video"`
+	Tools            bool `json:"tools"`
+	MaxOutputTokens  int  `json:"maxOutputTokens"`
+	ToolCaching      bool `json:"toolCaching"`
+	SystemPrompt     bool `json:"systemPrompt"`
+	MaxImages        int  `json:"maxImages"`
+	Profile          bool `json:"profile"`
+	MaxStopSequences int  `json:"maxStopSequences"`
+	ParallelTools    bool `json:"parallelTools"`
+}
+
+func (e capabilityEntry) capability() ModelCapability {
+	return ModelCapability{
+		Multimodal:       e.Multimodal,
+		Documents:        e.Documents,
+		Video:            e.Video,
+		Tools:            e.Tools,
+		MaxOutputTokens:  e.MaxOutputTokens,
+		ToolCaching:      e.ToolCaching,
+		SystemPrompt:     e.SystemPrompt,
+		MaxImages:        e.MaxImages,
+		Profile:          e.Profile,
+		MaxStopSequences: e.MaxStopSequences,
+		ParallelTools:    e.ParallelTools,
+	}
+}
+
+// loadCapabilitiesFile reads and validates the capabilities file at path.
+func loadCapabilitiesFile(path string) (map[string]ModelCapability, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("bedrock: read CapabilitiesFile: %w", err)
+	}
+	caps, err := parseCapabilities(data)
+	if err != nil {
+		return nil, fmt.Errorf("bedrock: CapabilitiesFile %s: %w", path, err)
+	}
+	return caps, nil
+}
+
+// parseCapabilities decodes a capabilities file, rejecting unknown fields,
+// trailing data, blank or inference-profile model IDs and negative limits.
+func parseCapabilities(data []byte) (map[string]ModelCapability, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var file capabilitiesFile
+	if err := dec.Decode(&file); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("unexpected data after the JSON object")
+	}
+	if file.Models == nil {
+		return nil, errors.New(`missing "models" object`)
+	}
+	caps := make(map[string]ModelCapability, len(file.Models))
+	for id, entry := range file.Models {
+		switch {
+		case strings.TrimSpace(id) == "":
+			return nil, errors.New("blank model ID")
+		case isProfileModelID(id):
+			return nil, fmt.Errorf("model %q: use the base model ID, without an inference profile prefix", id)
+		case entry.MaxOutputTokens < 0, entry.MaxImages < 0, entry.MaxStopSequences < 0:
+			return nil, fmt.Errorf("model %q: limits must not be negative", id)
+		}
+		caps[id] = entry.capability()
+	}
+	return caps, nil
+}
@@ -0,0 +1,127 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+)
+
+func writeCapabilitiesFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "capabilities.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestInit_CapabilitiesFile(t *testing.T) {
+	b := testInitializedBedrock()
+	b.ResolveModelVersions = true
+	b.CapabilitiesFile = writeCapabilitiesFile(t, `{"models": {
+		"acme.chat-v1:0": {"tools": true, "systemPrompt": true, "maxOutputTokens": 8192, "profile": true},
+		"anthropic.claude-3-haiku-20240307-v1:0": {"multimodal": true, "tools": true, "systemPrompt": true, "maxOutputTokens": 2048}
+	}}`)
+	b.Init(context.Background())
+
+	added, ok := b.modelCapability("us.acme.chat-v1:0")
+	if !ok {
+		t.Fatal("modelCapability(acme.chat-v1:0) not found after loading the file")
+	}
+	if want := (ModelCapability{Tools: true, SystemPrompt: true, MaxOutputTokens: 8192, Profile: true}); added != want {
+		t.Errorf("added capabilities = %+v, want %+v", added, want)
+	}
+	if got, err := b.resolveModelID("acme.chat"); err != nil || got != "acme.chat-v1:0" {
+		t.Errorf("resolveModelID(acme.chat) = %q, %v; want the added model", got, err)
+	}
+
+	overridden, _ := b.modelCapability("anthropic.claude-3-haiku-20240307-v1:0")
+	if overridden.MaxOutputTokens != 2048 || overridden.Documents {
+		t.Errorf("overridden capabilities = %+v, want the file's entry", overridden)
+	}
+	if err := b.checkMaxTokens("anthropic.claude-3-haiku-20240307-v1:0", &types.InferenceConfiguration{MaxTokens: aws.Int32(4096)}); err == nil {
+		t.Error("checkMaxTokens(4096) = nil, want the overridden 2048 limit enforced")
+	}
+	if untouched, _ := b.modelCapability("anthropic.claude-3-opus-20240229-v1:0"); untouched.MaxOutputTokens != 4096 {
+		t.Errorf("built-in entry changed: %+v", untouched)
+	}
+
+	if got, ok := b.Capabilities("us.acme.chat-v1:0"); !ok || got != added {
+		t.Errorf("b.Capabilities(us.acme.chat-v1:0) = %+v, %v; want the file's entry", got, ok)
+	}
+	if got, _ := b.Capabilities("anthropic.claude-3-haiku-20240307-v1:0"); got != overridden {
+		t.Errorf("b.Capabilities(claude-3-haiku) = %+v, want the file's entry", got)
+	}
+
+	// The file applies to b only.
+	if _, ok := Capabilities("acme.chat-v1:0"); ok {
+		t.Error("Capabilities(acme.chat-v1:0) found; the file leaked into the built-in map")
+	}
+	if builtIn, _ := Capabilities("anthropic.claude-3-haiku-20240307-v1:0"); builtIn.MaxOutputTokens != 4096 {
+		t.Errorf("built-in capabilities = %+v, want the built-in entry", builtIn)
+	}
+	other := testInitializedBedrock()
+	if err := other.checkMaxTokens("anthropic.claude-3-haiku-20240307-v1:0", &types.InferenceConfiguration{MaxTokens: aws.Int32(4096)}); err != nil {
+		t.Errorf("other instance checkMaxTokens(4096) = %v, want the built-in limit", err)
+	}
+}
+
+func TestInit_InvalidCapabilitiesFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "malformed", content: `{"models": `, want: "unexpected EOF"},
+		{name: "unknown field", content: `{"models": {"acme.chat-v1:0": {"tool": true}}}`, want: `unknown field "tool"`},
+		{name: "wrong type", content: `{"models": {"acme.chat-v1:0": {"maxOutputTokens": "8k"}}}`, want: "cannot unmarshal"},
+		{name: "missing models", content: `{}`, want: `missing "models" object`},
+		{name: "trailing data", content: `{"models": {}} {}`, want: "unexpected data"},
+		{name: "blank ID", content: `{"models": {" ": {}}}`, want: "blank model ID"},
+		{name: "profile ID", content: `{"models": {"us.acme.chat-v1:0": {}}}`, want: "without an inference profile prefix"},
+		{name: "negative limit", content: `{"models": {"acme.chat-v1:0": {"maxImages": -1}}}`, want: "must not be negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := testInitializedBedrock()
+			b.CapabilitiesFile = writeCapabilitiesFile(t, tt.content)
+			assertPanicsContains(t, tt.want, func() { b.Init(context.Background()) })
+		})
+	}
+
+	b := testInitializedBedrock()
+	b.CapabilitiesFile = filepath.Join(t.TempDir(), "missing.json")
+	assertPanicsContains(t, "read CapabilitiesFile", func() { b.Init(context.Background()) })
+}
+
+func TestParseCapabilities_EmptyModels(t *testing.T) {
+	caps, err := parseCapabilities([]byte(`{"models": {}}`))
+	if err != nil || len(caps) != 0 {
+		t.Errorf("parseCapabilities() = %v, %v; want no entries", caps, err)
+	}
+	if _, err := parseCapabilities([]byte(`[]`)); err == nil || !strings.Contains(err.Error(), "cannot unmarshal") {
+		t.Errorf("parseCapabilities([]) error = %v, want a schema error", err)
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/firebase/genkit/go/ai"
+)
+
+// [Config.Choices] is implemented as a tool the model is forced to call: its
+// single required argument is a string enum of the choices.
+const (
+	choiceToolName = "choose_one"
+	choiceArgument = "choice"
+)
+
+// checkChoiceList validates a non-nil Choices list: at least one choice,
+// none blank and no duplicates.
+func checkChoiceList(choices []string) error {
+	if len(choices) == 0 {
+		return errors.New("bedrock: Choices must list at least one choice")
+	}
+	for i, choice := range choices {
+		if strings.TrimSpace(choice) == "" {
+			return fmt.Errorf("bedrock: Choices[%d] is blank", i)
+		}
+		if slices.Contains(choices[:i], choice) {
+			return fmt.Errorf("bedrock: Choices lists %q more than once", choice)
+		}
+	}
+	return nil
+}
+
+// checkChoices checks that the request can use cfg.Choices, whose list
+// [Config.Validate] has checked. Choices replace tool calling, so the request
+// may not carry tools or a ToolChoice, and the model must support tool use.
+func (b *Bedrock) checkChoices(modelName string, cfg *Config, tools []*ai.ToolDefinition) error {
+	if len(tools) > 0 || cfg.ToolChoice != "" {
+		return errors.New("bedrock: Choices cannot be combined with tools or ToolChoice")
+	}
+	if caps, ok := b.modelCapability(modelName); ok && !caps.Tools {
+		return fmt.Errorf("bedrock: model %q does not support tool use, which Choices requires", modelName)
+	}
+	return nil
+}
+
+// choiceToolConfig returns the tool configuration that forces the model to
+// pick one of choices.
+func choiceToolConfig(choices []string) (*types.ToolConfiguration, error) {
+	tool := &ai.ToolDefinition{
+		Name:        choiceToolName,
+		Description: "Answer by choosing exactly one of the allowed values.",
+		InputSchema: NewObjectSchema(map[string]any{
+			choiceArgument: NewStringSchema("The chosen value.", choices),
+		}, []string{choiceArgument}),
+	}
+	toolConfig, err := toolsToConverseConfig([]*ai.ToolDefinition{tool})
+	if err != nil {
+		return nil, err
+	}
+	toolConfig.ToolChoice = &types.ToolChoiceMemberTool{
+		Value: types.SpecificToolChoice{Name: aws.String(choiceToolName)},
+	}
+	return toolConfig, nil
+}
+
+// applyChoice rewrites resp so its answer is the chosen value as a single
+// text part: the choice tool call is replaced and any text the model wrote
+// around it is dropped. Reasoning parts are kept. It fails when the model
+// did not call the tool or chose a value outside choices.
+func applyChoice(resp *ai.ModelResponse, choices []string) error {
+	if resp == nil || resp.Message == nil {
+		return nil
+	}
+	var chosen *ai.Part
+	content := make([]*ai.Part, 0, len(resp.Message.Content))
+	for _, part := range resp.Message.Content {
+		if isChoicePart(part) {
+			var err error
+			if chosen, err = choicePart(part, choices); err != nil {
+				return err
+			}
+			continue
+		}
+		if part != nil && !part.IsText() {
+			content = append(content, part)
+		}
+	}
+	if chosen == nil {
+		return fmt.Errorf("bedrock: model did not choose one of Choices (finish reason %q)", resp.FinishReason)
+	}
+	resp.Message.Content = append(content, chosen)
+	resp.FinishReason = ai.FinishReasonStop
+	setStopReason(resp, types.StopReasonEndTurn)
+	return nil
+}
+
+// choiceStreamCallback wraps cb so streamed chunks carry the chosen value as
+// text instead of the choice tool call, without the text around it.
+func choiceStreamCallback(cb func(context.Context, *ai.ModelResponseChunk) error, choices []string) func(context.Context, *ai.ModelResponseChunk) error {
+	return func(ctx context.Context, chunk *ai.ModelResponseChunk) error {
+		var content []*ai.Part
+		for _, part := range chunk.Content {
+			switch {
+			case isChoicePart(part):
+				chosen, err := choicePart(part, choices)
+				if err != nil {
+					return err
+				}
+				content = append(content, chosen)
+			case part != nil && !part.IsText():
+				content = append(content, part)
+			}
+		}
+		if len(content) == 0 {
+			return nil
+		}
+		filtered := *chunk
+		filtered.Content = content
+		return cb(ctx, &filtered)
+	}
+}
+
+func isChoicePart(part *ai.Part) bool {
+	return part != nil && part.IsToolRequest() && part.ToolRequest != nil && part.ToolRequest.Name == choiceToolName
+}
+
+// choicePart returns the value chosen in a choice tool call as a text part.
+func choicePart(part *ai.Part, choices []string) (*ai.Part, error) {
+	input, _ := part.ToolRequest.Input.(map[string]any)
+	choice, _ := input[choiceArgument].(string)
+	if !slices.Contains(choices, choice) {
+		return nil, fmt.Errorf("bedrock: model chose %q, which is not one of Choices", choice)
+	}
+	return ai.NewTextPart(choice), nil
+}
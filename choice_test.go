@@ -0,0 +1,187 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/firebase/genkit/go/ai"
+)
+
+func TestBuildConverseInput_ChoicesTool(t *testing.T) {
+	req := &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("Is this review positive? 'Loved it.'")},
+		Config:   &Config{Choices: []string{"positive", "negative", "neutral"}},
+	}
+	out, err := (&Bedrock{}).buildConverseInput("anthropic.claude-3-haiku-20240307-v1:0", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.ToolConfig == nil || len(out.ToolConfig.Tools) != 1 {
+		t.Fatalf("ToolConfig = %+v, want the choice tool only", out.ToolConfig)
+	}
+	spec, ok := out.ToolConfig.Tools[0].(*types.ToolMemberToolSpec)
+	if !ok || aws.ToString(spec.Value.Name) != choiceToolName {
+		t.Fatalf("tool = %#v, want %s", out.ToolConfig.Tools[0], choiceToolName)
+	}
+	forced, ok := out.ToolConfig.ToolChoice.(*types.ToolChoiceMemberTool)
+	if !ok || aws.ToString(forced.Value.Name) != choiceToolName {
+		t.Errorf("ToolChoice = %#v, want the choice tool forced", out.ToolConfig.ToolChoice)
+	}
+
+	raw, err := spec.Value.InputSchema.(*types.ToolInputSchemaMemberJson).Value.MarshalSmithyDocument()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema struct {
+		Properties map[string]struct {
+			Type string   `json:"type"`
+			Enum []string `json:"enum"`
+		} `json:"properties"`
+		Required []string `json:"required"`
+	}
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		t.Fatalf("decode schema %s: %v", raw, err)
+	}
+	arg := schema.Properties[choiceArgument]
+	if arg.Type != "string" || !reflect.DeepEqual(arg.Enum, []string{"positive", "negative", "neutral"}) {
+		t.Errorf("schema = %s, want a string enum of the choices", raw)
+	}
+	if !reflect.DeepEqual(schema.Required, []string{choiceArgument}) {
+		t.Errorf("required = %v, want [%s]", schema.Required, choiceArgument)
+	}
+}
+
+func TestBuildConverseInput_ChoicesValidation(t *testing.T) {
+	tool := &ai.ToolDefinition{Name: "get_weather"}
+	tests := []struct {
+		name    string
+		model   string
+		cfg     *Config
+		tools   []*ai.ToolDefinition
+		wantErr string
+	}{
+		{"empty", "anthropic.claude-3-haiku-20240307-v1:0", &Config{Choices: []string{}}, nil, "at least one choice"},
+		{"blank", "anthropic.claude-3-haiku-20240307-v1:0", &Config{Choices: []string{"yes", " "}}, nil, "Choices[1] is blank"},
+		{"duplicate", "anthropic.claude-3-haiku-20240307-v1:0", &Config{Choices: []string{"yes", "no", "yes"}}, nil, `"yes" more than once`},
+		{"with tools", "anthropic.claude-3-haiku-20240307-v1:0", &Config{Choices: []string{"yes"}}, []*ai.ToolDefinition{tool}, "cannot be combined"},
+		{"with tool choice", "anthropic.claude-3-haiku-20240307-v1:0", &Config{Choices: []string{"yes"}, ToolChoice: ToolChoiceAny}, nil, "cannot be combined"},
+		{"no tool use", "amazon.titan-text-express-v1", &Config{Choices: []string{"yes"}}, nil, "does not support tool use"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &ai.ModelRequest{Messages: []*ai.Message{ai.NewUserTextMessage("hi")}, Config: tt.cfg, Tools: tt.tools}
+			_, err := (&Bedrock{}).buildConverseInput(tt.model, req)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerate_ChoicesExtractsValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "chosen",
+			content: `{"text":"Let me classify this."},{"toolUse":{"toolUseId":"t1","name":"choose_one","input":{"choice":"negative"}}}`,
+			want:    "negative",
+		},
+		{
+			name:    "outside choices",
+			content: `{"toolUse":{"toolUseId":"t1","name":"choose_one","input":{"choice":"angry"}}}`,
+			wantErr: `chose "angry"`,
+		},
+		{
+			name:    "no tool call",
+			content: `{"text":"negative"}`,
+			wantErr: "did not choose",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = fmt.Fprintf(w, `{"output":{"message":{"role":"assistant","content":[%s]}},"stopReason":"tool_use"}`, tt.content)
+			}))
+			defer server.Close()
+
+			req := &ai.ModelRequest{
+				Messages: []*ai.Message{ai.NewUserTextMessage("Classify: 'Never again.'")},
+				Config:   &Config{Choices: []string{"positive", "negative"}},
+			}
+			resp, err := newTestBedrock(server).generateText(context.Background(), "anthropic.claude-3-haiku-20240307-v1:0", req, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Text() != tt.want || len(resp.Message.Content) != 1 {
+				t.Errorf("content = %+v, want the single text %q", resp.Message.Content, tt.want)
+			}
+			if len(resp.ToolRequests()) != 0 {
+				t.Errorf("tool requests = %+v, want none", resp.ToolRequests())
+			}
+			if resp.FinishReason != ai.FinishReasonStop {
+				t.Errorf("FinishReason = %q, want stop", resp.FinishReason)
+			}
+		})
+	}
+}
+
+func TestChoiceStreamCallback(t *testing.T) {
+	var got []*ai.Part
+	cb := choiceStreamCallback(func(_ context.Context, chunk *ai.ModelResponseChunk) error {
+		got = append(got, chunk.Content...)
+		return nil
+	}, []string{"yes", "no"})
+
+	ctx := context.Background()
+	if err := cb(ctx, &ai.ModelResponseChunk{Content: []*ai.Part{ai.NewTextPart("Thinking about it...")}}); err != nil {
+		t.Fatal(err)
+	}
+	choice := toolUsePart(1, &ai.ToolRequest{Name: choiceToolName, Input: map[string]any{choiceArgument: "yes"}})
+	if err := cb(ctx, &ai.ModelResponseChunk{Content: []*ai.Part{choice}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || !got[0].IsText() || got[0].Text != "yes" {
+		t.Errorf("streamed parts = %+v, want only the text \"yes\"", got)
+	}
+
+	bad := toolUsePart(1, &ai.ToolRequest{Name: choiceToolName, Input: map[string]any{choiceArgument: "maybe"}})
+	if err := cb(ctx, &ai.ModelResponseChunk{Content: []*ai.Part{bad}}); err == nil {
+		t.Error("expected an error for a value outside the choices")
+	}
+}
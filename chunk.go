@@ -0,0 +1,208 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// charsPerToken is the ratio ChunkText uses to estimate token counts. It is
+// the usual rule of thumb for English text with the Titan and Cohere
+// tokenizers; it does not count tokens exactly.
+const charsPerToken = 4
+
+// ChunkText splits text into chunks for embedding of at most maxTokens
+// estimated tokens each (one token per four characters). Chunks end at
+// sentence or paragraph boundaries; a sentence longer than maxTokens is split
+// between words, and a word longer than that is cut. Each chunk after the
+// first repeats whole sentences from the end of the previous one, up to
+// overlap tokens, so context carries across chunk boundaries. Chunks are
+// slices of text with surrounding whitespace trimmed. Blank text gives no
+// chunks. ChunkText panics if maxTokens is less than 1.
+func ChunkText(text string, maxTokens, overlap int) []string {
+	if maxTokens < 1 {
+		panic("bedrock.ChunkText: maxTokens must be at least 1")
+	}
+	units := chunkUnits(text, maxTokens*charsPerToken)
+	tokens := func(from, to textSpan) int {
+		return (to.endRune - from.startRune + charsPerToken - 1) / charsPerToken
+	}
+
+	var chunks []string
+	for i := 0; i < len(units); {
+		j := i
+		for j+1 < len(units) && tokens(units[i], units[j+1]) <= maxTokens {
+			j++
+		}
+		chunks = append(chunks, text[units[i].start:units[j].end])
+		if j == len(units)-1 {
+			break
+		}
+		// Start the next chunk with as many of this chunk's trailing units as
+		// fit in overlap, always moving past units[i].
+		next := j + 1
+		for next-1 > i && tokens(units[next-1], units[j]) <= overlap && tokens(units[next-1], units[j+1]) <= maxTokens {
+			next--
+		}
+		i = next
+	}
+	return chunks
+}
+
+// textSpan is a trimmed piece of ChunkText's input: byte offsets for slicing
+// and rune offsets for estimating tokens.
+type textSpan struct {
+	start, end         int
+	startRune, endRune int
+}
+
+// chunkUnits splits text into sentences of at most maxRunes runes, breaking
+// longer sentences between words and longer words anywhere.
+func chunkUnits(text string, maxRunes int) []textSpan {
+	var units []textSpan
+	for _, sentence := range sentenceSpans(text) {
+		if sentence.endRune-sentence.startRune <= maxRunes {
+			units = append(units, sentence)
+			continue
+		}
+		units = append(units, splitSpan(text, sentence, maxRunes)...)
+	}
+	return units
+}
+
+// sentenceSpans returns the sentences of text. A sentence ends at '.', '!'
+// or '?' (and any closing quotes or brackets) followed by whitespace, at a
+// CJK full stop, or at a blank line.
+func sentenceSpans(text string) []textSpan {
+	var spans []textSpan
+	var cur textSpan
+	open := false
+	newlines := 0
+	end := func() {
+		if open {
+			spans = append(spans, cur)
+			open = false
+		}
+	}
+	runeIdx := 0
+	for i, r := range text {
+		_, size := utf8.DecodeRuneInString(text[i:])
+		if unicode.IsSpace(r) {
+			if r == '\n' {
+				newlines++
+				if newlines >= 2 {
+					end()
+				}
+			}
+			if open && endsSentence(text[cur.start:cur.end]) {
+				end()
+			}
+			runeIdx++
+			continue
+		}
+		newlines = 0
+		if !open {
+			cur = textSpan{start: i, startRune: runeIdx}
+			open = true
+		}
+		cur.end, cur.endRune = i+size, runeIdx+1
+		runeIdx++
+		if r == '。' || r == '！' || r == '？' {
+			end()
+		}
+	}
+	end()
+	return spans
+}
+
+// endsSentence reports whether s ends with sentence punctuation, ignoring
+// closing quotes and brackets after it.
+func endsSentence(s string) bool {
+	for len(s) > 0 {
+		r, size := utf8.DecodeLastRuneInString(s)
+		switch r {
+		case '.', '!', '?':
+			return true
+		case '"', '\'', ')', ']', '”', '’':
+			s = s[:len(s)-size]
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// splitSpan splits span into pieces of at most maxRunes runes, between words
+// where possible.
+func splitSpan(text string, span textSpan, maxRunes int) []textSpan {
+	var pieces []textSpan
+	var cur textSpan
+	open := false
+	runeIdx := span.startRune
+	var word textSpan
+	inWord := false
+	addWord := func() {
+		inWord = false
+		for word.endRune-word.startRune > maxRunes {
+			// Cut an overlong word at maxRunes runes.
+			if open {
+				pieces = append(pieces, cur)
+				open = false
+			}
+			cut := word.start
+			for range maxRunes {
+				_, size := utf8.DecodeRuneInString(text[cut:])
+				cut += size
+			}
+			pieces = append(pieces, textSpan{word.start, cut, word.startRune, word.startRune + maxRunes})
+			word.start, word.startRune = cut, word.startRune+maxRunes
+		}
+		if open && word.endRune-cur.startRune <= maxRunes {
+			cur.end, cur.endRune = word.end, word.endRune
+			return
+		}
+		if open {
+			pieces = append(pieces, cur)
+		}
+		cur, open = word, true
+	}
+	for i, r := range text[span.start:span.end] {
+		i += span.start
+		if unicode.IsSpace(r) {
+			if inWord {
+				addWord()
+			}
+		} else {
+			if !inWord {
+				word = textSpan{start: i, startRune: runeIdx}
+				inWord = true
+			}
+			_, size := utf8.DecodeRuneInString(text[i:])
+			word.end, word.endRune = i+size, runeIdx+1
+		}
+		runeIdx++
+	}
+	if inWord {
+		addWord()
+	}
+	if open {
+		pieces = append(pieces, cur)
+	}
+	return pieces
+}
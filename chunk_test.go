@@ -0,0 +1,114 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func estimatedTokens(s string) int {
+	return (utf8.RuneCountInString(s) + charsPerToken - 1) / charsPerToken
+}
+
+func TestChunkText_SentenceBoundaries(t *testing.T) {
+	// Each sentence is 20 characters, 5 estimated tokens.
+	text := "The cat sat on mats. Dogs run in parks. Birds sing at dawn!\n\nFish swim in lakes. Is rain wet today? Sun warms the earth."
+	got := ChunkText(text, 11, 0)
+	want := []string{
+		"The cat sat on mats. Dogs run in parks.",
+		"Birds sing at dawn!\n\nFish swim in lakes.",
+		"Is rain wet today? Sun warms the earth.",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("chunks = %q, want %q", got, want)
+	}
+	for _, chunk := range got {
+		if n := estimatedTokens(chunk); n > 11 {
+			t.Errorf("chunk %q has %d estimated tokens, over 11", chunk, n)
+		}
+	}
+}
+
+func TestChunkText_Overlap(t *testing.T) {
+	text := "One two three four. Five six seven ok. Eight nine ten hey. Eleven twelve go on. Thirteen and done!"
+	got := ChunkText(text, 12, 5)
+	want := []string{
+		"One two three four. Five six seven ok.",
+		"Five six seven ok. Eight nine ten hey.",
+		"Eight nine ten hey. Eleven twelve go on.",
+		"Eleven twelve go on. Thirteen and done!",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("chunks = %q, want %q", got, want)
+	}
+
+	// An overlap too small for a whole sentence repeats nothing.
+	got = ChunkText(text, 12, 4)
+	if len(got) != 3 || strings.Join(got, " ") != text {
+		t.Errorf("chunks = %q, want the text split without repetition", got)
+	}
+
+	// An overlap as large as the chunk still moves forward.
+	got = ChunkText(text, 12, 100)
+	if len(got) != 4 || got[len(got)-1] != "Eleven twelve go on. Thirteen and done!" {
+		t.Errorf("chunks = %q, want each chunk to advance by one sentence", got)
+	}
+}
+
+func TestChunkText_LongSentenceSplitsBetweenWords(t *testing.T) {
+	text := "alpha beta gamma delta epsilon zeta eta theta iota kappa lambda mu"
+	got := ChunkText(text, 5, 0)
+	for _, chunk := range got {
+		if n := estimatedTokens(chunk); n > 5 {
+			t.Errorf("chunk %q has %d estimated tokens, over 5", chunk, n)
+		}
+		if strings.HasPrefix(chunk, " ") || strings.HasSuffix(chunk, " ") {
+			t.Errorf("chunk %q is not trimmed", chunk)
+		}
+	}
+	if strings.Join(got, " ") != text {
+		t.Errorf("chunks = %q do not rejoin to the text", got)
+	}
+
+	// A word longer than the budget is cut.
+	got = ChunkText("pneumonoultramicroscopic", 2, 0)
+	if want := []string{"pneumono", "ultramic", "roscopic"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("chunks = %q, want %q", got, want)
+	}
+}
+
+func TestChunkText_EdgeCases(t *testing.T) {
+	if got := ChunkText(" \n\t ", 10, 2); got != nil {
+		t.Errorf("blank text chunks = %q, want none", got)
+	}
+	if got := ChunkText("  Short text.  ", 10, 2); !reflect.DeepEqual(got, []string{"Short text."}) {
+		t.Errorf("chunks = %q, want the trimmed text", got)
+	}
+	// CJK full stops end sentences without a following space.
+	if got := ChunkText("今日は晴れです。明日は雨です。", 2, 0); !reflect.DeepEqual(got, []string{"今日は晴れです。", "明日は雨です。"}) {
+		t.Errorf("chunks = %q", got)
+	}
+	// Closing quotes stay with their sentence.
+	if got := ChunkText(`He said "stop." Then he left.`, 4, 0); !reflect.DeepEqual(got, []string{`He said "stop."`, "Then he left."}) {
+		t.Errorf("chunks = %q", got)
+	}
+	assertPanicsContains(t, "maxTokens must be at least 1", func() { ChunkText("hi", 0, 0) })
+}
@@ -0,0 +1,227 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/firebase/genkit/go/ai"
+)
+
+// citationsMetadataKey holds the []Citation attached to a cited text part.
+const citationsMetadataKey = "citations"
+
+// Citation is a span of a request document that Bedrock attributes a piece of
+// generated text to. Enable citations with [Config.Citations]; read them back
+// with [Citations].
+type Citation struct {
+	Title         string   `json:"title,omitempty"`      // Title of the cited document
+	Source        string   `json:"source,omitempty"`     // Source URL or identifier, when known
+	SourceText    []string `json:"sourceText,omitempty"` // Quoted text from the document
+	DocumentIndex int      `json:"documentIndex"`        // Index of the document in request order
+	Location      string   `json:"location,omitempty"`   // "char", "page", or "chunk"
+	Start         int      `json:"start"`                // Start of the span, in Location units
+	End           int      `json:"end"`                  // End of the span, in Location units
+	// KnowledgeBaseID is the knowledge base the cited document came from, set
+	// by [RetrieveAndGenerate].
+	KnowledgeBaseID string `json:"knowledgeBaseId,omitempty"`
+}
+
+// Citations returns the citations attached to a response text part, or nil.
+// It also reads parts that went through JSON (flow output, traces, stored
+// history), where the metadata holds the decoded []any form.
+func Citations(part *ai.Part) []Citation {
+	if part == nil {
+		return nil
+	}
+	switch v := part.Metadata[citationsMetadataKey].(type) {
+	case []Citation:
+		return v
+	case []any: // JSON round-trip
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil
+		}
+		var citations []Citation
+		if err := json.Unmarshal(data, &citations); err != nil {
+			return nil
+		}
+		return citations
+	}
+	return nil
+}
+
+// documentPartKey is the custom part key under which [NewDocumentParts]
+// stores a document's name and text.
+const documentPartKey = "bedrockDocument"
+
+// documentNameChars matches runs of characters Converse rejects in document
+// names, which may only hold alphanumerics, single spaces, hyphens,
+// parentheses and square brackets.
+var documentNameChars = regexp.MustCompile(`[^\p{L}\p{N}\-()\[\] ]+`)
+
+// NewDocumentParts converts retrieved documents into parts sent to Converse
+// as text document blocks with citations enabled, so models that support
+// citations (Anthropic Claude) can cite them; read the citations back with
+// [Citations]. Add the parts to a user message next to the question. Each
+// block is named after the document's "name" or "title" metadata, else
+// "Document N", with characters Converse rejects in names replaced and
+// repeated names numbered. Only the documents' text parts are sent.
+func NewDocumentParts(docs ...*ai.Document) []*ai.Part {
+	parts := make([]*ai.Part, 0, len(docs))
+	seen := map[string]int{}
+	for i, doc := range docs {
+		if doc == nil {
+			continue
+		}
+		name := documentName(doc, i)
+		if seen[name]++; seen[name] > 1 {
+			name = fmt.Sprintf("%s (%d)", name, seen[name])
+		}
+		parts = append(parts, ai.NewCustomPart(map[string]any{
+			documentPartKey: map[string]any{"name": name, "text": documentText(doc)},
+		}))
+	}
+	return parts
+}
+
+// documentName returns the Converse block name for the i'th document.
+func documentName(doc *ai.Document, i int) string {
+	for _, key := range []string{"name", "title"} {
+		if s, ok := doc.Metadata[key].(string); ok {
+			s = strings.Join(strings.Fields(documentNameChars.ReplaceAllString(s, " ")), " ")
+			if s != "" {
+				return s
+			}
+		}
+	}
+	return fmt.Sprintf("Document %d", i+1)
+}
+
+// isDocumentPart reports whether part was made by [NewDocumentParts].
+func isDocumentPart(part *ai.Part) bool {
+	if !part.IsCustom() {
+		return false
+	}
+	_, ok := part.Custom[documentPartKey].(map[string]any)
+	return ok
+}
+
+// documentPartBlock converts a part made by [NewDocumentParts] into a text
+// document block with citations enabled.
+func documentPartBlock(part *ai.Part) (types.ContentBlock, error) {
+	data, _ := part.Custom[documentPartKey].(map[string]any)
+	name, _ := data["name"].(string)
+	text, _ := data["text"].(string)
+	if name == "" {
+		return nil, errors.New("bedrock: document part has no name")
+	}
+	if text == "" {
+		return nil, fmt.Errorf("bedrock: document %q has no text", name)
+	}
+	return &types.ContentBlockMemberDocument{
+		Value: types.DocumentBlock{
+			Name:      aws.String(name),
+			Format:    types.DocumentFormatTxt,
+			Source:    &types.DocumentSourceMemberText{Value: text},
+			Citations: &types.CitationsConfig{Enabled: aws.Bool(true)},
+		},
+	}, nil
+}
+
+// enableDocumentCitations asks Bedrock for citations on every document block
+// in messages.
+func enableDocumentCitations(messages []types.Message) {
+	for _, msg := range messages {
+		for _, block := range msg.Content {
+			if doc, ok := block.(*types.ContentBlockMemberDocument); ok {
+				doc.Value.Citations = &types.CitationsConfig{Enabled: aws.Bool(true)}
+			}
+		}
+	}
+}
+
+// citationsBlockToParts converts a Converse citations content block into text
+// parts, each carrying the block's citations in its metadata.
+func citationsBlockToParts(block types.CitationsContentBlock) []*ai.Part {
+	citations := make([]Citation, 0, len(block.Citations))
+	for _, c := range block.Citations {
+		var sourceText []string
+		for _, sc := range c.SourceContent {
+			if text, ok := sc.(*types.CitationSourceContentMemberText); ok {
+				sourceText = append(sourceText, text.Value)
+			}
+		}
+		citations = append(citations, newCitation(c.Location, c.Title, c.Source, sourceText))
+	}
+
+	var parts []*ai.Part
+	for _, content := range block.Content {
+		text, ok := content.(*types.CitationGeneratedContentMemberText)
+		if !ok {
+			continue
+		}
+		part := ai.NewTextPart(text.Value)
+		if len(citations) > 0 {
+			part.Metadata = map[string]any{citationsMetadataKey: citations}
+		}
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// citationFromDelta converts a streamed citation delta.
+func citationFromDelta(d types.CitationsDelta) Citation {
+	var sourceText []string
+	for _, sc := range d.SourceContent {
+		if sc.Text != nil {
+			sourceText = append(sourceText, *sc.Text)
+		}
+	}
+	return newCitation(d.Location, d.Title, d.Source, sourceText)
+}
+
+func newCitation(location types.CitationLocation, title, source *string, sourceText []string) Citation {
+	c := Citation{
+		Title:      aws.ToString(title),
+		Source:     aws.ToString(source),
+		SourceText: sourceText,
+	}
+	var index, start, end *int32
+	switch l := location.(type) {
+	case *types.CitationLocationMemberDocumentChar:
+		c.Location = "char"
+		index, start, end = l.Value.DocumentIndex, l.Value.Start, l.Value.End
+	case *types.CitationLocationMemberDocumentPage:
+		c.Location = "page"
+		index, start, end = l.Value.DocumentIndex, l.Value.Start, l.Value.End
+	case *types.CitationLocationMemberDocumentChunk:
+		c.Location = "chunk"
+		index, start, end = l.Value.DocumentIndex, l.Value.Start, l.Value.End
+	}
+	c.DocumentIndex = int(aws.ToInt32(index))
+	c.Start = int(aws.ToInt32(start))
+	c.End = int(aws.ToInt32(end))
+	return c
+}
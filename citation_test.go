@@ -0,0 +1,212 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/firebase/genkit/go/ai"
+)
+
+func TestBuildConverseInput_CitationsEnableDocuments(t *testing.T) {
+	doc := ai.NewMediaPart("text/plain", base64.StdEncoding.EncodeToString([]byte("The sky is blue.")))
+	req := func(citations bool) *ai.ModelRequest {
+		return &ai.ModelRequest{
+			Messages: []*ai.Message{ai.NewUserMessage(doc, ai.NewTextPart("What colour is the sky?"))},
+			Config:   &Config{Citations: citations},
+		}
+	}
+
+	out, err := (&Bedrock{}).buildConverseInput("anthropic.claude-3-5-sonnet-20241022-v2:0", req(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, ok := out.Messages[0].Content[0].(*types.ContentBlockMemberDocument)
+	if !ok {
+		t.Fatalf("content[0] = %T, want document", out.Messages[0].Content[0])
+	}
+	if block.Value.Citations == nil || !aws.ToBool(block.Value.Citations.Enabled) {
+		t.Errorf("document Citations = %+v, want enabled", block.Value.Citations)
+	}
+
+	out, err = (&Bedrock{}).buildConverseInput("anthropic.claude-3-5-sonnet-20241022-v2:0", req(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := out.Messages[0].Content[0].(*types.ContentBlockMemberDocument).Value.Citations; c != nil {
+		t.Errorf("document Citations = %+v, want unset by default", c)
+	}
+}
+
+func TestConvertResponse_CitationsContent(t *testing.T) {
+	resp, err := (&Bedrock{}).convertResponse(&bedrockruntime.ConverseOutput{
+		Output: &types.ConverseOutputMemberMessage{Value: types.Message{Content: []types.ContentBlock{
+			&types.ContentBlockMemberText{Value: "According to the document, "},
+			&types.ContentBlockMemberCitationsContent{Value: types.CitationsContentBlock{
+				Content: []types.CitationGeneratedContent{
+					&types.CitationGeneratedContentMemberText{Value: "the sky is blue."},
+				},
+				Citations: []types.Citation{{
+					Title:         aws.String("document"),
+					SourceContent: []types.CitationSourceContent{&types.CitationSourceContentMemberText{Value: "The sky is blue."}},
+					Location: &types.CitationLocationMemberDocumentChar{Value: types.DocumentCharLocation{
+						DocumentIndex: aws.Int32(0),
+						Start:         aws.Int32(0),
+						End:           aws.Int32(16),
+					}},
+				}},
+			}},
+		}}},
+		StopReason: types.StopReasonEndTurn,
+	}, &ai.ModelRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := resp.Message.Content
+	if len(parts) != 2 || resp.Text() != "According to the document, the sky is blue." {
+		t.Fatalf("parts = %+v, want plain text then cited text", parts)
+	}
+	if got := Citations(parts[0]); got != nil {
+		t.Errorf("uncited part has citations %+v", got)
+	}
+	got := Citations(parts[1])
+	if len(got) != 1 {
+		t.Fatalf("citations = %+v, want 1", got)
+	}
+	want := Citation{Title: "document", SourceText: []string{"The sky is blue."}, DocumentIndex: 0, Location: "char", Start: 0, End: 16}
+	if got[0].Title != want.Title || got[0].Location != want.Location || got[0].End != want.End || len(got[0].SourceText) != 1 || got[0].SourceText[0] != want.SourceText[0] {
+		t.Errorf("citation = %+v, want %+v", got[0], want)
+	}
+}
+
+func TestConsumeStreamEvents_CitationDeltas(t *testing.T) {
+	resp, err := (&Bedrock{}).consumeStreamEvents(context.Background(), streamEvents(
+		textDelta(0, "The sky is blue."),
+		&types.ConverseStreamOutputMemberContentBlockDelta{Value: types.ContentBlockDeltaEvent{
+			ContentBlockIndex: aws.Int32(0),
+			Delta: &types.ContentBlockDeltaMemberCitation{Value: types.CitationsDelta{
+				Title:         aws.String("document"),
+				SourceContent: []types.CitationSourceContentDelta{{Text: aws.String("The sky is blue.")}},
+				Location: &types.CitationLocationMemberDocumentPage{Value: types.DocumentPageLocation{
+					DocumentIndex: aws.Int32(1),
+					Start:         aws.Int32(3),
+					End:           aws.Int32(4),
+				}},
+			}},
+		}},
+	), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := Citations(resp.Message.Content[0])
+	if len(got) != 1 || got[0].Location != "page" || got[0].DocumentIndex != 1 || got[0].Start != 3 || got[0].End != 4 {
+		t.Errorf("citations = %+v, want one page citation into document 1", got)
+	}
+}
+
+func TestCitations_AfterJSONRoundTrip(t *testing.T) {
+	want := []Citation{{Title: "doc", SourceText: []string{"quoted"}, DocumentIndex: 1, Location: "page", Start: 2, End: 3}}
+	part := ai.NewTextPart("cited")
+	part.Metadata = map[string]any{citationsMetadataKey: want}
+
+	data, err := json.Marshal(part)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded ai.Part
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := decoded.Metadata[citationsMetadataKey].([]any); !ok {
+		t.Fatalf("decoded metadata is %T, want []any", decoded.Metadata[citationsMetadataKey])
+	}
+	if got := Citations(&decoded); !reflect.DeepEqual(got, want) {
+		t.Errorf("Citations() = %+v, want %+v", got, want)
+	}
+
+	decoded.Metadata[citationsMetadataKey] = []any{"not a citation"}
+	if got := Citations(&decoded); got != nil {
+		t.Errorf("Citations() = %+v for malformed metadata, want nil", got)
+	}
+}
+
+func TestNewDocumentParts(t *testing.T) {
+	parts := NewDocumentParts(
+		ai.DocumentFromText("The sky is blue.", map[string]any{"title": "Sky: facts & figures"}),
+		ai.DocumentFromText("Grass is green.", nil),
+		nil,
+		ai.DocumentFromText("Clouds are white.", map[string]any{"name": "Sky  facts   figures"}),
+	)
+	req := &ai.ModelRequest{Messages: []*ai.Message{
+		ai.NewUserMessage(append(parts, ai.NewTextPart("What colour is the sky?"))...),
+	}}
+	// Round-trip the request through JSON, as flows and traces do.
+	raw, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded ai.ModelRequest
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range []*ai.ModelRequest{req, &decoded} {
+		out, err := (&Bedrock{}).buildConverseInput("anthropic.claude-3-5-sonnet-20241022-v2:0", r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content := out.Messages[0].Content
+		if len(content) != 4 {
+			t.Fatalf("content = %d blocks, want 3 documents and the question", len(content))
+		}
+		want := []struct{ name, text string }{
+			{"Sky facts figures", "The sky is blue."},
+			{"Document 2", "Grass is green."},
+			{"Sky facts figures (2)", "Clouds are white."},
+		}
+		for i, w := range want {
+			doc, ok := content[i].(*types.ContentBlockMemberDocument)
+			if !ok {
+				t.Fatalf("content[%d] = %T, want a document block", i, content[i])
+			}
+			if got := aws.ToString(doc.Value.Name); got != w.name {
+				t.Errorf("document %d name = %q, want %q", i, got, w.name)
+			}
+			if src, ok := doc.Value.Source.(*types.DocumentSourceMemberText); !ok || src.Value != w.text {
+				t.Errorf("document %d source = %#v, want text %q", i, doc.Value.Source, w.text)
+			}
+			if doc.Value.Citations == nil || !aws.ToBool(doc.Value.Citations.Enabled) {
+				t.Errorf("document %d citations = %+v, want enabled", i, doc.Value.Citations)
+			}
+		}
+	}
+
+	_, err = (&Bedrock{}).buildConverseInput("amazon.titan-text-express-v1", req)
+	if err == nil || !strings.Contains(err.Error(), "does not accept document input") {
+		t.Errorf("model without documents: error = %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+// DefaultContinuationRounds is the number of continuation calls
+// [ContinueGeneration] makes when maxRounds is 0.
+const DefaultContinuationRounds = 3
+
+// continuePrompt asks for the rest of a cut-off answer when the partial answer
+// cannot be sent as an assistant prefill (requests with tools).
+const continuePrompt = "Your previous answer was cut off. Continue exactly where it stopped, without repeating any of it."
+
+// ContinueGeneration keeps generating a response that stopped at the output
+// token limit (see [Truncated]). Each round appends the partial answer to the
+// conversation as the model's turn and calls model again, so the model picks
+// up where it stopped; requests with tools get an explicit "continue"
+// instruction instead, because Bedrock drops a trailing assistant turn there.
+// It stops when an answer is complete or after maxRounds calls
+// ([DefaultContinuationRounds] when 0), and returns the stitched text with
+// summed usage. A response that is not truncated is returned unchanged.
+func ContinueGeneration(ctx context.Context, model ai.Model, resp *ai.ModelResponse, maxRounds int) (*ai.ModelResponse, error) {
+	if model == nil {
+		return nil, errors.New("bedrock: ContinueGeneration requires a model")
+	}
+	if maxRounds < 0 {
+		return nil, fmt.Errorf("bedrock: maxRounds must not be negative, got %d", maxRounds)
+	}
+	if maxRounds == 0 {
+		maxRounds = DefaultContinuationRounds
+	}
+	if !Truncated(resp) {
+		return resp, nil
+	}
+	if resp.Request == nil {
+		return nil, errors.New("bedrock: cannot continue a response without its request")
+	}
+	if len(resp.ToolRequests()) > 0 {
+		return nil, errors.New("bedrock: cannot continue a truncated tool request")
+	}
+
+	original := resp.Request
+	var text strings.Builder
+	text.WriteString(resp.Text())
+	usage := resp.Usage
+	last := resp
+	for round := 0; round < maxRounds && Truncated(last); round++ {
+		req := *original
+		req.Messages = append(append([]*ai.Message{}, original.Messages...), ai.NewModelTextMessage(text.String()))
+		if len(original.Tools) > 0 {
+			req.Messages = append(req.Messages, ai.NewUserTextMessage(continuePrompt))
+		}
+		next, err := model.Generate(ctx, &req, nil)
+		if err != nil {
+			return nil, fmt.Errorf("bedrock: continuation round %d: %w", round+1, err)
+		}
+		if len(next.ToolRequests()) > 0 {
+			return nil, fmt.Errorf("bedrock: continuation round %d requested tools instead of continuing the answer", round+1)
+		}
+		text.WriteString(next.Text())
+		usage = addUsage(usage, next.Usage)
+		last = next
+	}
+
+	out := *last
+	out.Message = &ai.Message{Role: ai.RoleModel, Content: []*ai.Part{ai.NewTextPart(text.String())}, Metadata: last.Message.Metadata}
+	out.Usage = usage
+	out.Request = original
+	return &out, nil
+}
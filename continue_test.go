@@ -0,0 +1,132 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+)
+
+// scriptedConverseModel registers a Bedrock model whose Converse endpoint
+// answers with texts in order, each with its stop reason, and records each
+// request's messages.
+func scriptedConverseModel(t *testing.T, answers ...[2]string) (ai.Model, *[][]map[string]any) {
+	t.Helper()
+	var calls [][]map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []map[string]any `json:"messages"`
+		}
+		raw, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(raw, &body)
+		calls = append(calls, body.Messages)
+		answer := answers[min(len(calls), len(answers))-1]
+		text, _ := json.Marshal(answer[0])
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{
+			"output":     {"message":{"role":"assistant","content":[{"text":%s}]}},
+			"stopReason": %q,
+			"usage":      {"inputTokens":10,"outputTokens":4,"totalTokens":14}
+		}`, text, answer[1])
+	}))
+	t.Cleanup(server.Close)
+
+	b := testInitializedBedrock()
+	b.AWSConfig.HTTPClient = server.Client()
+	b.AWSConfig.BaseEndpoint = aws.String(server.URL)
+	g := genkit.Init(context.Background(), genkit.WithPlugins(b))
+	return b.DefineModel(g, ModelDefinition{Name: "amazon.nova-lite-v1:0", Type: "chat"}, nil), &calls
+}
+
+func TestContinueGeneration_StitchesTruncatedResponses(t *testing.T) {
+	ctx := context.Background()
+	model, calls := scriptedConverseModel(t,
+		[2]string{"Once upon", "max_tokens"},
+		[2]string{" a time, there", "max_tokens"},
+		[2]string{" was a fox.", "end_turn"},
+	)
+	first, err := model.Generate(ctx, &ai.ModelRequest{Messages: []*ai.Message{ai.NewUserTextMessage("Tell a story")}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Truncated(first) {
+		t.Fatal("first response is not truncated")
+	}
+
+	resp, err := ContinueGeneration(ctx, model, first, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Text(); got != "Once upon a time, there was a fox." {
+		t.Errorf("text = %q, want the stitched story", got)
+	}
+	if Truncated(resp) || resp.FinishReason != ai.FinishReasonStop {
+		t.Errorf("finish = %q, truncated = %v; want a complete answer", resp.FinishReason, Truncated(resp))
+	}
+	if resp.Usage == nil || resp.Usage.OutputTokens != 12 {
+		t.Errorf("usage = %+v, want output tokens summed over 3 calls", resp.Usage)
+	}
+	if len(*calls) != 3 {
+		t.Fatalf("calls = %d, want 3", len(*calls))
+	}
+	// The second continuation sends the partial answer so far as the
+	// assistant turn.
+	last := (*calls)[2]
+	prefill, _ := json.Marshal(last[len(last)-1])
+	if want := `{"content":[{"text":"Once upon a time, there"}],"role":"assistant"}`; string(prefill) != want {
+		t.Errorf("prefill = %s, want %s", prefill, want)
+	}
+}
+
+func TestContinueGeneration_StopsAfterMaxRounds(t *testing.T) {
+	ctx := context.Background()
+	model, calls := scriptedConverseModel(t, [2]string{"more", "max_tokens"})
+	first, err := model.Generate(ctx, &ai.ModelRequest{Messages: []*ai.Message{ai.NewUserTextMessage("Go on forever")}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := ContinueGeneration(ctx, model, first, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*calls) != 3 || resp.Text() != "moremoremore" || !Truncated(resp) {
+		t.Errorf("calls = %d, text = %q, truncated = %v; want 1+2 calls and a still-truncated answer", len(*calls), resp.Text(), Truncated(resp))
+	}
+}
+
+func TestContinueGeneration_CompleteResponseUnchanged(t *testing.T) {
+	model, calls := scriptedConverseModel(t, [2]string{"unused", "end_turn"})
+	resp := &ai.ModelResponse{Message: ai.NewModelTextMessage("done"), FinishReason: ai.FinishReasonStop}
+	got, err := ContinueGeneration(context.Background(), model, resp, 0)
+	if err != nil || got != resp || len(*calls) != 0 {
+		t.Errorf("got %v, %v after %d calls; want the response unchanged", got, err, len(*calls))
+	}
+	if _, err := ContinueGeneration(context.Background(), nil, resp, 0); err == nil {
+		t.Error("expected an error without a model")
+	}
+}
@@ -0,0 +1,300 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+// controlPlaneClient issues SigV4-signed REST-JSON calls to the Bedrock
+// control plane. The plugin depends only on the Bedrock Runtime SDK, so the
+// handful of control-plane operations it needs are called directly with the
+// same AWS config: its endpoint overrides, FIPS setting, HTTP client and
+// retryer apply as they would to an SDK client.
+type controlPlaneClient struct {
+	cfg      aws.Config
+	endpoint string // Resolved base URL; see controlPlaneEndpoint
+	signer   *v4.Signer
+	headers  map[string]string // Extra headers sent on every request; see Bedrock.RequestHeaders
+}
+
+func newControlPlaneClient(cfg aws.Config) *controlPlaneClient {
+	return &controlPlaneClient{
+		cfg:      cfg,
+		endpoint: controlPlaneEndpoint(context.Background(), cfg),
+		signer:   v4.NewSigner(),
+	}
+}
+
+// controlPlaneEndpoint resolves the control-plane base URL with the SDK's
+// precedence: a Bedrock-specific endpoint (AWS_ENDPOINT_URL_BEDROCK or the
+// shared config "services" section), then aws.Config.BaseEndpoint, then the
+// regional hostname, using the FIPS host when FIPS endpoints are enabled.
+func controlPlaneEndpoint(ctx context.Context, cfg aws.Config) string {
+	return serviceEndpoint(ctx, cfg, "Bedrock", "bedrock")
+}
+
+// serviceEndpoint resolves the base URL of the service with SDK ID sdkID and
+// regional hostname prefix host, as described for [controlPlaneEndpoint].
+func serviceEndpoint(ctx context.Context, cfg aws.Config, sdkID, host string) string {
+	if endpoint, ok := serviceEndpointOverride(ctx, cfg, sdkID); ok {
+		return endpoint
+	}
+	for _, src := range cfg.ConfigSources {
+		p, ok := src.(interface {
+			GetUseFIPSEndpoint(ctx context.Context) (aws.FIPSEndpointState, bool, error)
+		})
+		if !ok {
+			continue
+		}
+		if v, found, err := p.GetUseFIPSEndpoint(ctx); err == nil && found {
+			if v == aws.FIPSEndpointStateEnabled {
+				host += "-fips"
+			}
+			break
+		}
+	}
+	domain := "amazonaws.com"
+	if strings.HasPrefix(cfg.Region, "cn-") {
+		domain = "amazonaws.com.cn"
+	}
+	return fmt.Sprintf("https://%s.%s.%s", host, cfg.Region, domain)
+}
+
+// serviceEndpointOverride returns the endpoint configured for the service
+// with SDK ID sdkID (AWS_ENDPOINT_URL_<SDK ID>, the shared config "services"
+// section, or aws.Config.BaseEndpoint), and whether one is configured.
+func serviceEndpointOverride(ctx context.Context, cfg aws.Config, sdkID string) (string, bool) {
+	envVar := "AWS_ENDPOINT_URL_" + strings.ToUpper(strings.ReplaceAll(sdkID, " ", "_"))
+	_, global := os.LookupEnv("AWS_ENDPOINT_URL")
+	_, service := os.LookupEnv(envVar)
+	if !global || service {
+		for _, src := range cfg.ConfigSources {
+			p, ok := src.(interface {
+				GetServiceBaseEndpoint(ctx context.Context, sdkID string) (string, bool, error)
+			})
+			if !ok {
+				continue
+			}
+			if v, found, err := p.GetServiceBaseEndpoint(ctx, sdkID); err == nil && found && v != "" {
+				return strings.TrimRight(v, "/"), true
+			}
+		}
+	}
+	if cfg.BaseEndpoint != nil && *cfg.BaseEndpoint != "" {
+		return strings.TrimRight(*cfg.BaseEndpoint, "/"), true
+	}
+	return "", false
+}
+
+// retryer returns the configured retryer, or the SDK retryer for the
+// config's retry mode and maximum attempts, as SDK clients resolve it.
+func (c *controlPlaneClient) retryer() aws.Retryer {
+	if c.cfg.Retryer != nil {
+		if r := c.cfg.Retryer(); r != nil {
+			return r
+		}
+	}
+	standard := func(o *retry.StandardOptions) {
+		if c.cfg.RetryMaxAttempts > 0 {
+			o.MaxAttempts = c.cfg.RetryMaxAttempts
+		}
+	}
+	if c.cfg.RetryMode == aws.RetryModeAdaptive {
+		return retry.NewAdaptiveMode(func(o *retry.AdaptiveModeOptions) {
+			o.StandardOptions = append(o.StandardOptions, standard)
+		})
+	}
+	return retry.NewStandard(standard)
+}
+
+// do sends a signed request with in (if non-nil) as the JSON body and decodes
+// a 2xx JSON response into out (if non-nil). path must already be escaped.
+// Throttling, 5xx and connection errors are retried as the retryer allows.
+func (c *controlPlaneClient) do(ctx context.Context, method, path string, in, out any) error {
+	var body []byte
+	if in != nil {
+		var err error
+		body, err = json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("marshal request: %w", err)
+		}
+	}
+	if c.cfg.Credentials == nil {
+		return errors.New("no AWS credentials configured")
+	}
+
+	var respBody []byte
+	err := c.withRetries(ctx, func() error {
+		var err error
+		respBody, err = c.send(ctx, method, path, in != nil, body)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if out == nil || len(respBody) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("unmarshal response: %w", err)
+	}
+	return nil
+}
+
+// withRetries calls attempt until it succeeds, retrying throttling, 5xx and
+// connection errors as the retryer allows.
+func (c *controlPlaneClient) withRetries(ctx context.Context, attempt func() error) error {
+	retryer := c.retryer()
+	for n := 1; ; n++ {
+		err := attempt()
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil || n >= retryer.MaxAttempts() || !retryer.IsErrorRetryable(err) {
+			return err
+		}
+		if _, tokenErr := retryer.GetRetryToken(ctx, err); tokenErr != nil {
+			return err
+		}
+		delay, delayErr := retryer.RetryDelay(n, err)
+		if delayErr != nil {
+			return err
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// send performs a single signed attempt and returns the 2xx response body.
+func (c *controlPlaneClient) send(ctx context.Context, method, path string, hasBody bool, body []byte) ([]byte, error) {
+	resp, err := c.open(ctx, method, path, "application/json", hasBody, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	return respBody, nil
+}
+
+// open performs a single signed attempt and returns the 2xx response, whose
+// body the caller must close.
+func (c *controlPlaneClient) open(ctx context.Context, method, path, accept string, hasBody bool, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Accept", accept)
+	if hasBody {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range c.headers {
+		req.Header.Set(name, value)
+	}
+
+	creds, err := c.cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("retrieve AWS credentials: %w", err)
+	}
+	sum := sha256.Sum256(body)
+	if err := c.signer.SignHTTP(ctx, creds, req, hex.EncodeToString(sum[:]), "bedrock", c.cfg.Region, time.Now()); err != nil {
+		return nil, fmt.Errorf("sign request: %w", err)
+	}
+
+	var httpClient aws.HTTPClient = http.DefaultClient
+	if c.cfg.HTTPClient != nil {
+		httpClient = c.cfg.HTTPClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("read response: %w", err)
+		}
+		return nil, controlPlaneError(resp, respBody)
+	}
+	return resp, nil
+}
+
+// controlPlaneAPIError is a REST-JSON error response. It exposes the status
+// code and error code so the SDK retryers classify it like a service error.
+type controlPlaneAPIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *controlPlaneAPIError) Error() string {
+	return fmt.Sprintf("HTTP %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// HTTPStatusCode returns the response status code.
+func (e *controlPlaneAPIError) HTTPStatusCode() int { return e.StatusCode }
+
+// ErrorCode returns the service error type, e.g. "ThrottlingException".
+func (e *controlPlaneAPIError) ErrorCode() string { return e.Code }
+
+// controlPlaneError builds the error for a non-2xx response, preferring the
+// service's error type header and message field over the raw body.
+func controlPlaneError(resp *http.Response, body []byte) error {
+	var payload struct {
+		Message      string `json:"message"`
+		MessageUpper string `json:"Message"`
+	}
+	_ = json.Unmarshal(body, &payload)
+	msg := payload.Message
+	if msg == "" {
+		msg = payload.MessageUpper
+	}
+	if msg == "" {
+		msg = string(bytes.TrimSpace(body))
+	}
+	code, _, _ := strings.Cut(resp.Header.Get("X-Amzn-Errortype"), ":")
+	if code == "" {
+		code = http.StatusText(resp.StatusCode)
+	}
+	return &controlPlaneAPIError{StatusCode: resp.StatusCode, Code: code, Message: msg}
+}
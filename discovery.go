@@ -0,0 +1,163 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// FoundationModel describes a model listed by the Bedrock control plane's
+// ListFoundationModels operation.
+type FoundationModel struct {
+	ID               string   // Model ID, e.g. "anthropic.claude-3-5-haiku-20241022-v1:0"
+	Name             string   // Display name
+	Provider         string   // Provider name, e.g. "Anthropic"
+	InputModalities  []string // "TEXT", "IMAGE", "VIDEO" or "SPEECH"
+	OutputModalities []string // "TEXT", "IMAGE", "EMBEDDING" or "SPEECH"
+	Streaming        bool     // Whether the model supports response streaming
+	InferenceTypes   []string // "ON_DEMAND", "PROVISIONED" or "INFERENCE_PROFILE"
+	Lifecycle        string   // "ACTIVE" or "LEGACY"
+}
+
+// modelLister lists the foundation models available in the plugin's
+// region. The plugin creates one at Init from its AWS config.
+type modelLister interface {
+	ListFoundationModels(ctx context.Context) ([]FoundationModel, error)
+}
+
+type listFoundationModelsResponse struct {
+	ModelSummaries []struct {
+		ModelID                    string   `json:"modelId"`
+		ModelName                  string   `json:"modelName"`
+		ProviderName               string   `json:"providerName"`
+		InputModalities            []string `json:"inputModalities"`
+		OutputModalities           []string `json:"outputModalities"`
+		ResponseStreamingSupported bool     `json:"responseStreamingSupported"`
+		InferenceTypesSupported    []string `json:"inferenceTypesSupported"`
+		ModelLifecycle             struct {
+			Status string `json:"status"`
+		} `json:"modelLifecycle"`
+	} `json:"modelSummaries"`
+}
+
+// ListFoundationModels implements [modelLister].
+func (c *controlPlaneClient) ListFoundationModels(ctx context.Context) ([]FoundationModel, error) {
+	var resp listFoundationModelsResponse
+	if err := c.do(ctx, "GET", "/foundation-models", nil, &resp); err != nil {
+		return nil, err
+	}
+	models := make([]FoundationModel, 0, len(resp.ModelSummaries))
+	for _, s := range resp.ModelSummaries {
+		models = append(models, FoundationModel{
+			ID:               s.ModelID,
+			Name:             s.ModelName,
+			Provider:         s.ProviderName,
+			InputModalities:  s.InputModalities,
+			OutputModalities: s.OutputModalities,
+			Streaming:        s.ResponseStreamingSupported,
+			InferenceTypes:   s.InferenceTypesSupported,
+			Lifecycle:        s.ModelLifecycle.Status,
+		})
+	}
+	return models, nil
+}
+
+// RefreshModels lists the foundation models available in the plugin's
+// region and replaces the discovered model registry with them, so models
+// enabled after startup can be defined with capabilities from their
+// listing (see [Bedrock.DiscoverModels]). It is safe to call concurrently
+// with generation; on error the registry is left unchanged.
+func (b *Bedrock) RefreshModels(ctx context.Context) error {
+	b.mu.Lock()
+	initted, lister, timeout := b.initted, b.lister, b.RequestTimeout
+	b.mu.Unlock()
+	if !initted {
+		return errors.New("bedrock.RefreshModels: plugin not initialized")
+	}
+	return b.refreshModels(ctx, lister, timeout)
+}
+
+func (b *Bedrock) refreshModels(ctx context.Context, lister modelLister, timeout time.Duration) error {
+	if lister == nil {
+		return errors.New("bedrock.RefreshModels: model lister required")
+	}
+	ctx, cancel := withRequestTimeout(ctx, timeout)
+	defer cancel()
+	models, err := lister.ListFoundationModels(ctx)
+	if err != nil {
+		return fmt.Errorf("bedrock.RefreshModels: %w", err)
+	}
+	registry := make(map[string]FoundationModel, len(models))
+	for _, m := range models {
+		registry[m.ID] = m
+	}
+	b.discovered.Store(&registry)
+	slog.Debug("bedrock: refreshed discovered models", "count", len(registry))
+	return nil
+}
+
+// DiscoveredModels returns the models found by the last successful
+// discovery, sorted by ID, or nil before the first one.
+func (b *Bedrock) DiscoveredModels() []FoundationModel {
+	registry := b.discovered.Load()
+	if registry == nil {
+		return nil
+	}
+	ids := slices.Sorted(maps.Keys(*registry))
+	models := make([]FoundationModel, 0, len(ids))
+	for _, id := range ids {
+		models = append(models, (*registry)[id])
+	}
+	return models
+}
+
+// discoveredModel returns the discovered listing for a base model ID.
+func (b *Bedrock) discoveredModel(modelID string) (FoundationModel, bool) {
+	registry := b.discovered.Load()
+	if registry == nil {
+		return FoundationModel{}, false
+	}
+	m, ok := (*registry)[modelID]
+	return m, ok
+}
+
+// discoveredCapability derives capabilities for a model outside the curated
+// capability map from its listing. Listings do not report tool use, so it is
+// assumed, as for other uncurated models.
+func discoveredCapability(m FoundationModel) ModelCapability {
+	return ModelCapability{
+		Multimodal:   slices.Contains(m.InputModalities, "IMAGE"),
+		Video:        slices.Contains(m.InputModalities, "VIDEO"),
+		Tools:        true,
+		SystemPrompt: true,
+		Profile:      slices.Contains(m.InferenceTypes, "INFERENCE_PROFILE"),
+	}
+}
+
+// isLegacyModel reports whether a discovered listing marks modelID legacy.
+func (b *Bedrock) isLegacyModel(modelID string) bool {
+	m, ok := b.discoveredModel(modelID)
+	return ok && strings.EqualFold(m.Lifecycle, "LEGACY")
+}
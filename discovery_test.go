@@ -0,0 +1,147 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+type fakeModelLister struct {
+	mu     sync.Mutex
+	models []FoundationModel
+	err    error
+}
+
+func (f *fakeModelLister) ListFoundationModels(ctx context.Context) ([]FoundationModel, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.models, f.err
+}
+
+func (f *fakeModelLister) set(models []FoundationModel, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.models, f.err = models, err
+}
+
+func TestRefreshModels_UpdatesRegistry(t *testing.T) {
+	const newModel = "acme.vision-chat-v1:0"
+	lister := &fakeModelLister{models: []FoundationModel{
+		{ID: "amazon.nova-lite-v1:0", InputModalities: []string{"TEXT", "IMAGE", "VIDEO"}, Lifecycle: "ACTIVE"},
+	}}
+	b := &Bedrock{initted: true, lister: lister}
+	if err := b.RefreshModels(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.DiscoveredModels(); len(got) != 1 || got[0].ID != "amazon.nova-lite-v1:0" {
+		t.Fatalf("DiscoveredModels() = %+v, want nova-lite only", got)
+	}
+	if _, ok := b.discoveredModel(newModel); ok {
+		t.Fatalf("%s discovered before it was listed", newModel)
+	}
+
+	// Generation keeps reading the registry while it is replaced.
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				b.inferModelCapabilities(newModel, "chat")
+			}
+		}
+	}()
+	lister.set([]FoundationModel{
+		{ID: "amazon.nova-lite-v1:0", InputModalities: []string{"TEXT", "IMAGE", "VIDEO"}, Lifecycle: "ACTIVE"},
+		{ID: newModel, InputModalities: []string{"TEXT"}, InferenceTypes: []string{"ON_DEMAND"}, Lifecycle: "ACTIVE"},
+		{ID: "amazon.titan-text-lite-v1", InputModalities: []string{"TEXT"}, Lifecycle: "LEGACY"},
+	}, nil)
+	err := b.RefreshModels(context.Background())
+	close(stop)
+	wg.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := b.DiscoveredModels()
+	if len(got) != 3 || got[0].ID != newModel {
+		t.Fatalf("DiscoveredModels() = %+v, want 3 models sorted by ID", got)
+	}
+	info := b.inferModelCapabilities(newModel, "chat")
+	if info.Supports.Media || !info.Supports.Tools || info.Stage != ai.ModelStageUnstable {
+		t.Errorf("%s info = %+v (stage %s), want text-only unstable model with tools", newModel, info.Supports, info.Stage)
+	}
+	if stage := b.inferModelCapabilities("amazon.titan-text-lite-v1", "chat").Stage; stage != ai.ModelStageLegacy {
+		t.Errorf("legacy model stage = %s, want legacy", stage)
+	}
+
+	lister.set(nil, errors.New("throttled"))
+	if err := b.RefreshModels(context.Background()); err == nil || !strings.Contains(err.Error(), "bedrock.RefreshModels: throttled") {
+		t.Fatalf("RefreshModels error = %v, want the listing error", err)
+	}
+	if len(b.DiscoveredModels()) != 3 {
+		t.Errorf("a failed refresh changed the registry")
+	}
+}
+
+func TestRefreshModels_NotInitialized(t *testing.T) {
+	if err := (&Bedrock{}).RefreshModels(context.Background()); err == nil || !strings.Contains(err.Error(), "not initialized") {
+		t.Fatalf("RefreshModels error = %v, want not initialized", err)
+	}
+}
+
+func TestControlPlaneClient_ListFoundationModelsWireFormat(t *testing.T) {
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.Method + " " + r.URL.Path
+		_, _ = w.Write([]byte(`{"modelSummaries":[{"modelId":"amazon.nova-pro-v1:0","modelName":"Nova Pro","providerName":"Amazon",` +
+			`"inputModalities":["TEXT","IMAGE","VIDEO"],"outputModalities":["TEXT"],"responseStreamingSupported":true,` +
+			`"inferenceTypesSupported":["ON_DEMAND","INFERENCE_PROFILE"],"modelLifecycle":{"status":"ACTIVE"}}]}`))
+	}))
+	defer server.Close()
+
+	models, err := newControlPlaneClient(testControlPlaneConfig(server)).ListFoundationModels(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "GET /foundation-models" {
+		t.Errorf("request = %q, want GET /foundation-models", path)
+	}
+	if len(models) != 1 {
+		t.Fatalf("models = %+v, want one", models)
+	}
+	m := models[0]
+	if m.ID != "amazon.nova-pro-v1:0" || m.Provider != "Amazon" || !m.Streaming || m.Lifecycle != "ACTIVE" || len(m.InferenceTypes) != 2 {
+		t.Errorf("model = %+v", m)
+	}
+	if caps := discoveredCapability(m); !caps.Multimodal || !caps.Video || !caps.Profile {
+		t.Errorf("discoveredCapability() = %+v, want image, video and profile support", caps)
+	}
+}
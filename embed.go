@@ -21,15 +21,87 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"slices"
 	"strings"
-	"sync"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
 	"github.com/firebase/genkit/go/ai"
 )
 
+// EmbedOptions configures an embedding call. Pass it via
+// [ai.EmbedRequest.Options], e.g. with [ai.WithConfig] on [genkit.Embed].
+type EmbedOptions struct {
+	// Normalize controls whether Titan Text Embeddings V2 returns unit-length
+	// vectors. nil uses the default (true), which keeps cosine similarity
+	// well-behaved in vector stores; false returns raw vectors. Ignored by
+	// other embedding models.
+	Normalize *bool `json:"normalize,omitempty"`
+	// ClientNormalize scales the returned vectors to unit length in the
+	// plugin, for models without server-side normalization. nil uses
+	// [Bedrock.ClientNormalize]. Vectors Titan V2 already normalized are left
+	// as they are.
+	ClientNormalize *bool `json:"clientNormalize,omitempty"`
+}
+
+// defaultEmbedDimensions maps embedding model ID prefixes to the size of the
+// vectors they return by default.
+var defaultEmbedDimensions = map[string]int{
+	"amazon.titan-embed-text-v1":   1536,
+	"amazon.titan-embed-text-v2":   1024,
+	"amazon.titan-embed-image-v1":  1024,
+	"cohere.embed-english-v3":      1024,
+	"cohere.embed-multilingual-v3": 1024,
+	"cohere.embed-v4":              1536,
+}
+
+// titanTextV2Dimensions are the vector sizes Titan Text Embeddings V2 accepts.
+var titanTextV2Dimensions = []int{256, 512, 1024}
+
+// EmbedderDimensions returns the size of the vectors modelName returns: the
+// value set in [Bedrock.EmbedDimensions], else the model's default, or 0 when
+// it is not known. It is also reported in the info of embedders defined with
+// [Bedrock.DefineEmbedder].
+func (b *Bedrock) EmbedderDimensions(modelName string) int {
+	if d, ok := b.EmbedDimensions[modelName]; ok {
+		return d
+	}
+	base := baseModelID(modelName)
+	for prefix, d := range defaultEmbedDimensions {
+		if strings.HasPrefix(base, prefix) {
+			return d
+		}
+	}
+	return 0
+}
+
+// checkEmbedDimensions validates the EmbedDimensions entry for modelName, if
+// any: only Titan Text Embeddings V2 takes a vector size.
+func (b *Bedrock) checkEmbedDimensions(modelName string) error {
+	d, ok := b.EmbedDimensions[modelName]
+	if !ok {
+		return nil
+	}
+	if !isTitanTextV2(modelName) {
+		return fmt.Errorf("bedrock: EmbedDimensions[%q]: the model's vector size is not configurable; only Titan Text Embeddings V2 accepts one", modelName)
+	}
+	if !slices.Contains(titanTextV2Dimensions, d) {
+		return fmt.Errorf("bedrock: EmbedDimensions[%q] = %d; Titan Text Embeddings V2 accepts 256, 512 or 1024", modelName, d)
+	}
+	return nil
+}
+
+// normalize reports the effective Titan V2 normalize flag.
+func (o *EmbedOptions) normalize() bool {
+	if o == nil || o.Normalize == nil {
+		return true
+	}
+	return *o.Normalize
+}
+
 // embed routes an embedding request to the appropriate model-family handler.
 // Supported families:
 //   - Amazon Titan Embed Image (titan-embed-image) — multimodal text + image
@@ -43,69 +115,142 @@ func (b *Bedrock) embed(ctx context.Context, modelName string, req *ai.EmbedRequ
 	if len(req.Input) == 0 {
 		return nil, fmt.Errorf("embed: request contains no documents")
 	}
+	opts, err := embedOptions(req.Options)
+	if err != nil {
+		return nil, err
+	}
 
+	var resp *ai.EmbedResponse
 	switch {
 	case strings.Contains(modelName, "titan-embed-image"):
-		return b.embedTitanMultimodal(ctx, modelName, req)
+		resp, err = b.embedTitanMultimodal(ctx, modelName, req)
 	case strings.Contains(modelName, "titan-embed"):
-		return b.embedTitanText(ctx, modelName, req)
+		resp, err = b.embedTitanText(ctx, modelName, req, opts)
 	case strings.Contains(modelName, "cohere"):
-		return b.embedCohere(ctx, modelName, req)
+		resp, err = b.embedCohere(ctx, modelName, req)
 	case strings.Contains(modelName, "nova-embed"):
-		return b.embedNova(ctx, modelName, req)
+		resp, err = b.embedNova(ctx, modelName, req)
 	default:
 		return nil, fmt.Errorf("embed: unsupported embedding model %q", modelName)
 	}
+	if resp == nil {
+		return nil, err
+	}
+	if b.clientNormalize(modelName, opts) {
+		for _, e := range resp.Embeddings {
+			if e != nil {
+				normalizeVector(e.Embedding)
+			}
+		}
+	}
+	return resp, err
 }
 
-// embedConcurrencyLimit caps the number of simultaneous InvokeModel calls to
-// avoid AWS Bedrock ThrottlingException under large document batches.
-const embedConcurrencyLimit = 10
-
-// embedTitanText embeds documents using Amazon Titan text embedding models.
-// Documents are processed concurrently; results are reassembled in original order.
-func (b *Bedrock) embedTitanText(ctx context.Context, modelName string, req *ai.EmbedRequest) (*ai.EmbedResponse, error) {
-	embeddings := make([]*ai.Embedding, len(req.Input))
-	errs := make([]error, len(req.Input))
-	var wg sync.WaitGroup
-	sem := make(chan struct{}, embedConcurrencyLimit)
+// PartialEmbedError is returned when the context is cancelled or times out
+// partway through an embedding batch. Embeddings holds the vectors computed
+// so far, aligned with the request's documents: the entries of documents
+// that were not embedded are nil, so indexing can resume with just those.
+// The response returned with the error carries the same embeddings.
+type PartialEmbedError struct {
+	Embeddings []*ai.Embedding
+	Err        error // The context error, as reported by the interrupted call
+}
 
-	for i, doc := range req.Input {
-		wg.Add(1)
-		go func(idx int, d *ai.Document) {
-			defer wg.Done()
-			select {
-			case sem <- struct{}{}:
-				defer func() { <-sem }()
-			case <-ctx.Done():
-				errs[idx] = ctx.Err()
-				return
-			}
-			if d == nil {
-				errs[idx] = fmt.Errorf("embed: document %d is nil", idx)
-				return
-			}
-			text := documentText(d)
-			if text == "" {
-				errs[idx] = fmt.Errorf("embed: document %d has no text content", idx)
-				return
-			}
-			emb, err := b.getTitanTextEmbedding(ctx, modelName, text)
-			if err != nil {
-				errs[idx] = fmt.Errorf("embed: document %d: %w", idx, err)
-				return
-			}
-			embeddings[idx] = &ai.Embedding{Embedding: emb}
-		}(i, doc)
+func (e *PartialEmbedError) Error() string {
+	done := 0
+	for _, emb := range e.Embeddings {
+		if emb != nil {
+			done++
+		}
 	}
-	wg.Wait()
+	return fmt.Sprintf("bedrock: embedding interrupted after %d of %d documents: %v", done, len(e.Embeddings), e.Err)
+}
+
+func (e *PartialEmbedError) Unwrap() error { return e.Err }
 
+// embedResponse assembles the result of a batch embedded document by
+// document, with errs indexed like embeddings. The first error fails the
+// batch, unless every error is the context ending: the documents embedded
+// before then are returned with a [PartialEmbedError].
+func embedResponse(ctx context.Context, embeddings []*ai.Embedding, errs []error) (*ai.EmbedResponse, error) {
+	var first error
 	for _, err := range errs {
-		if err != nil {
+		if err == nil {
+			continue
+		}
+		if !interrupted(ctx, err) {
 			return nil, err
 		}
+		if first == nil {
+			first = err
+		}
 	}
-	return &ai.EmbedResponse{Embeddings: embeddings}, nil
+	resp := &ai.EmbedResponse{Embeddings: embeddings}
+	if first != nil {
+		return resp, &PartialEmbedError{Embeddings: embeddings, Err: first}
+	}
+	return resp, nil
+}
+
+// interrupted reports whether err comes from ctx being cancelled or timing
+// out.
+func interrupted(ctx context.Context, err error) bool {
+	return ctx.Err() != nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded))
+}
+
+// clientNormalize reports whether the plugin should scale modelName's vectors
+// to unit length: when asked to by opts or [Bedrock.ClientNormalize], unless
+// Titan V2 already returned them normalized.
+func (b *Bedrock) clientNormalize(modelName string, opts *EmbedOptions) bool {
+	enabled := b.ClientNormalize
+	if opts != nil && opts.ClientNormalize != nil {
+		enabled = *opts.ClientNormalize
+	}
+	if !enabled {
+		return false
+	}
+	return !isTitanTextV2(modelName) || !opts.normalize()
+}
+
+// normalizeVector scales v in place to unit L2 norm. Zero vectors are left
+// unchanged.
+func normalizeVector(v []float32) {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return
+	}
+	norm := math.Sqrt(sum)
+	for i, x := range v {
+		v[i] = float32(float64(x) / norm)
+	}
+}
+
+// embedTitanText embeds documents using Amazon Titan text embedding models.
+// Documents are processed concurrently under an adaptive limiter (see
+// [embedEach]); results are reassembled in original order.
+func (b *Bedrock) embedTitanText(ctx context.Context, modelName string, req *ai.EmbedRequest, opts *EmbedOptions) (*ai.EmbedResponse, error) {
+	embeddings := make([]*ai.Embedding, len(req.Input))
+	errs := embedEach(ctx, len(req.Input), func(idx int) error {
+		d := req.Input[idx]
+		if d == nil {
+			return fmt.Errorf("embed: document %d is nil", idx)
+		}
+		text := documentText(d)
+		if text == "" {
+			return fmt.Errorf("embed: document %d has no text content", idx)
+		}
+		emb, err := b.getTitanTextEmbedding(ctx, modelName, text, opts)
+		if err != nil {
+			return fmt.Errorf("embed: document %d: %w", idx, err)
+		}
+		embeddings[idx] = &ai.Embedding{Embedding: emb}
+		return nil
+	})
+
+	return embedResponse(ctx, embeddings, errs)
 }
 
 // embedTitanMultimodal embeds documents using the Amazon Titan multimodal
@@ -116,51 +261,28 @@ func (b *Bedrock) embedTitanText(ctx context.Context, modelName string, req *ai.
 // Titan multimodal only supports JPEG and PNG images.
 func (b *Bedrock) embedTitanMultimodal(ctx context.Context, modelName string, req *ai.EmbedRequest) (*ai.EmbedResponse, error) {
 	embeddings := make([]*ai.Embedding, len(req.Input))
-	errs := make([]error, len(req.Input))
-	var wg sync.WaitGroup
-	sem := make(chan struct{}, embedConcurrencyLimit)
-
-	for i, doc := range req.Input {
-		wg.Add(1)
-		go func(idx int, d *ai.Document) {
-			defer wg.Done()
-			select {
-			case sem <- struct{}{}:
-				defer func() { <-sem }()
-			case <-ctx.Done():
-				errs[idx] = ctx.Err()
-				return
-			}
-			if d == nil {
-				errs[idx] = fmt.Errorf("embed: document %d is nil", idx)
-				return
-			}
-			text := documentText(d)
-			mime, imgBase64 := imageFromDocument(d)
-			if text == "" && imgBase64 == "" {
-				errs[idx] = fmt.Errorf("embed: document %d has no text or image content", idx)
-				return
-			}
-			if imgBase64 != "" && !isTitanSupportedImageMIME(mime) {
-				errs[idx] = fmt.Errorf("embed: document %d image format %q is not supported by Titan (use JPEG or PNG)", idx, mime)
-				return
-			}
-			emb, err := b.getTitanMultimodalEmbedding(ctx, modelName, text, imgBase64)
-			if err != nil {
-				errs[idx] = fmt.Errorf("embed: document %d: %w", idx, err)
-				return
-			}
-			embeddings[idx] = &ai.Embedding{Embedding: emb}
-		}(i, doc)
-	}
-	wg.Wait()
-
-	for _, err := range errs {
+	errs := embedEach(ctx, len(req.Input), func(idx int) error {
+		d := req.Input[idx]
+		if d == nil {
+			return fmt.Errorf("embed: document %d is nil", idx)
+		}
+		text := documentText(d)
+		mime, imgBase64 := imageFromDocument(d)
+		if text == "" && imgBase64 == "" {
+			return fmt.Errorf("embed: document %d has no text or image content", idx)
+		}
+		if imgBase64 != "" && !isTitanSupportedImageMIME(mime) {
+			return fmt.Errorf("embed: document %d image format %q is not supported by Titan (use JPEG or PNG)", idx, mime)
+		}
+		emb, err := b.getTitanMultimodalEmbedding(ctx, modelName, text, imgBase64)
 		if err != nil {
-			return nil, err
+			return fmt.Errorf("embed: document %d: %w", idx, err)
 		}
-	}
-	return &ai.EmbedResponse{Embeddings: embeddings}, nil
+		embeddings[idx] = &ai.Embedding{Embedding: emb}
+		return nil
+	})
+
+	return embedResponse(ctx, embeddings, errs)
 }
 
 // embedCohere embeds documents using a Cohere embedding model. Text documents
@@ -196,8 +318,10 @@ func (b *Bedrock) embedCohere(ctx context.Context, modelName string, req *ai.Emb
 
 	embeddings := make([]*ai.Embedding, len(req.Input))
 
-	// Batch text documents in API calls of up to 96 documents (Bedrock Cohere limit).
+	// Batch text documents in API calls of up to 96 documents (Bedrock Cohere
+	// limit). Batches run one at a time, retried when throttled.
 	const cohereTextBatchSize = 96
+	limiter := newEmbedLimiter(1)
 	for i := 0; i < len(textSlots); i += cohereTextBatchSize {
 		end := min(i+cohereTextBatchSize, len(textSlots))
 		chunk := textSlots[i:end]
@@ -205,9 +329,18 @@ func (b *Bedrock) embedCohere(ctx context.Context, modelName string, req *ai.Emb
 		for j, s := range chunk {
 			texts[j] = s.content
 		}
-		batch, err := b.getCohereTextEmbeddings(ctx, modelName, texts)
+		var batch [][]float32
+		err := limiter.do(ctx, func() error {
+			var err error
+			batch, err = b.getCohereTextEmbeddings(ctx, modelName, texts)
+			return err
+		})
 		if err != nil {
-			return nil, fmt.Errorf("embed: Cohere text batch: %w", err)
+			err = fmt.Errorf("embed: Cohere text batch: %w", err)
+			if interrupted(ctx, err) {
+				return &ai.EmbedResponse{Embeddings: embeddings}, &PartialEmbedError{Embeddings: embeddings, Err: err}
+			}
+			return nil, err
 		}
 		if len(batch) != len(chunk) {
 			return nil, fmt.Errorf("embed: Cohere returned %d text embeddings for %d inputs", len(batch), len(chunk))
@@ -220,43 +353,27 @@ func (b *Bedrock) embedCohere(ctx context.Context, modelName string, req *ai.Emb
 	// Process image documents concurrently.
 	if len(imageSlots) > 0 {
 		imgEmbs := make([][]float32, len(imageSlots))
-		imgErrs := make([]error, len(imageSlots))
-		var wg sync.WaitGroup
-		sem := make(chan struct{}, embedConcurrencyLimit)
-
-		for i, s := range imageSlots {
-			wg.Add(1)
-			go func(batchIdx int, imgBase64 string) {
-				defer wg.Done()
-				select {
-				case sem <- struct{}{}:
-					defer func() { <-sem }()
-				case <-ctx.Done():
-					imgErrs[batchIdx] = ctx.Err()
-					return
-				}
-				batch, err := b.getCohereImageEmbeddings(ctx, modelName, []string{imgBase64})
-				if err != nil {
-					imgErrs[batchIdx] = err
-					return
-				}
-				if len(batch) == 0 {
-					imgErrs[batchIdx] = fmt.Errorf("cohere returned no embedding for image")
-					return
-				}
-				imgEmbs[batchIdx] = batch[0]
-			}(i, s.content)
-		}
-		wg.Wait()
-
-		for i, err := range imgErrs {
+		imgErrs := embedEach(ctx, len(imageSlots), func(batchIdx int) error {
+			batch, err := b.getCohereImageEmbeddings(ctx, modelName, []string{imageSlots[batchIdx].content})
 			if err != nil {
-				return nil, fmt.Errorf("embed: Cohere image document %d: %w", imageSlots[i].idx, err)
+				return err
 			}
-		}
+			if len(batch) == 0 {
+				return fmt.Errorf("cohere returned no embedding for image")
+			}
+			imgEmbs[batchIdx] = batch[0]
+			return nil
+		})
+
+		errs := make([]error, len(req.Input))
 		for i, s := range imageSlots {
+			if err := imgErrs[i]; err != nil {
+				errs[s.idx] = fmt.Errorf("embed: Cohere image document %d: %w", s.idx, err)
+				continue
+			}
 			embeddings[s.idx] = &ai.Embedding{Embedding: imgEmbs[i]}
 		}
+		return embedResponse(ctx, embeddings, errs)
 	}
 
 	return &ai.EmbedResponse{Embeddings: embeddings}, nil
@@ -266,51 +383,39 @@ func (b *Bedrock) embedCohere(ctx context.Context, modelName string, req *ai.Emb
 // Documents are processed concurrently; results are reassembled in original order.
 func (b *Bedrock) embedNova(ctx context.Context, modelName string, req *ai.EmbedRequest) (*ai.EmbedResponse, error) {
 	embeddings := make([]*ai.Embedding, len(req.Input))
-	errs := make([]error, len(req.Input))
-	var wg sync.WaitGroup
-	sem := make(chan struct{}, embedConcurrencyLimit)
-
-	for i, doc := range req.Input {
-		wg.Add(1)
-		go func(idx int, d *ai.Document) {
-			defer wg.Done()
-			select {
-			case sem <- struct{}{}:
-				defer func() { <-sem }()
-			case <-ctx.Done():
-				errs[idx] = ctx.Err()
-				return
-			}
-			if d == nil {
-				errs[idx] = fmt.Errorf("embed: document %d is nil", idx)
-				return
-			}
-			text := documentText(d)
-			if text == "" {
-				errs[idx] = fmt.Errorf("embed: document %d has no text content", idx)
-				return
-			}
-			emb, err := b.getNovaEmbedding(ctx, modelName, text)
-			if err != nil {
-				errs[idx] = fmt.Errorf("embed: document %d: %w", idx, err)
-				return
-			}
-			embeddings[idx] = &ai.Embedding{Embedding: emb}
-		}(i, doc)
-	}
-	wg.Wait()
-
-	for _, err := range errs {
+	errs := embedEach(ctx, len(req.Input), func(idx int) error {
+		d := req.Input[idx]
+		if d == nil {
+			return fmt.Errorf("embed: document %d is nil", idx)
+		}
+		text := documentText(d)
+		if text == "" {
+			return fmt.Errorf("embed: document %d has no text content", idx)
+		}
+		emb, err := b.getNovaEmbedding(ctx, modelName, text)
 		if err != nil {
-			return nil, err
+			return fmt.Errorf("embed: document %d: %w", idx, err)
 		}
-	}
-	return &ai.EmbedResponse{Embeddings: embeddings}, nil
+		embeddings[idx] = &ai.Embedding{Embedding: emb}
+		return nil
+	})
+
+	return embedResponse(ctx, embeddings, errs)
 }
 
 // getTitanTextEmbedding calls a Titan text embedding model for a single text.
-func (b *Bedrock) getTitanTextEmbedding(ctx context.Context, modelName, text string) ([]float32, error) {
-	body, err := json.Marshal(map[string]any{"inputText": text})
+// Titan V2 additionally receives the normalize flag from opts and any vector
+// size set in EmbedDimensions; V1 has no such parameters and rejects unknown
+// fields.
+func (b *Bedrock) getTitanTextEmbedding(ctx context.Context, modelName, text string, opts *EmbedOptions) ([]float32, error) {
+	reqBody := map[string]any{"inputText": text}
+	if isTitanTextV2(modelName) {
+		reqBody["normalize"] = opts.normalize()
+		if d, ok := b.EmbedDimensions[modelName]; ok {
+			reqBody["dimensions"] = d
+		}
+	}
+	body, err := json.Marshal(reqBody)
 	if err != nil {
 		return nil, err
 	}
@@ -542,6 +647,38 @@ func imageFromDocument(doc *ai.Document) (mimeType, base64Data string) {
 	return "", ""
 }
 
+// isTitanTextV2 reports whether modelName is a Titan Text Embeddings V2 model,
+// the only Titan text embedder that accepts normalize/dimensions parameters.
+func isTitanTextV2(modelName string) bool {
+	return strings.Contains(modelName, "titan-embed-text-v2")
+}
+
+// embedOptions extracts [EmbedOptions] from the request's Options field,
+// accepting either a value, pointer, or JSON-deserialized map. It returns nil
+// options when options are absent.
+func embedOptions(o any) (*EmbedOptions, error) {
+	switch v := o.(type) {
+	case nil:
+		return nil, nil
+	case *EmbedOptions:
+		return v, nil
+	case EmbedOptions:
+		return &v, nil
+	case map[string]any:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("embed: failed to marshal embed options: %w", err)
+		}
+		var opts EmbedOptions
+		if err := json.Unmarshal(b, &opts); err != nil {
+			return nil, fmt.Errorf("embed: failed to unmarshal embed options: %w", err)
+		}
+		return &opts, nil
+	default:
+		return nil, fmt.Errorf("embed: unsupported embed options type %T", o)
+	}
+}
+
 // isTitanSupportedImageMIME reports whether a MIME type is accepted by the
 // Amazon Titan multimodal embedding model (JPEG and PNG only).
 func isTitanSupportedImageMIME(mimeType string) bool {
@@ -0,0 +1,182 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/aws/smithy-go"
+)
+
+// embedConcurrencyLimit caps the number of simultaneous InvokeModel calls to
+// avoid AWS Bedrock ThrottlingException under large document batches.
+const embedConcurrencyLimit = 10
+
+// embedThrottleRetries is how many times a throttled embedding call is retried
+// by the limiter, on top of the AWS SDK's own retries.
+const embedThrottleRetries = 6
+
+// embedThrottleBackoff is the first delay before retrying a throttled call; it
+// doubles on each retry up to embedMaxThrottleBackoff. Variables for tests.
+var (
+	embedThrottleBackoff    = 500 * time.Millisecond
+	embedMaxThrottleBackoff = 20 * time.Second
+)
+
+// embedLimiter bounds concurrent embedding calls and adapts the bound to
+// Bedrock's rate limits: a throttled call halves it (down to 1), and each run
+// of limit consecutive successes raises it by one, up to max.
+type embedLimiter struct {
+	mu        sync.Mutex
+	cond      *sync.Cond
+	limit     int
+	max       int
+	inFlight  int
+	successes int
+}
+
+func newEmbedLimiter(max int) *embedLimiter {
+	l := &embedLimiter{limit: max, max: max}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+// acquire blocks until a call may start or ctx is done.
+func (l *embedLimiter) acquire(ctx context.Context) error {
+	stop := context.AfterFunc(ctx, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.cond.Broadcast()
+	})
+	defer stop()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for l.inFlight >= l.limit {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		l.cond.Wait()
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	l.inFlight++
+	return nil
+}
+
+// release ends a call, adjusting the limit by whether it was throttled.
+func (l *embedLimiter) release(throttled bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inFlight--
+	switch {
+	case throttled:
+		l.limit = max(1, l.limit/2)
+		l.successes = 0
+	case l.limit < l.max:
+		l.successes++
+		if l.successes >= l.limit {
+			l.limit++
+			l.successes = 0
+		}
+	}
+	l.cond.Broadcast()
+}
+
+// currentLimit returns the current concurrency bound.
+func (l *embedLimiter) currentLimit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+// do runs fn under the limiter, retrying with exponential backoff while it
+// fails with a throttling error. If ctx ends during a backoff, do returns
+// ctx.Err(), so callers see the interruption rather than the throttle.
+func (l *embedLimiter) do(ctx context.Context, fn func() error) error {
+	delay := embedThrottleBackoff
+	for attempt := 0; ; attempt++ {
+		if err := l.acquire(ctx); err != nil {
+			return err
+		}
+		err := fn()
+		throttled := isThrottlingError(err)
+		l.release(throttled)
+		if !throttled || attempt >= embedThrottleRetries {
+			return err
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		delay = min(2*delay, embedMaxThrottleBackoff)
+	}
+}
+
+// embedEach calls fn for indexes 0..n-1 concurrently under an adaptive
+// limiter and returns the per-index errors. At most embedConcurrencyLimit
+// workers run, however large n is.
+func embedEach(ctx context.Context, n int, fn func(idx int) error) []error {
+	limiter := newEmbedLimiter(embedConcurrencyLimit)
+	errs := make([]error, n)
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(n, embedConcurrencyLimit) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range indexes {
+				errs[idx] = limiter.do(ctx, func() error { return fn(idx) })
+			}
+		}()
+	}
+	for i := range n {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return errs
+}
+
+// isThrottlingError reports whether err is Bedrock rate limiting, as opposed
+// to quota exhaustion or a failed request.
+func isThrottlingError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var throttled *types.ThrottlingException
+	if errors.As(err, &throttled) {
+		return true
+	}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "ThrottlingException", "TooManyRequestsException":
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,302 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/firebase/genkit/go/ai"
+)
+
+// shortThrottleBackoff shrinks the limiter's retry delays for the test.
+func shortThrottleBackoff(t *testing.T) {
+	t.Helper()
+	prevBackoff, prevMax := embedThrottleBackoff, embedMaxThrottleBackoff
+	embedThrottleBackoff, embedMaxThrottleBackoff = time.Millisecond, 5*time.Millisecond
+	t.Cleanup(func() {
+		embedThrottleBackoff, embedMaxThrottleBackoff = prevBackoff, prevMax
+	})
+}
+
+func TestEmbedLimiter_BacksOffAndRecovers(t *testing.T) {
+	l := newEmbedLimiter(8)
+	ctx := context.Background()
+
+	for range 2 {
+		if err := l.acquire(ctx); err != nil {
+			t.Fatal(err)
+		}
+		l.release(true)
+	}
+	if got := l.currentLimit(); got != 2 {
+		t.Fatalf("limit after two throttles = %d, want 2", got)
+	}
+	for range 10 {
+		if err := l.acquire(ctx); err != nil {
+			t.Fatal(err)
+		}
+		l.release(true)
+	}
+	if got := l.currentLimit(); got != 1 {
+		t.Fatalf("limit never drops below 1, got %d", got)
+	}
+
+	// Each run of limit successes raises the limit by one: 1+2+...+7 = 28.
+	for range 28 {
+		if err := l.acquire(ctx); err != nil {
+			t.Fatal(err)
+		}
+		l.release(false)
+	}
+	if got := l.currentLimit(); got != 8 {
+		t.Fatalf("limit after recovery = %d, want 8", got)
+	}
+	l.release(false) // at max: stays put
+	if got := l.currentLimit(); got != 8 {
+		t.Fatalf("limit exceeded max: %d", got)
+	}
+}
+
+func TestEmbedLimiter_AcquireHonorsLimitAndContext(t *testing.T) {
+	l := newEmbedLimiter(1)
+	if err := l.acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.acquire(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("acquire over limit = %v, want DeadlineExceeded", err)
+	}
+	l.release(false)
+	if err := l.acquire(context.Background()); err != nil {
+		t.Fatalf("acquire after release: %v", err)
+	}
+}
+
+func TestEmbedLimiter_DoRetriesThrottling(t *testing.T) {
+	shortThrottleBackoff(t)
+	l := newEmbedLimiter(4)
+	calls := 0
+	err := l.do(context.Background(), func() error {
+		calls++
+		if calls < 3 {
+			return &types.ThrottlingException{Message: aws.String("slow down")}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("do: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d, want 3", calls)
+	}
+	// 4 -> 2 -> 1 on the throttles, then the success at limit 1 raises it to 2.
+	if got := l.currentLimit(); got != 2 {
+		t.Errorf("limit = %d, want 2", got)
+	}
+
+	calls = 0
+	wantErr := fmt.Errorf("validation failed")
+	if err := l.do(context.Background(), func() error { calls++; return wantErr }); err != wantErr {
+		t.Fatalf("do = %v, want %v", err, wantErr)
+	}
+	if calls != 1 {
+		t.Errorf("non-throttling error retried: %d calls", calls)
+	}
+}
+
+func TestEmbed_ThrottlingBacksOffAndPreservesOrder(t *testing.T) {
+	shortThrottleBackoff(t)
+
+	const docs = 40
+	const throttled = 12
+	var (
+		requests  atomic.Int32
+		mu        sync.Mutex
+		inFlight  int
+		peakAfter int // peak concurrency once every throttle has been sent
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := requests.Add(1)
+		mu.Lock()
+		inFlight++
+		if n > throttled+embedConcurrencyLimit {
+			peakAfter = max(peakAfter, inFlight)
+		}
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}()
+		time.Sleep(2 * time.Millisecond)
+
+		if n <= throttled {
+			w.Header().Set("X-Amzn-Errortype", "ThrottlingException")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"message":"Too many requests"}`)
+			return
+		}
+		var body struct {
+			InputText string `json:"inputText"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		idx, _ := strconv.Atoi(strings.TrimPrefix(body.InputText, "doc-"))
+		fmt.Fprint(w, titanTextResp([]float32{float32(idx)}))
+	}))
+	defer srv.Close()
+
+	// Disable SDK retries so every throttle reaches the limiter.
+	client := bedrockruntime.NewFromConfig(aws.Config{
+		Region:           "us-east-1",
+		Credentials:      credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+		HTTPClient:       srv.Client(),
+		BaseEndpoint:     aws.String(srv.URL),
+		RetryMaxAttempts: 1,
+	})
+	b := &Bedrock{client: client, initted: true}
+
+	input := make([]*ai.Document, docs)
+	for i := range input {
+		input[i] = ai.DocumentFromText(fmt.Sprintf("doc-%d", i), nil)
+	}
+	resp, err := b.embed(context.Background(), "amazon.titan-embed-text-v2:0", &ai.EmbedRequest{Input: input})
+	if err != nil {
+		t.Fatalf("embed: %v", err)
+	}
+	if len(resp.Embeddings) != docs {
+		t.Fatalf("got %d embeddings, want %d", len(resp.Embeddings), docs)
+	}
+	for i, e := range resp.Embeddings {
+		if len(e.Embedding) != 1 || e.Embedding[0] != float32(i) {
+			t.Errorf("embedding %d = %v, want [%d]", i, e.Embedding, i)
+		}
+	}
+	if got := int(requests.Load()); got != docs+throttled {
+		t.Errorf("requests = %d, want %d", got, docs+throttled)
+	}
+	if peakAfter >= embedConcurrencyLimit {
+		t.Errorf("peak concurrency after throttling = %d, want below %d", peakAfter, embedConcurrencyLimit)
+	}
+}
+
+func TestEmbedEach_BoundsWorkers(t *testing.T) {
+	const n = 1000
+	base := runtime.NumGoroutine()
+	var peak atomic.Int32
+	errs := embedEach(context.Background(), n, func(idx int) error {
+		extra := int32(runtime.NumGoroutine() - base)
+		for {
+			cur := peak.Load()
+			if extra <= cur || peak.CompareAndSwap(cur, extra) {
+				break
+			}
+		}
+		return nil
+	})
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("errs[%d] = %v", i, err)
+		}
+	}
+	if got := int(peak.Load()); got > 2*embedConcurrencyLimit {
+		t.Errorf("peak extra goroutines = %d, want about %d workers", got, embedConcurrencyLimit)
+	}
+}
+
+func TestEmbed_CancelledDuringThrottleBackoffReturnsPartialResults(t *testing.T) {
+	prevBackoff := embedThrottleBackoff
+	embedThrottleBackoff = time.Minute // Long enough that only cancellation ends it
+	t.Cleanup(func() { embedThrottleBackoff = prevBackoff })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	const docs = 3
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			InputText string `json:"inputText"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		if requests.Add(1) == docs {
+			// Every call has been answered; cancel while the throttled ones back off.
+			time.AfterFunc(50*time.Millisecond, cancel)
+		}
+		if body.InputText != "doc-0" {
+			w.Header().Set("X-Amzn-Errortype", "ThrottlingException")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"message":"Too many requests"}`)
+			return
+		}
+		fmt.Fprint(w, titanTextResp([]float32{7}))
+	}))
+	defer srv.Close()
+
+	client := bedrockruntime.NewFromConfig(aws.Config{
+		Region:           "us-east-1",
+		Credentials:      credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+		HTTPClient:       srv.Client(),
+		BaseEndpoint:     aws.String(srv.URL),
+		RetryMaxAttempts: 1,
+	})
+	b := &Bedrock{client: client, initted: true}
+
+	input := make([]*ai.Document, docs)
+	for i := range input {
+		input[i] = ai.DocumentFromText(fmt.Sprintf("doc-%d", i), nil)
+	}
+	resp, err := b.embed(ctx, "amazon.titan-embed-text-v2:0", &ai.EmbedRequest{Input: input})
+	var partial *PartialEmbedError
+	if !errors.As(err, &partial) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("error = %v, want a PartialEmbedError wrapping context.Canceled", err)
+	}
+	if resp == nil || len(resp.Embeddings) != docs {
+		t.Fatalf("resp = %+v, want %d aligned embeddings", resp, docs)
+	}
+	if e := resp.Embeddings[0]; e == nil || len(e.Embedding) != 1 || e.Embedding[0] != 7 {
+		t.Errorf("embedding 0 = %+v, want [7]", e)
+	}
+	for i := 1; i < docs; i++ {
+		if resp.Embeddings[i] != nil {
+			t.Errorf("embedding %d = %+v, want nil for the throttled document", i, resp.Embeddings[i])
+		}
+	}
+	if got := int(requests.Load()); got != docs {
+		t.Errorf("requests = %d, want %d (no retry after cancellation)", got, docs)
+	}
+}
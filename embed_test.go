@@ -21,11 +21,14 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 
@@ -207,6 +210,208 @@ func TestEmbedTitanText_NilDocumentErrors(t *testing.T) {
 	}
 }
 
+func TestEmbedTitanTextV2_NormalizeFlag(t *testing.T) {
+	f := false
+	tests := []struct {
+		name    string
+		options any
+		want    bool
+	}{
+		{name: "default", options: nil, want: true},
+		{name: "typed false", options: &EmbedOptions{Normalize: &f}, want: false},
+		{name: "map false", options: map[string]any{"normalize": false}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotBody map[string]any
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if err := json.Unmarshal(body, &gotBody); err != nil {
+					t.Errorf("json.Unmarshal: %v", err)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = fmt.Fprint(w, titanTextResp([]float32{3, 4}))
+			}))
+			defer server.Close()
+			b := newTestBedrock(server)
+
+			_, err := b.embed(context.Background(), "amazon.titan-embed-text-v2:0", &ai.EmbedRequest{
+				Input:   []*ai.Document{ai.DocumentFromText("hello", nil)},
+				Options: tt.options,
+			})
+			if err != nil {
+				t.Fatalf("embed error: %v", err)
+			}
+			if got, ok := gotBody["normalize"].(bool); !ok || got != tt.want {
+				t.Fatalf("normalize = %v, want %v", gotBody["normalize"], tt.want)
+			}
+		})
+	}
+}
+
+func TestEmbed_ClientNormalize(t *testing.T) {
+	on, off := true, false
+	tests := []struct {
+		name     string
+		model    string
+		plugin   bool
+		options  any
+		response string
+		want     []float32
+	}{
+		{name: "cohere plugin option", model: "cohere.embed-english-v3", plugin: true, response: cohereTypedResp([][]float32{{3, 4}}), want: []float32{0.6, 0.8}},
+		{name: "titan v1 request option", model: "amazon.titan-embed-text-v1", options: &EmbedOptions{ClientNormalize: &on}, response: titanTextResp([]float32{3, 4}), want: []float32{0.6, 0.8}},
+		{name: "map request option", model: "amazon.titan-embed-text-v1", options: map[string]any{"clientNormalize": true}, response: titanTextResp([]float32{3, 4}), want: []float32{0.6, 0.8}},
+		{name: "request overrides plugin", model: "amazon.titan-embed-text-v1", plugin: true, options: &EmbedOptions{ClientNormalize: &off}, response: titanTextResp([]float32{3, 4}), want: []float32{3, 4}},
+		{name: "off by default", model: "cohere.embed-english-v3", response: cohereTypedResp([][]float32{{3, 4}}), want: []float32{3, 4}},
+		// Titan V2 normalized server-side: the vector is returned as is.
+		{name: "titan v2 not renormalized", model: "amazon.titan-embed-text-v2:0", plugin: true, response: titanTextResp([]float32{3, 4}), want: []float32{3, 4}},
+		{name: "titan v2 raw", model: "amazon.titan-embed-text-v2:0", options: &EmbedOptions{Normalize: &off, ClientNormalize: &on}, response: titanTextResp([]float32{3, 4}), want: []float32{0.6, 0.8}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = fmt.Fprint(w, tt.response)
+			}))
+			defer server.Close()
+			b := newTestBedrock(server)
+			b.ClientNormalize = tt.plugin
+
+			resp, err := b.embed(context.Background(), tt.model, &ai.EmbedRequest{
+				Input:   []*ai.Document{ai.DocumentFromText("hello", nil)},
+				Options: tt.options,
+			})
+			if err != nil {
+				t.Fatalf("embed error: %v", err)
+			}
+			got := resp.Embeddings[0].Embedding
+			if gotNorm, wantNorm := l2Norm(got), l2Norm(tt.want); math.Abs(gotNorm-wantNorm) > 1e-6 {
+				t.Errorf("L2 norm = %v, want %v", gotNorm, wantNorm)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("embedding = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if math.Abs(float64(got[i]-tt.want[i])) > 1e-6 {
+					t.Fatalf("embedding = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func l2Norm(v []float32) float64 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	return math.Sqrt(sum)
+}
+
+func TestNormalizeVector_Zero(t *testing.T) {
+	v := []float32{0, 0, 0}
+	normalizeVector(v)
+	for _, x := range v {
+		if x != 0 {
+			t.Fatalf("normalizeVector(zero) = %v, want zeros", v)
+		}
+	}
+}
+
+func TestEmbedTitanTextV1_OmitsNormalize(t *testing.T) {
+	var gotBody map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &gotBody)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, titanTextResp([]float32{0.1}))
+	}))
+	defer server.Close()
+	b := newTestBedrock(server)
+
+	if _, err := b.embed(context.Background(), "amazon.titan-embed-text-v1", &ai.EmbedRequest{
+		Input: []*ai.Document{ai.DocumentFromText("hello", nil)},
+	}); err != nil {
+		t.Fatalf("embed error: %v", err)
+	}
+	if _, ok := gotBody["normalize"]; ok {
+		t.Fatalf("normalize sent to Titan V1: %v", gotBody)
+	}
+}
+
+func TestEmbedTitanTextV2_Dimensions(t *testing.T) {
+	var gotBody map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &gotBody)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, titanTextResp([]float32{0.1}))
+	}))
+	defer server.Close()
+	b := newTestBedrock(server)
+
+	req := &ai.EmbedRequest{Input: []*ai.Document{ai.DocumentFromText("hello", nil)}}
+	if _, err := b.embed(context.Background(), "amazon.titan-embed-text-v2:0", req); err != nil {
+		t.Fatalf("embed error: %v", err)
+	}
+	if _, ok := gotBody["dimensions"]; ok {
+		t.Fatalf("dimensions sent without EmbedDimensions: %v", gotBody)
+	}
+
+	b.EmbedDimensions = map[string]int{"amazon.titan-embed-text-v2:0": 256}
+	if _, err := b.embed(context.Background(), "amazon.titan-embed-text-v2:0", req); err != nil {
+		t.Fatalf("embed error: %v", err)
+	}
+	if gotBody["dimensions"] != float64(256) {
+		t.Fatalf("dimensions = %v, want 256", gotBody["dimensions"])
+	}
+}
+
+func TestEmbedderDimensions(t *testing.T) {
+	b := &Bedrock{EmbedDimensions: map[string]int{"amazon.titan-embed-text-v2:0": 512}}
+	for model, want := range map[string]int{
+		"amazon.titan-embed-text-v2:0": 512,
+		"amazon.titan-embed-text-v1":   1536,
+		"cohere.embed-english-v3":      1024,
+		"us.cohere.embed-v4:0":         1536,
+		"amazon.nova-embed-text-v1:0":  0,
+		"acme.unknown-embedder-v1:0":   0,
+		"amazon.titan-embed-image-v1":  1024,
+		"cohere.embed-multilingual-v3": 1024,
+	} {
+		if got := b.EmbedderDimensions(model); got != want {
+			t.Errorf("EmbedderDimensions(%q) = %d, want %d", model, got, want)
+		}
+	}
+
+	for _, tc := range []struct {
+		dims map[string]int
+		want string
+	}{
+		{map[string]int{"amazon.titan-embed-text-v2:0": 768}, "accepts 256, 512 or 1024"},
+		{map[string]int{"cohere.embed-english-v3": 512}, "not configurable"},
+	} {
+		b := &Bedrock{EmbedDimensions: tc.dims}
+		for model := range tc.dims {
+			if err := b.checkEmbedDimensions(model); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("checkEmbedDimensions(%q) = %v, want %q", model, err, tc.want)
+			}
+		}
+	}
+}
+
+func TestEmbed_UnsupportedOptionsType(t *testing.T) {
+	b := &Bedrock{}
+	_, err := b.embed(context.Background(), "amazon.titan-embed-text-v2:0", &ai.EmbedRequest{
+		Input:   []*ai.Document{ai.DocumentFromText("hello", nil)},
+		Options: 42,
+	})
+	if err == nil || !strings.Contains(err.Error(), "unsupported embed options type") {
+		t.Fatalf("error = %v, want unsupported options error", err)
+	}
+}
+
 // ---- Titan multimodal -------------------------------------------------------
 
 func TestEmbedTitanMultimodal_TextOnly(t *testing.T) {
@@ -452,6 +657,108 @@ func TestEmbedCohere_ImageDocument(t *testing.T) {
 	}
 }
 
+func TestEmbedCohere_CancelledMidBatchReturnsPartialResults(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Texts []string `json:"texts"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if calls.Add(1) > 1 {
+			// The second batch is cancelled in flight.
+			cancel()
+			<-r.Context().Done()
+			return
+		}
+		vecs := make([][]float32, len(body.Texts))
+		for i := range vecs {
+			vecs[i] = []float32{float32(i)}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, cohereTypedResp(vecs))
+	}))
+	defer server.Close()
+	b := newTestBedrock(server)
+
+	// 100 text documents (batches of 96 and 4) and an image at index 1.
+	docs := []*ai.Document{ai.DocumentFromText("doc", nil), {Content: []*ai.Part{ai.NewMediaPart("image/png", fakeImageDataURL)}}}
+	for len(docs) < 101 {
+		docs = append(docs, ai.DocumentFromText("doc", nil))
+	}
+	resp, err := b.embed(ctx, "cohere.embed-english-v3", &ai.EmbedRequest{Input: docs})
+	var partial *PartialEmbedError
+	if !errors.As(err, &partial) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("error = %v, want a PartialEmbedError wrapping context.Canceled", err)
+	}
+	if resp == nil || len(resp.Embeddings) != len(docs) {
+		t.Fatalf("resp = %+v, want %d aligned embeddings", resp, len(docs))
+	}
+	for i, e := range resp.Embeddings {
+		// The first batch holds text documents 0 and 2..96.
+		done := i == 0 || (i >= 2 && i <= 96)
+		if done != (e != nil) {
+			t.Fatalf("embedding %d present = %v, want %v", i, e != nil, done)
+		}
+	}
+	if got := resp.Embeddings[2].Embedding[0]; got != 1 {
+		t.Errorf("embedding 2 = %v, want the second vector of the batch", got)
+	}
+	if &partial.Embeddings[0] != &resp.Embeddings[0] {
+		t.Error("PartialEmbedError.Embeddings differs from the response embeddings")
+	}
+	if !strings.Contains(err.Error(), "after 96 of 101 documents") {
+		t.Errorf("error = %q, want the completed count", err)
+	}
+}
+
+func TestEmbedResponse_CancelledMidBatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	embeddings := make([]*ai.Embedding, 6)
+	var evens sync.WaitGroup
+	evens.Add(3)
+	errs := embedEach(ctx, len(embeddings), func(idx int) error {
+		if idx%2 == 0 {
+			embeddings[idx] = &ai.Embedding{Embedding: []float32{float32(idx)}}
+			evens.Done()
+			return nil
+		}
+		evens.Wait()
+		cancel()
+		<-ctx.Done()
+		return fmt.Errorf("embed: document %d: %w", idx, ctx.Err())
+	})
+	resp, err := embedResponse(ctx, embeddings, errs)
+	var partial *PartialEmbedError
+	if !errors.As(err, &partial) {
+		t.Fatalf("error = %v, want PartialEmbedError", err)
+	}
+	for i, e := range resp.Embeddings {
+		if want := i%2 == 0; want != (e != nil) {
+			t.Fatalf("embedding %d present = %v, want %v", i, e != nil, want)
+		}
+		if e != nil && e.Embedding[0] != float32(i) {
+			t.Errorf("embedding %d = %v, want it at its document's index", i, e.Embedding)
+		}
+	}
+}
+
+func TestEmbedResponse_OtherErrorsFailTheBatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	failed := errors.New("embed: document 1 has no text content")
+	resp, err := embedResponse(ctx, make([]*ai.Embedding, 3), []error{nil, failed, context.Canceled})
+	if resp != nil || !errors.Is(err, failed) {
+		t.Fatalf("embedResponse() = %v, %v; want the document error", resp, err)
+	}
+	var partial *PartialEmbedError
+	if errors.As(err, &partial) {
+		t.Error("error is a PartialEmbedError, want the batch failed")
+	}
+}
+
 func TestEmbedCohere_MixedTextAndImageDocumentsOrdered(t *testing.T) {
 	// Documents: [image, text, image, text]
 	// Expected result order preserved: [0]=img-emb, [1]=txt-emb, [2]=img-emb, [3]=txt-emb
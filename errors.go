@@ -0,0 +1,268 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/aws/smithy-go"
+	"github.com/firebase/genkit/go/ai"
+)
+
+// AccessDeniedCause classifies an [AccessDeniedError].
+type AccessDeniedCause string
+
+const (
+	// AccessDeniedModelAccess means the account has not been granted access
+	// to the model in the Bedrock console.
+	AccessDeniedModelAccess AccessDeniedCause = "model-access"
+	// AccessDeniedIAM means the caller's IAM policy does not allow the call.
+	AccessDeniedIAM AccessDeniedCause = "iam-permission"
+	// AccessDeniedUnknown means Bedrock's message did not say which applies.
+	AccessDeniedUnknown AccessDeniedCause = "unknown"
+)
+
+// AccessDeniedError is returned when Bedrock rejects a model call with
+// AccessDeniedException. Cause is inferred from Bedrock's message.
+type AccessDeniedError struct {
+	ModelID string
+	Region  string // "" when the client region is not known
+	Cause   AccessDeniedCause
+	Message string // Bedrock's error message
+	Err     error  // The underlying *types.AccessDeniedException
+}
+
+func (e *AccessDeniedError) Error() string {
+	where := fmt.Sprintf("model %q", e.ModelID)
+	if e.Region != "" {
+		where += " in " + e.Region
+	}
+	var hint string
+	switch e.Cause {
+	case AccessDeniedModelAccess:
+		hint = "enable access to this model in the Bedrock console (Model access) for this account and region"
+	case AccessDeniedIAM:
+		hint = "grant the caller bedrock:InvokeModel (and bedrock:InvokeModelWithResponseStream for streaming) on this model"
+	default:
+		hint = "check that model access is enabled in the Bedrock console and that the caller's IAM policy allows bedrock:InvokeModel"
+	}
+	return fmt.Sprintf("bedrock: access denied for %s: %s (Bedrock said: %s)", where, hint, e.Message)
+}
+
+func (e *AccessDeniedError) Unwrap() error { return e.Err }
+
+// accessDeniedCause infers why Bedrock denied access from its message.
+func accessDeniedCause(msg string) AccessDeniedCause {
+	lower := strings.ToLower(msg)
+	switch {
+	case strings.Contains(lower, "not authorized to perform"),
+		strings.Contains(lower, "no identity-based policy"),
+		strings.Contains(lower, "explicit deny"):
+		return AccessDeniedIAM
+	case strings.Contains(lower, "access to the model"),
+		strings.Contains(lower, "model access"),
+		strings.Contains(lower, "not subscribed"),
+		strings.Contains(lower, "aws marketplace"):
+		return AccessDeniedModelAccess
+	default:
+		return AccessDeniedUnknown
+	}
+}
+
+// ServiceQuotaExceededError is returned when Bedrock rejects a model call
+// with ServiceQuotaExceededException: an account-level quota is exhausted.
+// Unlike throttling it does not clear by itself, so it is not retryable.
+type ServiceQuotaExceededError struct {
+	ModelID string
+	Region  string // "" when the client region is not known
+	Message string // Bedrock's error message
+	Err     error  // The underlying SDK API error
+}
+
+func (e *ServiceQuotaExceededError) Error() string {
+	where := fmt.Sprintf("model %q", e.ModelID)
+	if e.Region != "" {
+		where += " in " + e.Region
+	}
+	return fmt.Sprintf("bedrock: service quota exceeded for %s: request a quota increase in the Service Quotas console (Amazon Bedrock) for this account and region; retrying will not help (Bedrock said: %s)", where, e.Message)
+}
+
+func (e *ServiceQuotaExceededError) Unwrap() error { return e.Err }
+
+// RetryableError reports false. The plugin wraps the error after the SDK
+// call has returned, so the SDK's own retries never see it; the method is
+// for callers that retry plugin calls themselves and classify errors with an
+// aws.Retryer, whose IsErrorRetryable honors it.
+func (e *ServiceQuotaExceededError) RetryableError() bool { return false }
+
+// ModelNotFoundError is returned when Bedrock rejects a model call because
+// the model ID is not one it knows. Suggestion is the closest model ID in the
+// plugin's capability map, or "" when none is close.
+type ModelNotFoundError struct {
+	ModelID    string
+	Region     string // "" when the client region is not known
+	Suggestion string
+	Message    string // Bedrock's error message
+	Err        error  // The underlying SDK API error
+}
+
+func (e *ModelNotFoundError) Error() string {
+	where := fmt.Sprintf("unknown model %q", e.ModelID)
+	if e.Region != "" {
+		where += " in " + e.Region
+	}
+	if e.Suggestion != "" {
+		where += fmt.Sprintf("; did you mean %q?", e.Suggestion)
+	}
+	return fmt.Sprintf("bedrock: %s (Bedrock said: %s)", where, e.Message)
+}
+
+func (e *ModelNotFoundError) Unwrap() error { return e.Err }
+
+// ValidationError is returned when Bedrock rejects a model call with
+// ValidationException. Field is the request field Bedrock's message names,
+// such as "temperature" or "inferenceConfig.maxTokens", or "" when the
+// message does not name one.
+type ValidationError struct {
+	ModelID string
+	Region  string // "" when the client region is not known
+	Field   string
+	Message string // Bedrock's error message
+	Err     error  // The underlying SDK API error
+}
+
+func (e *ValidationError) Error() string {
+	where := fmt.Sprintf("model %q", e.ModelID)
+	if e.Region != "" {
+		where += " in " + e.Region
+	}
+	if e.Field != "" {
+		return fmt.Sprintf("bedrock: invalid request for %s: field %q was rejected (Bedrock said: %s)", where, e.Field, e.Message)
+	}
+	return fmt.Sprintf("bedrock: invalid request for %s (Bedrock said: %s)", where, e.Message)
+}
+
+func (e *ValidationError) Unwrap() error { return e.Err }
+
+// validationFieldPatterns match the ways Bedrock and the model providers
+// name the offending field in a ValidationException message, most specific
+// first. The first capture group is the field.
+var validationFieldPatterns = []*regexp.Regexp{
+	// Smithy constraint errors: "Value '0' at 'inferenceConfig.maxTokens' failed to satisfy constraint".
+	regexp.MustCompile(`at '([^']+)' failed to satisfy constraint`),
+	// JSON schema errors: "Malformed input request: #/temperature: 2 is not less or equal to 1".
+	regexp.MustCompile(`#/([\w./-]+):`),
+	// "extraneous key [top_kk] is not permitted".
+	regexp.MustCompile(`extraneous key \[([^\]]+)\]`),
+	// Provider errors: "The model returned the following errors: temperature: range: 0..1".
+	regexp.MustCompile(`following errors: ([A-Za-z_][\w.]*): `),
+}
+
+// validationField returns the request field named in a ValidationException
+// message, or "" when it names none.
+func validationField(msg string) string {
+	for _, re := range validationFieldPatterns {
+		if m := re.FindStringSubmatch(msg); m != nil {
+			return strings.ReplaceAll(m[1], "/", ".")
+		}
+	}
+	return ""
+}
+
+// ContentFilteredError is returned instead of a response the model's content
+// filters or a guardrail blocked, when [Bedrock.ContentFilterAsError] is
+// set. Response is the blocked response, with FinishReason "blocked".
+type ContentFilteredError struct {
+	ModelID    string
+	StopReason string // Bedrock's stop reason, e.g. "content_filtered" or "guardrail_intervened"
+	Response   *ai.ModelResponse
+}
+
+func (e *ContentFilteredError) Error() string {
+	reason := e.StopReason
+	if reason == "" {
+		reason = "blocked"
+	}
+	return fmt.Sprintf("bedrock: response from model %q was blocked by content filtering (%s)", e.ModelID, reason)
+}
+
+// isModelNotFound reports whether apiErr is Bedrock rejecting the model ID
+// itself rather than the request.
+func isModelNotFound(apiErr smithy.APIError) bool {
+	msg := strings.ToLower(apiErr.ErrorMessage())
+	switch apiErr.ErrorCode() {
+	case "ResourceNotFoundException":
+		return strings.Contains(msg, "model")
+	case "ValidationException":
+		return strings.Contains(msg, "model identifier is invalid")
+	}
+	return false
+}
+
+// modelCallError wraps err from a Bedrock Runtime call for modelID,
+// returning an *AccessDeniedError for AccessDeniedException, a
+// *ServiceQuotaExceededError for ServiceQuotaExceededException, a
+// *ModelNotFoundError when Bedrock does not know the model ID, a
+// *ValidationError for any other ValidationException, and prefixing any
+// other error with what.
+func modelCallError(what, modelID, region string, err error) error {
+	// Converse does not model ServiceQuotaExceededException, so match the
+	// error code rather than the SDK type.
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode() == "ServiceQuotaExceededException" {
+		return &ServiceQuotaExceededError{
+			ModelID: modelID,
+			Region:  region,
+			Message: apiErr.ErrorMessage(),
+			Err:     err,
+		}
+	}
+	if errors.As(err, &apiErr) && isModelNotFound(apiErr) {
+		return &ModelNotFoundError{
+			ModelID:    modelID,
+			Region:     region,
+			Suggestion: suggestModelID(modelID, capabilityModelIDs()),
+			Message:    apiErr.ErrorMessage(),
+			Err:        err,
+		}
+	}
+	var denied *types.AccessDeniedException
+	if errors.As(err, &denied) {
+		return &AccessDeniedError{
+			ModelID: modelID,
+			Region:  region,
+			Cause:   accessDeniedCause(denied.ErrorMessage()),
+			Message: denied.ErrorMessage(),
+			Err:     err,
+		}
+	}
+	if errors.As(err, &apiErr) && apiErr.ErrorCode() == "ValidationException" {
+		return &ValidationError{
+			ModelID: modelID,
+			Region:  region,
+			Field:   validationField(apiErr.ErrorMessage()),
+			Message: apiErr.ErrorMessage(),
+			Err:     err,
+		}
+	}
+	return fmt.Errorf("%s: %w", what, err)
+}
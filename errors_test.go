@@ -0,0 +1,333 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/aws/smithy-go"
+	"github.com/firebase/genkit/go/ai"
+)
+
+func TestGenerateText_AccessDeniedGuidance(t *testing.T) {
+	tests := []struct {
+		name      string
+		message   string
+		wantCause AccessDeniedCause
+		wantHint  string
+	}{
+		{
+			name:      "model access",
+			message:   "You don't have access to the model with the specified model ID.",
+			wantCause: AccessDeniedModelAccess,
+			wantHint:  "enable access to this model in the Bedrock console",
+		},
+		{
+			name:      "iam permission",
+			message:   "User: arn:aws:iam::123456789012:user/dev is not authorized to perform: bedrock:InvokeModel on resource: arn:aws:bedrock:us-east-1::foundation-model/amazon.nova-lite-v1:0",
+			wantCause: AccessDeniedIAM,
+			wantHint:  "grant the caller bedrock:InvokeModel",
+		},
+		{
+			name:      "unknown",
+			message:   "Access denied.",
+			wantCause: AccessDeniedUnknown,
+			wantHint:  "check that model access is enabled",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("X-Amzn-Errortype", "AccessDeniedException")
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = fmt.Fprintf(w, `{"message":%q}`, tt.message)
+			}))
+			defer server.Close()
+			b := newTestBedrock(server)
+			b.awsConfig = aws.Config{Region: "us-east-1"}
+
+			_, err := b.generateText(context.Background(), "amazon.nova-lite-v1:0", &ai.ModelRequest{
+				Messages: []*ai.Message{ai.NewUserTextMessage("hi")},
+			}, nil)
+			var denied *AccessDeniedError
+			if !errors.As(err, &denied) {
+				t.Fatalf("error = %v (%T), want *AccessDeniedError", err, err)
+			}
+			if denied.Cause != tt.wantCause || denied.ModelID != "amazon.nova-lite-v1:0" || denied.Region != "us-east-1" {
+				t.Errorf("error = %+v", denied)
+			}
+			msg := err.Error()
+			for _, want := range []string{tt.wantHint, `"amazon.nova-lite-v1:0"`, "us-east-1", tt.message} {
+				if !strings.Contains(msg, want) {
+					t.Errorf("error %q does not contain %q", msg, want)
+				}
+			}
+			var sdkErr *types.AccessDeniedException
+			if !errors.As(err, &sdkErr) {
+				t.Error("error does not unwrap to *types.AccessDeniedException")
+			}
+		})
+	}
+}
+
+func TestGenerateText_ServiceQuotaExceeded(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Amzn-Errortype", "ServiceQuotaExceededException")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"message":"Your account has exceeded the quota for tokens per day."}`))
+	}))
+	defer server.Close()
+	b := newTestBedrock(server)
+	b.awsConfig = aws.Config{Region: "us-east-1"}
+
+	_, err := b.generateText(context.Background(), "amazon.nova-lite-v1:0", &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("hi")},
+	}, nil)
+	var quota *ServiceQuotaExceededError
+	if !errors.As(err, &quota) {
+		t.Fatalf("error = %v (%T), want *ServiceQuotaExceededError", err, err)
+	}
+	if quota.ModelID != "amazon.nova-lite-v1:0" || quota.Region != "us-east-1" || !strings.Contains(quota.Message, "tokens per day") {
+		t.Errorf("error = %+v", quota)
+	}
+	if !strings.Contains(err.Error(), "request a quota increase") {
+		t.Errorf("error %q has no quota guidance", err)
+	}
+	if quota.RetryableError() || retry.NewStandard().IsErrorRetryable(err) {
+		t.Error("quota errors must not be retryable")
+	}
+	if calls != 1 {
+		t.Errorf("Bedrock calls = %d, want 1 (no retries)", calls)
+	}
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "ServiceQuotaExceededException" {
+		t.Error("error does not unwrap to the ServiceQuotaExceededException API error")
+	}
+	var throttled *types.ThrottlingException
+	if errors.As(err, &throttled) {
+		t.Error("quota error must not classify as throttling")
+	}
+}
+
+func TestModelCallError_OtherErrorsWrapped(t *testing.T) {
+	base := errors.New("boom")
+	err := modelCallError("bedrock converse failed", "m", "us-east-1", base)
+	if !errors.Is(err, base) || err.Error() != "bedrock converse failed: boom" {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestGenerateText_ModelNotFoundSuggestsModel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Amzn-Errortype", "ValidationException")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"message":"The provided model identifier is invalid."}`))
+	}))
+	defer server.Close()
+	b := newTestBedrock(server)
+	b.awsConfig = aws.Config{Region: "us-east-1"}
+
+	_, err := b.generateText(context.Background(), "us.anthropic.claude-3-sonet-20240229-v1:0", &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("hi")},
+	}, nil)
+	var notFound *ModelNotFoundError
+	if !errors.As(err, &notFound) {
+		t.Fatalf("error = %v (%T), want *ModelNotFoundError", err, err)
+	}
+	if want := "us.anthropic.claude-3-sonnet-20240229-v1:0"; notFound.Suggestion != want {
+		t.Errorf("Suggestion = %q, want %q", notFound.Suggestion, want)
+	}
+	for _, want := range []string{`unknown model "us.anthropic.claude-3-sonet-20240229-v1:0" in us-east-1`, `did you mean "us.anthropic.claude-3-sonnet-20240229-v1:0"`, "model identifier is invalid"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+	var validation *types.ValidationException
+	if !errors.As(err, &validation) {
+		t.Error("error does not unwrap to *types.ValidationException")
+	}
+}
+
+func TestSuggestModelID(t *testing.T) {
+	tests := []struct {
+		modelID string
+		want    string
+	}{
+		{"anthropic.claude-3-sonet-20240229-v1:0", "anthropic.claude-3-sonnet-20240229-v1:0"},
+		{"amazon.nova-lite-v1", "amazon.nova-lite-v1:0"},
+		{"anthropic.claude-3-sonet", "anthropic.claude-3-sonnet"},
+		{"eu.amazon.nova-prro-v1:0", "eu.amazon.nova-pro-v1:0"},
+		{"anthropic.claude-3-sonnet-20240229-v1:0", ""}, // already known
+		{"my-custom-finetune", ""},
+		{"openai.gpt-4o-2024-08-06", ""},
+	}
+	for _, tt := range tests {
+		if got := suggestModelID(tt.modelID, capabilityModelIDs()); got != tt.want {
+			t.Errorf("suggestModelID(%q) = %q, want %q", tt.modelID, got, tt.want)
+		}
+	}
+}
+
+func TestResolveModelID_UnknownSuggestsModel(t *testing.T) {
+	_, err := ResolveModelID("anthropic.claude-3-sonet")
+	if err == nil || !strings.Contains(err.Error(), `did you mean "anthropic.claude-3-sonnet"?`) {
+		t.Errorf("error = %v, want a suggestion", err)
+	}
+	_, err = ResolveModelID("totally.unrelated-thing")
+	if err == nil || strings.Contains(err.Error(), "did you mean") {
+		t.Errorf("error = %v, want no suggestion", err)
+	}
+}
+
+func TestGenerateText_ContentFilterAsError(t *testing.T) {
+	for _, stop := range []string{"content_filtered", "guardrail_intervened"} {
+		t.Run(stop, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = fmt.Fprintf(w, `{"output":{"message":{"role":"assistant","content":[{"text":"Sorry."}]}},"stopReason":%q}`, stop)
+			}))
+			defer server.Close()
+			req := &ai.ModelRequest{Messages: []*ai.Message{ai.NewUserTextMessage("hi")}}
+
+			// Default: a finished response with the blocked reason.
+			resp, err := newTestBedrock(server).generateText(context.Background(), "amazon.nova-lite-v1:0", req, nil)
+			if err != nil {
+				t.Fatalf("generateText error: %v", err)
+			}
+			if resp.FinishReason != ai.FinishReasonBlocked || StopReason(resp) != stop {
+				t.Errorf("finish = %q (%q), want blocked (%q)", resp.FinishReason, StopReason(resp), stop)
+			}
+
+			b := newTestBedrock(server)
+			b.ContentFilterAsError = true
+			resp, err = b.generateText(context.Background(), "amazon.nova-lite-v1:0", req, nil)
+			var filtered *ContentFilteredError
+			if !errors.As(err, &filtered) {
+				t.Fatalf("error = %v (%T), want *ContentFilteredError", err, err)
+			}
+			if resp != nil {
+				t.Errorf("resp = %+v, want nil", resp)
+			}
+			if filtered.ModelID != "amazon.nova-lite-v1:0" || filtered.StopReason != stop {
+				t.Errorf("error = %+v", filtered)
+			}
+			if filtered.Response == nil || filtered.Response.Text() != "Sorry." {
+				t.Errorf("error response = %+v, want the blocked response", filtered.Response)
+			}
+			if !strings.Contains(err.Error(), "blocked by content filtering ("+stop+")") {
+				t.Errorf("error = %q", err)
+			}
+		})
+	}
+}
+
+func TestGenerateText_ContentFilterAsErrorKeepsOtherResponses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"output":{"message":{"role":"assistant","content":[{"text":"ok"}]}},"stopReason":"end_turn"}`)
+	}))
+	defer server.Close()
+	b := newTestBedrock(server)
+	b.ContentFilterAsError = true
+
+	resp, err := b.generateText(context.Background(), "amazon.nova-lite-v1:0", &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("hi")},
+	}, nil)
+	if err != nil || resp.FinishReason != ai.FinishReasonStop {
+		t.Fatalf("generateText() = %+v, %v; want a stopped response", resp, err)
+	}
+}
+
+func TestValidationField(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want string
+	}{
+		{"1 validation error detected: Value '0' at 'inferenceConfig.maxTokens' failed to satisfy constraint: Member must have value greater than or equal to 1", "inferenceConfig.maxTokens"},
+		{"Malformed input request: #/temperature: 2.0 is not less or equal to 1.0, please reformat your input and try again.", "temperature"},
+		{"Malformed input request: #/textGenerationConfig/topP: 1.5 is not less or equal to 1, please reformat your input and try again.", "textGenerationConfig.topP"},
+		{"Malformed input request: #: extraneous key [top_kk] is not permitted, please reformat your input and try again.", "top_kk"},
+		{"The model returned the following errors: temperature: range: 0..1", "temperature"},
+		{"The model returned the following errors: top_p: range: 0..1", "top_p"},
+		{"top_p: range: 0..1", ""},
+		{"Error: something went wrong", ""},
+		{"The maximum tokens you requested exceeds the model limit of 4096.", ""},
+		{"Malformed input request, please reformat your input and try again.", ""},
+	}
+	for _, tt := range tests {
+		if got := validationField(tt.msg); got != tt.want {
+			t.Errorf("validationField(%q) = %q, want %q", tt.msg, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateText_ValidationErrorNamesField(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Amzn-Errortype", "ValidationException")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"message":"The model returned the following errors: temperature: range: 0..1"}`))
+	}))
+	defer server.Close()
+	b := newTestBedrock(server)
+	b.awsConfig = aws.Config{Region: "us-east-1"}
+
+	_, err := b.generateText(context.Background(), "anthropic.claude-3-haiku-20240307-v1:0", &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("hi")},
+	}, nil)
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("error = %v (%T), want *ValidationError", err, err)
+	}
+	if invalid.Field != "temperature" || invalid.ModelID != "anthropic.claude-3-haiku-20240307-v1:0" || invalid.Region != "us-east-1" {
+		t.Errorf("ValidationError = %+v", invalid)
+	}
+	if want := `field "temperature" was rejected`; !strings.Contains(err.Error(), want) {
+		t.Errorf("error %q does not contain %q", err, want)
+	}
+	var validation *types.ValidationException
+	if !errors.As(err, &validation) {
+		t.Error("error does not unwrap to *types.ValidationException")
+	}
+}
+
+func TestModelCallError_ValidationWithoutField(t *testing.T) {
+	base := &types.ValidationException{Message: aws.String("Malformed input request, please reformat your input and try again.")}
+	err := modelCallError("bedrock converse failed", "m", "", base)
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) || invalid.Field != "" {
+		t.Fatalf("error = %v (%T), want *ValidationError without Field", err, err)
+	}
+	if want := `bedrock: invalid request for model "m" (Bedrock said: Malformed input request`; !strings.Contains(err.Error(), want) {
+		t.Errorf("error %q does not contain %q", err, want)
+	}
+}
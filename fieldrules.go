@@ -0,0 +1,125 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// fieldRuleAction is what the request builder does with a field that a
+// [fieldRule] excludes.
+type fieldRuleAction int
+
+const (
+	// fieldRuleDrop removes the field from the request and logs a warning.
+	fieldRuleDrop fieldRuleAction = iota
+	// fieldRuleReject fails the request.
+	fieldRuleReject
+)
+
+// fieldRule declares that models whose ID contains Models cannot take Field
+// while Feature is in use. Rules are data so provider quirks stay in one
+// table instead of spreading through the request builder.
+type fieldRule struct {
+	Models  string // Model ID substring, e.g. "anthropic."
+	Feature string // Key in ruleFeatures
+	Field   string // Key in ruleFields
+	Action  fieldRuleAction
+	Reason  string
+}
+
+// incompatibleFieldRules lists the known model, feature and field conflicts.
+var incompatibleFieldRules = []fieldRule{
+	{
+		Models:  "anthropic.",
+		Feature: "thinking",
+		Field:   "temperature",
+		Action:  fieldRuleReject,
+		Reason:  "Claude extended thinking runs at a fixed temperature; remove temperature or ThinkingBudget",
+	},
+	{
+		Models:  "anthropic.",
+		Feature: "thinking",
+		Field:   "topK",
+		Action:  fieldRuleDrop,
+		Reason:  "Claude extended thinking does not accept top_k",
+	},
+}
+
+// ruleFeatures reports whether a request config uses a feature named by a
+// [fieldRule].
+var ruleFeatures = map[string]func(*Config) bool{
+	"thinking": func(cfg *Config) bool {
+		_, explicit := cfg.AdditionalModelRequestFields["thinking"]
+		return cfg.ThinkingBudget > 0 || explicit
+	},
+}
+
+// ruleFields reads and clears the config fields a [fieldRule] can exclude.
+var ruleFields = map[string]struct {
+	set   func(*Config) bool
+	clear func(*Config)
+}{
+	"temperature": {func(c *Config) bool { return c.Temperature != nil }, func(c *Config) { c.Temperature = nil }},
+	"topP":        {func(c *Config) bool { return c.TopP != nil }, func(c *Config) { c.TopP = nil }},
+	"topK":        {func(c *Config) bool { return c.TopK != nil }, func(c *Config) { c.TopK = nil }},
+}
+
+// applyFieldRules checks cfg against rules for modelName. It fails on a
+// conflicting field whose rule rejects it and otherwise returns cfg, or a
+// copy without the fields that rules drop.
+func applyFieldRules(modelName string, cfg *Config, rules []fieldRule) (*Config, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	name := strings.ToLower(modelName)
+	out := cfg
+	for _, rule := range rules {
+		if !strings.Contains(name, rule.Models) || !ruleFeatures[rule.Feature](out) {
+			continue
+		}
+		field := ruleFields[rule.Field]
+		if !field.set(out) {
+			continue
+		}
+		if rule.Action == fieldRuleReject {
+			return nil, fmt.Errorf("bedrock: %s cannot be combined with %s for model %q: %s", rule.Field, rule.Feature, modelName, rule.Reason)
+		}
+		slog.Warn("bedrock: dropping a field the model does not accept with "+rule.Feature, "model", modelName, "field", rule.Field, "reason", rule.Reason)
+		if out == cfg {
+			copied := *cfg
+			out = &copied
+		}
+		field.clear(out)
+	}
+	return out, nil
+}
+
+// ruleExcludes reports whether a rule keeps field out of requests for
+// modelName with cfg, whether by dropping or rejecting it.
+func ruleExcludes(modelName string, cfg *Config, field string, rules []fieldRule) bool {
+	name := strings.ToLower(modelName)
+	for _, rule := range rules {
+		if rule.Field == field && strings.Contains(name, rule.Models) && ruleFeatures[rule.Feature](cfg) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/firebase/genkit/go/ai"
+)
+
+func TestBuildConverseInput_TemperatureWithThinkingRejected(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *Config
+	}{
+		{"thinking budget", &Config{MaxTokens: 4096, ThinkingBudget: 2048, Temperature: aws.Float32(0.2)}},
+		{"explicit thinking field", &Config{Temperature: aws.Float32(0.2), AdditionalModelRequestFields: map[string]any{
+			"thinking": map[string]any{"type": "enabled", "budget_tokens": 2048},
+		}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &ai.ModelRequest{Messages: []*ai.Message{ai.NewUserTextMessage("hi")}, Config: tt.cfg}
+			_, err := (&Bedrock{}).buildConverseInput("us.anthropic.claude-3-7-sonnet-20250219-v1:0", req)
+			if err == nil || !strings.Contains(err.Error(), "temperature cannot be combined with thinking") {
+				t.Errorf("error = %v, want the temperature/thinking conflict", err)
+			}
+		})
+	}
+}
+
+func TestBuildConverseInput_FieldRulesOnlyApplyWhenTriggered(t *testing.T) {
+	// Temperature without thinking, and on a model the rules do not cover,
+	// passes through.
+	for _, model := range []string{"anthropic.claude-3-7-sonnet-20250219-v1:0", "amazon.nova-lite-v1:0"} {
+		cfg := &Config{Temperature: aws.Float32(0.2)}
+		if model == "amazon.nova-lite-v1:0" {
+			cfg.AdditionalModelRequestFields = map[string]any{"thinking": true}
+		}
+		req := &ai.ModelRequest{Messages: []*ai.Message{ai.NewUserTextMessage("hi")}, Config: cfg}
+		out, err := (&Bedrock{}).buildConverseInput(model, req)
+		if err != nil {
+			t.Fatalf("%s: %v", model, err)
+		}
+		if out.InferenceConfig == nil || aws.ToFloat32(out.InferenceConfig.Temperature) != 0.2 {
+			t.Errorf("%s: InferenceConfig = %+v, want temperature 0.2", model, out.InferenceConfig)
+		}
+	}
+}
+
+func TestApplyFieldRules(t *testing.T) {
+	rules := []fieldRule{
+		{Models: "anthropic.", Feature: "thinking", Field: "temperature", Action: fieldRuleReject, Reason: "no"},
+		{Models: "anthropic.", Feature: "thinking", Field: "topK", Action: fieldRuleDrop, Reason: "no"},
+	}
+	k := 40
+	cfg := &Config{MaxTokens: 4096, ThinkingBudget: 2048, TopK: &k, TopP: aws.Float32(0.95)}
+
+	got, err := applyFieldRules("anthropic.claude-3-7-sonnet-20250219-v1:0", cfg, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.TopK != nil || got.TopP == nil {
+		t.Errorf("config = %+v, want topK dropped and topP kept", got)
+	}
+	if cfg.TopK == nil {
+		t.Error("the caller's config was modified")
+	}
+
+	// Dropping the rule set lets the field through: the builder consults only
+	// the rules it is given.
+	got, err = applyFieldRules("anthropic.claude-3-7-sonnet-20250219-v1:0", &Config{ThinkingBudget: 2048, Temperature: aws.Float32(1)}, rules[1:])
+	if err != nil || got.Temperature == nil {
+		t.Errorf("config = %+v, err = %v; want temperature kept without its rule", got, err)
+	}
+}
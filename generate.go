@@ -24,8 +24,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
@@ -50,18 +55,128 @@ var extendedClaudeMaxTokenPatterns = []string{
 }
 
 // generateText handles text generation using Bedrock Converse API
-func (b *Bedrock) generateText(ctx context.Context, modelName string, input *ai.ModelRequest, cb func(context.Context, *ai.ModelResponseChunk) error) (*ai.ModelResponse, error) {
+func (b *Bedrock) generateText(ctx context.Context, modelName string, input *ai.ModelRequest, cb func(context.Context, *ai.ModelResponseChunk) error) (resp *ai.ModelResponse, err error) {
+	defer func(start time.Time) { b.recordMetrics(modelName, start, resp, err) }(time.Now())
+	resp, err = b.generateTextOnce(ctx, modelName, input, cb)
+	if err == nil && b.ContentFilterAsError && resp.FinishReason == ai.FinishReasonBlocked {
+		return nil, &ContentFilteredError{ModelID: modelName, StopReason: StopReason(resp), Response: resp}
+	}
+	if err != nil || !b.validatesJSONOutput(input) {
+		return resp, err
+	}
+	return b.checkJSONOutput(ctx, input, resp, cb != nil, func(ctx context.Context, retry *ai.ModelRequest) (*ai.ModelResponse, error) {
+		return b.generateTextOnce(ctx, modelName, retry, nil)
+	})
+}
+
+func (b *Bedrock) generateTextOnce(ctx context.Context, modelName string, input *ai.ModelRequest, cb func(context.Context, *ai.ModelResponseChunk) error) (*ai.ModelResponse, error) {
+	if err := b.checkToolRounds(input); err != nil {
+		return nil, err
+	}
+	client, err := b.clientForRequest(input)
+	if err != nil {
+		return nil, err
+	}
+	input, err = b.fetchRemoteMedia(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	codec, ok, err := b.routeInvoke(modelName)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return b.generateInvoke(ctx, client, modelName, codec, input, cb)
+	}
+
 	// Convert Genkit request to Bedrock Converse input
 	converseInput, err := b.buildConverseInput(modelName, input)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build converse input: %w", err)
 	}
 
-	// Handle streaming vs non-streaming
-	if cb != nil {
-		return b.generateTextStream(ctx, converseInput, input, cb)
+	cfg, err := configFromRequest(input)
+	if err != nil {
+		return nil, err
+	}
+	var choices []string
+	if cfg != nil {
+		choices = cfg.Choices
+	}
+	if choices != nil && cb != nil {
+		cb = choiceStreamCallback(cb, choices)
+	}
+	if !includeReasoning(cfg) && cb != nil {
+		cb = reasoninglessStreamCallback(cb)
+	}
+
+	resp, err := b.converse(ctx, client, modelName, converseInput, input, cb)
+	if err != nil {
+		return nil, err
+	}
+	if choices != nil {
+		if err := applyChoice(resp, choices); err != nil {
+			return nil, err
+		}
+	}
+	if !includeReasoning(cfg) {
+		hideReasoning(resp)
+	}
+	markTruncated(resp)
+	markCacheHit(resp)
+	b.markRouting(resp, modelName, input)
+	return resp, nil
+}
+
+// markRouting records the serving region and inference profile on resp when
+// IncludeRoutingMetadata is set.
+func (b *Bedrock) markRouting(resp *ai.ModelResponse, modelName string, input *ai.ModelRequest) {
+	if !b.IncludeRoutingMetadata || resp == nil || resp.Message == nil {
+		return
+	}
+	if resp.Message.Metadata == nil {
+		resp.Message.Metadata = map[string]any{}
+	}
+	resp.Message.Metadata[regionMetadataKey] = b.requestRegion(input)
+	if profile := inferenceProfileID(modelName); profile != "" {
+		resp.Message.Metadata[inferenceProfileMetadataKey] = profile
+	}
+}
+
+// inferenceProfileID returns modelName if it names an inference profile: a
+// system-defined profile ID such as "us.anthropic.claude-3-haiku-20240307-v1:0"
+// or an inference profile ARN. Otherwise it returns "".
+func inferenceProfileID(modelName string) string {
+	if baseModelID(modelName) != modelName ||
+		strings.Contains(modelName, ":inference-profile/") ||
+		strings.Contains(modelName, ":application-inference-profile/") {
+		return modelName
+	}
+	return ""
+}
+
+// markTruncated flags a response that stopped at the token limit with
+// Metadata["truncated"] = true, so callers can continue the generation.
+func markTruncated(resp *ai.ModelResponse) {
+	if resp == nil || resp.Message == nil || resp.FinishReason != ai.FinishReasonLength {
+		return
+	}
+	if resp.Message.Metadata == nil {
+		resp.Message.Metadata = map[string]any{}
+	}
+	resp.Message.Metadata[truncatedMetadataKey] = true
+}
+
+// markCacheHit records on resp whether its input was partly read from the
+// prompt cache, as Metadata["cacheHit"], when the response reports usage.
+func markCacheHit(resp *ai.ModelResponse) {
+	if resp == nil || resp.Message == nil || resp.Usage == nil {
+		return
+	}
+	if resp.Message.Metadata == nil {
+		resp.Message.Metadata = map[string]any{}
 	}
-	return b.generateTextSync(ctx, converseInput, input)
+	resp.Message.Metadata[cacheHitMetadataKey] = resp.Usage.CachedContentTokens > 0
 }
 
 func (b *Bedrock) buildConverseInput(modelName string, input *ai.ModelRequest) (*bedrockruntime.ConverseInput, error) {
@@ -73,12 +188,51 @@ func (b *Bedrock) buildConverseInput(modelName string, input *ai.ModelRequest) (
 	if err != nil {
 		return nil, err
 	}
+	if err := b.checkStopSequences(modelName, cfg); err != nil {
+		return nil, err
+	}
+	if cfg, err = applyFieldRules(modelName, cfg, incompatibleFieldRules); err != nil {
+		return nil, err
+	}
+	cfg = b.applyFamilyDefaults(modelName, cfg)
 
-	systemPrompts, messages, err := convertMessages(input.Messages)
+	input, err = b.checkUserMessage(dropSystemMessages(input, cfg))
+	if err != nil {
+		return nil, err
+	}
+	input, err = b.checkToolSupport(modelName, input)
+	if err != nil {
+		return nil, err
+	}
+	input, err = b.limitToolResults(input, cfg)
+	if err != nil {
+		return nil, err
+	}
+	input, err = prepareToolResultImages(modelName, input)
 	if err != nil {
 		return nil, err
 	}
 
+	msgs := input.Messages
+	if !b.supportsSystemPrompt(modelName) {
+		msgs = foldSystemPrompt(msgs)
+	}
+	if b.ImagePreprocessing {
+		if msgs, err = preprocessImages(msgs); err != nil {
+			return nil, err
+		}
+	}
+	if err := b.checkMediaTypes(modelName, msgs); err != nil {
+		return nil, err
+	}
+	systemPrompts, messages, err := convertMessages(msgs)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.checkImageCount(modelName, messages); err != nil {
+		return nil, err
+	}
+
 	// When using tools, AWS Bedrock requires that the conversation doesn't end
 	// with an assistant message.
 	if len(input.Tools) > 0 && len(messages) > 0 {
@@ -96,6 +250,9 @@ func (b *Bedrock) buildConverseInput(modelName string, input *ai.ModelRequest) (
 			inferenceConfig.MaxTokens = aws.Int32(maxTokens)
 		}
 	}
+	if err := b.checkMaxTokens(modelName, inferenceConfig); err != nil {
+		return nil, err
+	}
 
 	converseInput := &bedrockruntime.ConverseInput{
 		ModelId:         aws.String(modelName),
@@ -105,21 +262,63 @@ func (b *Bedrock) buildConverseInput(modelName string, input *ai.ModelRequest) (
 	}
 
 	if cfg != nil {
-		if len(cfg.AdditionalModelRequestFields) > 0 {
-			converseInput.AdditionalModelRequestFields = document.NewLazyDocument(cfg.AdditionalModelRequestFields)
+		if cfg.Logprobs {
+			return nil, errLogprobsUnsupported(modelName)
+		}
+		fields, err := additionalRequestFields(modelName, cfg)
+		if err != nil {
+			return nil, err
+		}
+		if len(fields) > 0 {
+			doc, err := requestFieldsDocument(fields)
+			if err != nil {
+				return nil, err
+			}
+			converseInput.AdditionalModelRequestFields = doc
 		}
+		paths, err := additionalResponseFieldPaths(cfg.AdditionalResponseFieldPaths)
+		if err != nil {
+			return nil, err
+		}
+		converseInput.AdditionalModelResponseFieldPaths = paths
+		if cfg.Citations {
+			enableDocumentCitations(converseInput.Messages)
+		}
+		guardrail, err := buildGuardrailConfig(cfg.Guardrail)
+		if err != nil {
+			return nil, err
+		}
+		converseInput.GuardrailConfig = guardrail
 	}
 
-	// Handle tools
+	if cfg != nil && cfg.Choices != nil {
+		if err := b.checkChoices(modelName, cfg, input.Tools); err != nil {
+			return nil, err
+		}
+		toolConfig, err := choiceToolConfig(cfg.Choices)
+		if err != nil {
+			return nil, err
+		}
+		converseInput.ToolConfig = toolConfig
+		return converseInput, nil
+	}
+
+	// Handle tools. Bedrock rejects an empty toolConfig.tools, so an empty
+	// (non-nil) tools slice is treated like no tools and sends no toolConfig.
 	if len(input.Tools) > 0 {
 		if cfg != nil && cfg.ToolChoice == ToolChoiceNone {
 			return converseInput, nil
 		}
-		tools, err := b.convertTools(input.Tools)
+		toolConfig, err := toolsToConverseConfig(input.Tools)
 		if err != nil {
 			return nil, err
 		}
-		converseInput.ToolConfig = &types.ToolConfiguration{Tools: tools}
+		if b.CacheTools && b.supportsToolCaching(modelName) {
+			toolConfig.Tools = append(toolConfig.Tools, &types.ToolMemberCachePoint{
+				Value: types.CachePointBlock{Type: types.CachePointTypeDefault},
+			})
+		}
+		converseInput.ToolConfig = toolConfig
 		if cfg != nil && cfg.ToolChoice != "" {
 			choice, err := convertToolChoice(cfg.ToolChoice, input.Tools)
 			if err != nil {
@@ -132,6 +331,237 @@ func (b *Bedrock) buildConverseInput(modelName string, input *ai.ModelRequest) (
 	return converseInput, nil
 }
 
+// checkUserMessage fails requests with no conversation turns, that is empty
+// ones or ones holding only a system prompt, which Bedrock rejects with an
+// opaque ValidationException. With EmptyPromptUserTurn set it instead returns
+// a copy of the request with that text appended as the user message.
+func (b *Bedrock) checkUserMessage(input *ai.ModelRequest) (*ai.ModelRequest, error) {
+	for _, msg := range input.Messages {
+		if msg != nil && msg.Role != ai.RoleSystem {
+			return input, nil
+		}
+	}
+	if b == nil || b.EmptyPromptUserTurn == "" {
+		return nil, errors.New("bedrock: request must contain at least one user message (set Bedrock.EmptyPromptUserTurn to send a default one)")
+	}
+	out := *input
+	out.Messages = append(slices.Clip(input.Messages), ai.NewUserTextMessage(b.EmptyPromptUserTurn))
+	return &out, nil
+}
+
+// checkToolSupport fails requests with tools to models whose capabilities
+// have Tools: false, or returns a copy without the tools when
+// StripUnsupportedTools is set.
+func (b *Bedrock) checkToolSupport(modelName string, input *ai.ModelRequest) (*ai.ModelRequest, error) {
+	if len(input.Tools) == 0 {
+		return input, nil
+	}
+	caps, ok := b.modelCapability(modelName)
+	if !ok || caps.Tools {
+		return input, nil
+	}
+	if !b.StripUnsupportedTools {
+		return nil, fmt.Errorf("bedrock: model %q does not support tool use; use a tool-capable model such as %q, or set Bedrock.StripUnsupportedTools to drop the tools", modelName, suggestToolModel(modelName))
+	}
+	slog.Warn("bedrock: model does not support tool use; dropping tools from the request", "model", modelName, "tools", len(input.Tools))
+	stripped := *input
+	stripped.Tools = nil
+	return &stripped, nil
+}
+
+// checkMediaTypes rejects media parts of a kind (image, document or video)
+// that modelName does not accept, naming the part's MIME type. Models
+// without a capability entry are not checked.
+func (b *Bedrock) checkMediaTypes(modelName string, msgs []*ai.Message) error {
+	caps, ok := b.modelCapability(modelName)
+	if !ok {
+		return nil
+	}
+	for _, msg := range msgs {
+		if msg == nil {
+			continue
+		}
+		for _, part := range msg.Content {
+			if isDocumentPart(part) && !caps.Documents {
+				return fmt.Errorf("bedrock: model %q does not accept document input", modelName)
+			}
+			if part == nil || !part.IsMedia() {
+				continue
+			}
+			mime := mediaMIME(part)
+			var kind string
+			switch {
+			case documentFormatFor(mime) != "" && !caps.Documents:
+				kind = "document"
+			case strings.HasPrefix(mime, "image/") && !caps.Multimodal:
+				kind = "image"
+			case strings.HasPrefix(mime, "video/") && !caps.Video:
+				kind = "video"
+			default:
+				continue
+			}
+			return fmt.Errorf("bedrock: model %q does not accept %s input (%s)", modelName, kind, mime)
+		}
+	}
+	return nil
+}
+
+// checkImageCount rejects requests with more image blocks than modelName
+// accepts, e.g. Claude's 20 images per request.
+func (b *Bedrock) checkImageCount(modelName string, messages []types.Message) error {
+	images := 0
+	for _, msg := range messages {
+		for _, block := range msg.Content {
+			if _, ok := block.(*types.ContentBlockMemberImage); ok {
+				images++
+			}
+		}
+	}
+	return b.checkImageLimit(modelName, images)
+}
+
+// checkImageLimit rejects a request with images image blocks when modelName
+// accepts fewer.
+func (b *Bedrock) checkImageLimit(modelName string, images int) error {
+	caps, ok := b.modelCapability(modelName)
+	if !ok || caps.MaxImages == 0 || images <= caps.MaxImages {
+		return nil
+	}
+	return fmt.Errorf("bedrock: request has %d images but model %q accepts at most %d; split the images across requests", images, modelName, caps.MaxImages)
+}
+
+// checkStopSequences rejects config with more stop sequences than modelName
+// accepts, e.g. the legacy Cohere Command models' 4, instead of letting
+// Bedrock fail with an opaque ValidationException.
+func (b *Bedrock) checkStopSequences(modelName string, cfg *Config) error {
+	if cfg == nil {
+		return nil
+	}
+	caps, ok := b.modelCapability(modelName)
+	if !ok || caps.MaxStopSequences == 0 {
+		return nil
+	}
+	if n := len(cfg.StopSequences); n > caps.MaxStopSequences {
+		return fmt.Errorf("bedrock: request has %d stop sequences but model %q accepts at most %d", n, modelName, caps.MaxStopSequences)
+	}
+	return nil
+}
+
+// ToolRoundLimitError is returned when a request's history already holds
+// [Bedrock.MaxToolRounds] consecutive tool-use rounds.
+type ToolRoundLimitError struct {
+	Limit int // The configured MaxToolRounds
+}
+
+func (e *ToolRoundLimitError) Error() string {
+	return fmt.Sprintf("bedrock: tool-use round limit of %d reached; the model kept calling tools without a final answer", e.Limit)
+}
+
+// checkToolRounds enforces MaxToolRounds on input.
+func (b *Bedrock) checkToolRounds(input *ai.ModelRequest) error {
+	if b.MaxToolRounds <= 0 || input == nil {
+		return nil
+	}
+	if toolRounds(input.Messages) >= b.MaxToolRounds {
+		return &ToolRoundLimitError{Limit: b.MaxToolRounds}
+	}
+	return nil
+}
+
+// toolRounds counts the model turns that requested tools since the last user
+// message. Tool responses, which Genkit sends with the user or tool role,
+// don't end the count.
+func toolRounds(messages []*ai.Message) int {
+	rounds := 0
+	for i := len(messages) - 1; i >= 0; i-- {
+		msg := messages[i]
+		if msg == nil {
+			continue
+		}
+		switch msg.Role {
+		case ai.RoleModel:
+			for _, part := range msg.Content {
+				if part != nil && part.IsToolRequest() {
+					rounds++
+					break
+				}
+			}
+		case ai.RoleTool:
+		case ai.RoleUser:
+			if !isToolResponseMessage(msg) {
+				return rounds
+			}
+		default:
+			return rounds
+		}
+	}
+	return rounds
+}
+
+// isToolResponseMessage reports whether msg carries only tool responses.
+func isToolResponseMessage(msg *ai.Message) bool {
+	found := false
+	for _, part := range msg.Content {
+		if part == nil {
+			continue
+		}
+		if !part.IsToolResponse() {
+			return false
+		}
+		found = true
+	}
+	return found
+}
+
+// dropSystemMessages returns input without its system messages when cfg sets
+// NoSystemPrompt. input is not modified.
+func dropSystemMessages(input *ai.ModelRequest, cfg *Config) *ai.ModelRequest {
+	if cfg == nil || !cfg.NoSystemPrompt {
+		return input
+	}
+	out := *input
+	out.Messages = slices.DeleteFunc(slices.Clone(input.Messages), func(msg *ai.Message) bool {
+		return msg != nil && msg.Role == ai.RoleSystem
+	})
+	return &out
+}
+
+// foldSystemPrompt moves the text of system messages to the front of the
+// first user message, for models that reject a Converse system prompt. Cache
+// points in system messages are dropped. msgs is not modified.
+func foldSystemPrompt(msgs []*ai.Message) []*ai.Message {
+	var system []string
+	out := make([]*ai.Message, 0, len(msgs))
+	for _, msg := range msgs {
+		if msg == nil {
+			continue
+		}
+		if msg.Role != ai.RoleSystem {
+			out = append(out, msg)
+			continue
+		}
+		for _, part := range msg.Content {
+			if part != nil && part.IsText() && part.Text != "" {
+				system = append(system, part.Text)
+			}
+		}
+	}
+	if len(system) == 0 {
+		return out
+	}
+	prefix := ai.NewTextPart(strings.Join(system, "\n\n") + "\n\n")
+	for i, msg := range out {
+		if msg.Role == ai.RoleUser {
+			folded := *msg
+			folded.Content = append([]*ai.Part{prefix}, msg.Content...)
+			out[i] = &folded
+			return out
+		}
+	}
+	prefix.Text = strings.TrimSuffix(prefix.Text, "\n\n")
+	return append([]*ai.Message{{Role: ai.RoleUser, Content: []*ai.Part{prefix}}}, out...)
+}
+
 // convertMessages walks the ai.ModelRequest messages and produces a system
 // block list plus the user/assistant/tool conversation.
 func convertMessages(msgs []*ai.Message) ([]types.SystemContentBlock, []types.Message, error) {
@@ -167,9 +597,18 @@ func convertMessages(msgs []*ai.Message) ([]types.SystemContentBlock, []types.Me
 		if err != nil {
 			return nil, nil, err
 		}
+		if hidden := hiddenReasoningBlocks(msg); len(hidden) > 0 {
+			blocks = append(hidden, blocks...)
+		}
 		if len(blocks) == 0 {
 			continue
 		}
+		// Converse needs alternating roles, so the results of parallel tool
+		// calls that Genkit sends as separate messages form one user turn.
+		if n := len(messages); n > 0 && messages[n-1].Role == role && isToolResponseMessage(msg) {
+			messages[n-1].Content = append(messages[n-1].Content, blocks...)
+			continue
+		}
 		messages = append(messages, types.Message{Role: role, Content: blocks})
 	}
 	return system, messages, nil
@@ -218,19 +657,23 @@ func partsToContentBlocks(parts []*ai.Part) ([]types.ContentBlock, error) {
 			if toolResp == nil {
 				continue
 			}
-			outputText, err := toolResponseText(toolResp.Output)
+			content, err := toolResultContent(toolResp)
 			if err != nil {
 				return nil, err
 			}
 			blocks = append(blocks, &types.ContentBlockMemberToolResult{
 				Value: types.ToolResultBlock{
 					ToolUseId: aws.String(toolResp.Ref),
-					Content: []types.ToolResultContentBlock{
-						&types.ToolResultContentBlockMemberText{Value: outputText},
-					},
-					Status: types.ToolResultStatusSuccess,
+					Content:   content,
+					Status:    toolResultStatus(part),
 				},
 			})
+		case isDocumentPart(part):
+			block, err := documentPartBlock(part)
+			if err != nil {
+				return nil, err
+			}
+			blocks = append(blocks, block)
 		case part.IsCustom():
 			if cpt, ok := CachePointType(part); ok {
 				blocks = append(blocks, &types.ContentBlockMemberCachePoint{
@@ -244,6 +687,64 @@ func partsToContentBlocks(parts []*ai.Part) ([]types.ContentBlock, error) {
 	return blocks, nil
 }
 
+// toolResultStatus returns the Converse status for a tool response part:
+// error when it is flagged as a failed call (see [NewToolErrorPart]).
+func toolResultStatus(part *ai.Part) types.ToolResultStatus {
+	if IsToolError(part) {
+		return types.ToolResultStatusError
+	}
+	return types.ToolResultStatusSuccess
+}
+
+// toolResultContent returns the Converse content entries of a tool response:
+// its Output as text (JSON-encoded unless a string), followed by one entry per
+// part of its Content. JSON parts (see [ai.NewJSONPart]) become json entries,
+// other text parts text entries, and media parts image, document or video
+// entries. Output is left out when nil and Content is not empty.
+func toolResultContent(resp *ai.ToolResponse) ([]types.ToolResultContentBlock, error) {
+	var content []types.ToolResultContentBlock
+	if resp.Output != nil || len(resp.Content) == 0 {
+		text, err := toolResponseText(resp.Output)
+		if err != nil {
+			return nil, err
+		}
+		content = append(content, &types.ToolResultContentBlockMemberText{Value: text})
+	}
+	for _, part := range resp.Content {
+		if part == nil {
+			continue
+		}
+		switch {
+		case part.IsText() && part.ContentType == "application/json":
+			var v any
+			if err := json.Unmarshal([]byte(part.Text), &v); err != nil {
+				return nil, fmt.Errorf("bedrock: tool response %q: invalid JSON content: %w", resp.Name, err)
+			}
+			content = append(content, &types.ToolResultContentBlockMemberJson{Value: document.NewLazyDocument(v)})
+		case part.IsText():
+			content = append(content, &types.ToolResultContentBlockMemberText{Value: part.Text})
+		case part.IsMedia():
+			block, err := mediaToBlock(part)
+			if err != nil {
+				return nil, err
+			}
+			switch b := block.(type) {
+			case *types.ContentBlockMemberImage:
+				content = append(content, &types.ToolResultContentBlockMemberImage{Value: b.Value})
+			case *types.ContentBlockMemberDocument:
+				content = append(content, &types.ToolResultContentBlockMemberDocument{Value: b.Value})
+			case *types.ContentBlockMemberVideo:
+				content = append(content, &types.ToolResultContentBlockMemberVideo{Value: b.Value})
+			default:
+				return nil, fmt.Errorf("bedrock: tool response %q: unsupported media type %q", resp.Name, mediaMIME(part))
+			}
+		default:
+			return nil, fmt.Errorf("bedrock: tool response %q: unsupported content part kind %v", resp.Name, part.Kind)
+		}
+	}
+	return content, nil
+}
+
 func toolResponseText(output any) (string, error) {
 	if output == nil {
 		return "", nil
@@ -284,15 +785,26 @@ func mediaToBlock(part *ai.Part) (types.ContentBlock, error) {
 			},
 		}, nil
 	}
-	return nil, fmt.Errorf("bedrock: unsupported media MIME type %q (must be png/jpeg/gif/webp or one of pdf/csv/doc/docx/xls/xlsx/html/txt/md)", mime)
+	if format := videoFormatFor(mime); format != "" {
+		return &types.ContentBlockMemberVideo{
+			Value: types.VideoBlock{
+				Format: format,
+				Source: &types.VideoSourceMemberBytes{Value: fileData},
+			},
+		}, nil
+	}
+	if strings.HasPrefix(mime, "image/") {
+		return nil, fmt.Errorf("bedrock: unsupported image format %q; Converse accepts image/png, image/jpeg, image/gif and image/webp, so convert the image before sending it", mime)
+	}
+	return nil, fmt.Errorf("bedrock: unsupported media MIME type %q (must be png/jpeg/gif/webp, a video format such as mp4, or one of pdf/csv/doc/docx/xls/xlsx/html/txt/md)", mime)
 }
 
 func mediaMIME(part *ai.Part) string {
 	mime := strings.TrimSpace(part.ContentType)
-	if mime == "" && strings.HasPrefix(part.Text, "data:") {
-		header, _, ok := strings.Cut(part.Text, ",")
+	if text := strings.TrimSpace(part.Text); mime == "" && hasDataURIScheme(text) {
+		header, _, ok := strings.Cut(text, ",")
 		if ok {
-			header = strings.TrimPrefix(header, "data:")
+			header = header[len("data:"):]
 			mime, _, _ = strings.Cut(header, ";")
 		}
 	}
@@ -300,22 +812,25 @@ func mediaMIME(part *ai.Part) string {
 	return strings.ToLower(strings.TrimSpace(mime))
 }
 
-// decodeMediaPayload accepts either a raw "data:<mime>;base64,..." URL or a
-// bare base64 string and returns decoded bytes. Bedrock expects raw bytes; the
-// SDK base64-encodes them for the wire.
+// decodeMediaPayload accepts either a data URI (see [parseDataURI]) or a bare
+// base64 string and returns decoded bytes. Bedrock expects raw bytes; the SDK
+// base64-encodes them for the wire.
 func decodeMediaPayload(s string) ([]byte, error) {
 	s = strings.TrimSpace(s)
 	if s == "" {
 		return nil, errors.New("bedrock: media part has empty data")
 	}
-	if i := strings.Index(s, ";base64,"); i >= 0 {
-		s = strings.TrimSpace(s[i+len(";base64,"):])
-	} else if strings.HasPrefix(s, "data:") {
-		return nil, errors.New("bedrock: data URL must be base64-encoded (use ';base64,' prefix)")
-	} else if strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://") {
-		return nil, errors.New("bedrock: remote URLs are not supported; use a data URL or base64-encoded data")
+	if hasDataURIScheme(s) {
+		uri, err := parseDataURI(s)
+		if err != nil {
+			return nil, err
+		}
+		return uri.data, nil
 	}
-	fileData, err := base64.StdEncoding.DecodeString(s)
+	if strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://") {
+		return nil, errors.New("bedrock: remote URLs are not supported; use a data URL or base64-encoded data, or set Bedrock.RemoteMediaFetch")
+	}
+	fileData, err := decodeBase64Lenient(s)
 	if err != nil {
 		return nil, fmt.Errorf("bedrock: decode base64 media: %w", err)
 	}
@@ -324,9 +839,9 @@ func decodeMediaPayload(s string) ([]byte, error) {
 
 func imageFormatFor(mime string) types.ImageFormat {
 	switch mime {
-	case "image/png":
+	case "image/png", "image/x-png":
 		return types.ImageFormatPng
-	case "image/jpeg", "image/jpg":
+	case "image/jpeg", "image/jpg", "image/pjpeg":
 		return types.ImageFormatJpeg
 	case "image/gif":
 		return types.ImageFormatGif
@@ -337,6 +852,83 @@ func imageFormatFor(mime string) types.ImageFormat {
 	}
 }
 
+func videoFormatFor(mime string) types.VideoFormat {
+	switch mime {
+	case "video/mp4":
+		return types.VideoFormatMp4
+	case "video/quicktime":
+		return types.VideoFormatMov
+	case "video/x-matroska":
+		return types.VideoFormatMkv
+	case "video/webm":
+		return types.VideoFormatWebm
+	case "video/x-flv":
+		return types.VideoFormatFlv
+	case "video/mpeg":
+		return types.VideoFormatMpeg
+	case "video/x-ms-wmv":
+		return types.VideoFormatWmv
+	case "video/3gpp":
+		return types.VideoFormatThreeGp
+	default:
+		return ""
+	}
+}
+
+// toolUsePart returns a tool request part for the toolUse block at content
+// index idx, recording the index and toolUseId in its metadata.
+func toolUsePart(idx int, req *ai.ToolRequest) *ai.Part {
+	part := ai.NewToolRequestPart(req)
+	part.Metadata = map[string]any{
+		toolUseIndexMetadataKey: idx,
+		toolUseIDMetadataKey:    req.Ref,
+	}
+	return part
+}
+
+// audioBlockToPart converts an audio content block returned by Converse into a
+// Genkit media part. Inline audio becomes a base64 data URL; audio stored in S3
+// is surfaced as its s3:// URI.
+func audioBlockToPart(block types.AudioBlock) (*ai.Part, error) {
+	if block.Error != nil {
+		return nil, fmt.Errorf("bedrock: audio block error: %s", aws.ToString(block.Error.Message))
+	}
+	mime := audioMIMEFor(block.Format)
+	switch source := block.Source.(type) {
+	case *types.AudioSourceMemberBytes:
+		return ai.NewMediaPart(mime, "data:"+mime+";base64,"+base64.StdEncoding.EncodeToString(source.Value)), nil
+	case *types.AudioSourceMemberS3Location:
+		return ai.NewMediaPart(mime, aws.ToString(source.Value.Uri)), nil
+	default:
+		return nil, fmt.Errorf("bedrock: unhandled audio source variant %T", block.Source)
+	}
+}
+
+func audioMIMEFor(format types.AudioFormat) string {
+	switch format {
+	case types.AudioFormatMp3, types.AudioFormatMpeg, types.AudioFormatMpga:
+		return "audio/mpeg"
+	case types.AudioFormatWav:
+		return "audio/wav"
+	case types.AudioFormatOgg, types.AudioFormatOpus:
+		return "audio/ogg"
+	case types.AudioFormatFlac:
+		return "audio/flac"
+	case types.AudioFormatAac, types.AudioFormatXAac:
+		return "audio/aac"
+	case types.AudioFormatMp4, types.AudioFormatM4a:
+		return "audio/mp4"
+	case types.AudioFormatWebm:
+		return "audio/webm"
+	case types.AudioFormatMkv, types.AudioFormatMka:
+		return "audio/x-matroska"
+	case types.AudioFormatPcm:
+		return "audio/pcm"
+	default:
+		return "audio/" + string(format)
+	}
+}
+
 func documentFormatFor(mime string) types.DocumentFormat {
 	switch mime {
 	case "application/pdf":
@@ -363,14 +955,14 @@ func documentFormatFor(mime string) types.DocumentFormat {
 }
 
 // generateTextSync handles synchronous text generation
-func (b *Bedrock) generateTextSync(ctx context.Context, input *bedrockruntime.ConverseInput, originalInput *ai.ModelRequest) (*ai.ModelResponse, error) {
+func (b *Bedrock) generateTextSync(ctx context.Context, client BedrockClient, input *bedrockruntime.ConverseInput, originalInput *ai.ModelRequest) (*ai.ModelResponse, error) {
 	ctx, cancel := b.withRequestTimeout(ctx)
 	defer cancel()
 
 	// Call Bedrock Converse API
-	response, err := b.client.Converse(ctx, input)
+	response, err := client.Converse(ctx, input)
 	if err != nil {
-		return nil, fmt.Errorf("bedrock converse failed: %w", err)
+		return nil, modelCallError("bedrock converse failed", aws.ToString(input.ModelId), b.requestRegion(originalInput), err)
 	}
 
 	// Convert response to Genkit format
@@ -399,20 +991,141 @@ func (b *Bedrock) convertResponse(response *bedrockruntime.ConverseOutput, origi
 			return nil, err
 		}
 	}
-	if len(parts) == 0 {
-		parts = append(parts, ai.NewTextPart(""))
+	parts = withAnswerPart(parts)
+	var latency *int64
+	if response.Metrics != nil {
+		latency = response.Metrics.LatencyMs
 	}
 	return &ai.ModelResponse{
-		Message:      &ai.Message{Role: ai.RoleModel, Content: parts},
+		Message:      &ai.Message{Role: ai.RoleModel, Content: parts, Metadata: responseMetadata(latency, response.AdditionalModelResponseFields, response.StopReason)},
 		FinishReason: convertStopReasonToGenkit(response.StopReason),
 		Usage:        usageFromTokens(response.Usage),
 		Request:      originalInput,
 	}, nil
 }
 
+// buildGuardrailConfig validates g and converts it to the Converse guardrail
+// configuration. It returns nil when g is nil.
+func buildGuardrailConfig(g *GuardrailConfig) (*types.GuardrailConfiguration, error) {
+	if g == nil {
+		return nil, nil
+	}
+	if g.Identifier == "" || g.Version == "" {
+		return nil, errors.New("bedrock: guardrail config requires both identifier and version")
+	}
+	if _, err := guardrailStreamProcessingMode(g.StreamProcessingMode); err != nil {
+		return nil, err
+	}
+	out := &types.GuardrailConfiguration{
+		GuardrailIdentifier: aws.String(g.Identifier),
+		GuardrailVersion:    aws.String(g.Version),
+	}
+	if g.Trace {
+		out.Trace = types.GuardrailTraceEnabled
+	}
+	return out, nil
+}
+
+// guardrailStreamProcessingMode maps a [GuardrailConfig.StreamProcessingMode]
+// value to the SDK enum, defaulting to sync.
+func guardrailStreamProcessingMode(mode string) (types.GuardrailStreamProcessingMode, error) {
+	switch strings.ToLower(mode) {
+	case "", string(types.GuardrailStreamProcessingModeSync):
+		return types.GuardrailStreamProcessingModeSync, nil
+	case string(types.GuardrailStreamProcessingModeAsync):
+		return types.GuardrailStreamProcessingModeAsync, nil
+	default:
+		return "", fmt.Errorf("bedrock: invalid guardrail streamProcessingMode %q (want sync or async)", mode)
+	}
+}
+
+// responseMetadata returns the message metadata for a Converse response, or
+// nil when Bedrock reported nothing worth recording.
+func responseMetadata(latencyMs *int64, additional document.Interface, stopReason types.StopReason) map[string]any {
+	metadata := map[string]any{}
+	if stopReason != "" {
+		metadata[stopReasonMetadataKey] = string(stopReason)
+		if !slices.Contains(stopReason.Values(), stopReason) {
+			metadata[rawStopReasonMetadataKey] = string(stopReason)
+		}
+	}
+	if latencyMs != nil {
+		metadata[serverLatencyMetadataKey] = *latencyMs
+	}
+	if fields := additionalResponseFields(additional); len(fields) > 0 {
+		metadata[additionalFieldsMetadataKey] = fields
+	}
+	if len(metadata) == 0 {
+		return nil
+	}
+	return metadata
+}
+
+// setStopReason records stopReason on resp's message metadata, for responses
+// whose stop reason the plugin derives itself.
+func setStopReason(resp *ai.ModelResponse, stopReason types.StopReason) {
+	if resp.Message.Metadata == nil {
+		resp.Message.Metadata = map[string]any{}
+	}
+	resp.Message.Metadata[stopReasonMetadataKey] = string(stopReason)
+}
+
+// maxAdditionalResponseFieldPaths is the Converse limit on
+// additionalModelResponseFieldPaths.
+const maxAdditionalResponseFieldPaths = 10
+
+// additionalResponseFieldPaths validates [Config.AdditionalResponseFieldPaths]
+// against the Converse constraints: at most 10 JSON Pointer paths of 1 to 256
+// characters.
+func additionalResponseFieldPaths(paths []string) ([]string, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	if len(paths) > maxAdditionalResponseFieldPaths {
+		return nil, fmt.Errorf("bedrock: %d additionalResponseFieldPaths exceed the limit of %d", len(paths), maxAdditionalResponseFieldPaths)
+	}
+	for _, p := range paths {
+		if !strings.HasPrefix(p, "/") || len(p) > 256 {
+			return nil, fmt.Errorf("bedrock: additionalResponseFieldPaths entry %q must be a JSON Pointer such as \"/stop_sequence\" of at most 256 characters", p)
+		}
+	}
+	return paths, nil
+}
+
+// additionalResponseFields decodes a Converse additionalModelResponseFields
+// document into plain JSON values. Fields that are not a JSON object, or
+// fail to decode, are logged and dropped rather than failing the response.
+func additionalResponseFields(doc document.Interface) map[string]any {
+	if doc == nil {
+		return nil
+	}
+	data, err := doc.MarshalSmithyDocument()
+	if err != nil {
+		slog.Debug("bedrock: dropping undecodable additionalModelResponseFields", "err", err)
+		return nil
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		slog.Debug("bedrock: dropping non-object additionalModelResponseFields", "err", err)
+		return nil
+	}
+	return fields
+}
+
+// withAnswerPart appends an empty text part to response content that has no
+// answer: none at all, or only a reasoning part. ai.Message.Text returns a
+// lone part's text whatever its kind, so without it the reasoning of a
+// response cut off while thinking would read as the answer.
+func withAnswerPart(parts []*ai.Part) []*ai.Part {
+	if len(parts) == 0 || (len(parts) == 1 && parts[0].IsReasoning()) {
+		return append(parts, ai.NewTextPart(""))
+	}
+	return parts
+}
+
 func (b *Bedrock) contentBlocksToParts(blocks []types.ContentBlock, originalInput *ai.ModelRequest) ([]*ai.Part, error) {
 	out := make([]*ai.Part, 0, len(blocks))
-	for _, contentBlock := range blocks {
+	for i, contentBlock := range blocks {
 		switch block := contentBlock.(type) {
 		case *types.ContentBlockMemberText:
 			out = append(out, ai.NewTextPart(block.Value))
@@ -422,7 +1135,7 @@ func (b *Bedrock) contentBlocksToParts(blocks []types.ContentBlock, originalInpu
 			if err != nil {
 				return nil, err
 			}
-			out = append(out, ai.NewToolRequestPart(&ai.ToolRequest{
+			out = append(out, toolUsePart(i, &ai.ToolRequest{
 				Name:  aws.ToString(toolUse.Name),
 				Input: toolInput,
 				Ref:   aws.ToString(toolUse.ToolUseId),
@@ -435,6 +1148,14 @@ func (b *Bedrock) contentBlocksToParts(blocks []types.ContentBlock, originalInpu
 			if part != nil {
 				out = append(out, part)
 			}
+		case *types.ContentBlockMemberCitationsContent:
+			out = append(out, citationsBlockToParts(block.Value)...)
+		case *types.ContentBlockMemberAudio:
+			part, err := audioBlockToPart(block.Value)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, part)
 		default:
 			return nil, fmt.Errorf("bedrock: unhandled response content variant %T", contentBlock)
 		}
@@ -464,7 +1185,7 @@ func (b *Bedrock) unwrapToolInput(input document.Interface, toolName string, ori
 		tools = originalInput.Tools
 	}
 	if inputMap, ok := decoded.(map[string]any); ok {
-		return b.convertToolInputTypes(inputMap, toolName, tools), nil
+		return convertToolInputTypes(inputMap, toolName, tools), nil
 	}
 	return decoded, nil
 }
@@ -473,19 +1194,35 @@ func usageFromTokens(usage *types.TokenUsage) *ai.GenerationUsage {
 	if usage == nil {
 		return nil
 	}
-	return &ai.GenerationUsage{
+	out := &ai.GenerationUsage{
 		InputTokens:         int(aws.ToInt32(usage.InputTokens)),
 		OutputTokens:        int(aws.ToInt32(usage.OutputTokens)),
 		TotalTokens:         int(aws.ToInt32(usage.TotalTokens)),
 		CachedContentTokens: int(aws.ToInt32(usage.CacheReadInputTokens)),
 	}
+	if writes := aws.ToInt32(usage.CacheWriteInputTokens); writes > 0 {
+		out.Custom = map[string]float64{cacheWriteInputTokensUsageKey: float64(writes)}
+	}
+	return out
 }
 
-// configFromRequest decodes input.Config into a *Config. It accepts the typed
-// *Config/Config, *ai.GenerationCommonConfig/ai.GenerationCommonConfig, and the
-// historical map[string]any shape (used on resumed/serialized flows). It
-// returns (nil, nil) when no config is provided.
+// configFromRequest decodes input.Config into a *Config and validates it with
+// [Config.Validate]. It accepts the typed *Config/Config,
+// *ai.GenerationCommonConfig/ai.GenerationCommonConfig, and the historical
+// map[string]any shape (used on resumed/serialized flows). It returns
+// (nil, nil) when no config is provided.
 func configFromRequest(input *ai.ModelRequest) (*Config, error) {
+	cfg, err := decodeConfig(input)
+	if err != nil || cfg == nil {
+		return cfg, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func decodeConfig(input *ai.ModelRequest) (*Config, error) {
 	if input == nil || input.Config == nil {
 		return nil, nil
 	}
@@ -503,8 +1240,12 @@ func configFromRequest(input *ai.ModelRequest) (*Config, error) {
 		if err != nil {
 			return nil, fmt.Errorf("bedrock: marshal config: %w", err)
 		}
+		// UseNumber keeps AdditionalModelRequestFields numbers exact: integers
+		// stay integers instead of becoming float64.
 		var c Config
-		if err := json.Unmarshal(b, &c); err != nil {
+		decoder := json.NewDecoder(bytes.NewReader(b))
+		decoder.UseNumber()
+		if err := decoder.Decode(&c); err != nil {
 			return nil, fmt.Errorf("bedrock: decode config: %w", err)
 		}
 		// Preserve the historical max-token keys, which differ from Config's
@@ -556,9 +1297,158 @@ func configFromGenerationCommonConfig(v *ai.GenerationCommonConfig) *Config {
 		p := float32(v.TopP)
 		cfg.TopP = &p
 	}
+	if v.TopK != 0 {
+		k := v.TopK
+		cfg.TopK = &k
+	}
 	return cfg
 }
 
+// additionalRequestFields returns cfg.AdditionalModelRequestFields with
+// cfg.TopK added under the model family's field and, for Llama models, the
+// cfg.Llama options; for Claude models, cfg.ThinkingBudget and
+// cfg.AnthropicBeta. Keys the caller set explicitly win. Families without a
+// known topK field log and drop it.
+func additionalRequestFields(modelName string, cfg *Config) (map[string]any, error) {
+	name := strings.ToLower(modelName)
+	isLlama := strings.Contains(name, "meta.llama")
+	if cfg.Llama != nil && !isLlama {
+		slog.Debug("bedrock: ignoring Llama options for a non-Llama model", "model", modelName)
+	}
+	if cfg.Verbosity != "" && !strings.Contains(name, effortModel) {
+		slog.Debug("bedrock: model has no verbosity control; ignoring verbosity", "model", modelName)
+	}
+	if cfg.TopK == nil && (cfg.Llama == nil || !isLlama) && cfg.ThinkingBudget == 0 && len(cfg.AnthropicBeta) == 0 &&
+		(cfg.Verbosity == "" || !strings.Contains(name, effortModel)) {
+		return cfg.AdditionalModelRequestFields, nil
+	}
+	fields := make(map[string]any, len(cfg.AdditionalModelRequestFields)+2)
+	for k, v := range cfg.AdditionalModelRequestFields {
+		fields[k] = v
+	}
+	setDefault := func(key string, value any) {
+		if _, ok := fields[key]; !ok {
+			fields[key] = value
+		}
+	}
+	if cfg.TopK != nil {
+		switch {
+		case strings.Contains(name, "anthropic."), strings.Contains(name, "mistral."):
+			setDefault("top_k", *cfg.TopK)
+		case strings.Contains(name, "amazon.nova"):
+			ic, _ := fields["inferenceConfig"].(map[string]any)
+			merged := map[string]any{"topK": *cfg.TopK}
+			for k, v := range ic {
+				merged[k] = v
+			}
+			fields["inferenceConfig"] = merged
+		case strings.Contains(name, "cohere."):
+			setDefault("k", *cfg.TopK)
+		default:
+			slog.Warn("bedrock: model has no known topK field; dropping topK", "model", modelName)
+		}
+	}
+	if cfg.Llama != nil && isLlama {
+		if err := cfg.Llama.validate(); err != nil {
+			return nil, err
+		}
+		if p := cfg.Llama.RepetitionPenalty; p != nil {
+			setDefault("repetition_penalty", *p)
+		}
+	}
+	if cfg.ThinkingBudget > 0 {
+		if strings.Contains(name, "anthropic.") {
+			setDefault("thinking", map[string]any{"type": "enabled", "budget_tokens": cfg.ThinkingBudget})
+		} else {
+			slog.Warn("bedrock: ThinkingBudget applies to Anthropic Claude models only; dropping it", "model", modelName)
+		}
+	}
+	betas := slices.Clone(cfg.AnthropicBeta)
+	if len(betas) > 0 && !strings.Contains(name, "anthropic.") {
+		return nil, fmt.Errorf("bedrock: AnthropicBeta applies to Anthropic Claude models only, not %q", modelName)
+	}
+	if cfg.Verbosity != "" && strings.Contains(name, effortModel) {
+		if _, ok := fields["output_config"]; !ok {
+			fields["output_config"] = map[string]any{"effort": cfg.Verbosity}
+			if !slices.Contains(betas, effortBeta) {
+				betas = append(betas, effortBeta)
+			}
+		}
+	}
+	if len(betas) > 0 {
+		setDefault("anthropic_beta", betas)
+	}
+	return fields, nil
+}
+
+// Claude Opus 4.5 trades answer thoroughness against length with an effort
+// setting, sent as output_config.effort under a beta; [Config.Verbosity] maps
+// onto it.
+const (
+	effortModel = "anthropic.claude-opus-4-5"
+	effortBeta  = "effort-2025-11-24"
+)
+
+// requestFieldsDocument wraps fields as the additionalModelRequestFields
+// document. Values are first normalized with [documentValue] so integers
+// stay integers and nested structures keep their JSON shape.
+func requestFieldsDocument(fields map[string]any) (document.Interface, error) {
+	normalized, err := documentValue(fields)
+	if err != nil {
+		return nil, fmt.Errorf("bedrock: encode additionalModelRequestFields: %w", err)
+	}
+	return document.NewLazyDocument(normalized), nil
+}
+
+// documentValue converts v into the maps, slices and scalars the smithy
+// document encoder serializes faithfully. The encoder writes json.Number as
+// a string and ignores json struct tags, so json.Number becomes a
+// smithydoc.Number (written verbatim) and any other type, such as a struct
+// or typed map, is round-tripped through encoding/json with UseNumber.
+func documentValue(v any) (any, error) {
+	switch v := v.(type) {
+	case nil, bool, string,
+		int, int8, int16, int32, int64,
+		uint, uint8, uint16, uint32, uint64,
+		float32, float64, smithydoc.Number:
+		return v, nil
+	case json.Number:
+		return smithydoc.Number(v), nil
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, elem := range v {
+			converted, err := documentValue(elem)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", k, err)
+			}
+			out[k] = converted
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(v))
+		for i, elem := range v {
+			converted, err := documentValue(elem)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+			out[i] = converted
+		}
+		return out, nil
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		var decoded any
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&decoded); err != nil {
+			return nil, err
+		}
+		return documentValue(decoded)
+	}
+}
+
 func defaultMaxTokensForModel(modelName string) (int32, bool) {
 	name := strings.ToLower(modelName)
 	if !strings.Contains(name, "claude") {
@@ -572,6 +1462,32 @@ func defaultMaxTokensForModel(modelName string) (int32, bool) {
 	return defaultClaudeMaxTokens, true
 }
 
+// checkMaxTokens validates the requested maxTokens against the model's
+// documented output limit. Bedrock rejects oversized values with an opaque
+// ValidationException, so the plugin fails early with the limit in the
+// message, or clamps to the limit when [Bedrock.ClampMaxTokens] is set.
+// Models without a known limit are not checked.
+func (b *Bedrock) checkMaxTokens(modelName string, ic *types.InferenceConfiguration) error {
+	if ic == nil || ic.MaxTokens == nil {
+		return nil
+	}
+	caps, ok := b.modelCapability(modelName)
+	if !ok || caps.MaxOutputTokens <= 0 {
+		return nil
+	}
+	limit := int32(caps.MaxOutputTokens)
+	requested := *ic.MaxTokens
+	if requested <= limit {
+		return nil
+	}
+	if b == nil || !b.ClampMaxTokens {
+		return fmt.Errorf("bedrock: maxTokens %d exceeds the %d-token output limit of model %q (set Bedrock.ClampMaxTokens to clamp automatically)", requested, limit, modelName)
+	}
+	slog.Debug("bedrock: clamping maxTokens to model limit", "model", modelName, "requested", requested, "limit", limit)
+	ic.MaxTokens = aws.Int32(limit)
+	return nil
+}
+
 // buildInferenceConfig maps a *Config onto Bedrock's InferenceConfiguration.
 func buildInferenceConfig(cfg *Config) *types.InferenceConfiguration {
 	if cfg == nil {
@@ -601,14 +1517,39 @@ func buildInferenceConfig(cfg *Config) *types.InferenceConfiguration {
 	return ic
 }
 
-func (b *Bedrock) convertTools(tools []*ai.ToolDefinition) ([]types.Tool, error) {
+// toolNamePattern is the tool name rule Converse enforces.
+var toolNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// maxToolDescriptionLen caps tool descriptions, in characters, so an
+// over-long one fails naming its tool rather than with a ValidationException.
+const maxToolDescriptionLen = 10240
+
+// checkToolDefinition checks a tool's name and description against the
+// limits Converse enforces.
+func checkToolDefinition(tool *ai.ToolDefinition) error {
+	if tool.Name == "" {
+		return errors.New("bedrock: tool name required")
+	}
+	if !toolNamePattern.MatchString(tool.Name) {
+		return fmt.Errorf("bedrock: tool %q: name must be 1-64 letters, digits, underscores or hyphens", tool.Name)
+	}
+	if n := utf8.RuneCountInString(tool.Description); n > maxToolDescriptionLen {
+		return fmt.Errorf("bedrock: tool %q: description is %d characters, over the limit of %d", tool.Name, n, maxToolDescriptionLen)
+	}
+	return nil
+}
+
+// toolsToConverseConfig translates Genkit tool definitions into the Converse
+// tool configuration, carrying each tool's name, description and JSON Schema.
+// A tool without an input schema gets an empty object schema.
+func toolsToConverseConfig(tools []*ai.ToolDefinition) (*types.ToolConfiguration, error) {
 	out := make([]types.Tool, 0, len(tools))
 	for _, tool := range tools {
 		if tool == nil {
 			return nil, errors.New("bedrock: tool definition required")
 		}
-		if tool.Name == "" {
-			return nil, errors.New("bedrock: tool name required")
+		if err := checkToolDefinition(tool); err != nil {
+			return nil, err
 		}
 
 		schema := tool.InputSchema
@@ -616,7 +1557,7 @@ func (b *Bedrock) convertTools(tools []*ai.ToolDefinition) ([]types.Tool, error)
 			schema = map[string]any{"type": "object", "properties": map[string]any{}}
 		}
 		var inputSchema types.ToolInputSchema
-		if bedrockSchema, err := b.convertJSONSchemaToBedrockSchema(schema); err == nil && bedrockSchema != nil {
+		if bedrockSchema, err := convertJSONSchemaToBedrockSchema(schema); err == nil && bedrockSchema != nil {
 			inputSchema = *bedrockSchema
 		}
 
@@ -628,7 +1569,7 @@ func (b *Bedrock) convertTools(tools []*ai.ToolDefinition) ([]types.Tool, error)
 			},
 		})
 	}
-	return out, nil
+	return &types.ToolConfiguration{Tools: out}, nil
 }
 
 func convertToolChoice(choice string, tools []*ai.ToolDefinition) (types.ToolChoice, error) {
@@ -693,7 +1634,7 @@ func reasoningBlockToPart(block types.ReasoningContentBlock) (*ai.Part, error) {
 }
 
 // convertToolInputTypes converts tool input parameters to the correct types based on the tool schema
-func (b *Bedrock) convertToolInputTypes(inputMap map[string]any, toolName string, tools []*ai.ToolDefinition) any {
+func convertToolInputTypes(inputMap map[string]any, toolName string, tools []*ai.ToolDefinition) any {
 	// Find the tool definition for this tool call
 	var targetTool *ai.ToolDefinition
 	for _, tool := range tools {
@@ -709,11 +1650,11 @@ func (b *Bedrock) convertToolInputTypes(inputMap map[string]any, toolName string
 	}
 
 	// Convert the input map based on the schema
-	return b.convertMapWithSchema(inputMap, targetTool.InputSchema)
+	return convertMapWithSchema(inputMap, targetTool.InputSchema)
 }
 
 // convertMapWithSchema recursively converts a map's values to match the expected schema types
-func (b *Bedrock) convertMapWithSchema(inputMap map[string]any, schema map[string]any) any {
+func convertMapWithSchema(inputMap map[string]any, schema map[string]any) any {
 	if schema == nil {
 		return inputMap
 	}
@@ -726,7 +1667,7 @@ func (b *Bedrock) convertMapWithSchema(inputMap map[string]any, schema map[strin
 			for key, value := range inputMap {
 				if propSchema, exists := properties[key]; exists {
 					if propSchemaMap, ok := propSchema.(map[string]any); ok {
-						result[key] = b.convertValueWithSchema(value, propSchemaMap)
+						result[key] = convertValueWithSchema(value, propSchemaMap)
 					} else {
 						result[key] = value
 					}
@@ -743,7 +1684,7 @@ func (b *Bedrock) convertMapWithSchema(inputMap map[string]any, schema map[strin
 }
 
 // convertValueWithSchema converts a single value to match the expected schema type
-func (b *Bedrock) convertValueWithSchema(value any, schema map[string]any) any {
+func convertValueWithSchema(value any, schema map[string]any) any {
 	if schema == nil {
 		return value
 	}
@@ -840,7 +1781,7 @@ func (b *Bedrock) convertValueWithSchema(value any, schema map[string]any) any {
 			if arrayValue, ok := value.([]any); ok {
 				result := make([]any, len(arrayValue))
 				for i, item := range arrayValue {
-					result[i] = b.convertValueWithSchema(item, items)
+					result[i] = convertValueWithSchema(item, items)
 				}
 				return result
 			}
@@ -850,7 +1791,7 @@ func (b *Bedrock) convertValueWithSchema(value any, schema map[string]any) any {
 	// Handle objects
 	if schemaType == "object" {
 		if mapValue, ok := value.(map[string]any); ok {
-			return b.convertMapWithSchema(mapValue, schema)
+			return convertMapWithSchema(mapValue, schema)
 		}
 	}
 
@@ -859,13 +1800,13 @@ func (b *Bedrock) convertValueWithSchema(value any, schema map[string]any) any {
 }
 
 // convertJSONSchemaToBedrockSchema converts a JSON schema to Bedrock ToolInputSchema format
-func (b *Bedrock) convertJSONSchemaToBedrockSchema(schema any) (*types.ToolInputSchema, error) {
+func convertJSONSchemaToBedrockSchema(schema any) (*types.ToolInputSchema, error) {
 	if schema == nil {
 		return nil, fmt.Errorf("schema is nil")
 	}
 
 	// Convert schema to a map[string]any format
-	schemaMap, err := b.normalizeSchema(schema)
+	schemaMap, err := normalizeSchema(schema)
 	if err != nil {
 		return nil, fmt.Errorf("failed to normalize schema: %w", err)
 	}
@@ -887,25 +1828,25 @@ func (b *Bedrock) convertJSONSchemaToBedrockSchema(schema any) (*types.ToolInput
 }
 
 // normalizeSchema converts various schema formats to a standard map[string]any
-func (b *Bedrock) normalizeSchema(schema any) (map[string]any, error) {
+func normalizeSchema(schema any) (map[string]any, error) {
 	switch s := schema.(type) {
 	case map[string]any:
 		// Already in the correct format - validate it's a proper JSON Schema
-		return b.validateAndNormalizeJSONSchema(s), nil
+		return validateAndNormalizeJSONSchema(s), nil
 	case string:
 		// Try to parse JSON string
 		var schemaMap map[string]any
 		if err := json.Unmarshal([]byte(s), &schemaMap); err != nil {
 			return nil, fmt.Errorf("failed to parse schema JSON: %w", err)
 		}
-		return b.validateAndNormalizeJSONSchema(schemaMap), nil
+		return validateAndNormalizeJSONSchema(schemaMap), nil
 	case []byte:
 		// Try to parse JSON bytes
 		var schemaMap map[string]any
 		if err := json.Unmarshal(s, &schemaMap); err != nil {
 			return nil, fmt.Errorf("failed to parse schema JSON bytes: %w", err)
 		}
-		return b.validateAndNormalizeJSONSchema(schemaMap), nil
+		return validateAndNormalizeJSONSchema(schemaMap), nil
 	default:
 		// Try to marshal and unmarshal to get a map
 		jsonData, err := json.Marshal(schema)
@@ -916,12 +1857,12 @@ func (b *Bedrock) normalizeSchema(schema any) (map[string]any, error) {
 		if err := json.Unmarshal(jsonData, &schemaMap); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal schema: %w", err)
 		}
-		return b.validateAndNormalizeJSONSchema(schemaMap), nil
+		return validateAndNormalizeJSONSchema(schemaMap), nil
 	}
 }
 
 // validateAndNormalizeJSONSchema ensures the schema is a valid JSON Schema and adds required fields
-func (b *Bedrock) validateAndNormalizeJSONSchema(schema map[string]any) map[string]any {
+func validateAndNormalizeJSONSchema(schema map[string]any) map[string]any {
 	// Make a copy to avoid modifying the original
 	normalized := make(map[string]any)
 	for k, v := range schema {
@@ -1018,7 +1959,10 @@ func NewArraySchema(itemSchema map[string]any, description string) map[string]an
 
 // Helper functions
 
-// convertStopReasonToGenkit converts Bedrock stop reason to Genkit finish reason
+// convertStopReasonToGenkit converts Bedrock stop reason to Genkit finish
+// reason. Genkit has no finish reason for a turn that stops to call tools, so
+// tool_use maps to stop, as the Genkit tool loop expects; the Bedrock stop
+// reason is kept in the message metadata (see [StoppedForToolUse]).
 func convertStopReasonToGenkit(stopReason types.StopReason) ai.FinishReason {
 	switch stopReason {
 	case types.StopReasonEndTurn, types.StopReasonStopSequence, types.StopReasonToolUse:
@@ -21,12 +21,15 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/credentials"
@@ -201,6 +204,197 @@ func TestConfigFromRequest_UnsupportedType(t *testing.T) {
 	}
 }
 
+func TestConfigFromRequest_GenerationConfigShapes(t *testing.T) {
+	temp, topP, topK := float32(0.5), float32(0.75), 40
+	want := &GenerationConfig{
+		MaxTokens:      4096,
+		Temperature:    &temp,
+		TopP:           &topP,
+		TopK:           &topK,
+		StopSequences:  []string{"END"},
+		ThinkingBudget: 2048,
+		Guardrail:      &GuardrailConfig{Identifier: "gr-1", Version: "DRAFT"},
+	}
+	shapes := map[string]any{
+		"pointer": want,
+		"value":   *want,
+		"map": map[string]any{
+			"maxTokens":      float64(4096), // JSON-decoded numbers
+			"temperature":    0.5,
+			"topP":           0.75,
+			"topK":           float64(40),
+			"stopSequences":  []any{"END"},
+			"thinkingBudget": float64(2048),
+			"guardrail":      map[string]any{"identifier": "gr-1", "version": "DRAFT"},
+		},
+	}
+	for name, config := range shapes {
+		t.Run(name, func(t *testing.T) {
+			got, err := configFromRequest(&ai.ModelRequest{Config: config})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("config = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	f := func(v float32) *float32 { return &v }
+	i := func(v int) *int { return &v }
+	tests := []struct {
+		name    string
+		cfg     *Config
+		wantErr string
+	}{
+		{"nil", nil, ""},
+		{"zero", &Config{}, ""},
+		{"valid", &Config{MaxTokens: 4096, Temperature: f(1), TopP: f(0), TopK: i(1), ThinkingBudget: 1024}, ""},
+		{"negative maxTokens", &Config{MaxTokens: -1}, "maxTokens -1"},
+		{"temperature above 1", &Config{Temperature: f(2)}, ""}, // AI21 Jamba accepts up to 2
+		{"cohere temperature", &Config{Temperature: f(5)}, ""},
+		{"negative temperature", &Config{Temperature: f(-0.5)}, "temperature -0.5 must not be negative"},
+		{"topP", &Config{TopP: f(-0.5)}, "topP -0.5 out of range"},
+		{"topK", &Config{TopK: i(0)}, "topK 0 must be at least 1"},
+		{"empty stop sequence", &Config{StopSequences: []string{"a", ""}}, "stopSequences[1] is empty"},
+		{"blank anthropic beta", &Config{AnthropicBeta: []string{"token-efficient-tools-2025-02-19", " "}}, "anthropicBeta[1] is blank"},
+		{"thinking below minimum", &Config{ThinkingBudget: 512}, "below the minimum of 1024"},
+		{"thinking over maxTokens", &Config{MaxTokens: 2000, ThinkingBudget: 2000}, "less than maxTokens 2000"},
+		{"response field path", &Config{AdditionalResponseFieldPaths: []string{"stop_sequence"}}, "/"},
+		{"guardrail", &Config{Guardrail: &GuardrailConfig{Identifier: "gr"}}, "guardrail"},
+		{"llama", &Config{Llama: &LlamaConfig{RepetitionPenalty: f(3)}}, "repetitionPenalty 3"},
+		{"choices", &Config{Choices: []string{}}, "at least one choice"},
+		{"region", &Config{Region: "mars-1"}, `invalid region "mars-1"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	_, err := configFromRequest(&ai.ModelRequest{Config: map[string]any{"temperature": -1}})
+	if err == nil || !strings.Contains(err.Error(), "temperature -1") {
+		t.Errorf("configFromRequest error = %v, want validation error", err)
+	}
+}
+
+func TestAdditionalRequestFields_ThinkingBudget(t *testing.T) {
+	cfg := &Config{MaxTokens: 8000, ThinkingBudget: 4000}
+	got, err := additionalRequestFields("us.anthropic.claude-sonnet-4-20250514-v1:0", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"thinking": map[string]any{"type": "enabled", "budget_tokens": 4000}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("fields = %v, want %v", got, want)
+	}
+
+	explicit := map[string]any{"thinking": map[string]any{"type": "disabled"}}
+	got, _ = additionalRequestFields("anthropic.claude-3-7-sonnet-20250219-v1:0", &Config{ThinkingBudget: 4000, AdditionalModelRequestFields: explicit})
+	if !reflect.DeepEqual(got, explicit) {
+		t.Errorf("fields = %v, want the explicit thinking field kept", got)
+	}
+
+	if got, _ := additionalRequestFields("amazon.nova-pro-v1:0", cfg); len(got) != 0 {
+		t.Errorf("Nova fields = %v, want ThinkingBudget dropped", got)
+	}
+}
+
+func TestGenerate_AnthropicBetaInRequest(t *testing.T) {
+	var body struct {
+		AdditionalModelRequestFields map[string]any `json:"additionalModelRequestFields"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"output":{"message":{"role":"assistant","content":[{"text":"ok"}]}},"stopReason":"end_turn"}`))
+	}))
+	defer server.Close()
+
+	betas := []string{"token-efficient-tools-2025-02-19", "interleaved-thinking-2025-05-14"}
+	req := &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("hi")},
+		Config:   map[string]any{"anthropicBeta": []any{betas[0], betas[1]}},
+	}
+	if _, err := newTestBedrock(server).generateText(context.Background(), "us.anthropic.claude-3-7-sonnet-20250219-v1:0", req, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := body.AdditionalModelRequestFields["anthropic_beta"]; !reflect.DeepEqual(got, []any{betas[0], betas[1]}) {
+		t.Errorf("anthropic_beta = %#v, want %q", got, betas)
+	}
+
+	// An explicit anthropic_beta field wins.
+	explicit := map[string]any{"anthropic_beta": []string{"output-128k-2025-02-19"}}
+	got, err := additionalRequestFields("anthropic.claude-3-7-sonnet-20250219-v1:0", &Config{AnthropicBeta: betas, AdditionalModelRequestFields: explicit})
+	if err != nil || !reflect.DeepEqual(got, explicit) {
+		t.Errorf("fields = %v, err = %v; want the explicit field kept", got, err)
+	}
+}
+
+func TestBuildConverseInput_AnthropicBetaRejectedForOtherModels(t *testing.T) {
+	req := &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("hi")},
+		Config:   &Config{AnthropicBeta: []string{"token-efficient-tools-2025-02-19"}},
+	}
+	_, err := (&Bedrock{}).buildConverseInput("amazon.nova-pro-v1:0", req)
+	if err == nil || !strings.Contains(err.Error(), "AnthropicBeta applies to Anthropic Claude models only") {
+		t.Errorf("error = %v, want AnthropicBeta rejected for Nova", err)
+	}
+}
+
+func TestAdditionalRequestFields_Verbosity(t *testing.T) {
+	got, err := additionalRequestFields("global.anthropic.claude-opus-4-5-20251101-v1:0", &Config{Verbosity: "low"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"output_config":  map[string]any{"effort": "low"},
+		"anthropic_beta": []string{"effort-2025-11-24"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("fields = %v, want %v", got, want)
+	}
+
+	// The effort beta joins the request's own betas.
+	got, _ = additionalRequestFields("anthropic.claude-opus-4-5-20251101-v1:0", &Config{Verbosity: "high", AnthropicBeta: []string{"token-efficient-tools-2025-02-19"}})
+	if betas := got["anthropic_beta"]; !reflect.DeepEqual(betas, []string{"token-efficient-tools-2025-02-19", "effort-2025-11-24"}) {
+		t.Errorf("anthropic_beta = %v, want both betas", betas)
+	}
+
+	// An explicit output_config wins.
+	explicit := map[string]any{"output_config": map[string]any{"effort": "medium"}}
+	got, _ = additionalRequestFields("anthropic.claude-opus-4-5-20251101-v1:0", &Config{Verbosity: "low", AdditionalModelRequestFields: explicit})
+	if !reflect.DeepEqual(got, explicit) {
+		t.Errorf("fields = %v, want the explicit output_config kept", got)
+	}
+
+	for _, model := range []string{"amazon.nova-pro-v1:0", "anthropic.claude-sonnet-4-20250514-v1:0"} {
+		if got, err := additionalRequestFields(model, &Config{Verbosity: "low"}); err != nil || len(got) != 0 {
+			t.Errorf("%s: fields = %v, err = %v; want verbosity ignored", model, got, err)
+		}
+	}
+}
+
+func TestConfigValidate_Verbosity(t *testing.T) {
+	if err := (&Config{Verbosity: "medium"}).Validate(); err != nil {
+		t.Errorf("Validate() = %v, want nil", err)
+	}
+	if err := (&Config{Verbosity: "terse"}).Validate(); err == nil || !strings.Contains(err.Error(), "invalid verbosity") {
+		t.Errorf("Validate() = %v, want invalid verbosity", err)
+	}
+}
+
 func TestGenerateText_NilRequestWrapsBuildError(t *testing.T) {
 	_, err := (&Bedrock{}).generateText(context.Background(), "anthropic.claude-3-haiku-20240307-v1:0", nil, nil)
 	if err == nil || !strings.Contains(err.Error(), "failed to build converse input: model request is nil") {
@@ -304,6 +498,42 @@ func TestBuildConverseInput_ClaudePreservesConfiguredMaxTokens(t *testing.T) {
 	}
 }
 
+func TestBuildConverseInput_MaxTokensOverModelLimit(t *testing.T) {
+	req := func() *ai.ModelRequest {
+		return &ai.ModelRequest{
+			Messages: []*ai.Message{{Role: ai.RoleUser, Content: []*ai.Part{ai.NewTextPart("Hello")}}},
+			Config:   &Config{MaxTokens: 50000},
+		}
+	}
+
+	t.Run("clamp off errors", func(t *testing.T) {
+		_, err := (&Bedrock{}).buildConverseInput("us.anthropic.claude-3-5-sonnet-20241022-v2:0", req())
+		if err == nil || !strings.Contains(err.Error(), "exceeds the 8192-token output limit") {
+			t.Fatalf("error = %v, want output limit error", err)
+		}
+	})
+
+	t.Run("clamp on clamps", func(t *testing.T) {
+		out, err := (&Bedrock{ClampMaxTokens: true}).buildConverseInput("us.anthropic.claude-3-5-sonnet-20241022-v2:0", req())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := aws.ToInt32(out.InferenceConfig.MaxTokens); got != 8192 {
+			t.Fatalf("MaxTokens = %d, want clamped 8192", got)
+		}
+	})
+
+	t.Run("unknown model unchecked", func(t *testing.T) {
+		out, err := (&Bedrock{}).buildConverseInput("vendor.new-model-v1:0", req())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := aws.ToInt32(out.InferenceConfig.MaxTokens); got != 50000 {
+			t.Fatalf("MaxTokens = %d, want 50000", got)
+		}
+	})
+}
+
 func TestBuildConverseInput_SystemTextPrompt(t *testing.T) {
 	b := &Bedrock{}
 	out, err := b.buildConverseInput("model-id", &ai.ModelRequest{
@@ -332,6 +562,59 @@ func TestBuildConverseInput_SystemTextPrompt(t *testing.T) {
 	}
 }
 
+func TestBuildConverseInput_FoldsSystemPromptForUnsupportedModels(t *testing.T) {
+	b := &Bedrock{}
+	req := &ai.ModelRequest{
+		Messages: []*ai.Message{
+			{Role: ai.RoleSystem, Content: []*ai.Part{ai.NewTextPart("Be terse."), NewCachePointPart()}},
+			{Role: ai.RoleUser, Content: []*ai.Part{ai.NewTextPart("Hello")}},
+			{Role: ai.RoleModel, Content: []*ai.Part{ai.NewTextPart("Hi")}},
+			{Role: ai.RoleUser, Content: []*ai.Part{ai.NewTextPart("Bye")}},
+		},
+	}
+	out, err := b.buildConverseInput("cohere.command-text-v14", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.System != nil {
+		t.Fatalf("System = %v, want none for a model without system prompts", out.System)
+	}
+	if len(out.Messages) != 3 {
+		t.Fatalf("len(Messages) = %d, want 3", len(out.Messages))
+	}
+	first := out.Messages[0].Content
+	if len(first) != 2 {
+		t.Fatalf("first user message = %#v, want system text then user text", first)
+	}
+	if got := first[0].(*types.ContentBlockMemberText).Value; got != "Be terse.\n\n" {
+		t.Errorf("folded system text = %q", got)
+	}
+	if got := first[1].(*types.ContentBlockMemberText).Value; got != "Hello" {
+		t.Errorf("user text = %q, want Hello", got)
+	}
+	if len(out.Messages[2].Content) != 1 {
+		t.Error("system prompt must only be folded into the first user message")
+	}
+	if len(req.Messages[1].Content) != 1 {
+		t.Error("folding must not modify the request messages")
+	}
+
+	out, err = b.buildConverseInput("us.anthropic.claude-3-5-sonnet-20241022-v2:0", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.System) != 2 || len(out.Messages[0].Content) != 1 {
+		t.Errorf("System = %d blocks, first message = %d blocks; want system kept for Claude", len(out.System), len(out.Messages[0].Content))
+	}
+}
+
+func TestFoldSystemPrompt_NoUserMessage(t *testing.T) {
+	got := foldSystemPrompt([]*ai.Message{ai.NewSystemTextMessage("Be terse.")})
+	if len(got) != 1 || got[0].Role != ai.RoleUser || got[0].Text() != "Be terse." {
+		t.Fatalf("foldSystemPrompt() = %#v, want a single user message with the system text", got)
+	}
+}
+
 func TestBuildConverseInput_CachePointInSystem(t *testing.T) {
 	b := &Bedrock{}
 	out, err := b.buildConverseInput("model-id", &ai.ModelRequest{
@@ -394,6 +677,210 @@ func TestBuildConverseInput_ToolDefinitions(t *testing.T) {
 	}
 }
 
+func TestGenerateText_EmptyToolsSendsNoToolConfig(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"output":{"message":{"role":"assistant","content":[{"text":"hi"}]}},"stopReason":"end_turn"}`)
+	}))
+	defer server.Close()
+	b := newTestBedrock(server)
+
+	_, err := b.generateText(context.Background(), "anthropic.claude-3-haiku-20240307-v1:0", &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("hi")},
+		Tools:    []*ai.ToolDefinition{},
+		Config:   &Config{ToolChoice: ToolChoiceAuto},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tc, ok := body["toolConfig"]; ok {
+		t.Errorf("toolConfig = %v, want none for an empty tools slice", tc)
+	}
+}
+
+func TestToolsToConverseConfig(t *testing.T) {
+	weatherSchema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"city":  map[string]any{"type": "string", "description": "City name"},
+			"units": map[string]any{"type": "string", "enum": []any{"metric", "imperial"}},
+		},
+		"required": []any{"city"},
+	}
+	searchSchema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"filters": map[string]any{
+				"type":  "array",
+				"items": map[string]any{"type": "object", "properties": map[string]any{"field": map[string]any{"type": "string"}}},
+			},
+		},
+	}
+	cfg, err := toolsToConverseConfig([]*ai.ToolDefinition{
+		{Name: "get_weather", Description: "Current weather", InputSchema: weatherSchema},
+		{Name: "search", Description: "Search records", InputSchema: searchSchema},
+		{Name: "now", Description: "Current time"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ToolChoice != nil {
+		t.Errorf("ToolChoice = %v, want unset", cfg.ToolChoice)
+	}
+	want := []struct {
+		name, description string
+		schema            map[string]any
+	}{
+		{"get_weather", "Current weather", weatherSchema},
+		{"search", "Search records", searchSchema},
+		{"now", "Current time", map[string]any{"type": "object", "properties": map[string]any{}}},
+	}
+	if len(cfg.Tools) != len(want) {
+		t.Fatalf("len(Tools) = %d, want %d", len(cfg.Tools), len(want))
+	}
+	for i, w := range want {
+		spec, ok := cfg.Tools[i].(*types.ToolMemberToolSpec)
+		if !ok {
+			t.Fatalf("Tools[%d] = %T, want tool spec", i, cfg.Tools[i])
+		}
+		if aws.ToString(spec.Value.Name) != w.name || aws.ToString(spec.Value.Description) != w.description {
+			t.Errorf("Tools[%d] = %q/%q, want %q/%q", i, aws.ToString(spec.Value.Name), aws.ToString(spec.Value.Description), w.name, w.description)
+		}
+		js, ok := spec.Value.InputSchema.(*types.ToolInputSchemaMemberJson)
+		if !ok {
+			t.Fatalf("Tools[%d].InputSchema = %T, want JSON schema", i, spec.Value.InputSchema)
+		}
+		raw, err := js.Value.MarshalSmithyDocument()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got map[string]any
+		if err := json.Unmarshal(raw, &got); err != nil {
+			t.Fatal(err)
+		}
+		delete(got, "$schema") // Added by schema normalization
+		if !reflect.DeepEqual(got, w.schema) {
+			t.Errorf("Tools[%d] schema = %v, want %v", i, got, w.schema)
+		}
+	}
+}
+
+func TestToolsToConverseConfig_InvalidTools(t *testing.T) {
+	if _, err := toolsToConverseConfig([]*ai.ToolDefinition{nil}); err == nil {
+		t.Error("nil tool: want error")
+	}
+	if _, err := toolsToConverseConfig([]*ai.ToolDefinition{{Description: "anonymous"}}); err == nil || !strings.Contains(err.Error(), "tool name required") {
+		t.Errorf("unnamed tool error = %v, want tool name required", err)
+	}
+	for _, name := range []string{"get weather", "weather.lookup", strings.Repeat("a", 65)} {
+		_, err := toolsToConverseConfig([]*ai.ToolDefinition{{Name: "ok_tool-1"}, {Name: name}})
+		if err == nil || !strings.Contains(err.Error(), fmt.Sprintf("tool %q: name must be", name)) {
+			t.Errorf("tool name %q error = %v, want a name error naming the tool", name, err)
+		}
+	}
+	long := &ai.ToolDefinition{Name: "search", Description: strings.Repeat("é", maxToolDescriptionLen+1)}
+	if _, err := toolsToConverseConfig([]*ai.ToolDefinition{long}); err == nil || !strings.Contains(err.Error(), `tool "search": description is 10241 characters`) {
+		t.Errorf("long description error = %v, want a length error naming the tool", err)
+	}
+	long.Description = strings.Repeat("é", maxToolDescriptionLen)
+	if _, err := toolsToConverseConfig([]*ai.ToolDefinition{long}); err != nil {
+		t.Errorf("description at the limit: %v", err)
+	}
+}
+
+func TestBuildConverseInput_ToolsOnNonToolModel(t *testing.T) {
+	req := &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("weather in Paris?")},
+		Tools:    []*ai.ToolDefinition{{Name: "get_weather", InputSchema: map[string]any{"type": "object"}}},
+		Config:   &Config{ToolChoice: ToolChoiceRequired},
+	}
+
+	_, err := (&Bedrock{}).buildConverseInput("us.amazon.titan-text-express-v1", req)
+	if err == nil {
+		t.Fatal("expected error for tools on a model without tool use")
+	}
+	for _, want := range []string{`"us.amazon.titan-text-express-v1" does not support tool use`, `"amazon.nova-lite-v1:0"`, "StripUnsupportedTools"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error = %q, want it to contain %q", err, want)
+		}
+	}
+
+	out, err := (&Bedrock{StripUnsupportedTools: true}).buildConverseInput("amazon.titan-text-express-v1", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.ToolConfig != nil {
+		t.Errorf("ToolConfig = %+v, want tools stripped", out.ToolConfig)
+	}
+	if len(req.Tools) != 1 {
+		t.Error("stripping must not modify the caller's request")
+	}
+
+	info := (&Bedrock{StripUnsupportedTools: true}).inferModelCapabilities("amazon.titan-text-express-v1", "chat")
+	if !info.Supports.Tools {
+		t.Error("Supports.Tools = false; Genkit would reject tools before the plugin could strip them")
+	}
+	if (&Bedrock{}).inferModelCapabilities("amazon.titan-text-express-v1", "chat").Supports.Tools {
+		t.Error("Supports.Tools = true for a tool-less model without StripUnsupportedTools")
+	}
+}
+
+func TestBuildConverseInput_CacheTools(t *testing.T) {
+	req := func() *ai.ModelRequest {
+		return &ai.ModelRequest{
+			Messages: []*ai.Message{
+				{Role: ai.RoleUser, Content: []*ai.Part{ai.NewTextPart("use a tool")}},
+			},
+			Tools: []*ai.ToolDefinition{
+				{Name: "get_weather", InputSchema: map[string]any{"type": "object"}},
+				{Name: "get_time", InputSchema: map[string]any{"type": "object"}},
+			},
+		}
+	}
+
+	b := &Bedrock{CacheTools: true}
+	out, err := b.buildConverseInput("us.anthropic.claude-sonnet-4-20250514-v1:0", req())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tools := out.ToolConfig.Tools
+	if len(tools) != 3 {
+		t.Fatalf("len(Tools) = %d, want 2 tools + cache point", len(tools))
+	}
+	for i := 0; i < 2; i++ {
+		if _, ok := tools[i].(*types.ToolMemberToolSpec); !ok {
+			t.Errorf("Tools[%d] = %T, want *ToolMemberToolSpec", i, tools[i])
+		}
+	}
+	cp, ok := tools[2].(*types.ToolMemberCachePoint)
+	if !ok {
+		t.Fatalf("Tools[2] = %T, want *ToolMemberCachePoint after the tool specs", tools[2])
+	}
+	if cp.Value.Type != types.CachePointTypeDefault {
+		t.Errorf("cache point type = %q, want default", cp.Value.Type)
+	}
+
+	// Models without tool caching support get the tools unchanged.
+	out, err = b.buildConverseInput("amazon.nova-pro-v1:0", req())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.ToolConfig.Tools) != 2 {
+		t.Errorf("nova len(Tools) = %d, want 2 (no cache point)", len(out.ToolConfig.Tools))
+	}
+
+	// Disabled by default.
+	out, err = (&Bedrock{}).buildConverseInput("us.anthropic.claude-sonnet-4-20250514-v1:0", req())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.ToolConfig.Tools) != 2 {
+		t.Errorf("default len(Tools) = %d, want 2 (no cache point)", len(out.ToolConfig.Tools))
+	}
+}
+
 func TestBuildConverseInput_ToolRequestPart(t *testing.T) {
 	b := &Bedrock{}
 	out, err := b.buildConverseInput("model-id", &ai.ModelRequest{
@@ -478,6 +965,143 @@ func TestBuildConverseInput_ToolResultPart(t *testing.T) {
 	}
 }
 
+func TestGenerateText_ToolResultsOnlyContinuation(t *testing.T) {
+	var body struct {
+		Messages []struct {
+			Role    string           `json:"role"`
+			Content []map[string]any `json:"content"`
+		} `json:"messages"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"output":{"message":{"role":"assistant","content":[{"text":"It is sunny."}]}},"stopReason":"end_turn"}`)
+	}))
+	defer server.Close()
+
+	toolResult := func(ref, output string) *ai.Message {
+		return &ai.Message{Role: ai.RoleTool, Content: []*ai.Part{
+			ai.NewToolResponsePart(&ai.ToolResponse{Name: "get_weather", Ref: ref, Output: output}),
+		}}
+	}
+	tools := []*ai.ToolDefinition{{Name: "get_weather", InputSchema: map[string]any{"type": "object"}}}
+	tests := []struct {
+		name     string
+		messages []*ai.Message
+		wantRefs []string
+	}{
+		{
+			// A stateful agent sends only the results of the tools it ran.
+			name:     "results only",
+			messages: []*ai.Message{toolResult("call-1", "sunny")},
+			wantRefs: []string{"call-1"},
+		},
+		{
+			// Parallel tool results arrive as one message each.
+			name: "after parallel tool calls",
+			messages: []*ai.Message{
+				ai.NewUserTextMessage("weather in Paris and Rome?"),
+				{Role: ai.RoleModel, Content: []*ai.Part{
+					ai.NewToolRequestPart(&ai.ToolRequest{Name: "get_weather", Ref: "call-1", Input: map[string]any{"city": "Paris"}}),
+					ai.NewToolRequestPart(&ai.ToolRequest{Name: "get_weather", Ref: "call-2", Input: map[string]any{"city": "Rome"}}),
+				}},
+				toolResult("call-1", "sunny"),
+				toolResult("call-2", ""),
+			},
+			wantRefs: []string{"call-1", "call-2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := newTestBedrock(server).generateText(context.Background(), "anthropic.claude-3-haiku-20240307-v1:0", &ai.ModelRequest{
+				Messages: tt.messages,
+				Tools:    tools,
+			}, nil)
+			if err != nil {
+				t.Fatalf("generateText error: %v", err)
+			}
+			if resp.Text() != "It is sunny." {
+				t.Errorf("response = %q", resp.Text())
+			}
+			last := body.Messages[len(body.Messages)-1]
+			if last.Role != "user" {
+				t.Fatalf("final turn role = %q, want user", last.Role)
+			}
+			if len(last.Content) != len(tt.wantRefs) {
+				t.Fatalf("final turn = %v, want %d tool results", last.Content, len(tt.wantRefs))
+			}
+			for i, block := range last.Content {
+				result, ok := block["toolResult"].(map[string]any)
+				if !ok || result["toolUseId"] != tt.wantRefs[i] {
+					t.Errorf("final turn block %d = %v, want the %s tool result", i, block, tt.wantRefs[i])
+				}
+			}
+			for i := 1; i < len(body.Messages); i++ {
+				if body.Messages[i].Role == body.Messages[i-1].Role {
+					t.Errorf("messages %d and %d are both %s turns", i-1, i, body.Messages[i].Role)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildConverseInput_ToolErrorResult(t *testing.T) {
+	b := &Bedrock{}
+	flagged := ai.NewToolResponsePart(&ai.ToolResponse{Name: "get_weather", Ref: "call-2", Output: "upstream timeout"})
+	flagged.Metadata = map[string]any{"toolError": true}
+	out, err := b.buildConverseInput("model-id", &ai.ModelRequest{
+		Messages: []*ai.Message{
+			ai.NewUserTextMessage("weather in Paris and Rome?"),
+			{Role: ai.RoleModel, Content: []*ai.Part{
+				ai.NewToolRequestPart(&ai.ToolRequest{Name: "get_weather", Ref: "call-1", Input: map[string]any{"location": "Paris"}}),
+				ai.NewToolRequestPart(&ai.ToolRequest{Name: "get_weather", Ref: "call-2", Input: map[string]any{"location": "Rome"}}),
+			}},
+			{Role: ai.RoleTool, Content: []*ai.Part{
+				NewToolErrorPart("call-1", "get_weather", errors.New("city not found")),
+				flagged,
+			}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	toolTurn := out.Messages[2]
+	wantText := []string{`{"error":"city not found"}`, "upstream timeout"}
+	for i, block := range toolTurn.Content {
+		result := block.(*types.ContentBlockMemberToolResult).Value
+		if result.Status != types.ToolResultStatusError {
+			t.Errorf("result %d status = %q, want error", i, result.Status)
+		}
+		if got := result.Content[0].(*types.ToolResultContentBlockMemberText).Value; got != wantText[i] {
+			t.Errorf("result %d text = %q, want %q", i, got, wantText[i])
+		}
+	}
+}
+
+func TestIsToolError(t *testing.T) {
+	part := NewToolErrorPart("call-1", "lookup", errors.New("boom"))
+	if !IsToolError(part) {
+		t.Error("NewToolErrorPart result is not a tool error")
+	}
+	raw, err := json.Marshal(part)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded ai.Part
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !IsToolError(&decoded) {
+		t.Error("tool error flag lost in JSON round trip")
+	}
+	if IsToolError(ai.NewToolResponsePart(&ai.ToolResponse{Ref: "call-1", Output: "ok"})) {
+		t.Error("plain tool response reported as error")
+	}
+	if IsToolError(ai.NewTextPart("boom")) {
+		t.Error("text part reported as tool error")
+	}
+}
+
 func TestBuildConverseInput_AssistantRemovedWhenToolsPresent(t *testing.T) {
 	// Bedrock rejects conversations that end with an assistant message when tools are configured.
 	// The plugin removes the trailing assistant message automatically.
@@ -562,6 +1186,73 @@ func TestPartsToContentBlocks_ToolResponseJSONOutput(t *testing.T) {
 	}
 }
 
+func TestPartsToContentBlocks_ToolResponseMultipleContent(t *testing.T) {
+	blocks, err := partsToContentBlocks([]*ai.Part{
+		ai.NewToolResponsePart(&ai.ToolResponse{
+			Ref:  "call-1",
+			Name: "get_weather",
+			Content: []*ai.Part{
+				ai.NewJSONPart(`{"temp":21,"unit":"c"}`),
+				ai.NewTextPart("Temperatures are forecasts and may change."),
+			},
+		}),
+	})
+	if err != nil {
+		t.Fatalf("partsToContentBlocks() error = %v", err)
+	}
+	result := blocks[0].(*types.ContentBlockMemberToolResult).Value
+	if len(result.Content) != 2 {
+		t.Fatalf("tool result content len = %d, want 2 (no entry for a nil Output)", len(result.Content))
+	}
+	doc, ok := result.Content[0].(*types.ToolResultContentBlockMemberJson)
+	if !ok {
+		t.Fatalf("content[0] = %T, want json", result.Content[0])
+	}
+	raw, err := doc.Value.MarshalSmithyDocument()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(raw, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["temp"] != float64(21) || got["unit"] != "c" {
+		t.Errorf("json content = %v, want temp 21 and unit c", got)
+	}
+	text, ok := result.Content[1].(*types.ToolResultContentBlockMemberText)
+	if !ok || text.Value != "Temperatures are forecasts and may change." {
+		t.Errorf("content[1] = %#v, want the explanatory text", result.Content[1])
+	}
+
+	blocks, err = partsToContentBlocks([]*ai.Part{
+		ai.NewToolResponsePart(&ai.ToolResponse{
+			Ref:     "call-2",
+			Output:  "see chart",
+			Content: []*ai.Part{ai.NewMediaPart("image/png", "data:image/png;base64,iVBORw0KGgo=")},
+		}),
+	})
+	if err != nil {
+		t.Fatalf("partsToContentBlocks() error = %v", err)
+	}
+	result = blocks[0].(*types.ContentBlockMemberToolResult).Value
+	if len(result.Content) != 2 {
+		t.Fatalf("tool result content len = %d, want 2", len(result.Content))
+	}
+	if text, ok := result.Content[0].(*types.ToolResultContentBlockMemberText); !ok || text.Value != "see chart" {
+		t.Errorf("content[0] = %#v, want the Output text", result.Content[0])
+	}
+	if _, ok := result.Content[1].(*types.ToolResultContentBlockMemberImage); !ok {
+		t.Errorf("content[1] = %T, want image", result.Content[1])
+	}
+
+	_, err = partsToContentBlocks([]*ai.Part{
+		ai.NewToolResponsePart(&ai.ToolResponse{Ref: "call-3", Name: "lookup", Content: []*ai.Part{ai.NewJSONPart("{not json")}}),
+	})
+	if err == nil || !strings.Contains(err.Error(), "invalid JSON content") {
+		t.Errorf("invalid JSON content: error = %v", err)
+	}
+}
+
 func TestToolResponseText_UnmarshalableOutputErrors(t *testing.T) {
 	_, err := toolResponseText(func() {})
 	if err == nil || !strings.Contains(err.Error(), "marshal tool response") {
@@ -624,6 +1315,190 @@ func TestMediaToBlock_ImageAndDocument(t *testing.T) {
 	}
 }
 
+func TestBuildConverseInput_MultipleImagesInOneTurn(t *testing.T) {
+	b := &Bedrock{}
+	image := func(mime string) *ai.Part {
+		return ai.NewMediaPart(mime, "data:"+mime+";base64,"+base64.StdEncoding.EncodeToString([]byte(mime)))
+	}
+	out, err := b.buildConverseInput("anthropic.claude-3-5-sonnet-20241022-v2:0", &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserMessage(
+			ai.NewTextPart("Before:"), image("image/png"),
+			ai.NewTextPart("After:"), image("image/jpeg"),
+			ai.NewTextPart("Diff:"), image("image/webp"),
+			ai.NewTextPart("What changed?"),
+		)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Messages) != 1 {
+		t.Fatalf("len(Messages) = %d, want 1", len(out.Messages))
+	}
+	content := out.Messages[0].Content
+	wantFormats := []types.ImageFormat{types.ImageFormatPng, types.ImageFormatJpeg, types.ImageFormatWebp}
+	if len(content) != 7 {
+		t.Fatalf("len(Content) = %d, want 7 interleaved blocks", len(content))
+	}
+	for i, want := range wantFormats {
+		text, ok := content[2*i].(*types.ContentBlockMemberText)
+		if !ok {
+			t.Fatalf("Content[%d] = %T, want text", 2*i, content[2*i])
+		}
+		img, ok := content[2*i+1].(*types.ContentBlockMemberImage)
+		if !ok || img.Value.Format != want {
+			t.Fatalf("Content[%d] = %#v, want %s image after %q", 2*i+1, content[2*i+1], want, text.Value)
+		}
+	}
+}
+
+func TestBuildConverseInput_ImageLimit(t *testing.T) {
+	b := &Bedrock{}
+	parts := []*ai.Part{ai.NewTextPart("Describe these.")}
+	for range 21 {
+		parts = append(parts, ai.NewMediaPart("image/png", "data:image/png;base64,aW1n"))
+	}
+	req := &ai.ModelRequest{Messages: []*ai.Message{ai.NewUserMessage(parts...)}}
+
+	_, err := b.buildConverseInput("us.anthropic.claude-3-5-sonnet-20241022-v2:0", req)
+	if err == nil || !strings.Contains(err.Error(), "21 images") || !strings.Contains(err.Error(), "at most 20") {
+		t.Fatalf("error = %v, want image limit error", err)
+	}
+
+	req.Messages[0].Content = parts[:21]
+	if _, err := b.buildConverseInput("us.anthropic.claude-3-5-sonnet-20241022-v2:0", req); err != nil {
+		t.Fatalf("20 images: unexpected error %v", err)
+	}
+}
+
+func TestBuildConverseInput_StopSequenceLimit(t *testing.T) {
+	stops := func(n int) []string {
+		out := make([]string, n)
+		for i := range out {
+			out[i] = fmt.Sprintf("STOP%d", i)
+		}
+		return out
+	}
+	tests := []struct {
+		model   string
+		stops   int
+		wantErr string
+	}{
+		{model: "cohere.command-r-plus-v1:0", stops: 4},
+		{model: "cohere.command-r-plus-v1:0", stops: 5, wantErr: "5 stop sequences but model \"cohere.command-r-plus-v1:0\" accepts at most 4"},
+		{model: "mistral.mistral-large-2407-v1:0", stops: 10},
+		{model: "mistral.mistral-large-2407-v1:0", stops: 11, wantErr: "at most 10"},
+		{model: "us.anthropic.claude-sonnet-4-20250514-v1:0", stops: 8191},
+		{model: "us.anthropic.claude-sonnet-4-20250514-v1:0", stops: 8192, wantErr: "at most 8191"},
+		// No known limit: left to Bedrock.
+		{model: "amazon.nova-pro-v1:0", stops: 50},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s/%d", tt.model, tt.stops), func(t *testing.T) {
+			req := &ai.ModelRequest{
+				Messages: []*ai.Message{ai.NewUserTextMessage("Hi")},
+				Config:   &Config{StopSequences: stops(tt.stops)},
+			}
+			input, err := (&Bedrock{}).buildConverseInput(tt.model, req)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := len(input.InferenceConfig.StopSequences); got != tt.stops {
+				t.Errorf("stop sequences = %d, want %d", got, tt.stops)
+			}
+		})
+	}
+}
+
+func TestBuildConverseInput_MediaTypeValidation(t *testing.T) {
+	pdf := ai.NewMediaPart("application/pdf", "data:application/pdf;base64,JVBERi0=")
+	video := ai.NewMediaPart("video/mp4", "data:video/mp4;base64,AAAAIGZ0eXA=")
+	image := ai.NewMediaPart("image/png", "data:image/png;base64,aW1n")
+	tests := []struct {
+		name    string
+		model   string
+		part    *ai.Part
+		wantErr string
+	}{
+		{"pdf to a model without documents", "twelvelabs.pegasus-1-2-v1:0", pdf, `model "twelvelabs.pegasus-1-2-v1:0" does not accept document input (application/pdf)`},
+		{"video to a model without video", "us.anthropic.claude-sonnet-4-20250514-v1:0", video, `model "us.anthropic.claude-sonnet-4-20250514-v1:0" does not accept video input (video/mp4)`},
+		{"image to a text model", "amazon.nova-micro-v1:0", image, `does not accept image input (image/png)`},
+		{"pdf to Claude", "anthropic.claude-3-haiku-20240307-v1:0", pdf, ""},
+		{"pdf to a text model with documents", "amazon.nova-micro-v1:0", pdf, ""},
+		{"video to Nova", "us.amazon.nova-lite-v1:0", video, ""},
+		{"unknown model", "acme.future-model-v1:0", video, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &ai.ModelRequest{Messages: []*ai.Message{ai.NewUserMessage(ai.NewTextPart("Summarize."), tt.part)}}
+			input, err := (&Bedrock{}).buildConverseInput(tt.model, req)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(input.Messages[0].Content) != 2 {
+				t.Fatalf("content = %#v", input.Messages[0].Content)
+			}
+		})
+	}
+}
+
+func TestMediaToBlock_Video(t *testing.T) {
+	block, err := mediaToBlock(ai.NewMediaPart("video/quicktime", "data:video/quicktime;base64,AAAAFGZ0eXBxdCAg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, ok := block.(*types.ContentBlockMemberVideo)
+	if !ok {
+		t.Fatalf("block = %T, want *ContentBlockMemberVideo", block)
+	}
+	if v.Value.Format != types.VideoFormatMov {
+		t.Errorf("format = %q, want mov", v.Value.Format)
+	}
+	if src, ok := v.Value.Source.(*types.VideoSourceMemberBytes); !ok || len(src.Value) == 0 {
+		t.Errorf("source = %#v, want inline bytes", v.Value.Source)
+	}
+}
+
+func TestMediaToBlock_ImageFormats(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString([]byte("image bytes"))
+	tests := []struct {
+		mime string
+		want types.ImageFormat
+	}{
+		{mime: "image/png", want: types.ImageFormatPng},
+		{mime: "image/jpeg", want: types.ImageFormatJpeg},
+		{mime: "image/gif", want: types.ImageFormatGif},
+		{mime: "image/webp", want: types.ImageFormatWebp},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mime, func(t *testing.T) {
+			// Content type comes from the data URI alone.
+			block, err := mediaToBlock(&ai.Part{Kind: ai.PartMedia, Text: "data:" + tt.mime + ";base64," + encoded})
+			if err != nil {
+				t.Fatal(err)
+			}
+			image, ok := block.(*types.ContentBlockMemberImage)
+			if !ok {
+				t.Fatalf("block = %T, want *ContentBlockMemberImage", block)
+			}
+			if image.Value.Format != tt.want {
+				t.Errorf("image format = %q, want %q", image.Value.Format, tt.want)
+			}
+		})
+	}
+}
+
 func TestMediaToBlock_StrictValidation(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -650,10 +1525,20 @@ func TestMediaToBlock_StrictValidation(t *testing.T) {
 			part:        ai.NewMediaPart("application/zip", base64.StdEncoding.EncodeToString([]byte("bytes"))),
 			wantMessage: "unsupported media MIME type",
 		},
+		{
+			name:        "unsupported image format",
+			part:        ai.NewMediaPart("image/bmp", "data:image/bmp;base64,"+base64.StdEncoding.EncodeToString([]byte("bytes"))),
+			wantMessage: `unsupported image format "image/bmp"`,
+		},
 		{
 			name:        "malformed data URL",
-			part:        ai.NewMediaPart("image/png", "data:image/png,not-base64"),
-			wantMessage: "data URL must be base64-encoded",
+			part:        ai.NewMediaPart("image/png", "data:image/png;base64"),
+			wantMessage: "missing ',' between header and data",
+		},
+		{
+			name:        "data URL with invalid base64",
+			part:        ai.NewMediaPart("image/png", "data:image/png;base64,***"),
+			wantMessage: "invalid base64 data",
 		},
 	}
 	for _, tt := range tests {
@@ -854,6 +1739,48 @@ func TestConvertResponse_TokenUsage(t *testing.T) {
 	}
 }
 
+func TestGenerateText_CacheHitMetadata(t *testing.T) {
+	tests := []struct {
+		name  string
+		usage string
+		want  any // nil when the key should be absent
+	}{
+		{"cache read", `,"usage":{"inputTokens":10,"outputTokens":5,"totalTokens":1215,"cacheReadInputTokens":1200}`, true},
+		{"cache write only", `,"usage":{"inputTokens":10,"outputTokens":5,"totalTokens":1215,"cacheWriteInputTokens":1200}`, false},
+		{"no cache", `,"usage":{"inputTokens":10,"outputTokens":5,"totalTokens":15}`, false},
+		{"no usage", ``, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = fmt.Fprintf(w, `{"output":{"message":{"role":"assistant","content":[{"text":"hi"}]}},"stopReason":"end_turn"%s}`, tt.usage)
+			}))
+			defer server.Close()
+
+			resp, err := newTestBedrock(server).generateText(context.Background(), "anthropic.claude-3-5-haiku-20241022-v1:0", &ai.ModelRequest{
+				Messages: []*ai.Message{ai.NewUserTextMessage("hi")},
+			}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, ok := resp.Message.Metadata[cacheHitMetadataKey]
+			if tt.want == nil {
+				if ok {
+					t.Errorf("cacheHit = %v, want absent", got)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("cacheHit = %v, want %v", got, tt.want)
+			}
+			if CacheHit(resp) != tt.want {
+				t.Errorf("CacheHit() = %v, want %v", CacheHit(resp), tt.want)
+			}
+		})
+	}
+}
+
 func TestConvertResponse_SetsOriginalRequest(t *testing.T) {
 	b := &Bedrock{}
 	req := &ai.ModelRequest{}
@@ -940,14 +1867,294 @@ func TestConvertResponse_StopReasonMappedToFinishReason(t *testing.T) {
 		{types.StopReasonMalformedModelOutput, ai.FinishReasonOther},
 		{types.StopReasonMalformedToolUse, ai.FinishReasonOther},
 	}
-	for _, tt := range tests {
-		got, err := b.convertResponse(makeResp(tt.sr), &ai.ModelRequest{})
-		if err != nil {
-			t.Fatal(err)
-		}
-		if got.FinishReason != tt.want {
-			t.Errorf("stop=%q: FinishReason=%q, want %q", tt.sr, got.FinishReason, tt.want)
-		}
+	for _, tt := range tests {
+		got, err := b.convertResponse(makeResp(tt.sr), &ai.ModelRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.FinishReason != tt.want {
+			t.Errorf("stop=%q: FinishReason=%q, want %q", tt.sr, got.FinishReason, tt.want)
+		}
+	}
+}
+
+func TestConvertResponse_UnknownStopReasonPreserved(t *testing.T) {
+	resp, err := (&Bedrock{}).convertResponse(&bedrockruntime.ConverseOutput{
+		Output: &types.ConverseOutputMemberMessage{
+			Value: types.Message{Content: []types.ContentBlock{&types.ContentBlockMemberText{Value: "x"}}},
+		},
+		StopReason: "quota_paused",
+	}, &ai.ModelRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.FinishReason != ai.FinishReasonOther {
+		t.Errorf("FinishReason = %q, want other", resp.FinishReason)
+	}
+	if got, ok := RawStopReason(resp); !ok || got != "quota_paused" {
+		t.Errorf("RawStopReason() = %q, %v; want quota_paused", got, ok)
+	}
+	if got := resp.Message.Metadata["rawStopReason"]; got != "quota_paused" {
+		t.Errorf("metadata rawStopReason = %v, want quota_paused", got)
+	}
+
+	known, err := (&Bedrock{}).convertResponse(&bedrockruntime.ConverseOutput{
+		Output: &types.ConverseOutputMemberMessage{
+			Value: types.Message{Content: []types.ContentBlock{&types.ContentBlockMemberText{Value: "x"}}},
+		},
+		StopReason: types.StopReasonMalformedModelOutput,
+	}, &ai.ModelRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := RawStopReason(known); ok {
+		t.Errorf("RawStopReason() = %q for a known stop reason, want none", got)
+	}
+}
+
+func TestConvertResponse_ServerLatency(t *testing.T) {
+	resp, err := (&Bedrock{}).convertResponse(&bedrockruntime.ConverseOutput{
+		Output: &types.ConverseOutputMemberMessage{
+			Value: types.Message{Content: []types.ContentBlock{&types.ContentBlockMemberText{Value: "x"}}},
+		},
+		StopReason: types.StopReasonEndTurn,
+		Metrics:    &types.ConverseMetrics{LatencyMs: aws.Int64(412)},
+	}, &ai.ModelRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	latency, ok := ServerLatency(resp)
+	if !ok || latency != 412*time.Millisecond {
+		t.Errorf("ServerLatency() = %v, %v; want 412ms", latency, ok)
+	}
+	if resp.LatencyMs != 0 {
+		t.Errorf("LatencyMs = %v, want it left for Genkit's client-side measurement", resp.LatencyMs)
+	}
+
+	// JSON round-trips turn the metadata value into a float64.
+	resp.Message.Metadata[serverLatencyMetadataKey] = float64(412)
+	if latency, ok := ServerLatency(resp); !ok || latency != 412*time.Millisecond {
+		t.Errorf("ServerLatency() after round-trip = %v, %v; want 412ms", latency, ok)
+	}
+	if _, ok := ServerLatency(&ai.ModelResponse{Message: &ai.Message{}}); ok {
+		t.Error("ServerLatency() ok = true for a response without metrics")
+	}
+}
+
+func TestGenerateTextSync_AdditionalModelResponseFields(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{
+			"output": {"message":{"role":"assistant","content":[{"text":"ok"}]}},
+			"stopReason": "end_turn",
+			"additionalModelResponseFields": {"reasoningConfig":{"effort":"low"},"score":0.5}
+		}`)
+	}))
+	defer server.Close()
+
+	resp, err := newTestBedrock(server).generateText(context.Background(), "amazon.nova-lite-v1:0", &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("hi")},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := AdditionalResponseFields(resp)
+	want := map[string]any{"reasoningConfig": map[string]any{"effort": "low"}, "score": 0.5}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("AdditionalResponseFields() = %#v, want %#v", fields, want)
+	}
+	if AdditionalResponseFields(&ai.ModelResponse{Message: &ai.Message{}}) != nil {
+		t.Error("AdditionalResponseFields() non-nil for a response without fields")
+	}
+}
+
+func TestGenerateText_AdditionalResponseFieldPaths(t *testing.T) {
+	var gotBody map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&gotBody); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{
+			"output": {"message":{"role":"assistant","content":[{"text":"done"}]}},
+			"stopReason": "stop_sequence",
+			"additionalModelResponseFields": {"stop_sequence":"END"}
+		}`)
+	}))
+	defer server.Close()
+
+	resp, err := newTestBedrock(server).generateText(context.Background(), "anthropic.claude-3-haiku-20240307-v1:0", &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("hi")},
+		Config:   &Config{StopSequences: []string{"END"}, AdditionalResponseFieldPaths: []string{"/stop_sequence"}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []any{"/stop_sequence"}; !reflect.DeepEqual(gotBody["additionalModelResponseFieldPaths"], want) {
+		t.Errorf("additionalModelResponseFieldPaths = %#v, want %#v", gotBody["additionalModelResponseFieldPaths"], want)
+	}
+	if got := AdditionalResponseFields(resp); !reflect.DeepEqual(got, map[string]any{"stop_sequence": "END"}) {
+		t.Errorf("AdditionalResponseFields() = %#v", got)
+	}
+}
+
+func TestBuildConverseInput_AdditionalResponseFieldPathsValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		paths []string
+		want  string
+	}{
+		{"not a pointer", []string{"stop_sequence"}, "must be a JSON Pointer"},
+		{"too long", []string{"/" + strings.Repeat("a", 256)}, "at most 256 characters"},
+		{"too many", make([]string, 11), "exceed the limit of 10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &ai.ModelRequest{
+				Messages: []*ai.Message{ai.NewUserTextMessage("hi")},
+				Config:   &Config{AdditionalResponseFieldPaths: tt.paths},
+			}
+			if _, err := (&Bedrock{}).buildConverseInput("amazon.nova-lite-v1:0", req); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	req := &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("hi")},
+		Config:   &Config{AdditionalResponseFieldPaths: []string{"/stop_sequence"}},
+	}
+	input, err := (&Bedrock{}).buildConverseInput("amazon.nova-lite-v1:0", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := converseStreamInput(input, req).AdditionalModelResponseFieldPaths; !reflect.DeepEqual(got, []string{"/stop_sequence"}) {
+		t.Errorf("stream input paths = %v", got)
+	}
+}
+
+func TestGenerateText_MaxToolRounds(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{
+			"output": {"message":{"role":"assistant","content":[{"toolUse":{"toolUseId":"t%d","name":"search","input":{}}}]}},
+			"stopReason": "tool_use"
+		}`, calls)
+	}))
+	defer server.Close()
+	b := newTestBedrock(server)
+	b.MaxToolRounds = 3
+
+	// Play the agent loop: answer every tool request and call the model again.
+	req := &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("research this")},
+		Tools:    []*ai.ToolDefinition{{Name: "search", InputSchema: map[string]any{"type": "object"}}},
+	}
+	var err error
+	for i := 0; i < 10; i++ {
+		var resp *ai.ModelResponse
+		resp, err = b.generateText(context.Background(), "amazon.nova-lite-v1:0", req, nil)
+		if err != nil {
+			break
+		}
+		tr := resp.ToolRequests()[0].ToolRequest
+		req.Messages = append(req.Messages, resp.Message, &ai.Message{
+			Role:    ai.RoleTool,
+			Content: []*ai.Part{ai.NewToolResponsePart(&ai.ToolResponse{Name: tr.Name, Ref: tr.Ref, Output: "more"})},
+		})
+	}
+	var limitErr *ToolRoundLimitError
+	if !errors.As(err, &limitErr) || limitErr.Limit != 3 {
+		t.Fatalf("error = %v, want *ToolRoundLimitError with limit 3", err)
+	}
+	if calls != 3 {
+		t.Errorf("model calls = %d, want 3", calls)
+	}
+
+	// A new user message starts a new count.
+	req.Messages = append(req.Messages, ai.NewUserTextMessage("try again"))
+	if _, err := b.generateText(context.Background(), "amazon.nova-lite-v1:0", req, nil); err != nil {
+		t.Errorf("after a user message: %v", err)
+	}
+}
+
+func TestGenerateText_MaxTokensMarksTruncated(t *testing.T) {
+	stop := "max_tokens"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"output":{"message":{"role":"assistant","content":[{"text":"Once upon"}]}},"stopReason":%q}`, stop)
+	}))
+	defer server.Close()
+	b := newTestBedrock(server)
+	req := &ai.ModelRequest{Messages: []*ai.Message{ai.NewUserTextMessage("Tell a story")}}
+
+	resp, err := b.generateText(context.Background(), "amazon.nova-lite-v1:0", req, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.FinishReason != ai.FinishReasonLength || !Truncated(resp) || resp.Message.Metadata["truncated"] != true {
+		t.Errorf("finish = %q, metadata = %v; want length and truncated=true", resp.FinishReason, resp.Message.Metadata)
+	}
+
+	stop = "end_turn"
+	resp, err = b.generateText(context.Background(), "amazon.nova-lite-v1:0", req, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.FinishReason != ai.FinishReasonStop || Truncated(resp) {
+		t.Errorf("finish = %q, truncated = %v; want stop and not truncated", resp.FinishReason, Truncated(resp))
+	}
+}
+
+func TestGenerateText_RoutingMetadata(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"output":{"message":{"role":"assistant","content":[{"text":"ok"}]}},"stopReason":"end_turn"}`)
+	}))
+	defer server.Close()
+	cfg := aws.Config{
+		Region:       "us-east-1",
+		Credentials:  credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+		HTTPClient:   server.Client(),
+		BaseEndpoint: aws.String(server.URL),
+	}
+	b := &Bedrock{client: bedrockruntime.NewFromConfig(cfg), awsConfig: cfg, initted: true, IncludeRoutingMetadata: true}
+
+	tests := []struct {
+		name, model, region  string
+		wantRegion, wantProf string
+	}{
+		{"plain model ID", "amazon.nova-lite-v1:0", "", "us-east-1", ""},
+		{"cross-region profile", "eu.amazon.nova-lite-v1:0", "eu-west-1", "eu-west-1", "eu.amazon.nova-lite-v1:0"},
+		{"application profile ARN", "arn:aws:bedrock:us-east-1:123456789012:application-inference-profile/abc123", "", "us-east-1", "arn:aws:bedrock:us-east-1:123456789012:application-inference-profile/abc123"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := b.generateText(context.Background(), tt.model, &ai.ModelRequest{
+				Messages: []*ai.Message{ai.NewUserTextMessage("hi")},
+				Config:   &Config{Region: tt.region},
+			}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			region, profile, ok := ServedBy(resp)
+			if !ok || region != tt.wantRegion || profile != tt.wantProf {
+				t.Errorf("ServedBy() = %q, %q, %v; want %q, %q", region, profile, ok, tt.wantRegion, tt.wantProf)
+			}
+		})
+	}
+
+	b.IncludeRoutingMetadata = false
+	resp, err := b.generateText(context.Background(), "us.amazon.nova-lite-v1:0", &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("hi")},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := ServedBy(resp); ok {
+		t.Errorf("metadata = %v, want no routing metadata by default", resp.Message.Metadata)
 	}
 }
 
@@ -1096,9 +2303,8 @@ func TestNewArraySchema(t *testing.T) {
 // ---- normalizeSchema --------------------------------------------------------
 
 func TestNormalizeSchema_StringInput(t *testing.T) {
-	b := &Bedrock{}
 	input := `{"type":"object","properties":{"x":{"type":"integer"}}}`
-	result, err := b.normalizeSchema(input)
+	result, err := normalizeSchema(input)
 	if err != nil {
 		t.Fatalf("normalizeSchema string: %v", err)
 	}
@@ -1108,9 +2314,8 @@ func TestNormalizeSchema_StringInput(t *testing.T) {
 }
 
 func TestNormalizeSchema_BytesInput(t *testing.T) {
-	b := &Bedrock{}
 	input := []byte(`{"type":"object"}`)
-	result, err := b.normalizeSchema(input)
+	result, err := normalizeSchema(input)
 	if err != nil {
 		t.Fatalf("normalizeSchema bytes: %v", err)
 	}
@@ -1120,8 +2325,7 @@ func TestNormalizeSchema_BytesInput(t *testing.T) {
 }
 
 func TestNormalizeSchema_InvalidJSON(t *testing.T) {
-	b := &Bedrock{}
-	_, err := b.normalizeSchema("not json")
+	_, err := normalizeSchema("not json")
 	if err == nil {
 		t.Fatal("expected error for invalid JSON string")
 	}
@@ -1133,8 +2337,7 @@ func TestNormalizeSchema_StructInput(t *testing.T) {
 		Properties map[string]any `json:"properties,omitempty"`
 	}
 
-	b := &Bedrock{}
-	result, err := b.normalizeSchema(schemaStruct{
+	result, err := normalizeSchema(schemaStruct{
 		Type:       "object",
 		Properties: map[string]any{"city": map[string]any{"type": "string"}},
 	})
@@ -1158,8 +2361,7 @@ func TestNormalizeSchema_UnmarshalableInput(t *testing.T) {
 		Bad func() `json:"bad"`
 	}
 
-	b := &Bedrock{}
-	_, err := b.normalizeSchema(badSchema{Bad: func() {}})
+	_, err := normalizeSchema(badSchema{Bad: func() {}})
 	if err == nil || !strings.Contains(err.Error(), "failed to marshal schema") {
 		t.Fatalf("normalizeSchema unmarshalable input error = %v, want marshal error", err)
 	}
@@ -1168,7 +2370,6 @@ func TestNormalizeSchema_UnmarshalableInput(t *testing.T) {
 // ---- convertValueWithSchema -------------------------------------------------
 
 func TestConvertValueWithSchema_NumericConversions(t *testing.T) {
-	b := &Bedrock{}
 
 	tests := []struct {
 		name     string
@@ -1189,7 +2390,7 @@ func TestConvertValueWithSchema_NumericConversions(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := b.convertValueWithSchema(tt.value, tt.schema)
+			got := convertValueWithSchema(tt.value, tt.schema)
 			switch tt.wantType {
 			case "float64":
 				if _, ok := got.(float64); !ok {
@@ -1209,21 +2410,19 @@ func TestConvertValueWithSchema_NumericConversions(t *testing.T) {
 }
 
 func TestConvertValueWithSchema_JSONNumberToString(t *testing.T) {
-	b := &Bedrock{}
-	got := b.convertValueWithSchema(json.Number("123"), map[string]any{"type": "string"})
+	got := convertValueWithSchema(json.Number("123"), map[string]any{"type": "string"})
 	if got != "123" {
 		t.Fatalf("got %#v (%T), want string 123", got, got)
 	}
 }
 
 func TestConvertValueWithSchema_ArrayConversion(t *testing.T) {
-	b := &Bedrock{}
 	arr := []any{int(1), int(2), int(3)}
 	schema := map[string]any{
 		"type":  "array",
 		"items": map[string]any{"type": "number"},
 	}
-	got := b.convertValueWithSchema(arr, schema)
+	got := convertValueWithSchema(arr, schema)
 	result, ok := got.([]any)
 	if !ok {
 		t.Fatalf("got type %T, want []any", got)
@@ -1236,9 +2435,8 @@ func TestConvertValueWithSchema_ArrayConversion(t *testing.T) {
 }
 
 func TestConvertValueWithSchema_NilSchema(t *testing.T) {
-	b := &Bedrock{}
 	// Nil schema — value should be returned unchanged.
-	got := b.convertValueWithSchema("hello", nil)
+	got := convertValueWithSchema("hello", nil)
 	if got != "hello" {
 		t.Errorf("got %v, want hello", got)
 	}
@@ -1247,7 +2445,6 @@ func TestConvertValueWithSchema_NilSchema(t *testing.T) {
 // ---- convertMapWithSchema ---------------------------------------------------
 
 func TestConvertMapWithSchema_ObjectSchema(t *testing.T) {
-	b := &Bedrock{}
 	inputMap := map[string]any{
 		"count": int(3),
 		"label": "hello",
@@ -1259,7 +2456,7 @@ func TestConvertMapWithSchema_ObjectSchema(t *testing.T) {
 			"label": map[string]any{"type": "string"},
 		},
 	}
-	got := b.convertMapWithSchema(inputMap, schema)
+	got := convertMapWithSchema(inputMap, schema)
 	result, ok := got.(map[string]any)
 	if !ok {
 		t.Fatalf("got type %T, want map[string]any", got)
@@ -1273,9 +2470,8 @@ func TestConvertMapWithSchema_ObjectSchema(t *testing.T) {
 }
 
 func TestConvertMapWithSchema_NilSchema(t *testing.T) {
-	b := &Bedrock{}
 	inputMap := map[string]any{"x": int(1)}
-	got := b.convertMapWithSchema(inputMap, nil)
+	got := convertMapWithSchema(inputMap, nil)
 	result, ok := got.(map[string]any)
 	if !ok {
 		t.Fatalf("got type %T, want map[string]any", got)
@@ -1288,10 +2484,9 @@ func TestConvertMapWithSchema_NilSchema(t *testing.T) {
 // ---- convertToolInputTypes --------------------------------------------------
 
 func TestConvertToolInputTypes_NoMatchingTool(t *testing.T) {
-	b := &Bedrock{}
 	inputMap := map[string]any{"n": int(5)}
 	tools := []*ai.ToolDefinition{{Name: "other_tool", InputSchema: map[string]any{"type": "object"}}}
-	got := b.convertToolInputTypes(inputMap, "missing_tool", tools)
+	got := convertToolInputTypes(inputMap, "missing_tool", tools)
 	result, ok := got.(map[string]any)
 	if !ok {
 		t.Fatalf("got type %T, want map[string]any", got)
@@ -1302,7 +2497,6 @@ func TestConvertToolInputTypes_NoMatchingTool(t *testing.T) {
 }
 
 func TestConvertToolInputTypes_ConvertsMatchingTool(t *testing.T) {
-	b := &Bedrock{}
 	inputMap := map[string]any{"price": int(42)}
 	schema := map[string]any{
 		"type": "object",
@@ -1311,7 +2505,7 @@ func TestConvertToolInputTypes_ConvertsMatchingTool(t *testing.T) {
 		},
 	}
 	tools := []*ai.ToolDefinition{{Name: "get_price", InputSchema: schema}}
-	got := b.convertToolInputTypes(inputMap, "get_price", tools)
+	got := convertToolInputTypes(inputMap, "get_price", tools)
 	result, ok := got.(map[string]any)
 	if !ok {
 		t.Fatalf("got type %T, want map[string]any", got)
@@ -1323,6 +2517,85 @@ func TestConvertToolInputTypes_ConvertsMatchingTool(t *testing.T) {
 
 // ---- generateTextSync (integration via mock HTTP server) --------------------
 
+func TestGenerateText_RegionOverrideUsesRegionalClient(t *testing.T) {
+	var regions []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The SigV4 credential scope names the region the client signed for.
+		auth := r.Header.Get("Authorization")
+		for _, region := range []string{"us-east-1", "us-west-2"} {
+			if strings.Contains(auth, "/"+region+"/bedrock/aws4_request") {
+				regions = append(regions, region)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"output":{"message":{"role":"assistant","content":[{"text":"ok"}]}},"stopReason":"end_turn"}`)
+	}))
+	defer server.Close()
+
+	cfg := aws.Config{
+		Region:       "us-east-1",
+		Credentials:  credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+		HTTPClient:   server.Client(),
+		BaseEndpoint: aws.String(server.URL),
+	}
+	b := &Bedrock{client: bedrockruntime.NewFromConfig(cfg), awsConfig: cfg, initted: true}
+
+	call := func(region string) {
+		t.Helper()
+		_, err := b.generateText(context.Background(), "amazon.nova-lite-v1:0", &ai.ModelRequest{
+			Messages: []*ai.Message{ai.NewUserTextMessage("hi")},
+			Config:   &Config{Region: region},
+		}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	call("")
+	call("us-west-2")
+	call("us-west-2")
+	call("us-east-1")
+
+	want := []string{"us-east-1", "us-west-2", "us-west-2", "us-east-1"}
+	if strings.Join(regions, ",") != strings.Join(want, ",") {
+		t.Errorf("signed regions = %v, want %v", regions, want)
+	}
+	if len(b.regionClients) != 1 || b.regionClients["us-west-2"] == nil {
+		t.Errorf("regionClients = %v, want one cached us-west-2 client", b.regionClients)
+	}
+	if client, _ := b.clientForRegion("us-west-2"); client != b.regionClients["us-west-2"] {
+		t.Error("regional client was rebuilt instead of reused")
+	}
+}
+
+func TestClientForRegion_RejectsInvalidRegions(t *testing.T) {
+	b := &Bedrock{awsConfig: aws.Config{Region: "us-east-1"}, initted: true}
+	for _, region := range []string{"not a region", "us-east-1.evil.com", "US-EAST-1", "us-east-1/../x", "eu-west"} {
+		if _, err := b.clientForRegion(region); err == nil || !strings.Contains(err.Error(), "invalid region") {
+			t.Errorf("clientForRegion(%q) error = %v, want invalid region", region, err)
+		}
+	}
+	for _, region := range []string{"us-gov-west-1", "ap-southeast-2", "cn-north-1"} {
+		if _, err := b.clientForRegion(region); err != nil {
+			t.Errorf("clientForRegion(%q) error = %v", region, err)
+		}
+	}
+	if len(b.regionClients) != 3 {
+		t.Errorf("cached clients = %d, want 3", len(b.regionClients))
+	}
+
+	for i := len(b.regionClients); i < maxRegionClients; i++ {
+		if _, err := b.clientForRegion(fmt.Sprintf("zz-test-%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := b.clientForRegion("zz-extra-1"); err == nil {
+		t.Error("expected an error once the regional client cache is full")
+	}
+	if len(b.regionClients) != maxRegionClients {
+		t.Errorf("cached clients = %d, want %d", len(b.regionClients), maxRegionClients)
+	}
+}
+
 // TestGenerateTextSync_BasicRoundTrip exercises the full sync generation path
 // through a mock Bedrock Converse endpoint. It verifies that the plugin builds
 // a correct request body, that InferenceConfig is forwarded, and that the text
@@ -1528,3 +2801,458 @@ func TestBuildConverseInput_ToolSchemaConversionFailureFallsBack(t *testing.T) {
 		t.Errorf("InputSchema = %T, want nil fallback after conversion failure", spec.Value.InputSchema)
 	}
 }
+
+func TestConvertResponse_AudioContent(t *testing.T) {
+	b := &Bedrock{}
+	audio := []byte("RIFF....WAVEfmt ")
+	resp := &bedrockruntime.ConverseOutput{
+		Output: &types.ConverseOutputMemberMessage{
+			Value: types.Message{Content: []types.ContentBlock{
+				&types.ContentBlockMemberText{Value: "Here you go"},
+				&types.ContentBlockMemberAudio{Value: types.AudioBlock{
+					Format: types.AudioFormatWav,
+					Source: &types.AudioSourceMemberBytes{Value: audio},
+				}},
+				&types.ContentBlockMemberAudio{Value: types.AudioBlock{
+					Format: types.AudioFormatMp3,
+					Source: &types.AudioSourceMemberS3Location{Value: types.S3Location{Uri: aws.String("s3://bucket/reply.mp3")}},
+				}},
+			}},
+		},
+		StopReason: types.StopReasonEndTurn,
+	}
+
+	got, err := b.convertResponse(resp, &ai.ModelRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Message.Content) != 3 {
+		t.Fatalf("len(Content) = %d, want 3", len(got.Message.Content))
+	}
+	inline := got.Message.Content[1]
+	if !inline.IsMedia() || inline.ContentType != "audio/wav" {
+		t.Fatalf("inline part = %#v, want audio/wav media", inline)
+	}
+	if want := "data:audio/wav;base64," + base64.StdEncoding.EncodeToString(audio); inline.Text != want {
+		t.Errorf("inline URL = %q, want %q", inline.Text, want)
+	}
+	s3 := got.Message.Content[2]
+	if !s3.IsMedia() || s3.ContentType != "audio/mpeg" || s3.Text != "s3://bucket/reply.mp3" {
+		t.Errorf("s3 part = %#v, want audio/mpeg s3 media", s3)
+	}
+}
+
+func TestConvertResponse_AudioErrorBlock(t *testing.T) {
+	b := &Bedrock{}
+	resp := &bedrockruntime.ConverseOutput{
+		Output: &types.ConverseOutputMemberMessage{
+			Value: types.Message{Content: []types.ContentBlock{
+				&types.ContentBlockMemberAudio{Value: types.AudioBlock{
+					Format: types.AudioFormatWav,
+					Error:  &types.ErrorBlock{Message: aws.String("decode failed")},
+				}},
+			}},
+		},
+		StopReason: types.StopReasonEndTurn,
+	}
+	if _, err := b.convertResponse(resp, &ai.ModelRequest{}); err == nil || !strings.Contains(err.Error(), "decode failed") {
+		t.Fatalf("err = %v, want audio block error", err)
+	}
+}
+
+// TestGenerateTextSync_OmitsUnsetInferenceFields checks that only explicitly
+// configured inference fields reach the wire.
+func TestGenerateTextSync_OmitsUnsetInferenceFields(t *testing.T) {
+	var gotBody map[string]json.RawMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotBody = nil
+		if err := json.Unmarshal(body, &gotBody); err != nil {
+			t.Errorf("unmarshal request body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"output":{"message":{"role":"assistant","content":[{"text":"ok"}]}},"stopReason":"end_turn"}`)
+	}))
+	defer server.Close()
+	b := newTestBedrock(server)
+
+	generate := func(cfg any) {
+		t.Helper()
+		if _, err := b.generateText(context.Background(), "amazon.nova-lite-v1:0", &ai.ModelRequest{
+			Messages: []*ai.Message{ai.NewUserTextMessage("hi")},
+			Config:   cfg,
+		}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	generate(nil)
+	if _, ok := gotBody["inferenceConfig"]; ok {
+		t.Errorf("inferenceConfig = %s, want absent without config", gotBody["inferenceConfig"])
+	}
+	if _, ok := gotBody["additionalModelRequestFields"]; ok {
+		t.Errorf("additionalModelRequestFields = %s, want absent without config", gotBody["additionalModelRequestFields"])
+	}
+
+	generate(&Config{Temperature: aws.Float32(0.2)})
+	var ic map[string]any
+	if err := json.Unmarshal(gotBody["inferenceConfig"], &ic); err != nil {
+		t.Fatal(err)
+	}
+	if len(ic) != 1 || ic["temperature"] == nil {
+		t.Errorf("inferenceConfig = %v, want only temperature", ic)
+	}
+	if _, ok := gotBody["additionalModelRequestFields"]; ok {
+		t.Error("additionalModelRequestFields sent without topK")
+	}
+
+	generate(&ai.GenerationCommonConfig{TopK: 40})
+	if _, ok := gotBody["inferenceConfig"]; ok {
+		t.Errorf("inferenceConfig = %s, want absent when only topK is set", gotBody["inferenceConfig"])
+	}
+	if got := string(gotBody["additionalModelRequestFields"]); got != `{"inferenceConfig":{"topK":40}}` {
+		t.Errorf("additionalModelRequestFields = %s, want Nova topK", got)
+	}
+}
+
+func TestAdditionalRequestFields_TopK(t *testing.T) {
+	k := 25
+	tests := []struct {
+		model string
+		extra map[string]any
+		want  map[string]any
+	}{
+		{"anthropic.claude-3-haiku-20240307-v1:0", nil, map[string]any{"top_k": 25}},
+		{"us.anthropic.claude-3-5-sonnet-20241022-v2:0", map[string]any{"top_k": 5}, map[string]any{"top_k": 5}},
+		{"mistral.mistral-large-2402-v1:0", nil, map[string]any{"top_k": 25}},
+		{"amazon.nova-pro-v1:0", map[string]any{"inferenceConfig": map[string]any{"topK": 3}}, map[string]any{"inferenceConfig": map[string]any{"topK": 3}}},
+		{"cohere.command-r-v1:0", nil, map[string]any{"k": 25}},
+		{"meta.llama3-8b-instruct-v1:0", map[string]any{"foo": "bar"}, map[string]any{"foo": "bar"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			cfg := &Config{TopK: &k, AdditionalModelRequestFields: tt.extra}
+			if got, err := additionalRequestFields(tt.model, cfg); err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("additionalRequestFields() = %v, %v; want %v", got, err, tt.want)
+			}
+		})
+	}
+	if got, _ := additionalRequestFields("anthropic.claude-3-haiku-20240307-v1:0", &Config{}); got != nil {
+		t.Errorf("additionalRequestFields() without TopK = %v, want nil", got)
+	}
+}
+
+func TestAdditionalRequestFields_LlamaRepetitionPenalty(t *testing.T) {
+	cfg := &Config{Llama: &LlamaConfig{RepetitionPenalty: aws.Float32(1.2)}}
+
+	got, err := additionalRequestFields("us.meta.llama3-1-70b-instruct-v1:0", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]any{"repetition_penalty": float32(1.2)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Llama fields = %v, want %v", got, want)
+	}
+
+	got, err = additionalRequestFields("anthropic.claude-3-haiku-20240307-v1:0", cfg)
+	if err != nil || got != nil {
+		t.Errorf("Claude fields = %v, %v; want Llama options ignored", got, err)
+	}
+
+	for _, p := range []float32{0, -1, 2.5} {
+		bad := &Config{Llama: &LlamaConfig{RepetitionPenalty: aws.Float32(p)}}
+		if _, err := additionalRequestFields("meta.llama3-8b-instruct-v1:0", bad); err == nil || !strings.Contains(err.Error(), "repetitionPenalty") {
+			t.Errorf("penalty %v: error = %v, want range error", p, err)
+		}
+	}
+}
+
+func TestGenerate_KeepsEveryToolRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"output":{"message":{"role":"assistant","content":[
+			{"text":"Checking both cities."},
+			{"toolUse":{"toolUseId":"a","name":"get_weather","input":{"city":"Paris"}}},
+			{"toolUse":{"toolUseId":"b","name":"get_weather","input":{"city":"Rome"}}}
+		]}},"stopReason":"tool_use"}`))
+	}))
+	defer server.Close()
+
+	// Llama does not report ParallelTools, but the model's tool requests are
+	// returned as sent.
+	req := &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("Weather in Paris and Rome?")},
+		Tools:    []*ai.ToolDefinition{{Name: "get_weather"}},
+	}
+	resp, err := newTestBedrock(server).generateText(context.Background(), "meta.llama3-1-70b-instruct-v1:0", req, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reqs := resp.ToolRequests()
+	if len(reqs) != 2 || reqs[0].ToolRequest.Ref != "a" || reqs[1].ToolRequest.Ref != "b" {
+		t.Errorf("tool requests = %+v, want both in order", reqs)
+	}
+}
+
+func TestBuildConverseInput_AdditionalFieldsPreserveTypes(t *testing.T) {
+	type sampling struct {
+		Seed   int     `json:"seed"`
+		MinP   float64 `json:"min_p,omitempty"`
+		Ignore string  `json:"-"`
+	}
+	want := `{"big":9007199254740993,"count":3,"nested":{"list":[1,2.5,{"deep":true}],"ratio":0.5},"number":12345678901234567890,"sampling":{"seed":7}}`
+
+	configs := map[string]any{
+		"typed": &Config{AdditionalModelRequestFields: map[string]any{
+			"count":    3,
+			"big":      int64(9007199254740993),
+			"number":   json.Number("12345678901234567890"),
+			"nested":   map[string]any{"ratio": 0.5, "list": []any{1, 2.5, map[string]any{"deep": true}}},
+			"sampling": sampling{Seed: 7, Ignore: "x"},
+		}},
+		"map": map[string]any{"additionalModelRequestFields": map[string]any{
+			"count":    3,
+			"big":      json.Number("9007199254740993"),
+			"number":   json.Number("12345678901234567890"),
+			"nested":   map[string]any{"ratio": 0.5, "list": []any{1, 2.5, map[string]any{"deep": true}}},
+			"sampling": map[string]any{"seed": 7},
+		}},
+	}
+	for name, cfg := range configs {
+		t.Run(name, func(t *testing.T) {
+			req := &ai.ModelRequest{Messages: []*ai.Message{ai.NewUserTextMessage("hi")}, Config: cfg}
+			out, err := (&Bedrock{}).buildConverseInput("anthropic.claude-3-haiku-20240307-v1:0", req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			raw, err := out.AdditionalModelRequestFields.MarshalSmithyDocument()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got, wantValue any
+			if err := json.Unmarshal(raw, &got); err != nil {
+				t.Fatalf("decode %s: %v", raw, err)
+			}
+			_ = json.Unmarshal([]byte(want), &wantValue)
+			if !reflect.DeepEqual(got, wantValue) {
+				t.Errorf("additionalModelRequestFields = %s, want %s", raw, want)
+			}
+			for _, literal := range []string{`"count":3`, `9007199254740993`, `12345678901234567890`, `"seed":7`} {
+				if !strings.Contains(string(raw), literal) {
+					t.Errorf("additionalModelRequestFields = %s, want literal %s", raw, literal)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildConverseInput_LlamaOptions(t *testing.T) {
+	b := &Bedrock{}
+	req := &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("hi")},
+		Config:   map[string]any{"llama": map[string]any{"repetitionPenalty": 1.1}},
+	}
+	out, err := b.buildConverseInput("meta.llama3-8b-instruct-v1:0", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := out.AdditionalModelRequestFields.MarshalSmithyDocument()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(raw), `"repetition_penalty":1.1`) {
+		t.Errorf("additionalModelRequestFields = %s, want repetition_penalty", raw)
+	}
+
+	out, err = b.buildConverseInput("amazon.nova-lite-v1:0", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.AdditionalModelRequestFields != nil {
+		t.Error("Llama options must not reach non-Llama models")
+	}
+}
+
+func TestConvertResponse_ToolUseBlockMetadata(t *testing.T) {
+	b := &Bedrock{}
+	resp := &bedrockruntime.ConverseOutput{
+		Output: &types.ConverseOutputMemberMessage{
+			Value: types.Message{Content: []types.ContentBlock{
+				&types.ContentBlockMemberText{Value: "Checking both cities."},
+				&types.ContentBlockMemberToolUse{Value: types.ToolUseBlock{
+					ToolUseId: aws.String("tooluse_paris"),
+					Name:      aws.String("get_weather"),
+					Input:     document.NewLazyDocument(map[string]any{"city": "Paris"}),
+				}},
+				&types.ContentBlockMemberToolUse{Value: types.ToolUseBlock{
+					ToolUseId: aws.String("tooluse_rome"),
+					Name:      aws.String("get_weather"),
+					Input:     document.NewLazyDocument(map[string]any{"city": "Rome"}),
+				}},
+			}},
+		},
+		StopReason: types.StopReasonToolUse,
+	}
+
+	got, err := b.convertResponse(resp, &ai.ModelRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := ToolUseBlock(got.Message.Content[0]); ok {
+		t.Error("text part must not report a toolUse block")
+	}
+	want := []struct {
+		index int
+		id    string
+	}{{1, "tooluse_paris"}, {2, "tooluse_rome"}}
+	for i, w := range want {
+		part := got.Message.Content[i+1]
+		index, id, ok := ToolUseBlock(part)
+		if !ok || index != w.index || id != w.id {
+			t.Errorf("ToolUseBlock(part %d) = %d, %q, %v; want %d, %q", i+1, index, id, ok, w.index, w.id)
+		}
+		if id != part.ToolRequest.Ref {
+			t.Errorf("toolUseId %q != ToolRequest.Ref %q", id, part.ToolRequest.Ref)
+		}
+	}
+
+	// Metadata must survive serialization, e.g. a persisted agent transcript.
+	raw, err := json.Marshal(got.Message.Content[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded ai.Part
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if index, id, ok := ToolUseBlock(&decoded); !ok || index != 2 || id != "tooluse_rome" {
+		t.Errorf("after JSON round trip ToolUseBlock = %d, %q, %v", index, id, ok)
+	}
+}
+
+func TestBuildConverseInput_RequiresConversationTurn(t *testing.T) {
+	tests := []struct {
+		name     string
+		messages []*ai.Message
+	}{
+		{"empty", nil},
+		{"system only", []*ai.Message{ai.NewSystemTextMessage("You are terse.")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &ai.ModelRequest{Messages: tt.messages}
+			_, err := (&Bedrock{}).buildConverseInput("amazon.nova-lite-v1:0", req)
+			if err == nil || !strings.Contains(err.Error(), "request must contain at least one user message") {
+				t.Errorf("error = %v, want the missing user message error", err)
+			}
+
+			_, _, err = (&Bedrock{}).prepareInvokeRequest("cohere.command-text-v14", req)
+			if err == nil || !strings.Contains(err.Error(), "at least one user message") {
+				t.Errorf("invoke error = %v, want the missing user message error", err)
+			}
+
+			out, err := (&Bedrock{EmptyPromptUserTurn: "Begin."}).buildConverseInput("amazon.nova-lite-v1:0", req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(out.Messages) != 1 || out.Messages[0].Role != types.ConversationRoleUser {
+				t.Fatalf("messages = %+v, want one synthesized user turn", out.Messages)
+			}
+			if text, ok := out.Messages[0].Content[0].(*types.ContentBlockMemberText); !ok || text.Value != "Begin." {
+				t.Errorf("content = %#v, want the text \"Begin.\"", out.Messages[0].Content[0])
+			}
+			if len(req.Messages) != len(tt.messages) {
+				t.Error("the caller's request was modified")
+			}
+		})
+	}
+}
+
+func TestBuildConverseInput_NoSystemPrompt(t *testing.T) {
+	req := &ai.ModelRequest{
+		Messages: []*ai.Message{
+			ai.NewSystemTextMessage("You are terse."),
+			ai.NewUserTextMessage("Hi"),
+		},
+		Config: &Config{NoSystemPrompt: true},
+	}
+	out, err := (&Bedrock{}).buildConverseInput("amazon.nova-lite-v1:0", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.System) != 0 {
+		t.Errorf("system = %+v, want none", out.System)
+	}
+	if len(out.Messages) != 1 || len(out.Messages[0].Content) != 1 {
+		t.Fatalf("messages = %+v, want only the user turn", out.Messages)
+	}
+	if text := out.Messages[0].Content[0].(*types.ContentBlockMemberText).Value; text != "Hi" {
+		t.Errorf("user text = %q, want no folded system prompt", text)
+	}
+	if len(req.Messages) != 2 {
+		t.Error("the caller's request was modified")
+	}
+
+	invokeReq, _, err := (&Bedrock{}).prepareInvokeRequest("cohere.command-text-v14", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(invokeReq.Messages) != 1 || invokeReq.Messages[0].Role != ai.RoleUser {
+		t.Errorf("invoke messages = %+v, want only the user turn", invokeReq.Messages)
+	}
+
+	req.Messages = req.Messages[:1]
+	if _, err := (&Bedrock{}).buildConverseInput("amazon.nova-lite-v1:0", req); err == nil || !strings.Contains(err.Error(), "at least one user message") {
+		t.Errorf("system-only request: error = %v, want the missing user message error", err)
+	}
+}
+
+func TestGenerate_ToolUseStopDistinguishable(t *testing.T) {
+	tests := []struct {
+		stopReason string
+		content    string
+		wantTool   bool
+	}{
+		{"tool_use", `{"toolUse":{"toolUseId":"t1","name":"get_weather","input":{"city":"Paris"}}}`, true},
+		{"end_turn", `{"text":"It is sunny."}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.stopReason, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = fmt.Fprintf(w, `{"output":{"message":{"role":"assistant","content":[%s]}},"stopReason":%q}`, tt.content, tt.stopReason)
+			}))
+			defer server.Close()
+
+			req := &ai.ModelRequest{
+				Messages: []*ai.Message{ai.NewUserTextMessage("Weather in Paris?")},
+				Tools:    []*ai.ToolDefinition{{Name: "get_weather"}},
+			}
+			resp, err := newTestBedrock(server).generateText(context.Background(), "anthropic.claude-3-haiku-20240307-v1:0", req, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.FinishReason != ai.FinishReasonStop {
+				t.Errorf("FinishReason = %q, want stop", resp.FinishReason)
+			}
+			if StopReason(resp) != tt.stopReason || resp.FinishMessage != "" {
+				t.Errorf("StopReason = %q, FinishMessage = %q; want %q and no finish message", StopReason(resp), resp.FinishMessage, tt.stopReason)
+			}
+
+			// The stop reason survives a JSON round trip of the response.
+			raw, err := json.Marshal(resp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var decoded ai.ModelResponse
+			if err := json.Unmarshal(raw, &decoded); err != nil {
+				t.Fatal(err)
+			}
+			if got := StoppedForToolUse(&decoded); got != tt.wantTool {
+				t.Errorf("StoppedForToolUse = %v, want %v", got, tt.wantTool)
+			}
+		})
+	}
+	if StoppedForToolUse(nil) {
+		t.Error("StoppedForToolUse(nil) = true")
+	}
+}
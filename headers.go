@@ -0,0 +1,66 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// headerNamePattern matches a valid HTTP header name (an RFC 9110 token).
+var headerNamePattern = regexp.MustCompile("^[!#$%&'*+\\-.^_`|~0-9A-Za-z]+$")
+
+// reservedHeaders are set by the SDK or the request signer and cannot be
+// overridden with RequestHeaders; neither can X-Amz-* headers.
+var reservedHeaders = []string{"Authorization", "Content-Length", "Content-Type", "Host"}
+
+// checkRequestHeaders validates [Bedrock.RequestHeaders].
+func checkRequestHeaders(headers map[string]string) error {
+	for name, value := range headers {
+		if !headerNamePattern.MatchString(name) {
+			return fmt.Errorf("bedrock: invalid RequestHeaders name %q", name)
+		}
+		canonical := http.CanonicalHeaderKey(name)
+		for _, reserved := range reservedHeaders {
+			if canonical == reserved {
+				return fmt.Errorf("bedrock: RequestHeaders cannot set %s", reserved)
+			}
+		}
+		if strings.HasPrefix(canonical, "X-Amz-") {
+			return fmt.Errorf("bedrock: RequestHeaders cannot set %s; X-Amz-* headers are reserved for AWS", name)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("bedrock: RequestHeaders[%q] contains a line break", name)
+		}
+	}
+	return nil
+}
+
+// addRequestHeaders is a Bedrock Runtime client option that sends
+// RequestHeaders with every request. They are added before signing, so
+// they are covered by the signature.
+func (b *Bedrock) addRequestHeaders(o *bedrockruntime.Options) {
+	for name, value := range b.RequestHeaders {
+		o.APIOptions = append(o.APIOptions, smithyhttp.SetHeaderValue(name, value))
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+)
+
+// capturingTransport records outgoing requests and answers each with an
+// empty JSON object.
+type capturingTransport struct {
+	mu   sync.Mutex
+	reqs []*http.Request
+}
+
+func (c *capturingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.mu.Lock()
+	c.reqs = append(c.reqs, req)
+	c.mu.Unlock()
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(`{}`)),
+		Request:    req,
+	}, nil
+}
+
+func TestRequestHeaders_SentOnEveryRequest(t *testing.T) {
+	transport := &capturingTransport{}
+	b := testInitializedBedrock()
+	b.AWSConfig.HTTPClient = &http.Client{Transport: transport}
+	b.RequestHeaders = map[string]string{"X-Gateway-Key": "secret", "x-tenant": "acme"}
+	b.Init(context.Background())
+
+	ctx := context.Background()
+	_, _ = b.client.Converse(ctx, &bedrockruntime.ConverseInput{
+		ModelId:  aws.String("amazon.nova-lite-v1:0"),
+		Messages: []types.Message{{Role: types.ConversationRoleUser, Content: []types.ContentBlock{&types.ContentBlockMemberText{Value: "hi"}}}},
+	})
+	regional, err := b.clientForRegion("eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = regional.Converse(ctx, &bedrockruntime.ConverseInput{
+		ModelId:  aws.String("amazon.nova-lite-v1:0"),
+		Messages: []types.Message{{Role: types.ConversationRoleUser, Content: []types.ContentBlock{&types.ContentBlockMemberText{Value: "hi"}}}},
+	})
+	_, _ = b.batch.GetModelInvocationJob(ctx, "arn:aws:bedrock:us-east-1:123456789012:model-invocation-job/abc")
+
+	if len(transport.reqs) != 3 {
+		t.Fatalf("captured %d requests, want 3", len(transport.reqs))
+	}
+	for _, req := range transport.reqs {
+		if req.Header.Get("X-Gateway-Key") != "secret" || req.Header.Get("X-Tenant") != "acme" {
+			t.Errorf("%s %s headers = %v, want the RequestHeaders", req.Method, req.URL.Host, req.Header)
+		}
+		if signed := req.Header.Get("Authorization"); !strings.Contains(strings.ToLower(signed), "x-gateway-key") {
+			t.Errorf("%s %s: Authorization = %q, want X-Gateway-Key signed", req.Method, req.URL.Host, signed)
+		}
+	}
+}
+
+func TestInitPanicsOnInvalidRequestHeaders(t *testing.T) {
+	for header, want := range map[string]string{
+		"Bad Header":    `bedrock: invalid RequestHeaders name "Bad Header"`,
+		"authorization": "bedrock: RequestHeaders cannot set Authorization",
+		"X-Amz-Date":    "bedrock: RequestHeaders cannot set X-Amz-Date; X-Amz-* headers are reserved for AWS",
+	} {
+		t.Run(header, func(t *testing.T) {
+			b := testInitializedBedrock()
+			b.RequestHeaders = map[string]string{header: "v"}
+			assertPanicsWith(t, want, func() { b.Init(context.Background()) })
+		})
+	}
+	b := testInitializedBedrock()
+	b.RequestHeaders = map[string]string{"X-Trace": "a\r\nInjected: 1"}
+	assertPanicsWith(t, `bedrock: RequestHeaders["X-Trace"] contains a line break`, func() { b.Init(context.Background()) })
+}
@@ -23,8 +23,6 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
 	"github.com/firebase/genkit/go/ai"
 )
 
@@ -57,6 +55,9 @@ func (b *Bedrock) generateImage(ctx context.Context, modelName string, input *ai
 	if err != nil {
 		return nil, err
 	}
+	if b.ImageOutputS3 != nil && supportsS3ImageOutput(modelName) {
+		return b.imageS3Response(ctx, input, images)
+	}
 	return imageResponse(input, images)
 }
 
@@ -107,6 +108,7 @@ func imageResponse(input *ai.ModelRequest, images []string) (*ai.ModelResponse,
 			Content: parts,
 		},
 		FinishReason: ai.FinishReasonStop,
+		Usage:        &ai.GenerationUsage{OutputImages: len(parts)},
 	}, nil
 }
 
@@ -146,20 +148,9 @@ func (b *Bedrock) generateTitanImage(ctx context.Context, modelName, prompt stri
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	// Call InvokeModel
-	input := &bedrockruntime.InvokeModelInput{
-		ModelId:     aws.String(modelName),
-		Body:        body,
-		ContentType: aws.String("application/json"),
-		Accept:      aws.String("application/json"),
-	}
-
-	ctx, cancel := b.withRequestTimeout(ctx)
-	defer cancel()
-
-	response, err := b.client.InvokeModel(ctx, input)
+	responseBody, err := b.invokeImageModel(ctx, modelName, body, cb)
 	if err != nil {
-		return nil, fmt.Errorf("failed to invoke model: %w", err)
+		return nil, err
 	}
 
 	// Parse response
@@ -167,7 +158,7 @@ func (b *Bedrock) generateTitanImage(ctx context.Context, modelName, prompt stri
 		Images []string `json:"images"`
 	}
 
-	if err := json.Unmarshal(response.Body, &result); err != nil {
+	if err := json.Unmarshal(responseBody, &result); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
@@ -210,20 +201,9 @@ func (b *Bedrock) generateStableDiffusionImage(ctx context.Context, modelName, p
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	// Call InvokeModel
-	input := &bedrockruntime.InvokeModelInput{
-		ModelId:     aws.String(modelName),
-		Body:        body,
-		ContentType: aws.String("application/json"),
-		Accept:      aws.String("application/json"),
-	}
-
-	ctx, cancel := b.withRequestTimeout(ctx)
-	defer cancel()
-
-	response, err := b.client.InvokeModel(ctx, input)
+	responseBody, err := b.invokeImageModel(ctx, modelName, body, cb)
 	if err != nil {
-		return nil, fmt.Errorf("failed to invoke model: %w", err)
+		return nil, err
 	}
 
 	// Parse response
@@ -234,7 +214,7 @@ func (b *Bedrock) generateStableDiffusionImage(ctx context.Context, modelName, p
 		} `json:"artifacts"`
 	}
 
-	if err := json.Unmarshal(response.Body, &result); err != nil {
+	if err := json.Unmarshal(responseBody, &result); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
@@ -271,19 +251,9 @@ func (b *Bedrock) generateModernStabilityImage(ctx context.Context, modelName, p
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	input := &bedrockruntime.InvokeModelInput{
-		ModelId:     aws.String(modelName),
-		Body:        body,
-		ContentType: aws.String("application/json"),
-		Accept:      aws.String("application/json"),
-	}
-
-	ctx, cancel := b.withRequestTimeout(ctx)
-	defer cancel()
-
-	response, err := b.client.InvokeModel(ctx, input)
+	responseBody, err := b.invokeImageModel(ctx, modelName, body, cb)
 	if err != nil {
-		return nil, fmt.Errorf("failed to invoke model: %w", err)
+		return nil, err
 	}
 
 	var result struct {
@@ -291,7 +261,7 @@ func (b *Bedrock) generateModernStabilityImage(ctx context.Context, modelName, p
 		FinishReasons []*string `json:"finish_reasons"`
 	}
 
-	if err := json.Unmarshal(response.Body, &result); err != nil {
+	if err := json.Unmarshal(responseBody, &result); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
@@ -342,20 +312,9 @@ func (b *Bedrock) generateNovaCanvasImage(ctx context.Context, modelName, prompt
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	// Call InvokeModel
-	input := &bedrockruntime.InvokeModelInput{
-		ModelId:     aws.String(modelName),
-		Body:        body,
-		ContentType: aws.String("application/json"),
-		Accept:      aws.String("application/json"),
-	}
-
-	ctx, cancel := b.withRequestTimeout(ctx)
-	defer cancel()
-
-	response, err := b.client.InvokeModel(ctx, input)
+	responseBody, err := b.invokeImageModel(ctx, modelName, body, cb)
 	if err != nil {
-		return nil, fmt.Errorf("failed to invoke model: %w", err)
+		return nil, err
 	}
 
 	// Parse response (Nova Canvas uses similar format to Titan)
@@ -363,7 +322,7 @@ func (b *Bedrock) generateNovaCanvasImage(ctx context.Context, modelName, prompt
 		Images []string `json:"images"`
 	}
 
-	if err := json.Unmarshal(response.Body, &result); err != nil {
+	if err := json.Unmarshal(responseBody, &result); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
@@ -22,6 +22,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -124,6 +125,30 @@ func TestGenerateImage_NovaCanvasKeepsNestedConfigOverride(t *testing.T) {
 	assertImageResponse(t, resp, req, "nova-image")
 }
 
+func TestGenerateImage_UsagePricesImages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"images":["nova-image-1","nova-image-2"]}`)
+	}))
+	defer server.Close()
+
+	const modelID = "amazon.nova-canvas-v1:0"
+	resp, err := newTestBedrock(server).generateImage(context.Background(), modelID, imagePromptRequest("paint a city"), nil)
+	if err != nil {
+		t.Fatalf("generateImage error: %v", err)
+	}
+	if resp.Usage == nil || resp.Usage.OutputImages != 2 {
+		t.Fatalf("usage = %+v, want 2 output images", resp.Usage)
+	}
+	cost, err := EstimateCost(modelID, *resp.Usage)
+	if err != nil {
+		t.Fatalf("EstimateCost error: %v", err)
+	}
+	if want := 0.08; math.Abs(cost-want) > 1e-9 {
+		t.Fatalf("EstimateCost = %v, want %v", cost, want)
+	}
+}
+
 func TestGenerateImage_StableDiffusionXLUsesLegacyPayload(t *testing.T) {
 	var gotBody map[string]any
 
@@ -0,0 +1,178 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/firebase/genkit/go/ai"
+)
+
+// ImageStreamCodec decodes the InvokeModelWithResponseStream chunks of an
+// image model that reports generation progress. Register one with
+// [RegisterImageStreamCodec].
+type ImageStreamCodec interface {
+	// ParseImageEvent decodes one response stream chunk.
+	ParseImageEvent(payload []byte) (ImageStreamEvent, error)
+}
+
+// ImageStreamEvent is one decoded progress event of a streamed image
+// generation.
+type ImageStreamEvent struct {
+	// Progress is the fraction of the generation completed, from 0 to 1.
+	// Negative values mean the event does not report progress.
+	Progress float64
+	// Preview holds base64-encoded PNG intermediate images, if any.
+	Preview []string
+	// Response is the final response body, in the model's InvokeModel
+	// response format. The stream ends with the first event that sets it.
+	Response []byte
+}
+
+var (
+	imageStreamCodecsMu sync.RWMutex
+	// imageStreamCodecs maps base model ID prefixes to the codec that
+	// decodes their progress stream. No image model on Bedrock streams
+	// today, so there are no built-in entries.
+	imageStreamCodecs = map[string]ImageStreamCodec{}
+)
+
+// RegisterImageStreamCodec streams image generation for models whose base
+// model ID starts with prefix through InvokeModelWithResponseStream, decoded
+// with codec, when the caller passes a stream callback. The callback receives
+// a chunk per progress event (see [ImageProgress]). The longest matching
+// prefix wins. Registering a nil codec removes prefix. It is safe to call
+// concurrently with generation.
+func RegisterImageStreamCodec(prefix string, codec ImageStreamCodec) {
+	imageStreamCodecsMu.Lock()
+	defer imageStreamCodecsMu.Unlock()
+	if codec == nil {
+		delete(imageStreamCodecs, prefix)
+		return
+	}
+	imageStreamCodecs[prefix] = codec
+}
+
+// imageStreamCodecFor returns the stream codec registered for modelName.
+func imageStreamCodecFor(modelName string) (ImageStreamCodec, bool) {
+	imageStreamCodecsMu.RLock()
+	defer imageStreamCodecsMu.RUnlock()
+	return longestPrefixCodec(imageStreamCodecs, baseModelID(modelName))
+}
+
+// imageProgressChunkKey is the ModelResponseChunk.Custom key of an image
+// progress fraction.
+const imageProgressChunkKey = "imageProgress"
+
+// ImageProgress returns the completed fraction, from 0 to 1, that a streamed
+// image generation chunk reports. ok is false for chunks without progress.
+func ImageProgress(chunk *ai.ModelResponseChunk) (progress float64, ok bool) {
+	if chunk == nil {
+		return 0, false
+	}
+	custom, _ := chunk.Custom.(map[string]any)
+	progress, ok = custom[imageProgressChunkKey].(float64)
+	return progress, ok
+}
+
+// invokeImageModel sends an image generation request body to modelName and
+// returns the response body. With a stream callback and a registered
+// [ImageStreamCodec] the request is streamed and each progress event is sent
+// to cb; otherwise it is a single InvokeModel call.
+func (b *Bedrock) invokeImageModel(ctx context.Context, modelName string, body []byte, cb func(context.Context, *ai.ModelResponseChunk) error) ([]byte, error) {
+	ctx, cancel := b.withRequestTimeout(ctx)
+	defer cancel()
+
+	if codec, ok := imageStreamCodecFor(modelName); ok && cb != nil {
+		return b.streamImageModel(ctx, modelName, body, codec, cb)
+	}
+	response, err := b.client.InvokeModel(ctx, &bedrockruntime.InvokeModelInput{
+		ModelId:     aws.String(modelName),
+		Body:        body,
+		ContentType: aws.String("application/json"),
+		Accept:      aws.String("application/json"),
+	})
+	if err != nil {
+		return nil, modelCallError("failed to invoke model", modelName, b.awsConfig.Region, err)
+	}
+	return response.Body, nil
+}
+
+// streamImageModel runs an image generation through
+// InvokeModelWithResponseStream, forwarding progress to cb, and returns the
+// final response body.
+func (b *Bedrock) streamImageModel(ctx context.Context, modelName string, body []byte, codec ImageStreamCodec, cb func(context.Context, *ai.ModelResponseChunk) error) ([]byte, error) {
+	output, err := b.client.InvokeModelWithResponseStream(ctx, &bedrockruntime.InvokeModelWithResponseStreamInput{
+		ModelId:     aws.String(modelName),
+		Body:        body,
+		ContentType: aws.String("application/json"),
+		Accept:      aws.String("application/json"),
+	})
+	if err != nil {
+		return nil, modelCallError("failed to invoke model with response stream", modelName, b.awsConfig.Region, err)
+	}
+	stream := output.GetStream()
+	if stream == nil {
+		return nil, fmt.Errorf("bedrock image stream is nil")
+	}
+	defer stream.Close()
+
+	for event := range stream.Events() {
+		chunk, ok := event.(*types.ResponseStreamMemberChunk)
+		if !ok {
+			continue
+		}
+		decoded, err := codec.ParseImageEvent(chunk.Value.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("bedrock: decode image stream event: %w", err)
+		}
+		if decoded.Response != nil {
+			return decoded.Response, nil
+		}
+		if err := cb(ctx, imageProgressChunk(decoded)); err != nil {
+			return nil, err
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, fmt.Errorf("bedrock image stream error: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("bedrock: image stream for model %q ended without a final image", modelName)
+}
+
+// imageProgressChunk converts a progress event into a stream chunk carrying
+// the progress and any preview images.
+func imageProgressChunk(event ImageStreamEvent) *ai.ModelResponseChunk {
+	chunk := &ai.ModelResponseChunk{Index: 0}
+	if event.Progress >= 0 {
+		chunk.Custom = map[string]any{imageProgressChunkKey: event.Progress}
+	}
+	for _, image := range event.Preview {
+		if image != "" {
+			chunk.Content = append(chunk.Content, ai.NewMediaPart("image/png", "data:image/png;base64,"+image))
+		}
+	}
+	return chunk
+}
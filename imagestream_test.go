@@ -0,0 +1,187 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+// fakeImageStreamCodec decodes test chunks of the form
+// {"progress":0.5,"preview":"..."} or {"final":{...}}.
+type fakeImageStreamCodec struct{}
+
+func (fakeImageStreamCodec) ParseImageEvent(payload []byte) (ImageStreamEvent, error) {
+	var event struct {
+		Progress *float64       `json:"progress"`
+		Preview  string         `json:"preview"`
+		Final    map[string]any `json:"final"`
+	}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return ImageStreamEvent{}, err
+	}
+	if event.Final != nil {
+		body, err := json.Marshal(event.Final)
+		return ImageStreamEvent{Response: body}, err
+	}
+	out := ImageStreamEvent{Progress: -1}
+	if event.Progress != nil {
+		out.Progress = *event.Progress
+	}
+	if event.Preview != "" {
+		out.Preview = []string{event.Preview}
+	}
+	return out, nil
+}
+
+// registerFakeImageStreamCodec registers the fake codec for Titan image
+// models for the duration of the test.
+func registerFakeImageStreamCodec(t *testing.T) {
+	t.Helper()
+	RegisterImageStreamCodec("amazon.titan-image-", fakeImageStreamCodec{})
+	t.Cleanup(func() { RegisterImageStreamCodec("amazon.titan-image-", nil) })
+}
+
+// imageStreamServer serves InvokeModelWithResponseStream with one chunk per
+// payload, and InvokeModel with invokeBody.
+func imageStreamServer(t *testing.T, invokeBody string, payloads ...string) (*httptest.Server, *[]string) {
+	t.Helper()
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.EscapedPath())
+		if !strings.HasSuffix(r.URL.Path, "/invoke-with-response-stream") {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = fmt.Fprint(w, invokeBody)
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.amazon.eventstream")
+		for _, payload := range payloads {
+			part, _ := json.Marshal(map[string]string{"bytes": base64.StdEncoding.EncodeToString([]byte(payload))})
+			writeStreamEvent(t, w, "chunk", string(part))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &paths
+}
+
+func TestGenerateImage_StreamsProgress(t *testing.T) {
+	registerFakeImageStreamCodec(t)
+	server, paths := imageStreamServer(t, `{"images":["unused"]}`,
+		`{"progress":0.25}`,
+		`{"progress":0.75,"preview":"preview-image"}`,
+		`{"final":{"images":["final-image"]}}`,
+	)
+	b := newTestBedrock(server)
+	req := imagePromptRequest("a lighthouse")
+
+	var chunks []*ai.ModelResponseChunk
+	resp, err := b.generateImage(context.Background(), "amazon.titan-image-generator-v2:0", req, func(ctx context.Context, chunk *ai.ModelResponseChunk) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("generateImage error: %v", err)
+	}
+	if len(*paths) != 1 || !strings.HasSuffix((*paths)[0], "/invoke-with-response-stream") {
+		t.Fatalf("paths = %q, want one streaming call", *paths)
+	}
+	assertImageResponse(t, resp, req, "final-image")
+
+	if len(chunks) != 2 {
+		t.Fatalf("chunks = %d, want 2", len(chunks))
+	}
+	for i, want := range []float64{0.25, 0.75} {
+		if got, ok := ImageProgress(chunks[i]); !ok || got != want {
+			t.Errorf("chunk %d progress = %v, %v; want %v", i, got, ok, want)
+		}
+	}
+	if len(chunks[0].Content) != 0 {
+		t.Errorf("chunk 0 content = %d parts, want none", len(chunks[0].Content))
+	}
+	if len(chunks[1].Content) != 1 || chunks[1].Content[0].Text != "data:image/png;base64,preview-image" {
+		t.Errorf("chunk 1 content = %#v, want the preview image", chunks[1].Content)
+	}
+}
+
+func TestGenerateImage_NoCallbackSkipsStreaming(t *testing.T) {
+	registerFakeImageStreamCodec(t)
+	server, paths := imageStreamServer(t, `{"images":["sync-image"]}`)
+	b := newTestBedrock(server)
+	req := imagePromptRequest("a lighthouse")
+
+	resp, err := b.generateImage(context.Background(), "amazon.titan-image-generator-v2:0", req, nil)
+	if err != nil {
+		t.Fatalf("generateImage error: %v", err)
+	}
+	if len(*paths) != 1 || !strings.HasSuffix((*paths)[0], "/invoke") {
+		t.Fatalf("paths = %q, want one InvokeModel call", *paths)
+	}
+	assertImageResponse(t, resp, req, "sync-image")
+}
+
+func TestGenerateImage_UnregisteredModelDoesNotStream(t *testing.T) {
+	server, paths := imageStreamServer(t, `{"images":["sync-image"]}`)
+	b := newTestBedrock(server)
+	req := imagePromptRequest("a lighthouse")
+
+	var chunks int
+	resp, err := b.generateImage(context.Background(), "amazon.nova-canvas-v1:0", req, func(context.Context, *ai.ModelResponseChunk) error {
+		chunks++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("generateImage error: %v", err)
+	}
+	if len(*paths) != 1 || !strings.HasSuffix((*paths)[0], "/invoke") {
+		t.Fatalf("paths = %q, want one InvokeModel call", *paths)
+	}
+	if chunks != 0 {
+		t.Errorf("chunks = %d, want 0", chunks)
+	}
+	assertImageResponse(t, resp, req, "sync-image")
+}
+
+func TestGenerateImage_StreamWithoutFinalImage(t *testing.T) {
+	registerFakeImageStreamCodec(t)
+	server, _ := imageStreamServer(t, "", `{"progress":0.5}`)
+	b := newTestBedrock(server)
+
+	_, err := b.generateImage(context.Background(), "amazon.titan-image-generator-v2:0", imagePromptRequest("a lighthouse"), func(context.Context, *ai.ModelResponseChunk) error {
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "ended without a final image") {
+		t.Fatalf("error = %v, want missing final image", err)
+	}
+}
+
+func TestImageProgress_NoProgress(t *testing.T) {
+	if _, ok := ImageProgress(nil); ok {
+		t.Error("ImageProgress(nil) ok = true")
+	}
+	if _, ok := ImageProgress(&ai.ModelResponseChunk{Content: []*ai.Part{ai.NewTextPart("hi")}}); ok {
+		t.Error("ImageProgress(text chunk) ok = true")
+	}
+}
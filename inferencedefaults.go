@@ -0,0 +1,66 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import "strings"
+
+// familyDefault is the inference config applied, with
+// [Bedrock.FamilyInferenceDefaults], to requests for models whose ID
+// contains Models and that leave the field unset.
+type familyDefault struct {
+	Models      string // Model ID substring, e.g. "anthropic."; "" matches any model
+	Temperature float32
+}
+
+// familyInferenceDefaults lists the defaults per model family. The first
+// entry whose Models matches is used, so the catch-all entry comes last.
+var familyInferenceDefaults = []familyDefault{
+	{Models: "anthropic.", Temperature: 1.0},
+	{Models: "", Temperature: 0.7},
+}
+
+// applyFamilyDefaults returns cfg, or a copy with the family defaults for
+// modelName filled into the fields the caller left unset. Fields that
+// rules exclude for the features cfg uses, such as temperature with Claude
+// extended thinking, are left unset.
+func (b *Bedrock) applyFamilyDefaults(modelName string, cfg *Config) *Config {
+	if !b.FamilyInferenceDefaults {
+		return cfg
+	}
+	name := strings.ToLower(modelName)
+	for _, d := range familyInferenceDefaults {
+		if !strings.Contains(name, d.Models) {
+			continue
+		}
+		if cfg != nil && cfg.Temperature != nil {
+			return cfg
+		}
+		out := &Config{}
+		if cfg != nil {
+			copied := *cfg
+			out = &copied
+		}
+		if ruleExcludes(modelName, out, "temperature", incompatibleFieldRules) {
+			return cfg
+		}
+		t := d.Temperature
+		out.Temperature = &t
+		return out
+	}
+	return cfg
+}
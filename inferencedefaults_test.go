@@ -0,0 +1,83 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/firebase/genkit/go/ai"
+)
+
+func TestBuildConverseInput_FamilyInferenceDefaults(t *testing.T) {
+	tests := []struct {
+		name  string
+		model string
+		cfg   *Config
+		want  *float32
+	}{
+		{"anthropic default", "anthropic.claude-3-5-sonnet-20240620-v1:0", nil, aws.Float32(1.0)},
+		{"profile uses family", "us.anthropic.claude-3-5-sonnet-20240620-v1:0", &Config{MaxTokens: 100}, aws.Float32(1.0)},
+		{"other family default", "amazon.nova-pro-v1:0", nil, aws.Float32(0.7)},
+		{"user override wins", "anthropic.claude-3-5-sonnet-20240620-v1:0", &Config{Temperature: aws.Float32(0.2)}, aws.Float32(0.2)},
+		{"skipped with thinking", "anthropic.claude-3-7-sonnet-20250219-v1:0", &Config{MaxTokens: 4096, ThinkingBudget: 2048}, nil},
+	}
+	b := &Bedrock{FamilyInferenceDefaults: true}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &ai.ModelRequest{Messages: []*ai.Message{ai.NewUserTextMessage("hi")}, Config: tt.cfg}
+			in, err := b.buildConverseInput(tt.model, req)
+			if err != nil {
+				t.Fatalf("buildConverseInput: %v", err)
+			}
+			var got *float32
+			if in.InferenceConfig != nil {
+				got = in.InferenceConfig.Temperature
+			}
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Fatalf("temperature = %v, want %v", aws.ToFloat32(got), aws.ToFloat32(tt.want))
+			}
+		})
+	}
+	if tt := tests[3]; *tt.cfg.Temperature != 0.2 {
+		t.Errorf("request config was modified")
+	}
+}
+
+func TestBuildConverseInput_FamilyInferenceDefaultsOff(t *testing.T) {
+	req := &ai.ModelRequest{Messages: []*ai.Message{ai.NewUserTextMessage("hi")}}
+	in, err := (&Bedrock{}).buildConverseInput("anthropic.claude-3-5-sonnet-20240620-v1:0", req)
+	if err != nil {
+		t.Fatalf("buildConverseInput: %v", err)
+	}
+	if in.InferenceConfig != nil && in.InferenceConfig.Temperature != nil {
+		t.Errorf("temperature = %v, want unset", *in.InferenceConfig.Temperature)
+	}
+}
+
+func TestPrepareInvokeRequest_FamilyInferenceDefaults(t *testing.T) {
+	b := &Bedrock{FamilyInferenceDefaults: true}
+	req := &ai.ModelRequest{Messages: []*ai.Message{ai.NewUserTextMessage("hi")}}
+	_, cfg, err := b.prepareInvokeRequest("amazon.titan-text-express-v1", req)
+	if err != nil {
+		t.Fatalf("prepareInvokeRequest: %v", err)
+	}
+	if cfg == nil || aws.ToFloat32(cfg.Temperature) != 0.7 {
+		t.Errorf("cfg = %+v, want temperature 0.7", cfg)
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// InferenceProfile describes an inference profile listed by the Bedrock
+// control plane's ListInferenceProfiles operation.
+type InferenceProfile struct {
+	ID          string   // Profile ID, e.g. "us.anthropic.claude-3-5-haiku-20241022-v1:0"
+	ARN         string   // Profile ARN
+	Name        string   // Display name
+	Description string   // Description, "" when unset
+	Type        string   // "SYSTEM_DEFINED" (cross-region) or "APPLICATION"
+	Status      string   // "ACTIVE"
+	ModelARNs   []string // ARNs of the foundation models it routes to, one per destination region
+}
+
+// ModelID returns the foundation model ID the profile routes to, taken from
+// its first model ARN, or "" when it lists none.
+func (p InferenceProfile) ModelID() string {
+	if len(p.ModelARNs) == 0 {
+		return ""
+	}
+	arn := p.ModelARNs[0]
+	if i := strings.LastIndex(arn, "/"); i >= 0 {
+		return arn[i+1:]
+	}
+	return arn
+}
+
+// profileLister lists one page of the inference profiles available in the
+// plugin's region. The plugin creates one at Init from its AWS config.
+type profileLister interface {
+	ListInferenceProfiles(ctx context.Context, nextToken string) (profiles []InferenceProfile, next string, err error)
+}
+
+type listInferenceProfilesResponse struct {
+	InferenceProfileSummaries []struct {
+		InferenceProfileID   string `json:"inferenceProfileId"`
+		InferenceProfileARN  string `json:"inferenceProfileArn"`
+		InferenceProfileName string `json:"inferenceProfileName"`
+		Description          string `json:"description"`
+		Type                 string `json:"type"`
+		Status               string `json:"status"`
+		Models               []struct {
+			ModelARN string `json:"modelArn"`
+		} `json:"models"`
+	} `json:"inferenceProfileSummaries"`
+	NextToken string `json:"nextToken"`
+}
+
+// ListInferenceProfiles implements [profileLister].
+func (c *controlPlaneClient) ListInferenceProfiles(ctx context.Context, nextToken string) ([]InferenceProfile, string, error) {
+	path := "/inference-profiles"
+	if nextToken != "" {
+		path += "?nextToken=" + url.QueryEscape(nextToken)
+	}
+	var resp listInferenceProfilesResponse
+	if err := c.do(ctx, "GET", path, nil, &resp); err != nil {
+		return nil, "", err
+	}
+	profiles := make([]InferenceProfile, 0, len(resp.InferenceProfileSummaries))
+	for _, s := range resp.InferenceProfileSummaries {
+		p := InferenceProfile{
+			ID:          s.InferenceProfileID,
+			ARN:         s.InferenceProfileARN,
+			Name:        s.InferenceProfileName,
+			Description: s.Description,
+			Type:        s.Type,
+			Status:      s.Status,
+		}
+		for _, m := range s.Models {
+			p.ModelARNs = append(p.ModelARNs, m.ModelARN)
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles, resp.NextToken, nil
+}
+
+// ListInferenceProfiles lists the inference profiles available in the
+// plugin's region, following pagination, so callers can pick the right
+// cross-region profile for a model. It needs bedrock:ListInferenceProfiles.
+func (b *Bedrock) ListInferenceProfiles(ctx context.Context) ([]InferenceProfile, error) {
+	b.mu.Lock()
+	initted, lister, timeout := b.initted, b.profiles, b.RequestTimeout
+	b.mu.Unlock()
+	if !initted {
+		return nil, errors.New("bedrock.ListInferenceProfiles: plugin not initialized")
+	}
+	ctx, cancel := withRequestTimeout(ctx, timeout)
+	defer cancel()
+	return listInferenceProfiles(ctx, lister)
+}
+
+func listInferenceProfiles(ctx context.Context, lister profileLister) ([]InferenceProfile, error) {
+	if lister == nil {
+		return nil, errors.New("bedrock.ListInferenceProfiles: profile lister required")
+	}
+	var profiles []InferenceProfile
+	seen := map[string]bool{}
+	token := ""
+	for {
+		page, next, err := lister.ListInferenceProfiles(ctx, token)
+		if err != nil {
+			return nil, fmt.Errorf("bedrock.ListInferenceProfiles: %w", err)
+		}
+		profiles = append(profiles, page...)
+		if next == "" {
+			return profiles, nil
+		}
+		if seen[next] {
+			return nil, fmt.Errorf("bedrock.ListInferenceProfiles: pagination token %q repeated", next)
+		}
+		seen[next] = true
+		token = next
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeProfileLister serves pages keyed by the token that requests them.
+type fakeProfileLister struct {
+	pages  map[string][]InferenceProfile
+	next   map[string]string
+	err    error
+	tokens []string
+}
+
+func (f *fakeProfileLister) ListInferenceProfiles(ctx context.Context, nextToken string) ([]InferenceProfile, string, error) {
+	f.tokens = append(f.tokens, nextToken)
+	if f.err != nil {
+		return nil, "", f.err
+	}
+	return f.pages[nextToken], f.next[nextToken], nil
+}
+
+func TestListInferenceProfiles_FollowsPagination(t *testing.T) {
+	lister := &fakeProfileLister{
+		pages: map[string][]InferenceProfile{
+			"": {{
+				ID:        "us.anthropic.claude-3-5-haiku-20241022-v1:0",
+				ARN:       "arn:aws:bedrock:us-east-1:123456789012:inference-profile/us.anthropic.claude-3-5-haiku-20241022-v1:0",
+				Type:      "SYSTEM_DEFINED",
+				ModelARNs: []string{"arn:aws:bedrock:us-east-1::foundation-model/anthropic.claude-3-5-haiku-20241022-v1:0", "arn:aws:bedrock:us-west-2::foundation-model/anthropic.claude-3-5-haiku-20241022-v1:0"},
+			}},
+			"page-2": {{
+				ID:        "us.amazon.nova-pro-v1:0",
+				Type:      "SYSTEM_DEFINED",
+				ModelARNs: []string{"arn:aws:bedrock:us-east-1::foundation-model/amazon.nova-pro-v1:0"},
+			}},
+		},
+		next: map[string]string{"": "page-2"},
+	}
+	b := &Bedrock{initted: true, profiles: lister}
+
+	profiles, err := b.ListInferenceProfiles(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(profiles) != 2 || profiles[0].ID != "us.anthropic.claude-3-5-haiku-20241022-v1:0" || profiles[1].ID != "us.amazon.nova-pro-v1:0" {
+		t.Fatalf("profiles = %+v, want both pages in order", profiles)
+	}
+	if got := strings.Join(lister.tokens, ","); got != ",page-2" {
+		t.Errorf("tokens = %q, want the first page then page-2", got)
+	}
+	if got := profiles[0].ModelID(); got != "anthropic.claude-3-5-haiku-20241022-v1:0" {
+		t.Errorf("ModelID() = %q", got)
+	}
+	if got := (InferenceProfile{}).ModelID(); got != "" {
+		t.Errorf("ModelID() of a profile without models = %q, want empty", got)
+	}
+}
+
+func TestListInferenceProfiles_Errors(t *testing.T) {
+	if _, err := (&Bedrock{}).ListInferenceProfiles(context.Background()); err == nil || !strings.Contains(err.Error(), "not initialized") {
+		t.Errorf("error = %v, want not initialized", err)
+	}
+
+	b := &Bedrock{initted: true, profiles: &fakeProfileLister{err: errors.New("access denied")}}
+	if _, err := b.ListInferenceProfiles(context.Background()); err == nil || !strings.Contains(err.Error(), "bedrock.ListInferenceProfiles: access denied") {
+		t.Errorf("error = %v, want the listing error", err)
+	}
+
+	looping := &fakeProfileLister{next: map[string]string{"": "a", "a": "a"}}
+	if _, err := listInferenceProfiles(context.Background(), looping); err == nil || !strings.Contains(err.Error(), "repeated") {
+		t.Errorf("error = %v, want repeated token error", err)
+	}
+}
+
+func TestControlPlaneClient_ListInferenceProfilesWireFormat(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path+"?"+r.URL.RawQuery)
+		if r.URL.Query().Get("nextToken") == "" {
+			_, _ = w.Write([]byte(`{"inferenceProfileSummaries":[{"inferenceProfileId":"eu.amazon.nova-lite-v1:0",` +
+				`"inferenceProfileArn":"arn:aws:bedrock:eu-west-1:123456789012:inference-profile/eu.amazon.nova-lite-v1:0",` +
+				`"inferenceProfileName":"EU Nova Lite","description":"Routes to EU regions","type":"SYSTEM_DEFINED","status":"ACTIVE",` +
+				`"models":[{"modelArn":"arn:aws:bedrock:eu-west-1::foundation-model/amazon.nova-lite-v1:0"},` +
+				`{"modelArn":"arn:aws:bedrock:eu-central-1::foundation-model/amazon.nova-lite-v1:0"}]}],"nextToken":"tok/2="}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"inferenceProfileSummaries":[{"inferenceProfileId":"my-app-profile","type":"APPLICATION",` +
+			`"models":[{"modelArn":"arn:aws:bedrock:eu-west-1::foundation-model/amazon.nova-pro-v1:0"}]}]}`))
+	}))
+	defer server.Close()
+
+	profiles, err := listInferenceProfiles(context.Background(), newControlPlaneClient(testControlPlaneConfig(server)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 2 || requests[0] != "GET /inference-profiles?" || requests[1] != "GET /inference-profiles?nextToken=tok%2F2%3D" {
+		t.Errorf("requests = %q", requests)
+	}
+	if len(profiles) != 2 {
+		t.Fatalf("profiles = %+v, want two", profiles)
+	}
+	p := profiles[0]
+	if p.ID != "eu.amazon.nova-lite-v1:0" || p.Name != "EU Nova Lite" || p.Type != "SYSTEM_DEFINED" || p.Status != "ACTIVE" ||
+		!strings.HasSuffix(p.ARN, "inference-profile/eu.amazon.nova-lite-v1:0") || len(p.ModelARNs) != 2 || p.ModelID() != "amazon.nova-lite-v1:0" {
+		t.Errorf("profile = %+v", p)
+	}
+	if profiles[1].Type != "APPLICATION" || profiles[1].ModelID() != "amazon.nova-pro-v1:0" {
+		t.Errorf("profile = %+v", profiles[1])
+	}
+}
@@ -0,0 +1,251 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/firebase/genkit/go/ai"
+)
+
+// ProviderCodec translates between Genkit requests and a provider's native
+// InvokeModel JSON body, for chat models that are not served through the
+// Converse API. Register one with [RegisterProviderCodec].
+type ProviderCodec interface {
+	// BuildRequest returns the InvokeModel request body for req. cfg is the
+	// decoded request config and may be nil.
+	BuildRequest(modelID string, req *ai.ModelRequest, cfg *Config) ([]byte, error)
+	// ParseResponse converts the InvokeModel response body into a Genkit
+	// response. req is the original request, e.g. for tool schemas.
+	ParseResponse(body []byte, req *ai.ModelRequest) (*ai.ModelResponse, error)
+}
+
+var (
+	providerCodecsMu sync.RWMutex
+	// providerCodecs maps base model ID prefixes to the codec that serves
+	// them. The built-in entries cover text-completion models for which
+	// Converse rejects system prompts: Mistral instruct, Titan Text and
+	// Cohere Command.
+	providerCodecs = map[string]ProviderCodec{
+		"mistral.mistral-7b-instruct":   mistralInstructCodec{},
+		"mistral.mixtral-8x7b-instruct": mistralInstructCodec{},
+		"amazon.titan-text-":            titanTextCodec{},
+		"cohere.command-text-":          cohereCommandCodec{},
+		"cohere.command-light-text-":    cohereCommandCodec{},
+	}
+	// optInProviderCodecs are codecs for models that Converse serves by
+	// default; they are used only for models set to APIModeInvoke.
+	optInProviderCodecs = map[string]ProviderCodec{
+		"mistral.mistral-large": mistralChatCodec{},
+	}
+)
+
+// RegisterProviderCodec routes chat models whose base model ID (the ID
+// without an inference profile prefix) starts with prefix through InvokeModel
+// with codec, instead of Converse. The longest matching prefix wins, so a
+// registration can narrow or override a built-in one. Registering a nil codec
+// removes prefix. It is safe to call concurrently with generation.
+func RegisterProviderCodec(prefix string, codec ProviderCodec) {
+	providerCodecsMu.Lock()
+	defer providerCodecsMu.Unlock()
+	if codec == nil {
+		delete(providerCodecs, prefix)
+		return
+	}
+	providerCodecs[prefix] = codec
+}
+
+// errLogprobsUnsupported is returned for [Config.Logprobs] on models that do
+// not report token log probabilities.
+func errLogprobsUnsupported(modelName string) error {
+	return fmt.Errorf("bedrock: model %q does not return logprobs; Logprobs is supported by Cohere Command text models", modelName)
+}
+
+// invokeCodecFor returns the codec for modelName when the model is routed
+// through InvokeModel.
+func invokeCodecFor(modelName string) (ProviderCodec, bool) {
+	providerCodecsMu.RLock()
+	defer providerCodecsMu.RUnlock()
+	return longestPrefixCodec(providerCodecs, baseModelID(modelName))
+}
+
+// longestPrefixCodec returns the codec of the longest prefix of base in codecs.
+func longestPrefixCodec[C any](codecs map[string]C, base string) (C, bool) {
+	var match string
+	for prefix := range codecs {
+		if strings.HasPrefix(base, prefix) && len(prefix) > len(match) {
+			match = prefix
+		}
+	}
+	if match == "" {
+		var zero C
+		return zero, false
+	}
+	return codecs[match], true
+}
+
+// routeInvoke returns the codec for modelName when the model is called through
+// InvokeModel, honoring [Bedrock.APIModes]. Models set to APIModeInvoke
+// without a registered codec fall back to the opt-in codecs.
+func (b *Bedrock) routeInvoke(modelName string) (ProviderCodec, bool, error) {
+	mode, ok := b.APIModes[modelName]
+	if !ok {
+		mode = b.APIModes[baseModelID(modelName)]
+	}
+	if mode == APIModeConverse {
+		return nil, false, nil
+	}
+	codec, ok := invokeCodecFor(modelName)
+	if !ok && mode == APIModeInvoke {
+		codec, ok = longestPrefixCodec(optInProviderCodecs, baseModelID(modelName))
+	}
+	if !ok && mode == APIModeInvoke {
+		return nil, false, fmt.Errorf("bedrock: model %q is set to APIModeInvoke, but no ProviderCodec is registered for it; see RegisterProviderCodec", modelName)
+	}
+	return codec, ok, nil
+}
+
+// generateInvoke handles text generation through InvokeModel with a provider
+// codec. InvokeModel is not streamed; when cb is set the complete response is
+// delivered as a single chunk.
+func (b *Bedrock) generateInvoke(ctx context.Context, client BedrockClient, modelName string, codec ProviderCodec, input *ai.ModelRequest, cb func(context.Context, *ai.ModelResponseChunk) error) (*ai.ModelResponse, error) {
+	if input == nil {
+		return nil, errors.New("model request is nil")
+	}
+	input, cfg, err := b.prepareInvokeRequest(modelName, input)
+	if err != nil {
+		return nil, err
+	}
+	body, err := codec.BuildRequest(modelName, input, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build invoke model request: %w", err)
+	}
+
+	ctx, cancel := b.withRequestTimeout(ctx)
+	defer cancel()
+
+	out, err := client.InvokeModel(ctx, &bedrockruntime.InvokeModelInput{
+		ModelId:     aws.String(modelName),
+		Body:        body,
+		ContentType: aws.String("application/json"),
+		Accept:      aws.String("application/json"),
+	})
+	if err != nil {
+		return nil, modelCallError("bedrock invoke model failed", modelName, b.requestRegion(input), err)
+	}
+	if out == nil {
+		return nil, errors.New("bedrock: empty invoke model response")
+	}
+
+	resp, err := codec.ParseResponse(out.Body, input)
+	if err != nil {
+		return nil, err
+	}
+	resp.Request = input
+	markTruncated(resp)
+	markCacheHit(resp)
+	b.markRouting(resp, modelName, input)
+	if cb != nil {
+		if err := cb(ctx, &ai.ModelResponseChunk{Index: 0, Content: resp.Message.Content}); err != nil {
+			return nil, fmt.Errorf("callback error: %w", err)
+		}
+	}
+	return resp, nil
+}
+
+// prepareInvokeRequest applies the checks buildConverseInput applies on the
+// Converse path (NoSystemPrompt, a user message, tool support, tool result
+// limits, stop sequences and the maxTokens limit) and rejects config a codec
+// cannot honor. The returned config carries any clamped maxTokens.
+func (b *Bedrock) prepareInvokeRequest(modelName string, input *ai.ModelRequest) (*ai.ModelRequest, *Config, error) {
+	cfg, err := configFromRequest(input)
+	if err != nil {
+		return nil, nil, err
+	}
+	input, err = b.checkUserMessage(dropSystemMessages(input, cfg))
+	if err != nil {
+		return nil, nil, err
+	}
+	input, err = b.checkToolSupport(modelName, input)
+	if err != nil {
+		return nil, nil, err
+	}
+	input, err = b.limitToolResults(input, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg = b.applyFamilyDefaults(modelName, cfg)
+	if cfg == nil {
+		return input, nil, nil
+	}
+	if err := b.checkStopSequences(modelName, cfg); err != nil {
+		return nil, nil, err
+	}
+
+	if err := checkInvokeConfig(modelName, cfg); err != nil {
+		return nil, nil, err
+	}
+
+	if cfg.MaxTokens > 0 {
+		ic := &types.InferenceConfiguration{MaxTokens: aws.Int32(int32(cfg.MaxTokens))}
+		if err := b.checkMaxTokens(modelName, ic); err != nil {
+			return nil, nil, err
+		}
+		if int(*ic.MaxTokens) != cfg.MaxTokens {
+			clamped := *cfg
+			clamped.MaxTokens = int(*ic.MaxTokens)
+			cfg = &clamped
+		}
+	}
+	return input, cfg, nil
+}
+
+// checkInvokeConfig rejects config fields that only the Converse API
+// supports for modelName, which is served through InvokeModel.
+func checkInvokeConfig(modelName string, cfg *Config) error {
+	var unsupported []string
+	if cfg.Guardrail != nil {
+		unsupported = append(unsupported, "guardrail")
+	}
+	if cfg.Citations {
+		unsupported = append(unsupported, "citations")
+	}
+	if len(cfg.AdditionalModelRequestFields) > 0 {
+		unsupported = append(unsupported, "additionalModelRequestFields")
+	}
+	if len(cfg.AnthropicBeta) > 0 {
+		unsupported = append(unsupported, "anthropicBeta")
+	}
+	if len(cfg.AdditionalResponseFieldPaths) > 0 {
+		unsupported = append(unsupported, "additionalResponseFieldPaths")
+	}
+	if cfg.Choices != nil {
+		unsupported = append(unsupported, "choices")
+	}
+	if len(unsupported) > 0 {
+		return fmt.Errorf("bedrock: model %q is served through InvokeModel, which does not support %s", modelName, strings.Join(unsupported, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+// cohereCommandCodec speaks the Cohere Command (text generation) InvokeModel
+// format: the conversation rendered as "User:"/"Chatbot:" turns in "prompt",
+// and "generations" out. These models have no tool use, media input or system
+// role, but can return per-token log-likelihoods ([Config.Logprobs]).
+type cohereCommandCodec struct{}
+
+type cohereCommandRequest struct {
+	Prompt            string   `json:"prompt"`
+	MaxTokens         int      `json:"max_tokens,omitempty"`
+	Temperature       *float32 `json:"temperature,omitempty"`
+	P                 *float32 `json:"p,omitempty"`
+	K                 *int     `json:"k,omitempty"`
+	StopSequences     []string `json:"stop_sequences,omitempty"`
+	ReturnLikelihoods string   `json:"return_likelihoods,omitempty"`
+}
+
+type cohereCommandResponse struct {
+	Generations []struct {
+		Text             string `json:"text"`
+		FinishReason     string `json:"finish_reason"`
+		TokenLikelihoods []struct {
+			Token      string  `json:"token"`
+			Likelihood float64 `json:"likelihood"`
+		} `json:"token_likelihoods"`
+	} `json:"generations"`
+}
+
+func (cohereCommandCodec) BuildRequest(modelName string, input *ai.ModelRequest, cfg *Config) ([]byte, error) {
+	prompt, err := transcriptPrompt("Cohere Command", "Chatbot:", input.Messages)
+	if err != nil {
+		return nil, err
+	}
+	req := cohereCommandRequest{Prompt: prompt}
+	if cfg != nil {
+		req.MaxTokens = cfg.MaxTokens
+		req.Temperature = cfg.Temperature
+		req.P = cfg.TopP
+		req.K = cfg.TopK
+		req.StopSequences = cfg.StopSequences
+		if cfg.Logprobs {
+			req.ReturnLikelihoods = "GENERATION"
+		}
+	}
+	return json.Marshal(req)
+}
+
+func (cohereCommandCodec) ParseResponse(body []byte, input *ai.ModelRequest) (*ai.ModelResponse, error) {
+	var resp cohereCommandResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("bedrock: failed to unmarshal Cohere Command response: %w", err)
+	}
+	if len(resp.Generations) == 0 {
+		return nil, errors.New("bedrock: Cohere Command response has no generations")
+	}
+	gen := resp.Generations[0]
+	msg := ai.NewModelTextMessage(strings.TrimSpace(gen.Text))
+	if len(gen.TokenLikelihoods) > 0 {
+		logprobs := make([]TokenLogprob, len(gen.TokenLikelihoods))
+		for i, tl := range gen.TokenLikelihoods {
+			logprobs[i] = TokenLogprob{Token: tl.Token, Logprob: tl.Likelihood}
+		}
+		msg.Metadata = map[string]any{logprobsMetadataKey: logprobs}
+	}
+	return &ai.ModelResponse{
+		Message:      msg,
+		FinishReason: cohereFinishReason(gen.FinishReason),
+	}, nil
+}
+
+func cohereFinishReason(reason string) ai.FinishReason {
+	switch reason {
+	case "COMPLETE":
+		return ai.FinishReasonStop
+	case "MAX_TOKENS":
+		return ai.FinishReasonLength
+	case "ERROR_TOXIC":
+		return ai.FinishReasonBlocked
+	default:
+		return ai.FinishReasonOther
+	}
+}
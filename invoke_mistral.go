@@ -0,0 +1,376 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/firebase/genkit/go/ai"
+)
+
+// mistralInstructCodec speaks the text-completion InvokeModel format of
+// Mistral 7B Instruct and Mixtral 8x7B Instruct: a single "<s>[INST] ...
+// [/INST]" prompt in, "outputs" out. These models have no function calling.
+// Converse rejects system prompts for them, so the codec folds the system
+// prompt into the first instruction.
+type mistralInstructCodec struct{}
+
+type mistralInstructRequest struct {
+	Prompt      string   `json:"prompt"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+	Temperature *float32 `json:"temperature,omitempty"`
+	TopP        *float32 `json:"top_p,omitempty"`
+	TopK        *int     `json:"top_k,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+}
+
+type mistralInstructResponse struct {
+	Outputs []struct {
+		Text       string `json:"text"`
+		StopReason string `json:"stop_reason"`
+	} `json:"outputs"`
+}
+
+func (mistralInstructCodec) BuildRequest(modelName string, input *ai.ModelRequest, cfg *Config) ([]byte, error) {
+	if cfg != nil && cfg.Logprobs {
+		return nil, errLogprobsUnsupported(modelName)
+	}
+	prompt, err := mistralInstructPrompt(input.Messages)
+	if err != nil {
+		return nil, err
+	}
+	req := mistralInstructRequest{Prompt: prompt}
+	if cfg != nil {
+		req.MaxTokens = cfg.MaxTokens
+		req.Temperature = cfg.Temperature
+		req.TopP = cfg.TopP
+		req.TopK = cfg.TopK
+		req.Stop = cfg.StopSequences
+	}
+	return json.Marshal(req)
+}
+
+// mistralInstructPrompt renders messages in the Mistral instruction format:
+//
+//	<s>[INST] system\n\nuser [/INST] answer</s>[INST] user [/INST]
+//
+// Consecutive turns of the same role are joined. A trailing model message is
+// left open so the model continues it.
+func mistralInstructPrompt(messages []*ai.Message) (string, error) {
+	var system []string
+	type turn struct {
+		role ai.Role
+		text string
+	}
+	var turns []turn
+	for _, msg := range messages {
+		if msg == nil {
+			continue
+		}
+		var text strings.Builder
+		for _, part := range msg.Content {
+			if part == nil {
+				continue
+			}
+			switch {
+			case part.IsText():
+				text.WriteString(part.Text)
+			case part.IsToolRequest(), part.IsToolResponse():
+				return "", errors.New("bedrock: Mistral instruct models do not support tool use")
+			case part.IsMedia():
+				return "", errors.New("bedrock: Mistral instruct models do not support media input")
+			}
+		}
+		role := msg.Role
+		switch role {
+		case ai.RoleSystem:
+			system = append(system, text.String())
+			continue
+		case ai.RoleModel:
+		default:
+			role = ai.RoleUser
+		}
+		if n := len(turns); n > 0 && turns[n-1].role == role {
+			turns[n-1].text += "\n\n" + text.String()
+			continue
+		}
+		turns = append(turns, turn{role: role, text: text.String()})
+	}
+	if len(turns) == 0 || turns[0].role != ai.RoleUser {
+		return "", errors.New("bedrock: Mistral instruct prompts must start with a user message")
+	}
+	if len(system) > 0 {
+		turns[0].text = strings.Join(system, "\n\n") + "\n\n" + turns[0].text
+	}
+
+	var b strings.Builder
+	b.WriteString("<s>")
+	for i, t := range turns {
+		if t.role == ai.RoleUser {
+			b.WriteString("[INST] " + t.text + " [/INST]")
+			continue
+		}
+		b.WriteString(" " + t.text)
+		if i < len(turns)-1 {
+			b.WriteString("</s>")
+		}
+	}
+	return b.String(), nil
+}
+
+func (mistralInstructCodec) ParseResponse(body []byte, input *ai.ModelRequest) (*ai.ModelResponse, error) {
+	var resp mistralInstructResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("bedrock: failed to unmarshal Mistral response: %w", err)
+	}
+	if len(resp.Outputs) == 0 {
+		return nil, errors.New("bedrock: Mistral response has no outputs")
+	}
+	out := resp.Outputs[0]
+	return &ai.ModelResponse{
+		Message:      ai.NewModelTextMessage(strings.TrimPrefix(out.Text, " ")),
+		FinishReason: mistralFinishReason(out.StopReason),
+	}, nil
+}
+
+// MistralChatCodec returns the codec for the Mistral chat-completion
+// InvokeModel format used by Mistral Large, including function calling
+// through "tools" and "tool_calls". Converse serves these models by default;
+// register the codec with [RegisterProviderCodec] to call them through
+// InvokeModel instead:
+//
+//	bedrock.RegisterProviderCodec("mistral.mistral-large", bedrock.MistralChatCodec())
+func MistralChatCodec() ProviderCodec {
+	return mistralChatCodec{}
+}
+
+// mistralChatCodec implements [MistralChatCodec].
+type mistralChatCodec struct{}
+
+type mistralRequest struct {
+	Messages    []mistralMessage `json:"messages"`
+	Tools       []mistralTool    `json:"tools,omitempty"`
+	ToolChoice  string           `json:"tool_choice,omitempty"`
+	MaxTokens   int              `json:"max_tokens,omitempty"`
+	Temperature *float32         `json:"temperature,omitempty"`
+	TopP        *float32         `json:"top_p,omitempty"`
+	Stop        []string         `json:"stop,omitempty"`
+}
+
+type mistralMessage struct {
+	Role       string            `json:"role"`
+	Content    string            `json:"content"`
+	ToolCalls  []mistralToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string            `json:"tool_call_id,omitempty"`
+	Name       string            `json:"name,omitempty"`
+}
+
+type mistralTool struct {
+	Type     string              `json:"type"`
+	Function mistralToolFunction `json:"function"`
+}
+
+type mistralToolFunction struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters"`
+}
+
+type mistralToolCall struct {
+	ID       string              `json:"id"`
+	Type     string              `json:"type,omitempty"`
+	Function mistralFunctionCall `json:"function"`
+}
+
+type mistralFunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+type mistralResponse struct {
+	Choices []struct {
+		Message    mistralMessage `json:"message"`
+		StopReason string         `json:"stop_reason"`
+	} `json:"choices"`
+}
+
+func (mistralChatCodec) BuildRequest(modelName string, input *ai.ModelRequest, cfg *Config) ([]byte, error) {
+	if cfg != nil && cfg.Logprobs {
+		return nil, errLogprobsUnsupported(modelName)
+	}
+	req := mistralRequest{}
+	for _, msg := range input.Messages {
+		if msg == nil {
+			continue
+		}
+		converted, err := mistralMessages(msg)
+		if err != nil {
+			return nil, err
+		}
+		req.Messages = append(req.Messages, converted...)
+	}
+
+	toolChoice := ""
+	if cfg != nil {
+		req.MaxTokens = cfg.MaxTokens
+		req.Temperature = cfg.Temperature
+		req.TopP = cfg.TopP
+		req.Stop = cfg.StopSequences
+		toolChoice = cfg.ToolChoice
+	}
+	if len(input.Tools) > 0 && toolChoice != ToolChoiceNone {
+		for _, tool := range input.Tools {
+			if tool == nil || tool.Name == "" {
+				return nil, errors.New("bedrock: tool name required")
+			}
+			params := tool.InputSchema
+			if params == nil {
+				params = map[string]any{"type": "object", "properties": map[string]any{}}
+			}
+			req.Tools = append(req.Tools, mistralTool{
+				Type: "function",
+				Function: mistralToolFunction{
+					Name:        tool.Name,
+					Description: tool.Description,
+					Parameters:  params,
+				},
+			})
+		}
+		switch toolChoice {
+		case "", ToolChoiceAuto:
+			req.ToolChoice = "auto"
+		case ToolChoiceRequired, ToolChoiceAny:
+			req.ToolChoice = "any"
+		default:
+			return nil, fmt.Errorf("bedrock: Mistral does not support forcing the specific tool %q; use %q or %q", toolChoice, ToolChoiceAuto, ToolChoiceAny)
+		}
+	}
+	return json.Marshal(req)
+}
+
+// mistralMessages converts one Genkit message. Tool responses become separate
+// "tool" role messages, as Mistral expects one message per tool result.
+func mistralMessages(msg *ai.Message) ([]mistralMessage, error) {
+	var out []mistralMessage
+	var text strings.Builder
+	var calls []mistralToolCall
+	for _, part := range msg.Content {
+		if part == nil {
+			continue
+		}
+		switch {
+		case part.IsText():
+			text.WriteString(part.Text)
+		case part.IsToolRequest() && part.ToolRequest != nil:
+			args, err := json.Marshal(part.ToolRequest.Input)
+			if err != nil {
+				return nil, fmt.Errorf("bedrock: marshal tool request input: %w", err)
+			}
+			calls = append(calls, mistralToolCall{
+				ID:       part.ToolRequest.Ref,
+				Type:     "function",
+				Function: mistralFunctionCall{Name: part.ToolRequest.Name, Arguments: string(args)},
+			})
+		case part.IsToolResponse() && part.ToolResponse != nil:
+			content, err := toolResponseText(part.ToolResponse.Output)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, mistralMessage{
+				Role:       "tool",
+				Content:    content,
+				ToolCallID: part.ToolResponse.Ref,
+				Name:       part.ToolResponse.Name,
+			})
+		case part.IsMedia():
+			return nil, errors.New("bedrock: Mistral InvokeModel models do not support media input")
+		}
+	}
+	if text.Len() == 0 && len(calls) == 0 {
+		return out, nil
+	}
+	role := "user"
+	switch msg.Role {
+	case ai.RoleSystem:
+		role = "system"
+	case ai.RoleModel:
+		role = "assistant"
+	}
+	return append([]mistralMessage{{Role: role, Content: text.String(), ToolCalls: calls}}, out...), nil
+}
+
+func (mistralChatCodec) ParseResponse(body []byte, input *ai.ModelRequest) (*ai.ModelResponse, error) {
+	var resp mistralResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("bedrock: failed to unmarshal Mistral response: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return nil, errors.New("bedrock: Mistral response has no choices")
+	}
+	choice := resp.Choices[0]
+
+	var tools []*ai.ToolDefinition
+	if input != nil {
+		tools = input.Tools
+	}
+	var parts []*ai.Part
+	if choice.Message.Content != "" {
+		parts = append(parts, ai.NewTextPart(choice.Message.Content))
+	}
+	for _, call := range choice.Message.ToolCalls {
+		args, err := decodeToolInput(call.Function.Arguments)
+		if err != nil {
+			return nil, fmt.Errorf("bedrock: decode Mistral tool call %q arguments: %w", call.Function.Name, err)
+		}
+		if argMap, ok := args.(map[string]any); ok {
+			args = convertToolInputTypes(argMap, call.Function.Name, tools)
+		}
+		parts = append(parts, ai.NewToolRequestPart(&ai.ToolRequest{
+			Name:  call.Function.Name,
+			Input: args,
+			Ref:   call.ID,
+		}))
+	}
+	if len(parts) == 0 {
+		parts = append(parts, ai.NewTextPart(""))
+	}
+
+	out := &ai.ModelResponse{
+		Message:      &ai.Message{Role: ai.RoleModel, Content: parts},
+		FinishReason: mistralFinishReason(choice.StopReason),
+	}
+	if choice.StopReason == "tool_calls" {
+		setStopReason(out, types.StopReasonToolUse)
+	}
+	return out, nil
+}
+
+func mistralFinishReason(reason string) ai.FinishReason {
+	switch reason {
+	case "stop", "tool_calls":
+		return ai.FinishReasonStop
+	case "length", "model_length":
+		return ai.FinishReasonLength
+	default:
+		return ai.FinishReasonOther
+	}
+}
@@ -0,0 +1,694 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+func weatherToolRequest() *ai.ModelRequest {
+	return &ai.ModelRequest{
+		Messages: []*ai.Message{
+			{Role: ai.RoleSystem, Content: []*ai.Part{ai.NewTextPart("Be brief.")}},
+			{Role: ai.RoleUser, Content: []*ai.Part{ai.NewTextPart("Weather in Paris?")}},
+			{Role: ai.RoleModel, Content: []*ai.Part{ai.NewToolRequestPart(&ai.ToolRequest{
+				Name: "get_weather", Ref: "call00001", Input: map[string]any{"city": "Paris"},
+			})}},
+			{Role: ai.RoleTool, Content: []*ai.Part{ai.NewToolResponsePart(&ai.ToolResponse{
+				Name: "get_weather", Ref: "call00001", Output: map[string]any{"temp": 21},
+			})}},
+		},
+		Tools: []*ai.ToolDefinition{{
+			Name:        "get_weather",
+			Description: "Get the weather",
+			InputSchema: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"days": map[string]any{"type": "integer"}},
+			},
+		}},
+		Config: &Config{MaxTokens: 256, ToolChoice: ToolChoiceAny},
+	}
+}
+
+func TestMistralCodec_BuildRequestWithTools(t *testing.T) {
+	body, err := mistralChatCodec{}.BuildRequest("mistral.mistral-large-2407-v1:0", weatherToolRequest(), &Config{MaxTokens: 256, ToolChoice: ToolChoiceAny})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if got["tool_choice"] != "any" {
+		t.Errorf("tool_choice = %v, want any", got["tool_choice"])
+	}
+	if got["max_tokens"] != float64(256) {
+		t.Errorf("max_tokens = %v, want 256", got["max_tokens"])
+	}
+	tools := got["tools"].([]any)
+	fn := tools[0].(map[string]any)["function"].(map[string]any)
+	if fn["name"] != "get_weather" || fn["description"] != "Get the weather" {
+		t.Errorf("tool function = %v", fn)
+	}
+	if _, ok := fn["parameters"].(map[string]any)["properties"]; !ok {
+		t.Errorf("tool parameters = %v, want schema", fn["parameters"])
+	}
+
+	msgs := got["messages"].([]any)
+	if len(msgs) != 4 {
+		t.Fatalf("len(messages) = %d, want 4: %v", len(msgs), msgs)
+	}
+	wantRoles := []string{"system", "user", "assistant", "tool"}
+	for i, want := range wantRoles {
+		if role := msgs[i].(map[string]any)["role"]; role != want {
+			t.Errorf("messages[%d].role = %v, want %s", i, role, want)
+		}
+	}
+	call := msgs[2].(map[string]any)["tool_calls"].([]any)[0].(map[string]any)
+	if call["id"] != "call00001" {
+		t.Errorf("tool call id = %v", call["id"])
+	}
+	if args := call["function"].(map[string]any)["arguments"]; args != `{"city":"Paris"}` {
+		t.Errorf("tool call arguments = %v", args)
+	}
+	toolMsg := msgs[3].(map[string]any)
+	if toolMsg["tool_call_id"] != "call00001" || toolMsg["content"] != `{"temp":21}` {
+		t.Errorf("tool message = %v", toolMsg)
+	}
+}
+
+func TestMistralCodec_BuildRequestRejectsNamedToolChoice(t *testing.T) {
+	req := weatherToolRequest()
+	_, err := mistralChatCodec{}.BuildRequest("mistral.mistral-large-2407-v1:0", req, &Config{ToolChoice: "get_weather"})
+	if err == nil || !strings.Contains(err.Error(), "specific tool") {
+		t.Fatalf("error = %v, want named tool choice error", err)
+	}
+}
+
+func TestMistralCodec_ParseToolCalls(t *testing.T) {
+	body := []byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"Checking.","tool_calls":[
+		{"id":"abc123def","function":{"name":"get_weather","arguments":"{\"city\":\"Paris\",\"days\":\"3\"}"}}
+	]},"stop_reason":"tool_calls"}]}`)
+
+	resp, err := mistralChatCodec{}.ParseResponse(body, weatherToolRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.FinishReason != ai.FinishReasonStop || !StoppedForToolUse(resp) {
+		t.Errorf("FinishReason = %q, StopReason = %q; want stop, tool_use", resp.FinishReason, StopReason(resp))
+	}
+	if len(resp.Message.Content) != 2 {
+		t.Fatalf("len(content) = %d, want 2", len(resp.Message.Content))
+	}
+	if resp.Message.Content[0].Text != "Checking." {
+		t.Errorf("text = %q", resp.Message.Content[0].Text)
+	}
+	tr := resp.Message.Content[1].ToolRequest
+	if tr == nil || tr.Name != "get_weather" || tr.Ref != "abc123def" {
+		t.Fatalf("tool request = %+v", tr)
+	}
+	input := tr.Input.(map[string]any)
+	if input["city"] != "Paris" {
+		t.Errorf("city = %v, want Paris", input["city"])
+	}
+	if input["days"] != int64(3) {
+		t.Errorf("days = %#v, want int64(3) after schema conversion", input["days"])
+	}
+}
+
+func TestMistralCodec_ParseErrors(t *testing.T) {
+	if _, err := (mistralChatCodec{}).ParseResponse([]byte(`{"choices":[]}`), nil); err == nil {
+		t.Fatal("expected error for empty choices")
+	}
+	if _, err := (mistralChatCodec{}).ParseResponse([]byte(`not json`), nil); err == nil {
+		t.Fatal("expected error for malformed body")
+	}
+}
+
+func TestMistralInstructPrompt(t *testing.T) {
+	tests := []struct {
+		name     string
+		messages []*ai.Message
+		want     string
+	}{
+		{
+			name:     "single turn",
+			messages: []*ai.Message{ai.NewUserTextMessage("Hi")},
+			want:     "<s>[INST] Hi [/INST]",
+		},
+		{
+			name: "system and history",
+			messages: []*ai.Message{
+				ai.NewSystemTextMessage("Answer in French."),
+				ai.NewUserTextMessage("Hi"),
+				ai.NewModelTextMessage("Bonjour"),
+				ai.NewUserTextMessage("Thanks"),
+			},
+			want: "<s>[INST] Answer in French.\n\nHi [/INST] Bonjour</s>[INST] Thanks [/INST]",
+		},
+		{
+			name: "consecutive users and prefill",
+			messages: []*ai.Message{
+				ai.NewUserTextMessage("One"),
+				ai.NewUserTextMessage("Two"),
+				ai.NewModelTextMessage("{"),
+			},
+			want: "<s>[INST] One\n\nTwo [/INST] {",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mistralInstructPrompt(tt.messages)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("prompt = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMistralInstructPrompt_Errors(t *testing.T) {
+	if _, err := mistralInstructPrompt([]*ai.Message{ai.NewModelTextMessage("Hi")}); err == nil {
+		t.Error("expected error for prompt starting with a model message")
+	}
+	toolTurn := &ai.Message{Role: ai.RoleModel, Content: []*ai.Part{ai.NewToolRequestPart(&ai.ToolRequest{Name: "get_weather"})}}
+	if _, err := mistralInstructPrompt([]*ai.Message{ai.NewUserTextMessage("Hi"), toolTurn}); err == nil || !strings.Contains(err.Error(), "tool use") {
+		t.Errorf("error = %v, want tool use error", err)
+	}
+}
+
+func TestGenerateText_RoutesMistralInstructThroughInvokeModel(t *testing.T) {
+	var gotPath string
+	var gotBody map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.EscapedPath()
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &gotBody)
+		w.Header().Set("Content-Type", "application/json")
+		// Wire format of mistral.mistral-7b-instruct and mixtral-8x7b-instruct.
+		_, _ = fmt.Fprint(w, `{"outputs":[{"text":" Bonjour","stop_reason":"length"}]}`)
+	}))
+	defer server.Close()
+	b := newTestBedrock(server)
+
+	var chunks int
+	resp, err := b.generateText(context.Background(), "mistral.mistral-7b-instruct-v0:2", &ai.ModelRequest{
+		Messages: []*ai.Message{
+			ai.NewSystemTextMessage("Answer in French."),
+			{Role: ai.RoleUser, Content: []*ai.Part{ai.NewTextPart("Hi")}},
+		},
+		Config: &Config{MaxTokens: 64},
+	}, func(context.Context, *ai.ModelResponseChunk) error {
+		chunks++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(gotPath, "/invoke") {
+		t.Errorf("path = %q, want InvokeModel", gotPath)
+	}
+	if gotBody["prompt"] != "<s>[INST] Answer in French.\n\nHi [/INST]" || gotBody["max_tokens"] != float64(64) {
+		t.Errorf("request body = %v, want instruct prompt and max_tokens", gotBody)
+	}
+	if _, ok := gotBody["messages"]; ok {
+		t.Errorf("request body = %v, must not use the chat schema", gotBody)
+	}
+	if resp.Text() != "Bonjour" || resp.FinishReason != ai.FinishReasonLength {
+		t.Errorf("resp = %q/%q, want Bonjour/length", resp.Text(), resp.FinishReason)
+	}
+	if chunks != 1 {
+		t.Errorf("chunks = %d, want 1", chunks)
+	}
+}
+
+func TestMistralInstructCodec_ParseErrors(t *testing.T) {
+	if _, err := (mistralInstructCodec{}).ParseResponse([]byte(`{"outputs":[]}`), nil); err == nil {
+		t.Error("expected error for empty outputs")
+	}
+	if _, err := (mistralInstructCodec{}).ParseResponse([]byte(`{"choices":[{"message":{"content":"x"}}]}`), nil); err == nil {
+		t.Error("expected error for a chat-schema response")
+	}
+}
+
+func TestMistralChatCodec_RegisteredForMistralLarge(t *testing.T) {
+	RegisterProviderCodec("mistral.mistral-large", MistralChatCodec())
+	t.Cleanup(func() { RegisterProviderCodec("mistral.mistral-large", nil) })
+
+	var gotBody map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &gotBody)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"choices":[{"index":0,"message":{"role":"assistant","content":"","tool_calls":[{"id":"abc123xyz","function":{"name":"get_weather","arguments":"{\"location\":\"Paris\"}"}}]},"stop_reason":"tool_calls"}]}`)
+	}))
+	defer server.Close()
+	b := newTestBedrock(server)
+
+	resp, err := b.generateText(context.Background(), "mistral.mistral-large-2407-v1:0", weatherToolRequest(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := gotBody["tools"]; !ok {
+		t.Errorf("request body = %v, want tools", gotBody)
+	}
+	if reqs := resp.ToolRequests(); len(reqs) != 1 || reqs[0].ToolRequest.Name != "get_weather" || reqs[0].ToolRequest.Ref != "abc123xyz" {
+		t.Errorf("tool requests = %+v", reqs)
+	}
+}
+
+func TestInvokeCodecFor(t *testing.T) {
+	if _, ok := invokeCodecFor("mistral.mixtral-8x7b-instruct-v0:1"); !ok {
+		t.Error("Mixtral should route through InvokeModel")
+	}
+	if _, ok := invokeCodecFor("mistral.mistral-large-2407-v1:0"); ok {
+		t.Error("Mistral Large should stay on Converse")
+	}
+	if _, ok := invokeCodecFor("cohere.command-light-text-v14"); !ok {
+		t.Error("Cohere Command Light should route through InvokeModel")
+	}
+	if _, ok := invokeCodecFor("cohere.command-r-plus-v1:0"); ok {
+		t.Error("Cohere Command R+ should stay on Converse")
+	}
+	if _, ok := invokeCodecFor("anthropic.claude-3-haiku-20240307-v1:0"); ok {
+		t.Error("Claude should stay on Converse")
+	}
+}
+
+func TestAPIModes_ForcePath(t *testing.T) {
+	var gotPaths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPaths = append(gotPaths, r.URL.EscapedPath())
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/invoke") {
+			_, _ = fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"hi"},"stop_reason":"stop"}]}`)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"output":{"message":{"role":"assistant","content":[{"text":"hi"}]}},"stopReason":"end_turn"}`)
+	}))
+	defer server.Close()
+	b := newTestBedrock(server)
+	b.APIModes = map[string]APIMode{
+		"mistral.mixtral-8x7b-instruct-v0:1": APIModeConverse,
+		"mistral.mistral-large-2407-v1:0":    APIModeInvoke,
+	}
+
+	for _, tc := range []struct {
+		model, wantSuffix string
+	}{
+		{"mistral.mixtral-8x7b-instruct-v0:1", "/converse"},
+		{"us.mistral.mistral-large-2407-v1:0", "/invoke"},
+	} {
+		gotPaths = nil
+		resp, err := b.generateText(context.Background(), tc.model, &ai.ModelRequest{
+			Messages: []*ai.Message{ai.NewUserTextMessage("hello")},
+		}, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.model, err)
+		}
+		if len(gotPaths) != 1 || !strings.HasSuffix(gotPaths[0], tc.wantSuffix) {
+			t.Errorf("%s: paths = %v, want one call ending in %s", tc.model, gotPaths, tc.wantSuffix)
+		}
+		if resp.Text() != "hi" {
+			t.Errorf("%s: text = %q, want hi", tc.model, resp.Text())
+		}
+	}
+
+	b.APIModes["anthropic.claude-3-haiku-20240307-v1:0"] = APIModeInvoke
+	gotPaths = nil
+	_, err := b.generateText(context.Background(), "anthropic.claude-3-haiku-20240307-v1:0", &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("hello")},
+	}, nil)
+	if err == nil || !strings.Contains(err.Error(), "no ProviderCodec") {
+		t.Errorf("forced invoke without codec: error = %v", err)
+	}
+	if len(gotPaths) != 0 {
+		t.Errorf("forced invoke without codec called Bedrock: %v", gotPaths)
+	}
+}
+
+func TestInitPanicsOnUnknownAPIMode(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), "unknown APIModes") {
+			t.Errorf("recover() = %v, want unknown APIModes panic", r)
+		}
+	}()
+	b := &Bedrock{Region: "us-east-1", APIModes: map[string]APIMode{"amazon.nova-lite-v1:0": "rest"}}
+	b.Init(context.Background())
+}
+
+// echoCodec is a minimal custom provider codec for a fictional model family.
+type echoCodec struct{}
+
+func (echoCodec) BuildRequest(modelID string, req *ai.ModelRequest, cfg *Config) ([]byte, error) {
+	return json.Marshal(map[string]any{"prompt": req.Messages[0].Text(), "model": modelID})
+}
+
+func (echoCodec) ParseResponse(body []byte, req *ai.ModelRequest) (*ai.ModelResponse, error) {
+	var out struct {
+		Completion string `json:"completion"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil {
+		return nil, err
+	}
+	return &ai.ModelResponse{
+		Message:      ai.NewModelTextMessage(out.Completion),
+		FinishReason: ai.FinishReasonStop,
+	}, nil
+}
+
+func TestRegisterProviderCodec_RoutesCustomProvider(t *testing.T) {
+	RegisterProviderCodec("acme.echo", echoCodec{})
+	t.Cleanup(func() { RegisterProviderCodec("acme.echo", nil) })
+
+	var gotPath string
+	var gotBody map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.EscapedPath()
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &gotBody)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"completion":"echo: hello"}`)
+	}))
+	defer server.Close()
+	b := newTestBedrock(server)
+
+	resp, err := b.generateText(context.Background(), "us.acme.echo-v1:0", &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("hello")},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(gotPath, "/invoke") {
+		t.Errorf("path = %q, want InvokeModel", gotPath)
+	}
+	if gotBody["prompt"] != "hello" || gotBody["model"] != "us.acme.echo-v1:0" {
+		t.Errorf("request body = %v, want codec-built body", gotBody)
+	}
+	if resp.Text() != "echo: hello" || resp.Request == nil {
+		t.Errorf("resp = %q (request %v), want codec-parsed response", resp.Text(), resp.Request)
+	}
+}
+
+func TestRegisterProviderCodec_LongestPrefixWinsAndRemoval(t *testing.T) {
+	RegisterProviderCodec("mistral.", echoCodec{})
+	t.Cleanup(func() { RegisterProviderCodec("mistral.", nil) })
+
+	if codec, _ := invokeCodecFor("mistral.mistral-7b-instruct-v0:2"); codec != (mistralInstructCodec{}) {
+		t.Errorf("codec = %T, want the more specific built-in mistralInstructCodec", codec)
+	}
+	if codec, _ := invokeCodecFor("mistral.mistral-large-2407-v1:0"); codec != (echoCodec{}) {
+		t.Errorf("codec = %T, want echoCodec for the broader prefix", codec)
+	}
+
+	RegisterProviderCodec("mistral.", nil)
+	if _, ok := invokeCodecFor("mistral.mistral-large-2407-v1:0"); ok {
+		t.Error("removed prefix still routes through InvokeModel")
+	}
+}
+
+func TestGenerateText_InvokeModelValidatesRequest(t *testing.T) {
+	var calls int
+	var gotBody map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &gotBody)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"outputs":[{"text":"ok","stop_reason":"stop"}]}`)
+	}))
+	defer server.Close()
+
+	const model = "mistral.mixtral-8x7b-instruct-v0:1"
+	hi := []*ai.Message{ai.NewUserTextMessage("Hi")}
+	tests := []struct {
+		name    string
+		req     *ai.ModelRequest
+		wantErr string
+	}{
+		{"maxTokens over limit", &ai.ModelRequest{Messages: hi, Config: &Config{MaxTokens: 10000}}, "output limit"},
+		{"tools", weatherToolRequest(), "does not support tool use"},
+		{"guardrail", &ai.ModelRequest{Messages: hi, Config: &Config{Guardrail: &GuardrailConfig{Identifier: "gr", Version: "1"}}}, "does not support guardrail"},
+		{"citations", &ai.ModelRequest{Messages: hi, Config: &Config{Citations: true}}, "does not support citations"},
+		{"stop sequences over limit", &ai.ModelRequest{Messages: hi, Config: &Config{StopSequences: strings.Split(strings.Repeat("x", 11), "")}}, "at most 10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newTestBedrock(server).generateText(context.Background(), model, tt.req, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+	if calls != 0 {
+		t.Fatalf("invalid requests reached Bedrock %d times", calls)
+	}
+
+	b := newTestBedrock(server)
+	b.ClampMaxTokens = true
+	if _, err := b.generateText(context.Background(), model, &ai.ModelRequest{Messages: hi, Config: &Config{MaxTokens: 10000}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if gotBody["max_tokens"] != float64(4096) {
+		t.Errorf("max_tokens = %v, want clamped 4096", gotBody["max_tokens"])
+	}
+}
+
+func TestTitanTextCodec_BuildRequest(t *testing.T) {
+	temp, topP := float32(0.2), float32(0.9)
+	body, err := titanTextCodec{}.BuildRequest("amazon.titan-text-express-v1", &ai.ModelRequest{
+		Messages: []*ai.Message{
+			ai.NewSystemTextMessage("Be brief."),
+			ai.NewUserTextMessage("Hi"),
+			ai.NewModelTextMessage("Hello!"),
+			ai.NewUserTextMessage("Name a color."),
+		},
+	}, &Config{MaxTokens: 100, Temperature: &temp, TopP: &topP, StopSequences: []string{"User:"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatal(err)
+	}
+	wantPrompt := "Be brief.\n\nUser: Hi\nBot: Hello!\nUser: Name a color.\nBot:"
+	if got["inputText"] != wantPrompt {
+		t.Errorf("inputText = %q, want %q", got["inputText"], wantPrompt)
+	}
+	cfg, _ := got["textGenerationConfig"].(map[string]any)
+	if cfg["maxTokenCount"] != float64(100) || fmt.Sprint(cfg["temperature"]) != "0.2" || fmt.Sprint(cfg["topP"]) != "0.9" || fmt.Sprint(cfg["stopSequences"]) != "[User:]" {
+		t.Errorf("textGenerationConfig = %v", cfg)
+	}
+
+	body, err = titanTextCodec{}.BuildRequest("amazon.titan-text-lite-v1", &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("Hi")},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != `{"inputText":"User: Hi\nBot:"}` {
+		t.Errorf("body = %s, want no textGenerationConfig", body)
+	}
+
+	if _, err := (titanTextCodec{}).BuildRequest("amazon.titan-text-lite-v1", &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("Hi"), ai.NewModelTextMessage("Hello")},
+	}, nil); err == nil {
+		t.Error("expected error for a prompt ending with a model message")
+	}
+}
+
+func TestGenerateText_RoutesTitanTextThroughInvokeModel(t *testing.T) {
+	var gotPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.EscapedPath()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"inputTextTokenCount":5,"results":[{"tokenCount":2,"outputText":" Blue.","completionReason":"FINISH"}]}`)
+	}))
+	defer server.Close()
+
+	resp, err := newTestBedrock(server).generateText(context.Background(), "amazon.titan-text-express-v1", &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("Name a color.")},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(gotPath, "amazon.titan-text-express-v1/invoke") {
+		t.Errorf("path = %q, want InvokeModel", gotPath)
+	}
+	if resp.Text() != "Blue." || resp.FinishReason != ai.FinishReasonStop {
+		t.Errorf("resp = %q/%q, want Blue./stop", resp.Text(), resp.FinishReason)
+	}
+	if resp.Usage == nil || resp.Usage.InputTokens != 5 || resp.Usage.OutputTokens != 2 || resp.Usage.TotalTokens != 7 {
+		t.Errorf("usage = %+v", resp.Usage)
+	}
+}
+
+func TestTitanFinishReason(t *testing.T) {
+	for reason, want := range map[string]ai.FinishReason{
+		"FINISH":           ai.FinishReasonStop,
+		"LENGTH":           ai.FinishReasonLength,
+		"CONTENT_FILTERED": ai.FinishReasonBlocked,
+		"SOMETHING_NEW":    ai.FinishReasonOther,
+	} {
+		if got := titanFinishReason(reason); got != want {
+			t.Errorf("titanFinishReason(%q) = %q, want %q", reason, got, want)
+		}
+	}
+}
+
+func TestCohereCommandCodec_BuildRequest(t *testing.T) {
+	temp, topP, topK := float32(0.5), float32(0.75), 40
+	body, err := cohereCommandCodec{}.BuildRequest("cohere.command-text-v14", &ai.ModelRequest{
+		Messages: []*ai.Message{
+			{Role: ai.RoleSystem, Content: []*ai.Part{ai.NewTextPart("Be brief.")}},
+			ai.NewUserTextMessage("Name a color."),
+		},
+	}, &Config{MaxTokens: 20, Temperature: &temp, TopP: &topP, TopK: &topK, StopSequences: []string{"\n\n"}, Logprobs: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"prompt":             "Be brief.\n\nUser: Name a color.\nChatbot:",
+		"max_tokens":         float64(20),
+		"temperature":        0.5,
+		"p":                  0.75,
+		"k":                  float64(40),
+		"stop_sequences":     []any{"\n\n"},
+		"return_likelihoods": "GENERATION",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("request = %#v\nwant %#v", got, want)
+	}
+
+	body, err = cohereCommandCodec{}.BuildRequest("cohere.command-text-v14", &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("Hi")},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != `{"prompt":"User: Hi\nChatbot:"}` {
+		t.Errorf("request without config = %s", body)
+	}
+}
+
+func TestGenerateText_CohereCommandLogprobs(t *testing.T) {
+	var gotPath string
+	var gotBody map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.EscapedPath()
+		if err := json.NewDecoder(r.Body).Decode(&gotBody); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"id":"g1","generations":[{"id":"x","text":" Blue.","finish_reason":"COMPLETE",
+			"token_likelihoods":[{"token":" Blue","likelihood":-0.25},{"token":".","likelihood":-1.5}]}]}`)
+	}))
+	defer server.Close()
+
+	resp, err := newTestBedrock(server).generateText(context.Background(), "cohere.command-text-v14", &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("Name a color.")},
+		Config:   &Config{Logprobs: true},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(gotPath, "cohere.command-text-v14/invoke") {
+		t.Errorf("path = %q, want InvokeModel", gotPath)
+	}
+	if gotBody["return_likelihoods"] != "GENERATION" {
+		t.Errorf("return_likelihoods = %v, want GENERATION", gotBody["return_likelihoods"])
+	}
+	if resp.Text() != "Blue." || resp.FinishReason != ai.FinishReasonStop {
+		t.Errorf("resp = %q/%q, want Blue./stop", resp.Text(), resp.FinishReason)
+	}
+	want := []TokenLogprob{{Token: " Blue", Logprob: -0.25}, {Token: ".", Logprob: -1.5}}
+	if got := Logprobs(resp); !reflect.DeepEqual(got, want) {
+		t.Errorf("Logprobs() = %+v, want %+v", got, want)
+	}
+
+	// Metadata that went through JSON decodes to the same values.
+	raw, err := json.Marshal(resp.Message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg ai.Message
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if got := Logprobs(&ai.ModelResponse{Message: &msg}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Logprobs() after round trip = %+v, want %+v", got, want)
+	}
+	if Logprobs(&ai.ModelResponse{Message: ai.NewModelTextMessage("x")}) != nil {
+		t.Error("Logprobs() non-nil without logprobs")
+	}
+}
+
+func TestGenerateText_LogprobsUnsupported(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer server.Close()
+
+	for _, model := range []string{
+		"anthropic.claude-3-haiku-20240307-v1:0", // Converse
+		"amazon.titan-text-express-v1",
+		"mistral.mixtral-8x7b-instruct-v0:1",
+	} {
+		_, err := newTestBedrock(server).generateText(context.Background(), model, &ai.ModelRequest{
+			Messages: []*ai.Message{ai.NewUserTextMessage("Hi")},
+			Config:   &Config{Logprobs: true},
+		}, nil)
+		if err == nil || !strings.Contains(err.Error(), "does not return logprobs") {
+			t.Errorf("%s: error = %v, want logprobs unsupported", model, err)
+		}
+	}
+	if calls != 0 {
+		t.Errorf("unsupported requests reached Bedrock %d times", calls)
+	}
+}
+
+func TestCohereFinishReason(t *testing.T) {
+	for reason, want := range map[string]ai.FinishReason{
+		"COMPLETE":    ai.FinishReasonStop,
+		"MAX_TOKENS":  ai.FinishReasonLength,
+		"ERROR_TOXIC": ai.FinishReasonBlocked,
+		"ERROR":       ai.FinishReasonOther,
+	} {
+		if got := cohereFinishReason(reason); got != want {
+			t.Errorf("cohereFinishReason(%q) = %q, want %q", reason, got, want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+// titanTextCodec speaks the Amazon Titan Text InvokeModel format: the
+// conversation rendered as "User:"/"Bot:" turns in "inputText", sampling
+// options in "textGenerationConfig", and "results" out. Titan Text has no
+// tool use, media input or system role; system messages lead the prompt.
+type titanTextCodec struct{}
+
+type titanTextRequest struct {
+	InputText            string                     `json:"inputText"`
+	TextGenerationConfig *titanTextGenerationConfig `json:"textGenerationConfig,omitempty"`
+}
+
+type titanTextGenerationConfig struct {
+	MaxTokenCount int      `json:"maxTokenCount,omitempty"`
+	Temperature   *float32 `json:"temperature,omitempty"`
+	TopP          *float32 `json:"topP,omitempty"`
+	StopSequences []string `json:"stopSequences,omitempty"`
+}
+
+type titanTextResponse struct {
+	InputTextTokenCount int `json:"inputTextTokenCount"`
+	Results             []struct {
+		TokenCount       int    `json:"tokenCount"`
+		OutputText       string `json:"outputText"`
+		CompletionReason string `json:"completionReason"`
+	} `json:"results"`
+}
+
+func (titanTextCodec) BuildRequest(modelName string, input *ai.ModelRequest, cfg *Config) ([]byte, error) {
+	if cfg != nil && cfg.Logprobs {
+		return nil, errLogprobsUnsupported(modelName)
+	}
+	prompt, err := titanTextPrompt(input.Messages)
+	if err != nil {
+		return nil, err
+	}
+	req := titanTextRequest{InputText: prompt}
+	if cfg != nil && (cfg.MaxTokens > 0 || cfg.Temperature != nil || cfg.TopP != nil || len(cfg.StopSequences) > 0) {
+		req.TextGenerationConfig = &titanTextGenerationConfig{
+			MaxTokenCount: cfg.MaxTokens,
+			Temperature:   cfg.Temperature,
+			TopP:          cfg.TopP,
+			StopSequences: cfg.StopSequences,
+		}
+	}
+	return json.Marshal(req)
+}
+
+// titanTextPrompt renders messages in the conversational format Titan Text
+// is tuned for, ending with "Bot:" so the model answers the last user turn:
+//
+//	system
+//
+//	User: question
+//	Bot: answer
+//	User: question
+//	Bot:
+func titanTextPrompt(messages []*ai.Message) (string, error) {
+	return transcriptPrompt("Titan Text", "Bot:", messages)
+}
+
+// transcriptPrompt renders messages as "User:" and botLabel turns for
+// text-completion models, with system text leading the prompt. family names
+// the models in errors; they have no tool use or media input.
+func transcriptPrompt(family, botLabel string, messages []*ai.Message) (string, error) {
+	var system []string
+	var turns []string
+	lastRole := ai.Role("")
+	for _, msg := range messages {
+		if msg == nil {
+			continue
+		}
+		var text strings.Builder
+		for _, part := range msg.Content {
+			if part == nil {
+				continue
+			}
+			switch {
+			case part.IsText():
+				text.WriteString(part.Text)
+			case part.IsToolRequest(), part.IsToolResponse():
+				return "", fmt.Errorf("bedrock: %s models do not support tool use", family)
+			case part.IsMedia():
+				return "", fmt.Errorf("bedrock: %s models do not support media input", family)
+			}
+		}
+		switch msg.Role {
+		case ai.RoleSystem:
+			system = append(system, text.String())
+		case ai.RoleModel:
+			turns = append(turns, botLabel+" "+text.String())
+			lastRole = ai.RoleModel
+		default:
+			turns = append(turns, "User: "+text.String())
+			lastRole = ai.RoleUser
+		}
+	}
+	if lastRole != ai.RoleUser {
+		return "", fmt.Errorf("bedrock: %s prompts must end with a user message", family)
+	}
+	prompt := strings.Join(turns, "\n") + "\n" + botLabel
+	if len(system) > 0 {
+		prompt = strings.Join(system, "\n\n") + "\n\n" + prompt
+	}
+	return prompt, nil
+}
+
+func (titanTextCodec) ParseResponse(body []byte, input *ai.ModelRequest) (*ai.ModelResponse, error) {
+	var resp titanTextResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("bedrock: failed to unmarshal Titan Text response: %w", err)
+	}
+	if len(resp.Results) == 0 {
+		return nil, errors.New("bedrock: Titan Text response has no results")
+	}
+	result := resp.Results[0]
+	return &ai.ModelResponse{
+		Message:      ai.NewModelTextMessage(strings.TrimSpace(result.OutputText)),
+		FinishReason: titanFinishReason(result.CompletionReason),
+		Usage: &ai.GenerationUsage{
+			InputTokens:  resp.InputTextTokenCount,
+			OutputTokens: result.TokenCount,
+			TotalTokens:  resp.InputTextTokenCount + result.TokenCount,
+		},
+	}, nil
+}
+
+func titanFinishReason(reason string) ai.FinishReason {
+	switch reason {
+	case "FINISH", "STOP_CRITERIA_MET":
+		return ai.FinishReasonStop
+	case "LENGTH":
+		return ai.FinishReasonLength
+	case "CONTENT_FILTERED", "RAG_QUERY_WHEN_RAG_DISABLED":
+		return ai.FinishReasonBlocked
+	default:
+		return ai.FinishReasonOther
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// JSONValidationError reports model output that is not valid JSON or does not
+// match the schema requested in [ai.ModelRequest.Output].
+type JSONValidationError struct {
+	Text   string   // The model's raw output text
+	Errors []string // One entry per parse or schema violation
+}
+
+func (e *JSONValidationError) Error() string {
+	return "bedrock: model output does not match the requested JSON schema: " + strings.Join(e.Errors, "; ")
+}
+
+// validatesJSONOutput reports whether input asks for schema-shaped JSON that
+// the plugin is configured to check.
+func (b *Bedrock) validatesJSONOutput(input *ai.ModelRequest) bool {
+	return (b.ValidateJSONOutput || b.RepromptInvalidJSON) &&
+		input != nil && input.Output != nil &&
+		input.Output.Format == "json" && len(input.Output.Schema) > 0
+}
+
+// checkJSONOutput validates resp against the requested schema. With
+// RepromptInvalidJSON set it makes one corrective non-streaming call that
+// appends the invalid answer and the validation errors to the conversation;
+// the retry's usage is added to the returned response.
+func (b *Bedrock) checkJSONOutput(ctx context.Context, input *ai.ModelRequest, resp *ai.ModelResponse, streaming bool, generate func(context.Context, *ai.ModelRequest) (*ai.ModelResponse, error)) (*ai.ModelResponse, error) {
+	if !isFinalAnswer(resp) {
+		return resp, nil
+	}
+	verr := validateJSONOutput(resp, input.Output.Schema)
+	if verr == nil {
+		return resp, nil
+	}
+	if !b.RepromptInvalidJSON || streaming {
+		return nil, verr
+	}
+
+	retry := *input
+	retry.Messages = append(append([]*ai.Message{}, input.Messages...),
+		resp.Message,
+		ai.NewUserTextMessage(repromptText(verr)),
+	)
+	retryResp, err := generate(ctx, &retry)
+	if err != nil {
+		return nil, err
+	}
+	if !isFinalAnswer(retryResp) {
+		return retryResp, nil
+	}
+	if verr := validateJSONOutput(retryResp, input.Output.Schema); verr != nil {
+		return nil, verr
+	}
+	retryResp.Request = input
+	retryResp.Usage = addUsage(resp.Usage, retryResp.Usage)
+	return retryResp, nil
+}
+
+// isFinalAnswer reports whether resp is a completed answer whose text should
+// match the schema: a turn that requests tools, or that stopped for another
+// reason (length, blocked), is left for the caller to handle.
+func isFinalAnswer(resp *ai.ModelResponse) bool {
+	if resp == nil || resp.Message == nil {
+		return false
+	}
+	if resp.FinishReason != ai.FinishReasonStop {
+		return false
+	}
+	return len(resp.ToolRequests()) == 0
+}
+
+func repromptText(verr *JSONValidationError) string {
+	return "Your previous response was not valid JSON for the requested schema:\n- " +
+		strings.Join(verr.Errors, "\n- ") +
+		"\nReply again with only the corrected JSON and no other text."
+}
+
+// validateJSONOutput checks the text of resp against schema, returning nil
+// when it conforms.
+func validateJSONOutput(resp *ai.ModelResponse, schema map[string]any) *JSONValidationError {
+	var text string
+	if resp != nil {
+		text = resp.Text()
+	}
+	data := extractJSON(text)
+
+	var v any
+	if err := json.Unmarshal([]byte(data), &v); err != nil {
+		return &JSONValidationError{Text: text, Errors: []string{fmt.Sprintf("output is not valid JSON: %v", err)}}
+	}
+	schemaBytes, err := json.Marshal(schema)
+	if err != nil {
+		return &JSONValidationError{Text: text, Errors: []string{fmt.Sprintf("requested schema is not valid JSON: %v", err)}}
+	}
+	result, err := gojsonschema.Validate(gojsonschema.NewBytesLoader(schemaBytes), gojsonschema.NewStringLoader(data))
+	if err != nil {
+		return &JSONValidationError{Text: text, Errors: []string{fmt.Sprintf("validate against schema: %v", err)}}
+	}
+	if result.Valid() {
+		return nil
+	}
+	errs := make([]string, 0, len(result.Errors()))
+	for _, e := range result.Errors() {
+		errs = append(errs, e.String())
+	}
+	return &JSONValidationError{Text: text, Errors: errs}
+}
+
+// extractJSON trims whitespace and a surrounding Markdown code fence, which
+// models often add around JSON answers.
+func extractJSON(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "```") {
+		return text
+	}
+	text = strings.TrimPrefix(text, "```")
+	if nl := strings.IndexByte(text, '\n'); nl >= 0 {
+		text = text[nl+1:] // Drop the info string, e.g. "json"
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), "```"))
+}
+
+// addUsage sums two usage reports; either may be nil.
+func addUsage(a, b *ai.GenerationUsage) *ai.GenerationUsage {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	sum := *b
+	sum.InputTokens += a.InputTokens
+	sum.OutputTokens += a.OutputTokens
+	sum.TotalTokens += a.TotalTokens
+	sum.CachedContentTokens += a.CachedContentTokens
+	if len(a.Custom) > 0 {
+		sum.Custom = make(map[string]float64, len(a.Custom)+len(b.Custom))
+		for k, v := range b.Custom {
+			sum.Custom[k] = v
+		}
+		for k, v := range a.Custom {
+			sum.Custom[k] += v
+		}
+	}
+	return &sum
+}
@@ -0,0 +1,209 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/firebase/genkit/go/ai"
+)
+
+var cityOutput = &ai.ModelOutputConfig{
+	Format: "json",
+	Schema: map[string]any{
+		"type":       "object",
+		"required":   []any{"city", "population"},
+		"properties": map[string]any{"city": map[string]any{"type": "string"}, "population": map[string]any{"type": "integer"}},
+	},
+}
+
+// converseServer answers successive Converse calls with texts in order and
+// records each request's messages.
+func converseServer(t *testing.T, texts ...string) (*Bedrock, *[][]map[string]any) {
+	t.Helper()
+	var calls [][]map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []map[string]any `json:"messages"`
+		}
+		raw, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(raw, &body); err != nil {
+			t.Errorf("unmarshal request body: %v", err)
+		}
+		calls = append(calls, body.Messages)
+		text, _ := json.Marshal(texts[min(len(calls), len(texts))-1])
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{
+			"output":     {"message":{"role":"assistant","content":[{"text":%s}]}},
+			"stopReason": "end_turn",
+			"usage":      {"inputTokens":10,"outputTokens":5,"totalTokens":15}
+		}`, text)
+	}))
+	t.Cleanup(server.Close)
+
+	client := bedrockruntime.NewFromConfig(aws.Config{
+		Region:       "us-east-1",
+		Credentials:  credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+		HTTPClient:   server.Client(),
+		BaseEndpoint: aws.String(server.URL),
+	})
+	return &Bedrock{client: client, initted: true}, &calls
+}
+
+func cityRequest() *ai.ModelRequest {
+	return &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("Largest city in Japan as JSON.")},
+		Output:   cityOutput,
+	}
+}
+
+func TestGenerateText_ValidJSONPassesThrough(t *testing.T) {
+	b, calls := converseServer(t, "```json\n{\"city\": \"Tokyo\", \"population\": 14000000}\n```")
+	b.RepromptInvalidJSON = true
+
+	resp, err := b.generateText(context.Background(), "amazon.nova-lite-v1:0", cityRequest(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*calls) != 1 {
+		t.Errorf("Converse calls = %d, want 1", len(*calls))
+	}
+	if !strings.Contains(resp.Text(), "Tokyo") {
+		t.Errorf("text = %q, want the original answer", resp.Text())
+	}
+}
+
+func TestGenerateText_InvalidJSONReprompts(t *testing.T) {
+	b, calls := converseServer(t,
+		`{"city": "Tokyo", "population": "about 14 million"}`,
+		`{"city": "Tokyo", "population": 14000000}`,
+	)
+	b.RepromptInvalidJSON = true
+
+	req := cityRequest()
+	resp, err := b.generateText(context.Background(), "amazon.nova-lite-v1:0", req, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*calls) != 2 {
+		t.Fatalf("Converse calls = %d, want 2", len(*calls))
+	}
+	retry := (*calls)[1]
+	if len(retry) != 3 || retry[1]["role"] != "assistant" {
+		t.Fatalf("retry messages = %v, want user, invalid assistant answer, correction", retry)
+	}
+	correction, _ := json.Marshal(retry[2])
+	if !strings.Contains(string(correction), "population") {
+		t.Errorf("correction message = %s, want it to name the failing field", correction)
+	}
+	if !strings.Contains(resp.Text(), "14000000") {
+		t.Errorf("text = %q, want the corrected answer", resp.Text())
+	}
+	if resp.Request != req {
+		t.Error("Request should be the caller's original request")
+	}
+	if resp.Usage == nil || resp.Usage.TotalTokens != 30 {
+		t.Errorf("Usage = %+v, want both calls counted (30 tokens)", resp.Usage)
+	}
+}
+
+func TestGenerateText_InvalidJSONWithoutReprompt(t *testing.T) {
+	b, calls := converseServer(t, "Tokyo, roughly 14 million people.")
+	b.ValidateJSONOutput = true
+
+	_, err := b.generateText(context.Background(), "amazon.nova-lite-v1:0", cityRequest(), nil)
+	var verr *JSONValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("error = %v, want *JSONValidationError", err)
+	}
+	if verr.Text != "Tokyo, roughly 14 million people." || !strings.Contains(verr.Errors[0], "not valid JSON") {
+		t.Errorf("validation error = %+v", verr)
+	}
+	if len(*calls) != 1 {
+		t.Errorf("Converse calls = %d, want 1 (reprompt disabled)", len(*calls))
+	}
+}
+
+func TestGenerateText_JSONValidationOffByDefault(t *testing.T) {
+	b, _ := converseServer(t, "not json")
+	if _, err := b.generateText(context.Background(), "amazon.nova-lite-v1:0", cityRequest(), nil); err != nil {
+		t.Fatalf("error = %v, want validation to be opt-in", err)
+	}
+}
+
+func TestGenerateText_JSONValidationSkipsToolTurns(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{
+			"output":     {"message":{"role":"assistant","content":[{"toolUse":{"toolUseId":"t1","name":"lookup_city","input":{"country":"Japan"}}}]}},
+			"stopReason": "tool_use",
+			"usage":      {"inputTokens":10,"outputTokens":5,"totalTokens":15}
+		}`)
+	}))
+	defer server.Close()
+	b := newTestBedrock(server)
+	b.RepromptInvalidJSON = true
+
+	req := cityRequest()
+	req.Tools = []*ai.ToolDefinition{{Name: "lookup_city", InputSchema: map[string]any{"type": "object"}}}
+	resp, err := b.generateText(context.Background(), "amazon.nova-lite-v1:0", req, nil)
+	if err != nil {
+		t.Fatalf("tool turn failed JSON validation: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Converse calls = %d, want 1 (no reprompt for a tool turn)", calls)
+	}
+	if len(resp.ToolRequests()) != 1 {
+		t.Errorf("tool requests = %d, want 1", len(resp.ToolRequests()))
+	}
+}
+
+func TestGenerateText_JSONValidationSkipsTruncatedAnswers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{
+			"output":     {"message":{"role":"assistant","content":[{"text":"{\"city\": \"Tok"}]}},
+			"stopReason": "max_tokens",
+			"usage":      {"inputTokens":10,"outputTokens":5,"totalTokens":15}
+		}`)
+	}))
+	defer server.Close()
+	b := newTestBedrock(server)
+	b.ValidateJSONOutput = true
+
+	resp, err := b.generateText(context.Background(), "amazon.nova-lite-v1:0", cityRequest(), nil)
+	if err != nil {
+		t.Fatalf("truncated answer failed JSON validation: %v", err)
+	}
+	if resp.FinishReason != ai.FinishReasonLength {
+		t.Errorf("finish reason = %q, want length", resp.FinishReason)
+	}
+}
@@ -0,0 +1,403 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/core"
+	"github.com/firebase/genkit/go/core/api"
+	"github.com/firebase/genkit/go/genkit"
+)
+
+// Search types for [KnowledgeBaseOptions.SearchType].
+const (
+	SearchTypeHybrid   = "HYBRID"   // Vector search combined with keyword search
+	SearchTypeSemantic = "SEMANTIC" // Vector search only
+)
+
+// maxKnowledgeBaseResults is the most results a Retrieve call returns.
+const maxKnowledgeBaseResults = 100
+
+// Metadata keys set on documents retrieved from a knowledge base, next to the
+// metadata attributes stored with the source.
+const (
+	knowledgeBaseIDMetadataKey = "knowledgeBaseId" // ID of the knowledge base
+	kbScoreMetadataKey         = "score"           // Relevance score
+	kbSourceMetadataKey        = "source"          // Source URI or URL of the chunk
+	kbNameMetadataKey          = "name"            // Last element of the source, unless the source sets one
+)
+
+// KnowledgeBaseOptions configures a knowledge base retrieval. Pass it via
+// [ai.RetrieverRequest.Options].
+type KnowledgeBaseOptions struct {
+	// NumberOfResults caps the number of chunks returned, at most 100. If 0,
+	// Bedrock returns 5.
+	NumberOfResults int `json:"numberOfResults,omitempty"`
+	// SearchType overrides the knowledge base's search type: SearchTypeHybrid
+	// adds keyword search, which helps keyword-heavy queries, and
+	// SearchTypeSemantic uses vector search only. Hybrid search needs a
+	// vector store with a filterable text field. If "", Bedrock chooses.
+	SearchType string `json:"searchType,omitempty"`
+}
+
+func (o *KnowledgeBaseOptions) validate() error {
+	if o == nil {
+		return nil
+	}
+	if o.NumberOfResults < 0 || o.NumberOfResults > maxKnowledgeBaseResults {
+		return fmt.Errorf("numberOfResults %d out of range [1, %d]", o.NumberOfResults, maxKnowledgeBaseResults)
+	}
+	switch o.SearchType {
+	case "", SearchTypeHybrid, SearchTypeSemantic:
+		return nil
+	}
+	return fmt.Errorf("unknown searchType %q; use %q or %q", o.SearchType, SearchTypeHybrid, SearchTypeSemantic)
+}
+
+// knowledgeBaseClient calls the Bedrock Agents runtime Retrieve operation.
+// The plugin creates one at Init from its AWS config.
+type knowledgeBaseClient interface {
+	Retrieve(ctx context.Context, knowledgeBaseID string, in *kbRetrieveRequest) (*kbRetrieveResponse, error)
+}
+
+type kbRetrieveRequest struct {
+	RetrievalQuery         kbText                    `json:"retrievalQuery"`
+	RetrievalConfiguration *kbRetrievalConfiguration `json:"retrievalConfiguration,omitempty"`
+}
+
+type kbText struct {
+	Text string `json:"text"`
+}
+
+type kbRetrievalConfiguration struct {
+	VectorSearchConfiguration kbVectorSearchConfiguration `json:"vectorSearchConfiguration"`
+}
+
+type kbVectorSearchConfiguration struct {
+	NumberOfResults    int    `json:"numberOfResults,omitempty"`
+	OverrideSearchType string `json:"overrideSearchType,omitempty"`
+}
+
+type kbRetrieveResponse struct {
+	RetrievalResults []kbRetrievalResult `json:"retrievalResults"`
+}
+
+type kbRetrievalResult struct {
+	Content  kbText         `json:"content"`
+	Location map[string]any `json:"location"`
+	Metadata map[string]any `json:"metadata"`
+	Score    float64        `json:"score"`
+}
+
+// newKBRetrieveRequest builds the Retrieve body for query, leaving the
+// retrieval configuration out when opts sets nothing.
+func newKBRetrieveRequest(query string, opts *KnowledgeBaseOptions) *kbRetrieveRequest {
+	req := &kbRetrieveRequest{RetrievalQuery: kbText{Text: query}}
+	if opts != nil && (opts.NumberOfResults > 0 || opts.SearchType != "") {
+		req.RetrievalConfiguration = &kbRetrievalConfiguration{
+			VectorSearchConfiguration: kbVectorSearchConfiguration{
+				NumberOfResults:    opts.NumberOfResults,
+				OverrideSearchType: opts.SearchType,
+			},
+		}
+	}
+	return req
+}
+
+// DefineKnowledgeBaseRetriever defines a retriever, named after
+// knowledgeBaseID, that queries a Bedrock Knowledge Base. Each retrieved
+// chunk is returned as a text document whose metadata holds the source's
+// metadata attributes, the relevance "score", the "source" URI or URL, and
+// the "knowledgeBaseId". Options are passed as [KnowledgeBaseOptions].
+func (b *Bedrock) DefineKnowledgeBaseRetriever(g *genkit.Genkit, knowledgeBaseID string) ai.Retriever {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.initted {
+		panic("bedrock: Init not called")
+	}
+	if strings.TrimSpace(knowledgeBaseID) == "" {
+		panic("bedrock: knowledge base ID required")
+	}
+
+	name := api.NewName(provider, knowledgeBaseID)
+	opts := &ai.RetrieverOptions{
+		Label:        name,
+		ConfigSchema: core.InferSchemaMap(KnowledgeBaseOptions{}),
+	}
+	return genkit.DefineRetriever(g, name, opts, func(ctx context.Context, req *ai.RetrieverRequest) (*ai.RetrieverResponse, error) {
+		kbOpts, err := knowledgeBaseOptions(req.Options)
+		if err != nil {
+			return nil, err
+		}
+		ctx, cancel := b.withRequestTimeout(ctx)
+		defer cancel()
+		docs, err := retrieveKnowledgeBase(ctx, b.knowledgeBases, knowledgeBaseID, documentText(req.Query), kbOpts)
+		if err != nil {
+			return nil, err
+		}
+		return &ai.RetrieverResponse{Documents: docs}, nil
+	})
+}
+
+// retrieveKnowledgeBase queries one knowledge base and converts its results
+// into documents, in the order Bedrock ranked them.
+func retrieveKnowledgeBase(ctx context.Context, client knowledgeBaseClient, knowledgeBaseID, query string, opts *KnowledgeBaseOptions) ([]*ai.Document, error) {
+	if client == nil {
+		return nil, errors.New("bedrock: knowledge base client required")
+	}
+	if query == "" {
+		return nil, fmt.Errorf("bedrock: knowledge base %q: query has no text content", knowledgeBaseID)
+	}
+	if err := opts.validate(); err != nil {
+		return nil, fmt.Errorf("bedrock: knowledge base %q: %w", knowledgeBaseID, err)
+	}
+
+	resp, err := client.Retrieve(ctx, knowledgeBaseID, newKBRetrieveRequest(query, opts))
+	if err != nil {
+		return nil, fmt.Errorf("bedrock: knowledge base %q: retrieve: %w", knowledgeBaseID, err)
+	}
+	docs := make([]*ai.Document, 0, len(resp.RetrievalResults))
+	for _, result := range resp.RetrievalResults {
+		if result.Content.Text == "" {
+			continue
+		}
+		metadata := maps.Clone(result.Metadata)
+		if metadata == nil {
+			metadata = map[string]any{}
+		}
+		metadata[knowledgeBaseIDMetadataKey] = knowledgeBaseID
+		metadata[kbScoreMetadataKey] = result.Score
+		if source := kbLocationSource(result.Location); source != "" {
+			metadata[kbSourceMetadataKey] = source
+			if _, ok := metadata[kbNameMetadataKey]; !ok {
+				metadata[kbNameMetadataKey] = path.Base(strings.TrimRight(source, "/"))
+			}
+		}
+		docs = append(docs, ai.DocumentFromText(result.Content.Text, metadata))
+	}
+	return docs, nil
+}
+
+// kbLocationSource returns the URI, URL or ID identifying a retrieved chunk's
+// source, from the location member matching its "type" (s3Location,
+// webLocation, confluenceLocation, ...), or "".
+func kbLocationSource(location map[string]any) string {
+	for key, value := range location {
+		member, ok := value.(map[string]any)
+		if !ok || !strings.HasSuffix(key, "Location") {
+			continue
+		}
+		for _, field := range []string{"uri", "url", "id"} {
+			if s, ok := member[field].(string); ok && s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// knowledgeBaseOptions extracts [KnowledgeBaseOptions] from a retriever
+// request's Options field, accepting either a value, pointer, or
+// JSON-deserialized map. It returns nil options when options are absent.
+func knowledgeBaseOptions(o any) (*KnowledgeBaseOptions, error) {
+	switch v := o.(type) {
+	case nil:
+		return nil, nil
+	case *KnowledgeBaseOptions:
+		return v, nil
+	case KnowledgeBaseOptions:
+		return &v, nil
+	case map[string]any:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("bedrock: failed to marshal knowledge base options: %w", err)
+		}
+		var opts KnowledgeBaseOptions
+		if err := json.Unmarshal(b, &opts); err != nil {
+			return nil, fmt.Errorf("bedrock: failed to unmarshal knowledge base options: %w", err)
+		}
+		return &opts, nil
+	default:
+		return nil, fmt.Errorf("bedrock: unsupported knowledge base options type %T", o)
+	}
+}
+
+// Retrieve implements [knowledgeBaseClient].
+func (c *agentRuntimeClient) Retrieve(ctx context.Context, knowledgeBaseID string, in *kbRetrieveRequest) (*kbRetrieveResponse, error) {
+	var resp kbRetrieveResponse
+	if err := c.rest.do(ctx, "POST", "/knowledgebases/"+url.PathEscape(knowledgeBaseID)+"/retrieve", in, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// RetrieveAndGenerateInput names the knowledge bases to query, the question,
+// and the model that answers it.
+type RetrieveAndGenerateInput struct {
+	KnowledgeBaseIDs []string // Knowledge bases to query (at least one)
+	// ModelID is the chat model that writes the answer. It must accept
+	// documents and cite them, e.g. Anthropic Claude (required).
+	ModelID string
+	Query   string                // The question (required)
+	Options *KnowledgeBaseOptions // Retrieval options for every knowledge base (optional)
+	Config  *Config               // Generation options (optional)
+}
+
+// RetrieveAndGenerate answers in.Query from the content of one or more
+// Bedrock Knowledge Bases. Bedrock's RetrieveAndGenerate API queries a single
+// knowledge base per call, so the plugin retrieves from each knowledge base
+// concurrently, merges the chunks by descending score, and sends them to
+// in.ModelID through Converse as cited documents (see [NewDocumentParts]).
+// Cited answer text is returned as text parts whose [Citations] name the
+// source knowledge base in KnowledgeBaseID.
+func RetrieveAndGenerate(ctx context.Context, g *genkit.Genkit, in RetrieveAndGenerateInput) (*ai.ModelResponse, error) {
+	if g == nil {
+		return nil, errors.New("bedrock.RetrieveAndGenerate: Genkit instance required")
+	}
+	p, _ := genkit.LookupPlugin(g, provider).(*Bedrock)
+	if p == nil {
+		return nil, errors.New("bedrock.RetrieveAndGenerate: bedrock plugin not registered")
+	}
+	return p.retrieveAndGenerate(ctx, in)
+}
+
+func (b *Bedrock) retrieveAndGenerate(ctx context.Context, in RetrieveAndGenerateInput) (*ai.ModelResponse, error) {
+	const op = "bedrock.RetrieveAndGenerate"
+	b.mu.Lock()
+	initted := b.initted
+	client := b.knowledgeBases
+	b.mu.Unlock()
+	if !initted {
+		return nil, fmt.Errorf("%s: plugin not initialized", op)
+	}
+	if len(in.KnowledgeBaseIDs) == 0 {
+		return nil, fmt.Errorf("%s: at least one knowledge base ID required", op)
+	}
+	for i, id := range in.KnowledgeBaseIDs {
+		if strings.TrimSpace(id) == "" {
+			return nil, fmt.Errorf("%s: knowledge base ID %d is blank", op, i)
+		}
+	}
+	if strings.TrimSpace(in.Query) == "" {
+		return nil, fmt.Errorf("%s: query required", op)
+	}
+
+	docs, err := b.retrieveKnowledgeBases(ctx, client, in)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("%s: the knowledge bases returned no results for the query", op)
+	}
+
+	req := &ai.ModelRequest{Messages: []*ai.Message{
+		ai.NewUserMessage(append(NewDocumentParts(docs...), ai.NewTextPart(in.Query))...),
+	}}
+	if in.Config != nil {
+		req.Config = in.Config
+	}
+	resp, err := b.generateRequest(ctx, op, in.ModelID, req)
+	if err != nil {
+		return nil, err
+	}
+	attributeKnowledgeBaseCitations(resp, docs)
+	return resp, nil
+}
+
+// retrieveKnowledgeBases queries every knowledge base of in concurrently and
+// returns their chunks merged by descending score. The first failure cancels
+// the other lookups and fails the whole retrieval.
+func (b *Bedrock) retrieveKnowledgeBases(ctx context.Context, client knowledgeBaseClient, in RetrieveAndGenerateInput) ([]*ai.Document, error) {
+	ctx, cancel := b.withRequestTimeout(ctx)
+	defer cancel()
+	ctx, cancelLookups := context.WithCancel(ctx)
+	defer cancelLookups()
+
+	results := make([][]*ai.Document, len(in.KnowledgeBaseIDs))
+	var (
+		wg       sync.WaitGroup
+		failOnce sync.Once
+		firstErr error
+	)
+	for i, id := range in.KnowledgeBaseIDs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			docs, err := retrieveKnowledgeBase(ctx, client, id, in.Query, in.Options)
+			if err != nil {
+				failOnce.Do(func() {
+					firstErr = err
+					cancelLookups()
+				})
+				return
+			}
+			results[i] = docs
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	docs := slices.Concat(results...)
+	slices.SortStableFunc(docs, func(a, b *ai.Document) int {
+		sa, _ := a.Metadata[kbScoreMetadataKey].(float64)
+		sb, _ := b.Metadata[kbScoreMetadataKey].(float64)
+		return cmp.Compare(sb, sa)
+	})
+	return docs, nil
+}
+
+// attributeKnowledgeBaseCitations sets the KnowledgeBaseID, and the Source
+// when Bedrock left it empty, of every citation in resp from the document it
+// cites, docs being the documents sent in order.
+func attributeKnowledgeBaseCitations(resp *ai.ModelResponse, docs []*ai.Document) {
+	if resp == nil || resp.Message == nil {
+		return
+	}
+	for _, part := range resp.Message.Content {
+		citations := Citations(part)
+		if len(citations) == 0 {
+			continue
+		}
+		for i, c := range citations {
+			if c.DocumentIndex < 0 || c.DocumentIndex >= len(docs) {
+				continue
+			}
+			doc := docs[c.DocumentIndex]
+			citations[i].KnowledgeBaseID, _ = doc.Metadata[knowledgeBaseIDMetadataKey].(string)
+			if c.Source == "" {
+				citations[i].Source, _ = doc.Metadata[kbSourceMetadataKey].(string)
+			}
+		}
+		part.Metadata[citationsMetadataKey] = citations
+	}
+}
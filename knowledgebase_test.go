@@ -0,0 +1,344 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+)
+
+// fakeKnowledgeBaseClient returns canned results per knowledge base,
+// recording the requests it was called with.
+type fakeKnowledgeBaseClient struct {
+	results map[string][]kbRetrievalResult
+	err     error
+
+	mu  sync.Mutex // Guards got; RetrieveAndGenerate calls Retrieve concurrently
+	got map[string]*kbRetrieveRequest
+}
+
+func (f *fakeKnowledgeBaseClient) Retrieve(_ context.Context, knowledgeBaseID string, in *kbRetrieveRequest) (*kbRetrieveResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.got == nil {
+		f.got = map[string]*kbRetrieveRequest{}
+	}
+	f.got[knowledgeBaseID] = in
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &kbRetrieveResponse{RetrievalResults: f.results[knowledgeBaseID]}, nil
+}
+
+func kbResult(text, uri string, score float64) kbRetrievalResult {
+	return kbRetrievalResult{
+		Content:  kbText{Text: text},
+		Location: map[string]any{"type": "S3", "s3Location": map[string]any{"uri": uri}},
+		Metadata: map[string]any{"x-amz-bedrock-kb-chunk-id": "chunk-" + text},
+		Score:    score,
+	}
+}
+
+func TestKnowledgeBaseRetriever_ForwardsSearchType(t *testing.T) {
+	ctx := context.Background()
+	b := testInitializedBedrock()
+	g := genkit.Init(ctx, genkit.WithPlugins(b))
+	client := &fakeKnowledgeBaseClient{results: map[string][]kbRetrievalResult{
+		"KB12345678": {kbResult("Refunds take 5 days.", "s3://docs/policies/refunds.pdf", 0.9)},
+	}}
+	b.knowledgeBases = client
+	retriever := b.DefineKnowledgeBaseRetriever(g, "KB12345678")
+	if retriever.Name() != "bedrock/KB12345678" {
+		t.Errorf("Name() = %q, want bedrock/KB12345678", retriever.Name())
+	}
+
+	for _, options := range []any{
+		&KnowledgeBaseOptions{NumberOfResults: 3, SearchType: SearchTypeHybrid},
+		map[string]any{"numberOfResults": float64(3), "searchType": "HYBRID"}, // JSON-decoded
+	} {
+		resp, err := retriever.Retrieve(ctx, &ai.RetrieverRequest{
+			Query:   ai.DocumentFromText("refund policy", nil),
+			Options: options,
+		})
+		if err != nil {
+			t.Fatalf("Retrieve(%v): %v", options, err)
+		}
+		want := &kbRetrieveRequest{
+			RetrievalQuery: kbText{Text: "refund policy"},
+			RetrievalConfiguration: &kbRetrievalConfiguration{
+				VectorSearchConfiguration: kbVectorSearchConfiguration{NumberOfResults: 3, OverrideSearchType: "HYBRID"},
+			},
+		}
+		if got := client.got["KB12345678"]; !reflect.DeepEqual(got, want) {
+			t.Errorf("request = %+v, want %+v", got, want)
+		}
+		if len(resp.Documents) != 1 {
+			t.Fatalf("documents = %+v, want 1", resp.Documents)
+		}
+		doc := resp.Documents[0]
+		if documentText(doc) != "Refunds take 5 days." {
+			t.Errorf("text = %q", documentText(doc))
+		}
+		wantMeta := map[string]any{
+			"x-amz-bedrock-kb-chunk-id": "chunk-Refunds take 5 days.",
+			"knowledgeBaseId":           "KB12345678",
+			"score":                     0.9,
+			"source":                    "s3://docs/policies/refunds.pdf",
+			"name":                      "refunds.pdf",
+		}
+		if !reflect.DeepEqual(doc.Metadata, wantMeta) {
+			t.Errorf("metadata = %v, want %v", doc.Metadata, wantMeta)
+		}
+	}
+
+	if _, err := retriever.Retrieve(ctx, &ai.RetrieverRequest{Query: ai.DocumentFromText("refund policy", nil)}); err != nil {
+		t.Fatal(err)
+	}
+	if got := client.got["KB12345678"]; got.RetrievalConfiguration != nil {
+		t.Errorf("retrievalConfiguration = %+v, want none without options", got.RetrievalConfiguration)
+	}
+
+	assertPanicsWith(t, "bedrock: Init not called", func() {
+		(&Bedrock{}).DefineKnowledgeBaseRetriever(g, "KB12345678")
+	})
+}
+
+func TestRetrieveKnowledgeBase_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		client  knowledgeBaseClient
+		query   string
+		opts    *KnowledgeBaseOptions
+		wantErr string
+	}{
+		{"nil client", nil, "q", nil, "client required"},
+		{"empty query", &fakeKnowledgeBaseClient{}, "", nil, "no text content"},
+		{"lowercase search type", &fakeKnowledgeBaseClient{}, "q", &KnowledgeBaseOptions{SearchType: "hybrid"}, `unknown searchType "hybrid"; use "HYBRID" or "SEMANTIC"`},
+		{"too many results", &fakeKnowledgeBaseClient{}, "q", &KnowledgeBaseOptions{NumberOfResults: 101}, "numberOfResults 101 out of range"},
+		{"negative results", &fakeKnowledgeBaseClient{}, "q", &KnowledgeBaseOptions{NumberOfResults: -1}, "numberOfResults -1 out of range"},
+		{"service error", &fakeKnowledgeBaseClient{err: errors.New("boom")}, "q", nil, "retrieve: boom"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := retrieveKnowledgeBase(context.Background(), tt.client, "KB12345678", tt.query, tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := knowledgeBaseOptions("HYBRID"); err == nil || !strings.Contains(err.Error(), "unsupported knowledge base options type string") {
+		t.Errorf("knowledgeBaseOptions(string) error = %v", err)
+	}
+}
+
+func TestAgentRuntimeClient_Retrieve(t *testing.T) {
+	var gotPath, gotAuth string
+	var gotBody map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.EscapedPath()
+		gotAuth = r.Header.Get("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"retrievalResults":[{"content":{"type":"TEXT","text":"Refunds take 5 days."},
+			"location":{"type":"WEB","webLocation":{"url":"https://example.com/refunds"}},"score":0.75}]}`))
+	}))
+	defer server.Close()
+
+	client := newAgentRuntimeClient(testControlPlaneConfig(server))
+	docs, err := retrieveKnowledgeBase(context.Background(), client, "KB12345678", "refund policy",
+		&KnowledgeBaseOptions{SearchType: SearchTypeSemantic})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "/knowledgebases/KB12345678/retrieve"; gotPath != want {
+		t.Errorf("path = %q, want %q", gotPath, want)
+	}
+	if !strings.Contains(gotAuth, "/bedrock/aws4_request") {
+		t.Errorf("Authorization = %q, want a SigV4 signature for bedrock", gotAuth)
+	}
+	want := map[string]any{
+		"retrievalQuery":         map[string]any{"text": "refund policy"},
+		"retrievalConfiguration": map[string]any{"vectorSearchConfiguration": map[string]any{"overrideSearchType": "SEMANTIC"}},
+	}
+	if !reflect.DeepEqual(gotBody, want) {
+		t.Errorf("body = %v, want %v", gotBody, want)
+	}
+	if len(docs) != 1 || docs[0].Metadata["source"] != "https://example.com/refunds" || docs[0].Metadata["score"] != 0.75 {
+		t.Errorf("documents = %+v", docs)
+	}
+}
+
+func TestRetrieveAndGenerate_MultipleKnowledgeBases(t *testing.T) {
+	var gotBody struct {
+		Messages []struct {
+			Content []map[string]any `json:"content"`
+		} `json:"messages"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"output":{"message":{"role":"assistant","content":[
+			{"citationsContent":{"content":[{"text":"Refunds take 5 days."}],"citations":[
+				{"title":"refunds.pdf","sourceContent":[{"text":"Refunds take 5 days."}],"location":{"documentChar":{"documentIndex":0,"start":0,"end":20}}}]}},
+			{"citationsContent":{"content":[{"text":" Returns need a receipt."}],"citations":[
+				{"title":"returns.html","sourceContent":[{"text":"Returns need a receipt."}],"location":{"documentChar":{"documentIndex":1,"start":0,"end":23}}}]}}
+		]}},"stopReason":"end_turn"}`))
+	}))
+	defer server.Close()
+
+	b := newTestBedrock(server)
+	client := &fakeKnowledgeBaseClient{results: map[string][]kbRetrievalResult{
+		"KBPOLICIES": {kbResult("Refunds take 5 days.", "s3://docs/refunds.pdf", 0.9)},
+		"KBSTORE":    {kbResult("Returns need a receipt.", "s3://store/returns.html", 0.6)},
+	}}
+	b.knowledgeBases = client
+
+	resp, err := b.retrieveAndGenerate(context.Background(), RetrieveAndGenerateInput{
+		KnowledgeBaseIDs: []string{"KBSTORE", "KBPOLICIES"},
+		ModelID:          "anthropic.claude-3-5-sonnet-20241022-v2:0",
+		Query:            "How do refunds work?",
+		Options:          &KnowledgeBaseOptions{SearchType: SearchTypeHybrid},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"KBSTORE", "KBPOLICIES"} {
+		if got := client.got[id]; got == nil || got.RetrievalConfiguration.VectorSearchConfiguration.OverrideSearchType != "HYBRID" {
+			t.Errorf("%s request = %+v, want the shared options", id, got)
+		}
+	}
+
+	// The chunks are sent highest score first, then the question.
+	if len(gotBody.Messages) != 1 || len(gotBody.Messages[0].Content) != 3 {
+		t.Fatalf("messages = %+v, want one user message with two documents and the query", gotBody.Messages)
+	}
+	content := gotBody.Messages[0].Content
+	for i, want := range []string{"refunds pdf", "returns html"} {
+		doc, _ := content[i]["document"].(map[string]any)
+		if doc["name"] != want {
+			t.Errorf("content[%d] document = %v, want name %q", i, content[i], want)
+		}
+	}
+	if content[2]["text"] != "How do refunds work?" {
+		t.Errorf("content[2] = %v, want the query", content[2])
+	}
+
+	if resp.Text() != "Refunds take 5 days. Returns need a receipt." {
+		t.Errorf("text = %q", resp.Text())
+	}
+	var got []Citation
+	for _, part := range resp.Message.Content {
+		got = append(got, Citations(part)...)
+	}
+	if len(got) != 2 {
+		t.Fatalf("citations = %+v, want 2", got)
+	}
+	for i, want := range []struct{ kb, source string }{
+		{"KBPOLICIES", "s3://docs/refunds.pdf"},
+		{"KBSTORE", "s3://store/returns.html"},
+	} {
+		if got[i].KnowledgeBaseID != want.kb || got[i].Source != want.source {
+			t.Errorf("citation %d = %+v, want knowledge base %s and source %s", i, got[i], want.kb, want.source)
+		}
+	}
+}
+
+func TestRetrieveAndGenerate_Errors(t *testing.T) {
+	valid := RetrieveAndGenerateInput{KnowledgeBaseIDs: []string{"KB1"}, ModelID: "anthropic.claude-3-5-sonnet-20241022-v2:0", Query: "q"}
+	tests := []struct {
+		name    string
+		client  knowledgeBaseClient
+		in      func(RetrieveAndGenerateInput) RetrieveAndGenerateInput
+		wantErr string
+	}{
+		{"no knowledge bases", &fakeKnowledgeBaseClient{}, func(in RetrieveAndGenerateInput) RetrieveAndGenerateInput { in.KnowledgeBaseIDs = nil; return in }, "at least one knowledge base ID"},
+		{"blank knowledge base", &fakeKnowledgeBaseClient{}, func(in RetrieveAndGenerateInput) RetrieveAndGenerateInput {
+			in.KnowledgeBaseIDs = []string{"KB1", " "}
+			return in
+		}, "knowledge base ID 1 is blank"},
+		{"no query", &fakeKnowledgeBaseClient{}, func(in RetrieveAndGenerateInput) RetrieveAndGenerateInput { in.Query = ""; return in }, "query required"},
+		{"bad search type", &fakeKnowledgeBaseClient{}, func(in RetrieveAndGenerateInput) RetrieveAndGenerateInput {
+			in.Options = &KnowledgeBaseOptions{SearchType: "KEYWORD"}
+			return in
+		}, `unknown searchType "KEYWORD"`},
+		{"retrieve error", &fakeKnowledgeBaseClient{err: errors.New("boom")}, func(in RetrieveAndGenerateInput) RetrieveAndGenerateInput { return in }, "boom"},
+		{"no results", &fakeKnowledgeBaseClient{}, func(in RetrieveAndGenerateInput) RetrieveAndGenerateInput { return in }, "no results"},
+		{"no model", &fakeKnowledgeBaseClient{results: map[string][]kbRetrievalResult{"KB1": {kbResult("text", "s3://b/k", 1)}}}, func(in RetrieveAndGenerateInput) RetrieveAndGenerateInput {
+			in.ModelID = ""
+			return in
+		}, "model ID required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Bedrock{initted: true, knowledgeBases: tt.client}
+			_, err := b.retrieveAndGenerate(context.Background(), tt.in(valid))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) || !strings.HasPrefix(err.Error(), "bedrock.RetrieveAndGenerate: ") {
+				t.Errorf("error = %v, want bedrock.RetrieveAndGenerate error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := (&Bedrock{}).retrieveAndGenerate(context.Background(), valid); err == nil || !strings.Contains(err.Error(), "not initialized") {
+		t.Errorf("uninitialized error = %v", err)
+	}
+}
+
+// failingKnowledgeBaseClient fails lookups of one knowledge base and blocks
+// the others until their context is cancelled.
+type failingKnowledgeBaseClient struct {
+	failing string
+}
+
+func (f failingKnowledgeBaseClient) Retrieve(ctx context.Context, knowledgeBaseID string, _ *kbRetrieveRequest) (*kbRetrieveResponse, error) {
+	if knowledgeBaseID == f.failing {
+		return nil, errors.New("access denied")
+	}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestRetrieveKnowledgeBases_FirstErrorCancelsOthers(t *testing.T) {
+	b := &Bedrock{RequestTimeout: time.Minute}
+	in := RetrieveAndGenerateInput{KnowledgeBaseIDs: []string{"KBSLOW", "KBBAD", "KBSLOW2"}, Query: "q"}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := b.retrieveKnowledgeBases(context.Background(), failingKnowledgeBaseClient{failing: "KBBAD"}, in)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), `knowledge base "KBBAD"`) || errors.Is(err, context.Canceled) {
+			t.Errorf("error = %v, want only the KBBAD failure", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("retrieveKnowledgeBases kept waiting for the other lookups after one failed")
+	}
+}
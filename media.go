@@ -0,0 +1,108 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// dataURI is a parsed RFC 2397 data URI.
+type dataURI struct {
+	mediaType string // Lower-cased MIME type without parameters; "" when omitted
+	data      []byte
+}
+
+// hasDataURIScheme reports whether s starts with a (case-insensitive) "data:"
+// scheme.
+func hasDataURIScheme(s string) bool {
+	return len(s) >= len("data:") && strings.EqualFold(s[:len("data:")], "data:")
+}
+
+// parseDataURI parses "data:[<mediatype>][;param=value]*[;base64],<data>".
+// Base64 payloads tolerate embedded whitespace, missing padding, the URL-safe
+// alphabet, and percent-encoding; payloads without ";base64" are
+// percent-decoded as raw bytes.
+func parseDataURI(s string) (*dataURI, error) {
+	s = strings.TrimSpace(s)
+	if !hasDataURIScheme(s) {
+		return nil, errors.New(`bedrock: malformed data URI: missing "data:" scheme`)
+	}
+	header, payload, ok := strings.Cut(s[len("data:"):], ",")
+	if !ok {
+		return nil, errors.New("bedrock: malformed data URI: missing ',' between header and data")
+	}
+
+	params := strings.Split(header, ";")
+	mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+	if mediaType != "" && !strings.Contains(mediaType, "/") {
+		return nil, fmt.Errorf("bedrock: malformed data URI: invalid media type %q", mediaType)
+	}
+	isBase64 := false
+	for _, p := range params[1:] {
+		if strings.EqualFold(strings.TrimSpace(p), "base64") {
+			isBase64 = true
+		}
+	}
+
+	var data []byte
+	if isBase64 {
+		decoded, err := decodeBase64Lenient(payload)
+		if err != nil {
+			return nil, fmt.Errorf("bedrock: malformed data URI: invalid base64 data: %w", err)
+		}
+		data = decoded
+	} else {
+		unescaped, err := url.PathUnescape(strings.TrimSpace(payload))
+		if err != nil {
+			return nil, fmt.Errorf("bedrock: malformed data URI: invalid percent-encoding: %w", err)
+		}
+		data = []byte(unescaped)
+	}
+	if len(data) == 0 {
+		return nil, errors.New("bedrock: malformed data URI: empty data")
+	}
+	return &dataURI{mediaType: mediaType, data: data}, nil
+}
+
+// decodeBase64Lenient decodes standard or URL-safe base64, with or without
+// padding, after removing whitespace and percent-encoding.
+func decodeBase64Lenient(s string) ([]byte, error) {
+	if strings.Contains(s, "%") {
+		unescaped, err := url.PathUnescape(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid percent-encoding: %w", err)
+		}
+		s = unescaped
+	}
+	s = strings.Join(strings.Fields(s), "")
+
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err == nil {
+		return data, nil
+	}
+	for _, enc := range []*base64.Encoding{base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if alt, altErr := enc.DecodeString(s); altErr == nil {
+			return alt, nil
+		}
+	}
+	return nil, err
+}
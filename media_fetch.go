@@ -0,0 +1,151 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+// Defaults for [Bedrock.RemoteMediaTimeout] and [Bedrock.RemoteMediaMaxBytes].
+const (
+	defaultRemoteMediaTimeout  = 10 * time.Second
+	defaultRemoteMediaMaxBytes = 25 << 20
+)
+
+// fetchRemoteMedia returns input with each https:// media part downloaded and
+// inlined as a data URL, when RemoteMediaFetch is set. input is not
+// modified.
+func (b *Bedrock) fetchRemoteMedia(ctx context.Context, input *ai.ModelRequest) (*ai.ModelRequest, error) {
+	if !b.RemoteMediaFetch || input == nil {
+		return input, nil
+	}
+	msgs, err := mapMediaParts(input.Messages, func(part *ai.Part) (*ai.Part, error) {
+		if !strings.HasPrefix(strings.TrimSpace(part.Text), "https://") {
+			return part, nil
+		}
+		return b.fetchMediaPart(ctx, part)
+	})
+	if err != nil {
+		return nil, err
+	}
+	fetched := *input
+	fetched.Messages = msgs
+	return &fetched, nil
+}
+
+// fetchMediaPart downloads the part's URL and returns a copy of the part
+// carrying the bytes as a data URL. The part's content type wins over the
+// response's Content-Type.
+func (b *Bedrock) fetchMediaPart(ctx context.Context, part *ai.Part) (*ai.Part, error) {
+	rawURL := strings.TrimSpace(part.Text)
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("bedrock: invalid media URL %q: %w", rawURL, err)
+	}
+	if err := b.checkRemoteMediaURL(u); err != nil {
+		return nil, err
+	}
+
+	timeout := b.RemoteMediaTimeout
+	if timeout <= 0 {
+		timeout = defaultRemoteMediaTimeout
+	}
+	maxBytes := b.RemoteMediaMaxBytes
+	if maxBytes <= 0 {
+		maxBytes = defaultRemoteMediaMaxBytes
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("bedrock: fetch media %s: %w", u.Redacted(), err)
+	}
+	resp, err := b.remoteMediaClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("bedrock: fetch media %s: %w", u.Redacted(), err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("bedrock: fetch media %s: status %s", u.Redacted(), resp.Status)
+	}
+	if resp.ContentLength > maxBytes {
+		return nil, fmt.Errorf("bedrock: fetch media %s: %d bytes exceed RemoteMediaMaxBytes (%d)", u.Redacted(), resp.ContentLength, maxBytes)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("bedrock: fetch media %s: %w", u.Redacted(), err)
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, fmt.Errorf("bedrock: fetch media %s: body exceeds RemoteMediaMaxBytes (%d)", u.Redacted(), maxBytes)
+	}
+
+	contentType := mediaMIME(part)
+	if contentType == "" {
+		contentType, _, _ = mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	}
+	if contentType == "" {
+		return nil, fmt.Errorf("bedrock: fetch media %s: no content type on the part or the response", u.Redacted())
+	}
+	copied := *part
+	copied.ContentType = contentType
+	copied.Text = "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(data)
+	return &copied, nil
+}
+
+// checkRemoteMediaURL allows only https URLs on RemoteMediaHosts (any host
+// when the list is empty).
+func (b *Bedrock) checkRemoteMediaURL(u *url.URL) error {
+	if u.Scheme != "https" {
+		return fmt.Errorf("bedrock: media URL %s must use https", u.Redacted())
+	}
+	host := u.Hostname()
+	if len(b.RemoteMediaHosts) > 0 && !slices.ContainsFunc(b.RemoteMediaHosts, func(h string) bool { return strings.EqualFold(h, host) }) {
+		return fmt.Errorf("bedrock: media host %q is not in RemoteMediaHosts", host)
+	}
+	return nil
+}
+
+// remoteMediaClient returns the HTTP client for media downloads, which
+// re-checks the URL on each redirect so a listed host cannot redirect
+// elsewhere.
+func (b *Bedrock) remoteMediaClient() *http.Client {
+	client := http.Client{}
+	if b.mediaHTTPClient != nil {
+		client = *b.mediaHTTPClient
+	}
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return b.checkRemoteMediaURL(req.URL)
+	}
+	return &client
+}
@@ -0,0 +1,155 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+var remoteImageBytes = []byte("\x89PNG\r\n\x1a\nremote image")
+
+// newMediaServer serves remoteImageBytes at /cat.png, a redirect to other at
+// /moved.png, and a slow response at /slow.png.
+func newMediaServer(t *testing.T, other string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cat.png":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write(remoteImageBytes)
+		case "/moved.png":
+			http.Redirect(w, r, other, http.StatusFound)
+		case "/slow.png":
+			select {
+			case <-time.After(2 * time.Second):
+			case <-r.Context().Done():
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestGenerateText_RemoteMediaFetch(t *testing.T) {
+	media := newMediaServer(t, "")
+	var gotBody map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&gotBody); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"output":{"message":{"role":"assistant","content":[{"text":"A cat."}]}},"stopReason":"end_turn"}`)
+	}))
+	defer server.Close()
+
+	b := newTestBedrock(server)
+	b.RemoteMediaFetch = true
+	b.RemoteMediaHosts = []string{"127.0.0.1"}
+	b.mediaHTTPClient = media.Client()
+
+	req := &ai.ModelRequest{Messages: []*ai.Message{ai.NewUserMessage(
+		ai.NewTextPart("What is this?"),
+		// No content type on the part: the response's Content-Type is used.
+		&ai.Part{Kind: ai.PartMedia, Text: media.URL + "/cat.png"},
+	)}}
+	resp, err := b.generateText(context.Background(), "anthropic.claude-3-5-sonnet-20241022-v2:0", req, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Text() != "A cat." {
+		t.Errorf("text = %q", resp.Text())
+	}
+	if req.Messages[0].Content[1].Text != media.URL+"/cat.png" {
+		t.Error("request was modified")
+	}
+
+	content := gotBody["messages"].([]any)[0].(map[string]any)["content"].([]any)
+	image := content[1].(map[string]any)["image"].(map[string]any)
+	if image["format"] != "png" {
+		t.Errorf("format = %v, want png", image["format"])
+	}
+	data, _ := base64.StdEncoding.DecodeString(image["source"].(map[string]any)["bytes"].(string))
+	if !bytes.Equal(data, remoteImageBytes) {
+		t.Errorf("image bytes = %q, want the fetched image", data)
+	}
+}
+
+func TestFetchRemoteMedia_Errors(t *testing.T) {
+	elsewhere := newMediaServer(t, "")
+	media := newMediaServer(t, strings.Replace(elsewhere.URL, "127.0.0.1", "localhost", 1)+"/cat.png")
+	mediaURL, _ := url.Parse(media.URL)
+
+	tests := []struct {
+		name    string
+		setup   func(b *Bedrock)
+		url     string
+		wantErr string
+	}{
+		{name: "disabled", setup: func(b *Bedrock) { b.RemoteMediaFetch = false }, url: "/cat.png", wantErr: "remote URLs are not supported"},
+		{name: "host not listed", setup: func(b *Bedrock) { b.RemoteMediaHosts = []string{"cdn.example.com"} }, url: "/cat.png", wantErr: `media host "127.0.0.1" is not in RemoteMediaHosts`},
+		{name: "redirect to unlisted host", url: "/moved.png", wantErr: `media host "localhost" is not in RemoteMediaHosts`},
+		{name: "size cap", setup: func(b *Bedrock) { b.RemoteMediaMaxBytes = 4 }, url: "/cat.png", wantErr: "exceed RemoteMediaMaxBytes (4)"},
+		{name: "timeout", setup: func(b *Bedrock) { b.RemoteMediaTimeout = 20 * time.Millisecond }, url: "/slow.png", wantErr: "deadline exceeded"},
+		{name: "not found", url: "/missing.png", wantErr: "status 404"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Bedrock{
+				RemoteMediaFetch: true,
+				RemoteMediaHosts: []string{mediaURL.Hostname()},
+				mediaHTTPClient:  media.Client(),
+			}
+			if tt.setup != nil {
+				tt.setup(b)
+			}
+			req := &ai.ModelRequest{Messages: []*ai.Message{ai.NewUserMessage(ai.NewMediaPart("image/png", media.URL+tt.url))}}
+			fetched, err := b.fetchRemoteMedia(context.Background(), req)
+			if err == nil {
+				_, err = b.buildConverseInput("anthropic.claude-3-5-sonnet-20241022-v2:0", fetched)
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	// http:// is never fetched.
+	b := &Bedrock{RemoteMediaFetch: true}
+	req := &ai.ModelRequest{Messages: []*ai.Message{ai.NewUserMessage(ai.NewMediaPart("image/png", "http://example.com/cat.png"))}}
+	fetched, err := b.fetchRemoteMedia(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.buildConverseInput("amazon.nova-lite-v1:0", fetched); err == nil || !strings.Contains(err.Error(), "remote URLs are not supported") {
+		t.Errorf("http URL: error = %v", err)
+	}
+}
@@ -0,0 +1,290 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // Register the GIF decoder for image.Decode.
+	"image/jpeg"
+	"image/png"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/firebase/genkit/go/ai"
+)
+
+// Converse limits for a single image block.
+const (
+	maxImageBytes     = 3_932_160 // 3.75 MB
+	maxImageDimension = 8000      // Pixels, per side
+)
+
+// maxImageShrinkSteps bounds how often preprocessImage scales an image down
+// to meet maxImageBytes.
+const maxImageShrinkSteps = 8
+
+// preprocessImages returns msgs with each image part that Converse would
+// reject converted or downscaled by [preprocessImage]. msgs is not modified.
+func preprocessImages(msgs []*ai.Message) ([]*ai.Message, error) {
+	return mapMediaParts(msgs, func(part *ai.Part) (*ai.Part, error) {
+		if !strings.HasPrefix(mediaMIME(part), "image/") {
+			return part, nil
+		}
+		return preprocessImagePart(part)
+	})
+}
+
+// mapMediaParts returns msgs with each media part replaced by fn(part).
+// Messages whose parts fn returns unchanged are shared with msgs, which is
+// not modified.
+func mapMediaParts(msgs []*ai.Message, fn func(*ai.Part) (*ai.Part, error)) ([]*ai.Message, error) {
+	var out []*ai.Message
+	for i, msg := range msgs {
+		if msg == nil {
+			continue
+		}
+		var content []*ai.Part
+		for j, part := range msg.Content {
+			if part == nil || !part.IsMedia() {
+				continue
+			}
+			replaced, err := fn(part)
+			if err != nil {
+				return nil, err
+			}
+			if replaced == part {
+				continue
+			}
+			if content == nil {
+				content = append([]*ai.Part(nil), msg.Content...)
+			}
+			content[j] = replaced
+		}
+		if content == nil {
+			continue
+		}
+		if out == nil {
+			out = append([]*ai.Message(nil), msgs...)
+		}
+		copied := *msg
+		copied.Content = content
+		out[i] = &copied
+	}
+	if out == nil {
+		return msgs, nil
+	}
+	return out, nil
+}
+
+// preprocessImagePart returns part itself when Converse accepts it as is, or
+// a copy carrying the converted image as a data URL.
+func preprocessImagePart(part *ai.Part) (*ai.Part, error) {
+	mime := mediaMIME(part)
+	data, err := decodeMediaPayload(part.Text)
+	if err != nil {
+		return nil, err
+	}
+	newMIME, newData, err := preprocessImage(mime, data)
+	if err != nil {
+		return nil, err
+	}
+	if newData == nil {
+		return part, nil
+	}
+	copied := *part
+	copied.ContentType = newMIME
+	copied.Text = "data:" + newMIME + ";base64," + base64.StdEncoding.EncodeToString(newData)
+	return &copied, nil
+}
+
+// preprocessImage makes an image acceptable to Converse: formats Converse
+// does not accept are converted to PNG, and images over maxImageDimension
+// pixels a side or maxImageBytes are scaled down, keeping their aspect ratio.
+// BMP is decoded here; other formats decode when the program registers a
+// decoder with the image package. It returns nil data when the image needs no
+// change or cannot be decoded, leaving the rejection to the usual checks.
+func preprocessImage(mime string, data []byte) (string, []byte, error) {
+	supported := imageFormatFor(mime) != ""
+	if supported && len(data) <= maxImageBytes {
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil || (cfg.Width <= maxImageDimension && cfg.Height <= maxImageDimension) {
+			return "", nil, nil
+		}
+	}
+
+	var img image.Image
+	var err error
+	if mime == "image/bmp" || mime == "image/x-ms-bmp" {
+		img, err = decodeBMP(data)
+		if err != nil {
+			return "", nil, fmt.Errorf("bedrock: preprocess image: %w", err)
+		}
+	} else {
+		img, _, err = image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return "", nil, nil
+		}
+	}
+
+	encode, outMIME := encodePNG, "image/png"
+	if imageFormatFor(mime) == types.ImageFormatJpeg {
+		encode, outMIME = encodeJPEG, "image/jpeg"
+	}
+	bounds := img.Bounds()
+	if w, h := bounds.Dx(), bounds.Dy(); w > maxImageDimension || h > maxImageDimension {
+		scale := float64(maxImageDimension) / float64(max(w, h))
+		img = scaleImage(img, scale)
+	}
+	for range maxImageShrinkSteps {
+		out, err := encode(img)
+		if err != nil {
+			return "", nil, fmt.Errorf("bedrock: preprocess image: %w", err)
+		}
+		if len(out) <= maxImageBytes {
+			return outMIME, out, nil
+		}
+		img = scaleImage(img, 0.75)
+	}
+	return "", nil, fmt.Errorf("bedrock: preprocess image: cannot reduce %s image below %d bytes", mime, maxImageBytes)
+}
+
+func encodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	err := png.Encode(&buf, img)
+	return buf.Bytes(), err
+}
+
+func encodeJPEG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90})
+	return buf.Bytes(), err
+}
+
+// scaleImage resizes img by scale (< 1), averaging the source pixels that
+// cover each destination pixel.
+func scaleImage(img image.Image, scale float64) image.Image {
+	src := img.Bounds()
+	w := max(1, int(float64(src.Dx())*scale))
+	h := max(1, int(float64(src.Dy())*scale))
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		y0 := src.Min.Y + y*src.Dy()/h
+		y1 := max(y0+1, src.Min.Y+(y+1)*src.Dy()/h)
+		for x := range w {
+			x0 := src.Min.X + x*src.Dx()/w
+			x1 := max(x0+1, src.Min.X+(x+1)*src.Dx()/w)
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := color.NRGBAModel.Convert(img.At(sx, sy)).(color.NRGBA)
+					r += uint64(c.R)
+					g += uint64(c.G)
+					b += uint64(c.B)
+					a += uint64(c.A)
+					n++
+				}
+			}
+			dst.SetNRGBA(x, y, color.NRGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: uint8(a / n)})
+		}
+	}
+	return dst
+}
+
+// decodeBMP decodes an uncompressed Windows bitmap with 8 (paletted), 24 or
+// 32 bits per pixel, the forms image editors and screenshots produce. The
+// standard library has no BMP decoder.
+func decodeBMP(data []byte) (image.Image, error) {
+	const fileHeaderLen = 14
+	if len(data) < fileHeaderLen+40 || string(data[:2]) != "BM" {
+		return nil, errors.New("invalid BMP header")
+	}
+	le := binary.LittleEndian
+	pixelOffset := int(le.Uint32(data[10:]))
+	headerLen := int(le.Uint32(data[14:]))
+	width := int(int32(le.Uint32(data[18:])))
+	height := int(int32(le.Uint32(data[22:])))
+	bpp := int(le.Uint16(data[28:]))
+	compression := le.Uint32(data[30:])
+	colorsUsed := int(le.Uint32(data[46:]))
+
+	if headerLen < 40 || compression != 0 {
+		return nil, errors.New("unsupported BMP: only uncompressed bitmaps are supported")
+	}
+	topDown := height < 0
+	if topDown {
+		height = -height
+	}
+	if width <= 0 || height <= 0 || width > 1<<15 || height > 1<<15 {
+		return nil, fmt.Errorf("invalid BMP dimensions %dx%d", width, height)
+	}
+
+	var palette []color.NRGBA
+	switch bpp {
+	case 8:
+		if colorsUsed == 0 || colorsUsed > 256 {
+			colorsUsed = 256
+		}
+		start := fileHeaderLen + headerLen
+		if start+4*colorsUsed > len(data) {
+			return nil, errors.New("truncated BMP palette")
+		}
+		palette = make([]color.NRGBA, colorsUsed)
+		for i := range palette {
+			e := data[start+4*i:]
+			palette[i] = color.NRGBA{R: e[2], G: e[1], B: e[0], A: 0xff}
+		}
+	case 24, 32:
+	default:
+		return nil, fmt.Errorf("unsupported BMP: %d bits per pixel", bpp)
+	}
+
+	stride := (bpp*width + 31) / 32 * 4
+	if pixelOffset < 0 || pixelOffset+stride*height > len(data) {
+		return nil, errors.New("truncated BMP pixel data")
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for row := range height {
+		y := height - 1 - row
+		if topDown {
+			y = row
+		}
+		line := data[pixelOffset+row*stride:]
+		for x := range width {
+			var c color.NRGBA
+			switch bpp {
+			case 8:
+				idx := int(line[x])
+				if idx >= len(palette) {
+					return nil, fmt.Errorf("BMP palette index %d out of range", idx)
+				}
+				c = palette[idx]
+			default:
+				p := line[x*bpp/8:]
+				c = color.NRGBA{R: p[2], G: p[1], B: p[0], A: 0xff}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img, nil
+}
@@ -0,0 +1,230 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/firebase/genkit/go/ai"
+)
+
+// encodeTestBMP encodes img as a bottom-up BMP with bpp 24, or 8 with a
+// palette of the image's distinct colors.
+func encodeTestBMP(t *testing.T, img image.Image, bpp int) []byte {
+	t.Helper()
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	var palette []color.NRGBA
+	index := map[color.NRGBA]int{}
+	if bpp == 8 {
+		for y := range h {
+			for x := range w {
+				c := color.NRGBAModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.NRGBA)
+				if _, ok := index[c]; !ok {
+					index[c] = len(palette)
+					palette = append(palette, c)
+				}
+			}
+		}
+	}
+	stride := (bpp*w + 31) / 32 * 4
+	offset := 14 + 40 + 4*len(palette)
+	buf := make([]byte, offset+stride*h)
+	le := binary.LittleEndian
+	copy(buf, "BM")
+	le.PutUint32(buf[2:], uint32(len(buf)))
+	le.PutUint32(buf[10:], uint32(offset))
+	le.PutUint32(buf[14:], 40)
+	le.PutUint32(buf[18:], uint32(w))
+	le.PutUint32(buf[22:], uint32(h))
+	le.PutUint16(buf[26:], 1)
+	le.PutUint16(buf[28:], uint16(bpp))
+	le.PutUint32(buf[46:], uint32(len(palette)))
+	for i, c := range palette {
+		copy(buf[54+4*i:], []byte{c.B, c.G, c.R, 0})
+	}
+	for y := range h {
+		row := buf[offset+(h-1-y)*stride:]
+		for x := range w {
+			c := color.NRGBAModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.NRGBA)
+			if bpp == 8 {
+				row[x] = byte(index[c])
+			} else {
+				copy(row[3*x:], []byte{c.B, c.G, c.R})
+			}
+		}
+	}
+	return buf
+}
+
+func testImage(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(40 * x), G: uint8(90 * y), B: 200, A: 0xff})
+		}
+	}
+	return img
+}
+
+func dataURL(mime string, data []byte) string {
+	return "data:" + mime + ";base64," + base64.StdEncoding.EncodeToString(data)
+}
+
+// converseImage builds a Converse request for one image part and returns its
+// image block.
+func converseImage(t *testing.T, b *Bedrock, part *ai.Part) types.ImageBlock {
+	t.Helper()
+	req := &ai.ModelRequest{Messages: []*ai.Message{ai.NewUserMessage(ai.NewTextPart("Describe this."), part)}}
+	input, err := b.buildConverseInput("anthropic.claude-3-5-sonnet-20241022-v2:0", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, ok := input.Messages[0].Content[1].(*types.ContentBlockMemberImage)
+	if !ok {
+		t.Fatalf("content[1] = %T, want image", input.Messages[0].Content[1])
+	}
+	return block.Value
+}
+
+func imageBytes(t *testing.T, block types.ImageBlock) []byte {
+	t.Helper()
+	src, ok := block.Source.(*types.ImageSourceMemberBytes)
+	if !ok {
+		t.Fatalf("source = %T, want bytes", block.Source)
+	}
+	return src.Value
+}
+
+func TestImagePreprocessing_BMPToPNG(t *testing.T) {
+	want := testImage(3, 2) // 9-byte rows exercise BMP row padding
+	for _, bpp := range []int{24, 8} {
+		part := ai.NewMediaPart("image/bmp", dataURL("image/bmp", encodeTestBMP(t, want, bpp)))
+
+		if _, err := (&Bedrock{}).buildConverseInput("anthropic.claude-3-5-sonnet-20241022-v2:0", &ai.ModelRequest{
+			Messages: []*ai.Message{ai.NewUserMessage(part)},
+		}); err == nil || !strings.Contains(err.Error(), "unsupported image format") {
+			t.Fatalf("without preprocessing: error = %v, want unsupported image format", err)
+		}
+
+		block := converseImage(t, &Bedrock{ImagePreprocessing: true}, part)
+		if block.Format != types.ImageFormatPng {
+			t.Fatalf("%d-bit: format = %q, want png", bpp, block.Format)
+		}
+		got, err := png.Decode(bytes.NewReader(imageBytes(t, block)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Bounds() != want.Bounds() {
+			t.Fatalf("%d-bit: bounds = %v, want %v", bpp, got.Bounds(), want.Bounds())
+		}
+		for y := range 2 {
+			for x := range 3 {
+				if g := color.NRGBAModel.Convert(got.At(x, y)); g != want.At(x, y) {
+					t.Errorf("%d-bit: pixel (%d,%d) = %v, want %v", bpp, x, y, g, want.At(x, y))
+				}
+			}
+		}
+	}
+}
+
+func TestImagePreprocessing_DownscalesOversizedImage(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage(maxImageDimension+800, 22)); err != nil {
+		t.Fatal(err)
+	}
+	part := ai.NewMediaPart("image/png", dataURL("image/png", buf.Bytes()))
+
+	block := converseImage(t, &Bedrock{ImagePreprocessing: true}, part)
+	if block.Format != types.ImageFormatPng {
+		t.Fatalf("format = %q, want png", block.Format)
+	}
+	cfg, err := png.DecodeConfig(bytes.NewReader(imageBytes(t, block)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Width != maxImageDimension || cfg.Height != 20 {
+		t.Errorf("size = %dx%d, want %dx20", cfg.Width, cfg.Height, maxImageDimension)
+	}
+}
+
+func TestImagePreprocessing_ShrinksImageOverByteLimit(t *testing.T) {
+	// Opaque noise barely compresses: 1200x1200 RGB is ~4.3 MB as PNG.
+	rng := rand.New(rand.NewSource(1))
+	img := image.NewNRGBA(image.Rect(0, 0, 1200, 1200))
+	rng.Read(img.Pix)
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 0xff
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() <= maxImageBytes {
+		t.Fatalf("test image is %d bytes, want over %d", buf.Len(), maxImageBytes)
+	}
+
+	mime, data, err := preprocessImage("image/png", buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mime != "image/png" || len(data) > maxImageBytes {
+		t.Fatalf("got %s of %d bytes, want png within %d", mime, len(data), maxImageBytes)
+	}
+	cfg, err := png.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Width != cfg.Height || cfg.Width >= 1200 {
+		t.Errorf("size = %dx%d, want a smaller square", cfg.Width, cfg.Height)
+	}
+}
+
+func TestPreprocessImages_LeavesAcceptedImagesAlone(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage(4, 4)); err != nil {
+		t.Fatal(err)
+	}
+	msgs := []*ai.Message{ai.NewUserMessage(
+		ai.NewTextPart("hi"),
+		ai.NewMediaPart("image/png", dataURL("image/png", buf.Bytes())),
+		ai.NewMediaPart("image/webp", dataURL("image/webp", []byte("not decodable here"))),
+	)}
+	got, err := preprocessImages(msgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if &got[0] != &msgs[0] || got[0] != msgs[0] {
+		t.Error("messages were copied although no image changed")
+	}
+
+	if _, err := preprocessImages([]*ai.Message{ai.NewUserMessage(
+		ai.NewMediaPart("image/bmp", dataURL("image/bmp", []byte("BM truncated"))),
+	)}); err == nil || !strings.Contains(err.Error(), "preprocess image") {
+		t.Errorf("corrupt BMP: error = %v", err)
+	}
+}
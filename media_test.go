@@ -0,0 +1,82 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDataURI_Variants(t *testing.T) {
+	tests := []struct {
+		name     string
+		uri      string
+		wantMIME string
+		wantData string
+	}{
+		{name: "standard base64", uri: "data:image/png;base64,aGVsbG8=", wantMIME: "image/png", wantData: "hello"},
+		{name: "missing padding", uri: "data:image/png;base64,aGVsbG8", wantMIME: "image/png", wantData: "hello"},
+		{name: "embedded whitespace", uri: "  data:image/png;base64,aGVs\n bG8=\n", wantMIME: "image/png", wantData: "hello"},
+		{name: "url-encoded base64", uri: "data:image/png;base64,aGVsbG8%3D", wantMIME: "image/png", wantData: "hello"},
+		{name: "url-safe alphabet", uri: "data:application/octet-stream;base64,-_8", wantMIME: "application/octet-stream", wantData: "\xfb\xff"},
+		{name: "extra parameters", uri: "data:text/plain;charset=utf-8;base64,aGVsbG8=", wantMIME: "text/plain", wantData: "hello"},
+		{name: "uppercase scheme and type", uri: "DATA:Image/PNG;BASE64,aGVsbG8=", wantMIME: "image/png", wantData: "hello"},
+		{name: "percent-encoded without base64", uri: "data:text/plain,hello%20world", wantMIME: "text/plain", wantData: "hello world"},
+		{name: "omitted media type", uri: "data:;base64,aGVsbG8=", wantMIME: "", wantData: "hello"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDataURI(tt.uri)
+			if err != nil {
+				t.Fatalf("parseDataURI() error = %v", err)
+			}
+			if got.mediaType != tt.wantMIME {
+				t.Errorf("mediaType = %q, want %q", got.mediaType, tt.wantMIME)
+			}
+			if string(got.data) != tt.wantData {
+				t.Errorf("data = %q, want %q", got.data, tt.wantData)
+			}
+		})
+	}
+}
+
+func TestParseDataURI_Malformed(t *testing.T) {
+	tests := []struct {
+		name    string
+		uri     string
+		wantErr string
+	}{
+		{name: "not a data URI", uri: "https://example.com/a.png", wantErr: `missing "data:" scheme`},
+		{name: "missing comma", uri: "data:image/png;base64", wantErr: "missing ','"},
+		{name: "invalid media type", uri: "data:png;base64,aGVsbG8=", wantErr: "invalid media type"},
+		{name: "invalid base64", uri: "data:image/png;base64,a", wantErr: "invalid base64 data"},
+		{name: "invalid percent-encoding", uri: "data:text/plain,%zz", wantErr: "invalid percent-encoding"},
+		{name: "empty data", uri: "data:image/png;base64,", wantErr: "empty data"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseDataURI(tt.uri)
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), "malformed data URI") || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"expvar"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+// Keys of the per-model maps [Bedrock.Metrics] records into.
+const (
+	metricRequests     = "requests"
+	metricErrors       = "errors"
+	metricInputTokens  = "input_tokens"
+	metricOutputTokens = "output_tokens"
+	metricLatency      = "latency_ms"
+)
+
+// latencyBucketsMs are the upper bounds, in milliseconds, of the latency
+// histogram buckets; slower calls fall in the implicit +Inf bucket.
+var latencyBucketsMs = []float64{100, 250, 500, 1000, 2500, 5000, 10000, 30000, 60000}
+
+// LatencyHistogram is an [expvar.Var] counting call latencies in cumulative
+// buckets, in the shape of a Prometheus histogram. Its JSON form is
+//
+//	{"buckets": {"100": 3, ..., "+Inf": 7}, "count": 7, "sum_ms": 5120.5}
+type LatencyHistogram struct {
+	mu     sync.Mutex
+	counts []int64 // One per latencyBucketsMs entry, plus +Inf; not cumulative
+	sum    float64
+}
+
+// Observe records a call that took d.
+func (h *LatencyHistogram) Observe(d time.Duration) {
+	ms := float64(d) / float64(time.Millisecond)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.counts == nil {
+		h.counts = make([]int64, len(latencyBucketsMs)+1)
+	}
+	i := 0
+	for i < len(latencyBucketsMs) && ms > latencyBucketsMs[i] {
+		i++
+	}
+	h.counts[i]++
+	h.sum += ms
+}
+
+// Count returns the number of recorded calls.
+func (h *LatencyHistogram) Count() int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var n int64
+	for _, c := range h.counts {
+		n += c
+	}
+	return n
+}
+
+// String returns the histogram as JSON, implementing [expvar.Var].
+func (h *LatencyHistogram) String() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var sb strings.Builder
+	sb.WriteString(`{"buckets": {`)
+	var total int64
+	for i := range len(latencyBucketsMs) + 1 {
+		if h.counts != nil {
+			total += h.counts[i]
+		}
+		le := "+Inf"
+		if i < len(latencyBucketsMs) {
+			le = fmt.Sprint(latencyBucketsMs[i])
+		}
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		fmt.Fprintf(&sb, "%q: %d", le, total)
+	}
+	fmt.Fprintf(&sb, `}, "count": %d, "sum_ms": %g}`, total, h.sum)
+	return sb.String()
+}
+
+// recordMetrics adds a text generation call on modelName to b.Metrics, if set.
+func (b *Bedrock) recordMetrics(modelName string, start time.Time, resp *ai.ModelResponse, err error) {
+	m := b.Metrics
+	if m == nil {
+		return
+	}
+	metricMap(m, metricRequests).Add(modelName, 1)
+	if err != nil {
+		metricMap(m, metricErrors).Add(modelName, 1)
+	}
+	if resp != nil && resp.Usage != nil {
+		metricMap(m, metricInputTokens).Add(modelName, int64(resp.Usage.InputTokens))
+		metricMap(m, metricOutputTokens).Add(modelName, int64(resp.Usage.OutputTokens))
+	}
+	latency := metricMap(m, metricLatency)
+	metricsMu.Lock()
+	h, ok := latency.Get(modelName).(*LatencyHistogram)
+	if !ok {
+		h = &LatencyHistogram{}
+		latency.Set(modelName, h)
+	}
+	metricsMu.Unlock()
+	h.Observe(time.Since(start))
+}
+
+// metricMap returns the per-model map stored under key in m, creating it on
+// first use.
+func metricMap(m *expvar.Map, key string) *expvar.Map {
+	if sub, ok := m.Get(key).(*expvar.Map); ok {
+		return sub
+	}
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	if sub, ok := m.Get(key).(*expvar.Map); ok {
+		return sub
+	}
+	sub := new(expvar.Map)
+	m.Set(key, sub)
+	return sub
+}
+
+// metricsMu serializes creation of the nested maps and histograms, since one
+// expvar.Map may be shared by several plugins.
+var metricsMu sync.Mutex
@@ -0,0 +1,123 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMetricsRecordsGenerateCalls(t *testing.T) {
+	var fail atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if fail.Load() {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprint(w, `{"message":"bad request"}`)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{
+			"output": {"message":{"role":"assistant","content":[{"text":"Paris"}]}},
+			"stopReason": "end_turn",
+			"usage": {"inputTokens": 12, "outputTokens": 3, "totalTokens": 15}
+		}`)
+	}))
+	defer server.Close()
+
+	b := newTestBedrock(server)
+	b.Metrics = new(expvar.Map)
+	const model = "amazon.nova-lite-v1:0"
+	ctx := context.Background()
+	for range 2 {
+		if _, err := b.GenerateText(ctx, model, "Capital of France?"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fail.Store(true)
+	if _, err := b.GenerateText(ctx, model, "Capital of France?"); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	counter := func(key string) string {
+		m, ok := b.Metrics.Get(key).(*expvar.Map)
+		if !ok {
+			t.Fatalf("metric %q missing", key)
+		}
+		v := m.Get(model)
+		if v == nil {
+			return ""
+		}
+		return v.String()
+	}
+	for key, want := range map[string]string{
+		metricRequests:     "3",
+		metricErrors:       "1",
+		metricInputTokens:  "24",
+		metricOutputTokens: "6",
+	} {
+		if got := counter(key); got != want {
+			t.Errorf("%s = %s, want %s", key, got, want)
+		}
+	}
+
+	h, ok := b.Metrics.Get(metricLatency).(*expvar.Map).Get(model).(*LatencyHistogram)
+	if !ok {
+		t.Fatal("latency histogram missing")
+	}
+	if got := h.Count(); got != 3 {
+		t.Errorf("latency count = %d, want 3", got)
+	}
+	var all map[string]any
+	if err := json.Unmarshal([]byte(b.Metrics.String()), &all); err != nil {
+		t.Fatalf("metrics are not valid JSON: %v\n%s", err, b.Metrics.String())
+	}
+}
+
+func TestLatencyHistogram(t *testing.T) {
+	var h LatencyHistogram
+	h.Observe(50 * time.Millisecond)
+	h.Observe(300 * time.Millisecond)
+	h.Observe(2 * time.Minute)
+
+	var got struct {
+		Buckets map[string]int64 `json:"buckets"`
+		Count   int64            `json:"count"`
+		SumMs   float64          `json:"sum_ms"`
+	}
+	if err := json.Unmarshal([]byte(h.String()), &got); err != nil {
+		t.Fatalf("String() is not valid JSON: %v\n%s", err, h.String())
+	}
+	for le, want := range map[string]int64{"100": 1, "250": 1, "500": 2, "60000": 2, "+Inf": 3} {
+		if got.Buckets[le] != want {
+			t.Errorf("bucket %s = %d, want %d", le, got.Buckets[le], want)
+		}
+	}
+	if got.Count != 3 || got.SumMs != 120350 {
+		t.Errorf("count, sum = %d, %g; want 3, 120350", got.Count, got.SumMs)
+	}
+	if (&LatencyHistogram{}).String() == "" {
+		t.Error("empty histogram has no JSON form")
+	}
+}
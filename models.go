@@ -18,22 +18,61 @@
 package bedrock
 
 import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/firebase/genkit/go/ai"
 )
 
 // inferenceProfilePrefixes lists all valid inference profile region prefixes.
 // When an inference profile is passed instead of a model ID, the prefix is
-// stripped before checking capabilities.
-var inferenceProfilePrefixes = []string{
-	"global.",
-	"us-gov.",
-	"us.",
-	"eu.",
-	"jp.",
-	"apac.",
-	"au.",
+// stripped before checking capabilities. [RegisterProfilePrefix] appends to it.
+var (
+	inferenceProfilePrefixesMu sync.RWMutex
+	inferenceProfilePrefixes   = []string{
+		"global.",
+		"us-gov.",
+		"us.",
+		"eu.",
+		"jp.",
+		"apac.",
+		"au.",
+	}
+)
+
+// profilePrefixPattern matches an inference profile prefix such as "ca." or
+// "us-gov.".
+var profilePrefixPattern = regexp.MustCompile(`^[a-z]+(?:-[a-z]+)*\.$`)
+
+// RegisterProfilePrefix adds an inference profile prefix, such as "ca.", for
+// a geography AWS added after this release. Model IDs with the prefix are then
+// recognized as inference profiles: the prefix is stripped for capability
+// lookup, and it becomes a valid [Bedrock.DefaultProfilePrefix]. The trailing
+// dot is optional. It panics on a malformed prefix, registering a known prefix
+// is a no-op, and it is safe to call concurrently with generation.
+func RegisterProfilePrefix(prefix string) {
+	prefix = strings.TrimSuffix(prefix, ".") + "."
+	if !profilePrefixPattern.MatchString(prefix) {
+		panic(fmt.Sprintf("bedrock: invalid inference profile prefix %q", prefix))
+	}
+	inferenceProfilePrefixesMu.Lock()
+	defer inferenceProfilePrefixesMu.Unlock()
+	if !slices.Contains(inferenceProfilePrefixes, prefix) {
+		inferenceProfilePrefixes = append(inferenceProfilePrefixes, prefix)
+	}
+}
+
+// profilePrefixes returns a snapshot of the known inference profile prefixes.
+func profilePrefixes() []string {
+	inferenceProfilePrefixesMu.RLock()
+	defer inferenceProfilePrefixesMu.RUnlock()
+	return slices.Clone(inferenceProfilePrefixes)
 }
 
 // modelCapabilities maps base Bedrock model IDs to their capabilities.
@@ -41,63 +80,72 @@ var inferenceProfilePrefixes = []string{
 // This consolidates the previous multimodalModels and toolSupportedModels lists.
 var modelCapabilities = map[string]ModelCapability{
 	// Anthropic Claude 3 models
-	"anthropic.claude-3-haiku-20240307-v1:0":    {Multimodal: true, Tools: true},
-	"anthropic.claude-3-sonnet-20240229-v1:0":   {Multimodal: true, Tools: true},
-	"anthropic.claude-3-opus-20240229-v1:0":     {Multimodal: true, Tools: true},
-	"anthropic.claude-3-5-haiku-20241022-v1:0":  {Multimodal: false, Tools: true},
-	"anthropic.claude-3-5-sonnet-20240620-v1:0": {Multimodal: true, Tools: true},
-	"anthropic.claude-3-5-sonnet-20241022-v2:0": {Multimodal: true, Tools: true},
-	"anthropic.claude-3-7-sonnet-20250219-v1:0": {Multimodal: true, Tools: true},
+	"anthropic.claude-3-haiku-20240307-v1:0":    {Multimodal: true, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 4096, MaxImages: 20, Profile: true, MaxStopSequences: 8191, ParallelTools: true},
+	"anthropic.claude-3-sonnet-20240229-v1:0":   {Multimodal: true, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 4096, MaxImages: 20, Profile: true, MaxStopSequences: 8191, ParallelTools: true},
+	"anthropic.claude-3-opus-20240229-v1:0":     {Multimodal: true, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 4096, MaxImages: 20, Profile: true, MaxStopSequences: 8191, ParallelTools: true},
+	"anthropic.claude-3-5-haiku-20241022-v1:0":  {Multimodal: false, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 8192, ToolCaching: true, Profile: true, MaxStopSequences: 8191, ParallelTools: true},
+	"anthropic.claude-3-5-sonnet-20240620-v1:0": {Multimodal: true, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 8192, MaxImages: 20, Profile: true, MaxStopSequences: 8191, ParallelTools: true},
+	"anthropic.claude-3-5-sonnet-20241022-v2:0": {Multimodal: true, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 8192, MaxImages: 20, Profile: true, MaxStopSequences: 8191, ParallelTools: true},
+	"anthropic.claude-3-7-sonnet-20250219-v1:0": {Multimodal: true, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 64000, MaxImages: 20, ToolCaching: true, Profile: true, MaxStopSequences: 8191, ParallelTools: true},
 	// Anthropic Claude 4/4.5/4.6 models
-	"anthropic.claude-haiku-4-5-20251001-v1:0":  {Multimodal: true, Tools: true},
-	"anthropic.claude-opus-4-1-20250805-v1:0":   {Multimodal: true, Tools: true},
-	"anthropic.claude-opus-4-20250514-v1:0":     {Multimodal: true, Tools: true},
-	"anthropic.claude-sonnet-4-20250514-v1:0":   {Multimodal: true, Tools: true},
-	"anthropic.claude-sonnet-4-5-20250929-v1:0": {Multimodal: true, Tools: true},
-	"anthropic.claude-opus-4-5-20251101-v1:0":   {Multimodal: true, Tools: true},
-	"anthropic.claude-sonnet-4-6":               {Multimodal: true, Tools: true},
-	"anthropic.claude-opus-4-6-v1":              {Multimodal: true, Tools: true},
+	"anthropic.claude-haiku-4-5-20251001-v1:0":  {Multimodal: true, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 64000, MaxImages: 20, ToolCaching: true, Profile: true, MaxStopSequences: 8191, ParallelTools: true},
+	"anthropic.claude-opus-4-1-20250805-v1:0":   {Multimodal: true, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 32000, MaxImages: 20, ToolCaching: true, Profile: true, MaxStopSequences: 8191, ParallelTools: true},
+	"anthropic.claude-opus-4-20250514-v1:0":     {Multimodal: true, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 32000, MaxImages: 20, ToolCaching: true, Profile: true, MaxStopSequences: 8191, ParallelTools: true},
+	"anthropic.claude-sonnet-4-20250514-v1:0":   {Multimodal: true, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 64000, MaxImages: 20, ToolCaching: true, Profile: true, MaxStopSequences: 8191, ParallelTools: true},
+	"anthropic.claude-sonnet-4-5-20250929-v1:0": {Multimodal: true, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 64000, MaxImages: 20, ToolCaching: true, Profile: true, MaxStopSequences: 8191, ParallelTools: true},
+	"anthropic.claude-opus-4-5-20251101-v1:0":   {Multimodal: true, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 64000, MaxImages: 20, ToolCaching: true, Profile: true, MaxStopSequences: 8191, ParallelTools: true},
+	"anthropic.claude-sonnet-4-6":               {Multimodal: true, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 64000, MaxImages: 20, ToolCaching: true, Profile: true, MaxStopSequences: 8191, ParallelTools: true},
+	"anthropic.claude-opus-4-6-v1":              {Multimodal: true, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 128000, MaxImages: 20, ToolCaching: true, Profile: true, MaxStopSequences: 8191, ParallelTools: true},
 	// Provisioned-throughput variants (28k/48k/200k context)
-	"anthropic.claude-3-haiku-20240307-v1:0:48k":   {Multimodal: true, Tools: true},
-	"anthropic.claude-3-haiku-20240307-v1:0:200k":  {Multimodal: true, Tools: true},
-	"anthropic.claude-3-sonnet-20240229-v1:0:28k":  {Multimodal: true, Tools: true},
-	"anthropic.claude-3-sonnet-20240229-v1:0:200k": {Multimodal: true, Tools: true},
+	"anthropic.claude-3-haiku-20240307-v1:0:48k":   {Multimodal: true, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 4096, MaxImages: 20, MaxStopSequences: 8191, ParallelTools: true},
+	"anthropic.claude-3-haiku-20240307-v1:0:200k":  {Multimodal: true, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 4096, MaxImages: 20, MaxStopSequences: 8191, ParallelTools: true},
+	"anthropic.claude-3-sonnet-20240229-v1:0:28k":  {Multimodal: true, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 4096, MaxImages: 20, MaxStopSequences: 8191, ParallelTools: true},
+	"anthropic.claude-3-sonnet-20240229-v1:0:200k": {Multimodal: true, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 4096, MaxImages: 20, MaxStopSequences: 8191, ParallelTools: true},
 	// Amazon Nova models
-	"amazon.nova-micro-v1:0":   {Multimodal: false, Tools: true},
-	"amazon.nova-lite-v1:0":    {Multimodal: true, Tools: true},
-	"amazon.nova-pro-v1:0":     {Multimodal: true, Tools: true},
-	"amazon.nova-premier-v1:0": {Multimodal: true, Tools: true},
-	// Cohere Command models
-	"cohere.command-r-v1:0":      {Multimodal: false, Tools: true},
-	"cohere.command-r-plus-v1:0": {Multimodal: false, Tools: true},
+	"amazon.nova-micro-v1:0":   {Multimodal: false, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 10000, Profile: true, ParallelTools: true},
+	"amazon.nova-lite-v1:0":    {Multimodal: true, Documents: true, Video: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 10000, Profile: true, ParallelTools: true},
+	"amazon.nova-pro-v1:0":     {Multimodal: true, Documents: true, Video: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 10000, Profile: true, ParallelTools: true},
+	"amazon.nova-premier-v1:0": {Multimodal: true, Documents: true, Video: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 32000, Profile: true, ParallelTools: true},
+	// Amazon Titan Text models (no tool use or system prompt; served through InvokeModel)
+	"amazon.titan-text-express-v1":   {Multimodal: false, Tools: false, MaxOutputTokens: 8192},
+	"amazon.titan-text-lite-v1":      {Multimodal: false, Tools: false, MaxOutputTokens: 4096},
+	"amazon.titan-text-premier-v1:0": {Multimodal: false, Tools: false, MaxOutputTokens: 3072},
+	// Cohere Command models (the legacy text models take no system prompt)
+	"cohere.command-text-v14":       {Multimodal: false, Tools: false, MaxOutputTokens: 4000, MaxStopSequences: 4},
+	"cohere.command-light-text-v14": {Multimodal: false, Tools: false, MaxOutputTokens: 4000, MaxStopSequences: 4},
+	"cohere.command-r-v1:0":         {Multimodal: false, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 4000, MaxStopSequences: 4, ParallelTools: true},
+	"cohere.command-r-plus-v1:0":    {Multimodal: false, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 4000, MaxStopSequences: 4, ParallelTools: true},
 	// Mistral models
-	"mistral.mistral-large-2402-v1:0": {Multimodal: false, Tools: true},
-	"mistral.mistral-large-2407-v1:0": {Multimodal: false, Tools: true},
-	"mistral.mistral-small-2402-v1:0": {Multimodal: false, Tools: true},
-	"mistral.pixtral-large-2502-v1:0": {Multimodal: true, Tools: true},
+	"mistral.mistral-large-2402-v1:0": {Multimodal: false, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 8192, MaxStopSequences: 10},
+	"mistral.mistral-large-2407-v1:0": {Multimodal: false, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 8192, MaxStopSequences: 10, ParallelTools: true},
+	"mistral.mistral-small-2402-v1:0": {Multimodal: false, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 8192, MaxStopSequences: 10},
+	// Served through InvokeModel with the instruct codec (see providerCodecs).
+	"mistral.mistral-7b-instruct-v0:2":   {Multimodal: false, Tools: false, MaxOutputTokens: 8192, MaxStopSequences: 10},
+	"mistral.mixtral-8x7b-instruct-v0:1": {Multimodal: false, Tools: false, MaxOutputTokens: 4096, MaxStopSequences: 10},
+	"mistral.pixtral-large-2502-v1:0":    {Multimodal: true, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 8192, Profile: true, MaxStopSequences: 10, ParallelTools: true},
 	// AI21 Labs Jamba models
-	"ai21.jamba-1-5-large-v1:0": {Multimodal: false, Tools: true},
-	"ai21.jamba-1-5-mini-v1:0":  {Multimodal: false, Tools: true},
+	"ai21.jamba-1-5-large-v1:0": {Multimodal: false, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 4096},
+	"ai21.jamba-1-5-mini-v1:0":  {Multimodal: false, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 4096},
 	// Meta Llama models
-	"meta.llama3-8b-instruct-v1:0":           {Multimodal: false, Tools: true},
-	"meta.llama3-70b-instruct-v1:0":          {Multimodal: false, Tools: true},
-	"meta.llama3-1-8b-instruct-v1:0":         {Multimodal: false, Tools: true},
-	"meta.llama3-1-70b-instruct-v1:0":        {Multimodal: false, Tools: true},
-	"meta.llama3-1-405b-instruct-v1:0":       {Multimodal: false, Tools: true},
-	"meta.llama3-2-1b-instruct-v1:0":         {Multimodal: false, Tools: true},
-	"meta.llama3-2-3b-instruct-v1:0":         {Multimodal: false, Tools: true},
-	"meta.llama3-2-11b-instruct-v1:0":        {Multimodal: true, Tools: true},
-	"meta.llama3-2-90b-instruct-v1:0":        {Multimodal: true, Tools: true},
-	"meta.llama3-3-70b-instruct-v1:0":        {Multimodal: false, Tools: true},
-	"meta.llama4-maverick-17b-instruct-v1:0": {Multimodal: true, Tools: true},
-	"meta.llama4-scout-17b-instruct-v1:0":    {Multimodal: true, Tools: true},
+	"meta.llama3-8b-instruct-v1:0":           {Multimodal: false, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 2048},
+	"meta.llama3-70b-instruct-v1:0":          {Multimodal: false, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 2048},
+	"meta.llama3-1-8b-instruct-v1:0":         {Multimodal: false, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 2048, Profile: true},
+	"meta.llama3-1-70b-instruct-v1:0":        {Multimodal: false, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 2048, Profile: true},
+	"meta.llama3-1-405b-instruct-v1:0":       {Multimodal: false, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 2048},
+	"meta.llama3-2-1b-instruct-v1:0":         {Multimodal: false, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 2048, Profile: true},
+	"meta.llama3-2-3b-instruct-v1:0":         {Multimodal: false, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 2048, Profile: true},
+	"meta.llama3-2-11b-instruct-v1:0":        {Multimodal: true, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 2048, Profile: true},
+	"meta.llama3-2-90b-instruct-v1:0":        {Multimodal: true, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 2048, Profile: true},
+	"meta.llama3-3-70b-instruct-v1:0":        {Multimodal: false, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 2048, Profile: true},
+	"meta.llama4-maverick-17b-instruct-v1:0": {Multimodal: true, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 8192, Profile: true},
+	"meta.llama4-scout-17b-instruct-v1:0":    {Multimodal: true, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 8192, Profile: true},
 	// DeepSeek models
-	"deepseek.r1-v1:0": {Multimodal: false, Tools: true},
+	"deepseek.r1-v1:0": {Multimodal: false, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 32768, Profile: true},
 	// Writer models
-	"writer.palmyra-x4-v1:0": {Multimodal: false, Tools: true},
-	"writer.palmyra-x5-v1:0": {Multimodal: false, Tools: true},
+	"writer.palmyra-x4-v1:0": {Multimodal: false, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 8192, Profile: true},
+	"writer.palmyra-x5-v1:0": {Multimodal: false, Documents: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 8192, Profile: true},
 	// TwelveLabs models
-	"twelvelabs.pegasus-1-2-v1:0": {Multimodal: false, Tools: true},
+	"twelvelabs.pegasus-1-2-v1:0": {Multimodal: false, Video: true, Tools: true, SystemPrompt: true, MaxOutputTokens: 4096, Profile: true},
 }
 
 // inferModelCapabilities infers model capabilities based on model name and type.
@@ -110,15 +158,22 @@ func (b *Bedrock) inferModelCapabilities(modelName, modelType string) *ai.ModelI
 	baseModelID := b.stripInferenceProfilePrefix(modelName)
 
 	// Look up capabilities from the map
-	caps, found := modelCapabilities[baseModelID]
+	caps, found := b.modelCapability(baseModelID)
 	stage := ai.ModelStageStable
 	if !found {
 		caps = ModelCapability{
-			Multimodal: true,
-			Tools:      true,
+			Multimodal:   true,
+			Tools:        true,
+			SystemPrompt: true,
+		}
+		if m, ok := b.discoveredModel(baseModelID); ok {
+			caps = discoveredCapability(m)
 		}
 		stage = ai.ModelStageUnstable
 	}
+	if b.isLegacyModel(baseModelID) {
+		stage = ai.ModelStageLegacy
+	}
 
 	switch modelType {
 	case "image":
@@ -154,13 +209,16 @@ func (b *Bedrock) inferModelCapabilities(modelName, modelType string) *ai.ModelI
 			},
 		}
 	default: // chat, text models
+		// With StripUnsupportedTools the plugin drops tools itself, so Genkit
+		// must let requests with tools through to it.
+		tools := caps.Tools || b.StripUnsupportedTools
 		return &ai.ModelInfo{
 			Label: modelName,
 			Stage: stage,
 			Supports: &ai.ModelSupports{
 				Multiturn:   true,
-				Tools:       caps.Tools,
-				ToolChoice:  caps.Tools,
+				Tools:       tools,
+				ToolChoice:  tools,
 				SystemRole:  true,
 				Media:       caps.Multimodal,
 				Constrained: ai.ConstrainedSupportNone,
@@ -169,7 +227,83 @@ func (b *Bedrock) inferModelCapabilities(modelName, modelType string) *ai.ModelI
 	}
 }
 
+// toolModelSuggestions names a tool-capable model per vendor prefix, used to
+// suggest an alternative when tools are sent to a model without tool use.
+var toolModelSuggestions = map[string]string{
+	"amazon":    "amazon.nova-lite-v1:0",
+	"anthropic": "anthropic.claude-3-5-haiku-20241022-v1:0",
+	"cohere":    "cohere.command-r-plus-v1:0",
+	"ai21":      "ai21.jamba-1-5-mini-v1:0",
+	"meta":      "meta.llama3-1-70b-instruct-v1:0",
+	"mistral":   "mistral.mistral-large-2407-v1:0",
+}
+
+// suggestToolModel returns a tool-capable model from the same vendor as
+// modelID, falling back to Amazon Nova Lite.
+func suggestToolModel(modelID string) string {
+	vendor, _, _ := strings.Cut(baseModelID(modelID), ".")
+	if suggestion, ok := toolModelSuggestions[vendor]; ok {
+		return suggestion
+	}
+	return toolModelSuggestions["amazon"]
+}
+
+// lookupModelCapability returns the curated capabilities for modelID,
+// stripping any inference profile prefix first. ok is false for models
+// outside the capability map.
+func lookupModelCapability(modelID string) (ModelCapability, bool) {
+	caps, ok := modelCapabilities[baseModelID(modelID)]
+	return caps, ok
+}
+
+// modelCapability is [lookupModelCapability] with the entries of the
+// plugin's CapabilitiesFile checked first.
+func (b *Bedrock) modelCapability(modelID string) (ModelCapability, bool) {
+	if caps, ok := b.capabilityOverrides[baseModelID(modelID)]; ok {
+		return caps, true
+	}
+	return lookupModelCapability(modelID)
+}
+
+// capabilityModelIDs returns the model IDs in the capability map.
+func capabilityModelIDs() []string {
+	return slices.Collect(maps.Keys(modelCapabilities))
+}
+
+// capabilityModelIDs returns the model IDs in the capability map and the
+// plugin's CapabilitiesFile.
+func (b *Bedrock) capabilityModelIDs() []string {
+	ids := capabilityModelIDs()
+	for id := range b.capabilityOverrides {
+		if _, ok := modelCapabilities[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// supportsSystemPrompt reports whether modelID accepts a Converse system
+// prompt. Unknown models are assumed to.
+func (b *Bedrock) supportsSystemPrompt(modelID string) bool {
+	caps, ok := b.modelCapability(modelID)
+	return !ok || caps.SystemPrompt
+}
+
+// supportsToolCaching reports whether modelID accepts a cache point in its
+// tool configuration. Unknown models are assumed not to.
+func (b *Bedrock) supportsToolCaching(modelID string) bool {
+	caps, ok := b.modelCapability(modelID)
+	return ok && caps.ToolCaching
+}
+
 func (b *Bedrock) stripInferenceProfilePrefix(modelID string) string {
+	return baseModelID(modelID)
+}
+
+// baseModelID strips a known inference profile prefix from modelID.
+func baseModelID(modelID string) string {
+	inferenceProfilePrefixesMu.RLock()
+	defer inferenceProfilePrefixesMu.RUnlock()
 	for _, prefix := range inferenceProfilePrefixes {
 		if strings.HasPrefix(modelID, prefix) {
 			return strings.TrimPrefix(modelID, prefix)
@@ -177,3 +311,133 @@ func (b *Bedrock) stripInferenceProfilePrefix(modelID string) string {
 	}
 	return modelID
 }
+
+// suggestModelID returns the model ID closest to modelID by edit distance,
+// keeping its inference profile prefix, or "" when modelID is already known or
+// nothing is close enough to be a likely typo. Candidates are the known
+// model IDs and their versionless forms, which [ResolveModelID] accepts.
+func suggestModelID(modelID string, known []string) string {
+	base := baseModelID(modelID)
+	if slices.Contains(known, base) {
+		return ""
+	}
+	candidates := map[string]bool{}
+	for _, id := range known {
+		candidates[id] = true
+		if loc := modelVersionSuffix.FindStringIndex(id); loc != nil {
+			candidates[id[:loc[0]]] = true
+		}
+	}
+	maxDistance := max(2, len(base)/5)
+	best, bestDistance := "", maxDistance+1
+	for _, id := range slices.Sorted(maps.Keys(candidates)) {
+		if d := levenshtein(base, id); d < bestDistance {
+			best, bestDistance = id, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return strings.TrimSuffix(modelID, base) + best
+}
+
+// levenshtein returns the number of single-byte insertions, deletions and
+// substitutions that turn a into b.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// isProfileModelID reports whether modelID carries an inference profile
+// prefix, so its capabilities are those of the base model after the prefix.
+func isProfileModelID(modelID string) bool {
+	return baseModelID(modelID) != modelID
+}
+
+// modelVersionSuffix matches the trailing version of a Bedrock model ID, such
+// as "-20241022-v2:0", "-2407-v1:0", "-v1:0", or "-v1".
+var modelVersionSuffix = regexp.MustCompile(`-(?:(\d{4,8})-)?v(\d+)(?::(\d+))?$`)
+
+// ResolveModelID maps a versionless model ID such as
+// "anthropic.claude-3-5-sonnet" to the latest matching version in the
+// capability map ("anthropic.claude-3-5-sonnet-20241022-v2:0"). Inference
+// profile prefixes are preserved. IDs that are already in the map, already
+// carry a version suffix, or are ARNs are returned unchanged. It fails when the
+// ID matches no known model or several model families (for example
+// "anthropic.claude-3-5", which matches both Haiku and Sonnet).
+func ResolveModelID(modelID string) (string, error) {
+	return resolveModelVersion(modelID, capabilityModelIDs())
+}
+
+// resolveModelVersion is [ResolveModelID] against the known model IDs.
+func resolveModelVersion(modelID string, known []string) (string, error) {
+	base := baseModelID(modelID)
+	prefix := strings.TrimSuffix(modelID, base)
+	if slices.Contains(known, base) || strings.HasPrefix(modelID, "arn:") || modelVersionSuffix.MatchString(base) {
+		return modelID, nil
+	}
+
+	families := map[string][]string{}
+	for _, id := range known {
+		loc := modelVersionSuffix.FindStringIndex(id)
+		if loc == nil || !strings.HasPrefix(id, base+"-") {
+			continue
+		}
+		family := id[:loc[0]]
+		families[family] = append(families[family], id)
+	}
+
+	var versions []string
+	switch {
+	case len(families[base]) > 0:
+		versions = families[base]
+	case len(families) == 1:
+		for _, ids := range families {
+			versions = ids
+		}
+	case len(families) == 0:
+		if suggestion := suggestModelID(modelID, known); suggestion != "" {
+			return "", fmt.Errorf("bedrock: unknown model ID %q: no versioned model in the capability map matches it; did you mean %q?", modelID, suggestion)
+		}
+		return "", fmt.Errorf("bedrock: unknown model ID %q: no versioned model in the capability map matches it", modelID)
+	default:
+		names := make([]string, 0, len(families))
+		for family := range families {
+			names = append(names, family)
+		}
+		sort.Strings(names)
+		return "", fmt.Errorf("bedrock: model ID %q is ambiguous; it matches %s", modelID, strings.Join(names, ", "))
+	}
+
+	sort.Slice(versions, func(i, j int) bool { return modelVersionLess(versions[j], versions[i]) })
+	return prefix + versions[0], nil
+}
+
+// modelVersionLess orders two IDs of the same model family by release date,
+// then major and minor version.
+func modelVersionLess(a, b string) bool {
+	va, vb := modelVersionSuffix.FindStringSubmatch(a), modelVersionSuffix.FindStringSubmatch(b)
+	for i := 1; i < len(va); i++ {
+		x, _ := strconv.Atoi(va[i])
+		y, _ := strconv.Atoi(vb[i])
+		if x != y {
+			return x < y
+		}
+	}
+	return a < b
+}
@@ -0,0 +1,101 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import "strings"
+
+// completePartialJSON closes the strings, objects and arrays left open in s,
+// a prefix of a JSON document, and decodes the result like a complete tool
+// input (see decodeToolInput). It is best effort: an unfinished object key is
+// dropped, and ok is false when the prefix ends somewhere it cannot be
+// closed, such as inside a literal or after a key.
+func completePartialJSON(s string) (v any, ok bool) {
+	var closers []byte
+	inString, escaped := false, false
+	stringStart, isKey := 0, false // Where the last string began, and whether it is an object key
+	unicodeEscape := -1            // Index of the last "\u" escape in s, while in a string
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+				if c == 'u' {
+					unicodeEscape = i - 1
+				}
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString, unicodeEscape = true, -1
+			stringStart, isKey = i, isObjectKey(s[:i], closers)
+		case '{':
+			closers = append(closers, '}')
+		case '[':
+			closers = append(closers, ']')
+		case '}', ']':
+			if len(closers) == 0 || closers[len(closers)-1] != c {
+				return nil, false
+			}
+			closers = closers[:len(closers)-1]
+		}
+	}
+
+	if inString && isKey {
+		// An unfinished key has no value yet; drop it.
+		s, inString = s[:stringStart], false
+	}
+	var sb strings.Builder
+	if inString {
+		switch {
+		case escaped:
+			s = s[:len(s)-1]
+		case unicodeEscape >= 0 && len(s)-unicodeEscape < 6:
+			s = s[:unicodeEscape]
+		}
+		sb.WriteString(s)
+		sb.WriteByte('"')
+	} else {
+		s = strings.TrimRight(s, " \t\r\n")
+		s = strings.TrimSuffix(s, ",")
+		sb.WriteString(s)
+		if strings.HasSuffix(s, ":") {
+			sb.WriteString("null")
+		}
+	}
+	for i := len(closers) - 1; i >= 0; i-- {
+		sb.WriteByte(closers[i])
+	}
+	v, err := decodeToolInput(sb.String())
+	return v, err == nil
+}
+
+// isObjectKey reports whether a string starting after prefix is an object
+// key: it is directly inside an object and follows its "{" or a ",".
+func isObjectKey(prefix string, closers []byte) bool {
+	if len(closers) == 0 || closers[len(closers)-1] != '}' {
+		return false
+	}
+	prefix = strings.TrimRight(prefix, " \t\r\n")
+	return strings.HasSuffix(prefix, "{") || strings.HasSuffix(prefix, ",")
+}
@@ -0,0 +1,70 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestCompletePartialJSON(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want string // JSON of the completed value; "" when it cannot be completed
+	}{
+		{`{`, `{}`},
+		{`{"a":1`, `{"a":1}`},
+		{`{"a":1,`, `{"a":1}`},
+		{`{"a":`, `{"a":null}`},
+		{`{"a":"he`, `{"a":"he"}`},
+		{`{"a":"line\`, `{"a":"line"}`},
+		{`{"a":"caf\u00`, `{"a":"caf"}`},
+		{`{"a":"café`, `{"a":"café"}`},
+		{`{"a":[1,[2,`, `{"a":[1,[2]]}`},
+		{`{"a":"}]"`, `{"a":"}]"}`},
+		{`[{"a":1},{"b`, `[{"a":1},{}]`},
+		{`{"a":1, "bc`, `{"a":1}`},
+		{`[{"a":1},{"b"`, ``},
+		{`{"a":tr`, ``},
+		{`{"a":1]`, ``},
+		{`{"a":1}`, `{"a":1}`},
+	} {
+		got, ok := completePartialJSON(tc.in)
+		if tc.want == "" {
+			if ok {
+				t.Errorf("completePartialJSON(%q) = %v, want failure", tc.in, got)
+			}
+			continue
+		}
+		if !ok {
+			t.Errorf("completePartialJSON(%q) failed, want %s", tc.in, tc.want)
+			continue
+		}
+		var want any
+		if err := json.Unmarshal([]byte(tc.want), &want); err != nil {
+			t.Fatal(err)
+		}
+		gotJSON, _ := json.Marshal(got)
+		var gotNorm any
+		_ = json.Unmarshal(gotJSON, &gotNorm)
+		if !reflect.DeepEqual(gotNorm, want) {
+			t.Errorf("completePartialJSON(%q) = %s, want %s", tc.in, gotJSON, tc.want)
+		}
+	}
+}
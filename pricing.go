@@ -0,0 +1,128 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+// ModelPricing is the on-demand price of a Bedrock model, in USD.
+type ModelPricing struct {
+	InputPer1K      float64 // Per 1,000 uncached input tokens
+	OutputPer1K     float64 // Per 1,000 output tokens
+	CacheReadPer1K  float64 // Per 1,000 tokens read from the prompt cache (0: billed as input)
+	CacheWritePer1K float64 // Per 1,000 tokens written to the prompt cache (0: billed as input)
+	PerImage        float64 // Per generated image
+}
+
+// modelPricing maps base Bedrock model IDs to us-east-1 on-demand list
+// prices. Inference profile prefixes are stripped before lookup. Keep this
+// table in sync with https://aws.amazon.com/bedrock/pricing/; prices for
+// other regions, provisioned throughput, and batch inference differ.
+var modelPricing = map[string]ModelPricing{
+	// Anthropic Claude models
+	"anthropic.claude-3-haiku-20240307-v1:0":    {InputPer1K: 0.00025, OutputPer1K: 0.00125},
+	"anthropic.claude-3-sonnet-20240229-v1:0":   {InputPer1K: 0.003, OutputPer1K: 0.015},
+	"anthropic.claude-3-opus-20240229-v1:0":     {InputPer1K: 0.015, OutputPer1K: 0.075},
+	"anthropic.claude-3-5-haiku-20241022-v1:0":  {InputPer1K: 0.0008, OutputPer1K: 0.004, CacheReadPer1K: 0.00008, CacheWritePer1K: 0.001},
+	"anthropic.claude-3-5-sonnet-20240620-v1:0": {InputPer1K: 0.003, OutputPer1K: 0.015},
+	"anthropic.claude-3-5-sonnet-20241022-v2:0": {InputPer1K: 0.003, OutputPer1K: 0.015},
+	"anthropic.claude-3-7-sonnet-20250219-v1:0": {InputPer1K: 0.003, OutputPer1K: 0.015, CacheReadPer1K: 0.0003, CacheWritePer1K: 0.00375},
+	"anthropic.claude-haiku-4-5-20251001-v1:0":  {InputPer1K: 0.001, OutputPer1K: 0.005, CacheReadPer1K: 0.0001, CacheWritePer1K: 0.00125},
+	"anthropic.claude-sonnet-4-20250514-v1:0":   {InputPer1K: 0.003, OutputPer1K: 0.015, CacheReadPer1K: 0.0003, CacheWritePer1K: 0.00375},
+	"anthropic.claude-sonnet-4-5-20250929-v1:0": {InputPer1K: 0.003, OutputPer1K: 0.015, CacheReadPer1K: 0.0003, CacheWritePer1K: 0.00375},
+	"anthropic.claude-opus-4-20250514-v1:0":     {InputPer1K: 0.015, OutputPer1K: 0.075, CacheReadPer1K: 0.0015, CacheWritePer1K: 0.01875},
+	"anthropic.claude-opus-4-1-20250805-v1:0":   {InputPer1K: 0.015, OutputPer1K: 0.075, CacheReadPer1K: 0.0015, CacheWritePer1K: 0.01875},
+	"anthropic.claude-opus-4-5-20251101-v1:0":   {InputPer1K: 0.005, OutputPer1K: 0.025, CacheReadPer1K: 0.0005, CacheWritePer1K: 0.00625},
+	// Amazon Nova models (cache writes are not billed separately)
+	"amazon.nova-micro-v1:0":   {InputPer1K: 0.000035, OutputPer1K: 0.00014, CacheReadPer1K: 0.00000875},
+	"amazon.nova-lite-v1:0":    {InputPer1K: 0.00006, OutputPer1K: 0.00024, CacheReadPer1K: 0.000015},
+	"amazon.nova-pro-v1:0":     {InputPer1K: 0.0008, OutputPer1K: 0.0032, CacheReadPer1K: 0.0002},
+	"amazon.nova-premier-v1:0": {InputPer1K: 0.0025, OutputPer1K: 0.0125},
+	// Cohere Command models
+	"cohere.command-r-v1:0":      {InputPer1K: 0.0005, OutputPer1K: 0.0015},
+	"cohere.command-r-plus-v1:0": {InputPer1K: 0.003, OutputPer1K: 0.015},
+	// Mistral models
+	"mistral.mistral-large-2402-v1:0": {InputPer1K: 0.004, OutputPer1K: 0.012},
+	"mistral.mistral-small-2402-v1:0": {InputPer1K: 0.001, OutputPer1K: 0.003},
+	"mistral.pixtral-large-2502-v1:0": {InputPer1K: 0.002, OutputPer1K: 0.006},
+	// Meta Llama models
+	"meta.llama3-8b-instruct-v1:0":           {InputPer1K: 0.0003, OutputPer1K: 0.0006},
+	"meta.llama3-70b-instruct-v1:0":          {InputPer1K: 0.00265, OutputPer1K: 0.0035},
+	"meta.llama3-1-8b-instruct-v1:0":         {InputPer1K: 0.00022, OutputPer1K: 0.00022},
+	"meta.llama3-1-70b-instruct-v1:0":        {InputPer1K: 0.00072, OutputPer1K: 0.00072},
+	"meta.llama3-1-405b-instruct-v1:0":       {InputPer1K: 0.0024, OutputPer1K: 0.0024},
+	"meta.llama3-2-1b-instruct-v1:0":         {InputPer1K: 0.0001, OutputPer1K: 0.0001},
+	"meta.llama3-2-3b-instruct-v1:0":         {InputPer1K: 0.00015, OutputPer1K: 0.00015},
+	"meta.llama3-2-11b-instruct-v1:0":        {InputPer1K: 0.00016, OutputPer1K: 0.00016},
+	"meta.llama3-2-90b-instruct-v1:0":        {InputPer1K: 0.00072, OutputPer1K: 0.00072},
+	"meta.llama3-3-70b-instruct-v1:0":        {InputPer1K: 0.00072, OutputPer1K: 0.00072},
+	"meta.llama4-maverick-17b-instruct-v1:0": {InputPer1K: 0.00024, OutputPer1K: 0.00097},
+	"meta.llama4-scout-17b-instruct-v1:0":    {InputPer1K: 0.00017, OutputPer1K: 0.00066},
+	// DeepSeek models
+	"deepseek.r1-v1:0": {InputPer1K: 0.00135, OutputPer1K: 0.0054},
+	// Image generation models (standard quality, 1024x1024)
+	"amazon.titan-image-generator-v1":   {PerImage: 0.01},
+	"amazon.titan-image-generator-v2:0": {PerImage: 0.01},
+	"amazon.nova-canvas-v1:0":           {PerImage: 0.04},
+	"stability.stable-diffusion-xl-v1":  {PerImage: 0.04},
+	"stability.sd3-large-v1:0":          {PerImage: 0.08},
+	"stability.stable-image-core-v1:1":  {PerImage: 0.04},
+	"stability.stable-image-ultra-v1:1": {PerImage: 0.14},
+}
+
+// EstimateCost returns the estimated on-demand cost in USD of a single
+// request to modelID with the given usage, typically [ai.ModelResponse.Usage].
+//
+// Uncached input tokens, cache reads ([ai.GenerationUsage.CachedContentTokens]),
+// cache writes (reported under the "cacheWriteInputTokens" custom usage key),
+// output tokens, and generated images are each billed at their own rate. The
+// estimate uses us-east-1 list prices and is intended for dashboards, not
+// billing reconciliation.
+func EstimateCost(modelID string, usage ai.GenerationUsage) (float64, error) {
+	if modelID == "" {
+		return 0, errors.New("bedrock.EstimateCost: model ID required")
+	}
+	pricing, ok := modelPricing[baseModelID(modelID)]
+	if !ok {
+		return 0, fmt.Errorf("bedrock.EstimateCost: no pricing for model %q", modelID)
+	}
+
+	cacheWrite := 0
+	if usage.Custom != nil {
+		cacheWrite = int(usage.Custom[cacheWriteInputTokensUsageKey])
+	}
+	readRate := pricing.CacheReadPer1K
+	if readRate == 0 {
+		readRate = pricing.InputPer1K
+	}
+	writeRate := pricing.CacheWritePer1K
+	if writeRate == 0 {
+		writeRate = pricing.InputPer1K
+	}
+
+	cost := float64(usage.InputTokens)/1000*pricing.InputPer1K +
+		float64(usage.CachedContentTokens)/1000*readRate +
+		float64(cacheWrite)/1000*writeRate +
+		float64(usage.OutputTokens)/1000*pricing.OutputPer1K +
+		float64(usage.OutputImages)*pricing.PerImage
+	return cost, nil
+}
@@ -0,0 +1,92 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/firebase/genkit/go/ai"
+)
+
+func TestEstimateCost_TokensAndCache(t *testing.T) {
+	usage := ai.GenerationUsage{
+		InputTokens:         2000,
+		OutputTokens:        1000,
+		CachedContentTokens: 10000,
+		Custom:              map[string]float64{cacheWriteInputTokensUsageKey: 4000},
+	}
+	got, err := EstimateCost("us.anthropic.claude-3-7-sonnet-20250219-v1:0", usage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 2*0.003 + 10*0.0003 + 4*0.00375 + 1*0.015
+	want := 0.006 + 0.003 + 0.015 + 0.015
+	if math.Abs(got-want) > 1e-9 {
+		t.Fatalf("EstimateCost = %v, want %v", got, want)
+	}
+}
+
+func TestEstimateCost_CacheBilledAsInputWithoutDiscount(t *testing.T) {
+	got, err := EstimateCost("anthropic.claude-3-haiku-20240307-v1:0", ai.GenerationUsage{
+		InputTokens:         1000,
+		CachedContentTokens: 1000,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 0.0005; math.Abs(got-want) > 1e-9 {
+		t.Fatalf("EstimateCost = %v, want %v", got, want)
+	}
+}
+
+func TestEstimateCost_Images(t *testing.T) {
+	got, err := EstimateCost("amazon.nova-canvas-v1:0", ai.GenerationUsage{OutputImages: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 0.12; math.Abs(got-want) > 1e-9 {
+		t.Fatalf("EstimateCost = %v, want %v", got, want)
+	}
+}
+
+func TestEstimateCost_Errors(t *testing.T) {
+	if _, err := EstimateCost("", ai.GenerationUsage{}); err == nil {
+		t.Fatal("expected error for empty model ID")
+	}
+	_, err := EstimateCost("vendor.unknown-v1:0", ai.GenerationUsage{InputTokens: 1})
+	if err == nil || !strings.Contains(err.Error(), "no pricing") {
+		t.Fatalf("error = %v, want no pricing error", err)
+	}
+}
+
+func TestUsageFromTokens_CacheWriteCustomKey(t *testing.T) {
+	got := usageFromTokens(&types.TokenUsage{
+		InputTokens:           aws.Int32(10),
+		CacheWriteInputTokens: aws.Int32(300),
+	})
+	if got.Custom[cacheWriteInputTokensUsageKey] != 300 {
+		t.Fatalf("Custom = %v, want cacheWriteInputTokens=300", got.Custom)
+	}
+	if got := usageFromTokens(&types.TokenUsage{InputTokens: aws.Int32(10)}); got.Custom != nil {
+		t.Fatalf("Custom = %v, want nil without cache writes", got.Custom)
+	}
+}
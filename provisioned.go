@@ -0,0 +1,94 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/smithy-go"
+	"github.com/firebase/genkit/go/ai"
+)
+
+// provisionedUnavailableCodes are the error codes with which Bedrock rejects
+// a call to provisioned throughput that is exhausted, not yet ready, or gone.
+var provisionedUnavailableCodes = []string{
+	"ThrottlingException",
+	"ServiceUnavailableException",
+	"ModelNotReadyException",
+	"ResourceNotFoundException",
+}
+
+// provisionedModelARN returns the provisioned throughput ARN configured in
+// ProvisionedModels for modelName, exact or without its inference profile
+// prefix.
+func (b *Bedrock) provisionedModelARN(modelName string) (string, bool) {
+	arn, ok := b.ProvisionedModels[modelName]
+	if !ok {
+		arn, ok = b.ProvisionedModels[baseModelID(modelName)]
+	}
+	return arn, ok && arn != ""
+}
+
+// provisionedUnavailable reports whether err means the provisioned
+// throughput could not serve the call, so on-demand may.
+func provisionedUnavailable(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return slices.Contains(provisionedUnavailableCodes, apiErr.ErrorCode()) || isModelNotFound(apiErr)
+}
+
+// converse calls Converse, or ConverseStream when cb is set, for modelName.
+// When ProvisionedModels maps the model to a provisioned throughput ARN the
+// call goes there first, and falls back to the on-demand model when the
+// provisioned capacity is unavailable and nothing has been streamed yet.
+func (b *Bedrock) converse(ctx context.Context, client BedrockClient, modelName string, input *bedrockruntime.ConverseInput, originalInput *ai.ModelRequest, cb func(context.Context, *ai.ModelResponseChunk) error) (*ai.ModelResponse, error) {
+	call := func(input *bedrockruntime.ConverseInput, cb func(context.Context, *ai.ModelResponseChunk) error) (*ai.ModelResponse, error) {
+		if cb != nil {
+			return b.generateTextStream(ctx, client, input, originalInput, cb)
+		}
+		return b.generateTextSync(ctx, client, input, originalInput)
+	}
+	arn, ok := b.provisionedModelARN(modelName)
+	if !ok {
+		return call(input, cb)
+	}
+
+	provisioned := *input
+	provisioned.ModelId = aws.String(arn)
+	streamed := false
+	provisionedCB := cb
+	if cb != nil {
+		provisionedCB = func(ctx context.Context, chunk *ai.ModelResponseChunk) error {
+			streamed = true
+			return cb(ctx, chunk)
+		}
+	}
+	resp, err := call(&provisioned, provisionedCB)
+	if err == nil || streamed || ctx.Err() != nil || !provisionedUnavailable(err) {
+		return resp, err
+	}
+	slog.Warn("bedrock: provisioned throughput unavailable; falling back to on-demand", "model", modelName, "provisioned", arn, "error", err)
+	return call(input, cb)
+}
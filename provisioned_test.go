@@ -0,0 +1,168 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/firebase/genkit/go/ai"
+)
+
+const testProvisionedARN = "arn:aws:bedrock:us-east-1:123456789012:provisioned-model/abc123"
+
+// provisionedServer answers Converse calls, failing those to the provisioned
+// ARN with errorType unless it is empty, and records the model each call
+// addressed.
+func provisionedServer(t *testing.T, errorType string) (*httptest.Server, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var models []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		model := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/model/"), "/converse")
+		mu.Lock()
+		models = append(models, model)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		if model == testProvisionedARN && errorType != "" {
+			w.Header().Set("X-Amzn-Errortype", errorType)
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = fmt.Fprint(w, `{"message":"provisioned model unavailable"}`)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"output":{"message":{"role":"assistant","content":[{"text":"served by %s"}]}},"stopReason":"end_turn"}`, model)
+	}))
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return models
+	}
+}
+
+func TestGenerateText_ProvisionedModelPreferred(t *testing.T) {
+	server, models := provisionedServer(t, "")
+	defer server.Close()
+	b := newTestBedrock(server)
+	b.ProvisionedModels = map[string]string{"anthropic.claude-3-haiku-20240307-v1:0": testProvisionedARN}
+
+	// A profile ID maps through its base model.
+	resp, err := b.generateText(context.Background(), "us.anthropic.claude-3-haiku-20240307-v1:0", &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("hi")},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := models(); len(got) != 1 || got[0] != testProvisionedARN {
+		t.Errorf("models called = %q, want only the provisioned ARN", got)
+	}
+	if got := resp.Text(); got != "served by "+testProvisionedARN {
+		t.Errorf("text = %q", got)
+	}
+}
+
+func TestGenerateText_ProvisionedModelFallsBackToOnDemand(t *testing.T) {
+	server, models := provisionedServer(t, "ResourceNotFoundException")
+	defer server.Close()
+	b := newTestBedrock(server)
+	b.ProvisionedModels = map[string]string{"anthropic.claude-3-haiku-20240307-v1:0": testProvisionedARN}
+
+	resp, err := b.generateText(context.Background(), "anthropic.claude-3-haiku-20240307-v1:0", &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("hi")},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{testProvisionedARN, "anthropic.claude-3-haiku-20240307-v1:0"}
+	if got := models(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("models called = %q, want %q", got, want)
+	}
+	if got := resp.Text(); got != "served by anthropic.claude-3-haiku-20240307-v1:0" {
+		t.Errorf("text = %q", got)
+	}
+}
+
+func TestGenerateText_ProvisionedModelOtherErrorsNotRetried(t *testing.T) {
+	server, models := provisionedServer(t, "AccessDeniedException")
+	defer server.Close()
+	b := newTestBedrock(server)
+	b.ProvisionedModels = map[string]string{"anthropic.claude-3-haiku-20240307-v1:0": testProvisionedARN}
+
+	_, err := b.generateText(context.Background(), "anthropic.claude-3-haiku-20240307-v1:0", &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("hi")},
+	}, nil)
+	var denied *AccessDeniedError
+	if !errors.As(err, &denied) {
+		t.Fatalf("error = %v (%T), want *AccessDeniedError", err, err)
+	}
+	if got := models(); len(got) != 1 {
+		t.Errorf("models called = %q, want no on-demand fallback", got)
+	}
+}
+
+func TestGenerateText_UnmappedModelCallsOnDemand(t *testing.T) {
+	server, models := provisionedServer(t, "")
+	defer server.Close()
+	b := newTestBedrock(server)
+	b.ProvisionedModels = map[string]string{"amazon.nova-pro-v1:0": testProvisionedARN}
+
+	if _, err := b.generateText(context.Background(), "amazon.nova-lite-v1:0", &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("hi")},
+	}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := models(); len(got) != 1 || got[0] != "amazon.nova-lite-v1:0" {
+		t.Errorf("models called = %q, want on-demand only", got)
+	}
+}
+
+func TestProvisionedUnavailable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&types.ThrottlingException{Message: aws.String("Too many requests")}, true},
+		{&types.ServiceUnavailableException{Message: aws.String("unavailable")}, true},
+		{&types.ModelNotReadyException{Message: aws.String("not ready")}, true},
+		{&types.ResourceNotFoundException{Message: aws.String("not found")}, true},
+		{&types.ValidationException{Message: aws.String("The provided model identifier is invalid.")}, true},
+		{&types.ValidationException{Message: aws.String("temperature: range: 0..1")}, false},
+		{&types.AccessDeniedException{Message: aws.String("denied")}, false},
+		{errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		if got := provisionedUnavailable(fmt.Errorf("call: %w", tt.err)); got != tt.want {
+			t.Errorf("provisionedUnavailable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestInitPanicsOnEmptyProvisionedModelARN(t *testing.T) {
+	isolateAWSConfig(t)
+
+	assertPanicsContains(t, `ProvisionedModels["amazon.nova-pro-v1:0"] is empty`, func() {
+		(&Bedrock{Region: "us-east-1", ProvisionedModels: map[string]string{"amazon.nova-pro-v1:0": ""}}).Init(context.Background())
+	})
+}
@@ -0,0 +1,137 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/firebase/genkit/go/ai"
+)
+
+// hiddenReasoningMetadataKey holds the reasoning parts of a response whose
+// request set [Config.IncludeReasoning] to false, on the response message
+// metadata. They are sent back to the model on later turns like inline
+// reasoning, as Claude requires with tools and thinking.
+const hiddenReasoningMetadataKey = "bedrockReasoning"
+
+// includeReasoning reports whether cfg keeps reasoning parts in responses.
+func includeReasoning(cfg *Config) bool {
+	return cfg == nil || cfg.IncludeReasoning == nil || *cfg.IncludeReasoning
+}
+
+// hideReasoning moves the reasoning parts of resp into its message metadata
+// and logs them at debug level.
+func hideReasoning(resp *ai.ModelResponse) {
+	if resp == nil || resp.Message == nil {
+		return
+	}
+	var hidden, content []*ai.Part
+	for _, part := range resp.Message.Content {
+		if part != nil && part.IsReasoning() {
+			hidden = append(hidden, part)
+			continue
+		}
+		content = append(content, part)
+	}
+	if len(hidden) == 0 {
+		return
+	}
+	for _, part := range hidden {
+		slog.Debug("bedrock: reasoning excluded from response", "reasoning", part.Text)
+	}
+	resp.Message.Content = withAnswerPart(content)
+	if resp.Message.Metadata == nil {
+		resp.Message.Metadata = map[string]any{}
+	}
+	resp.Message.Metadata[hiddenReasoningMetadataKey] = hidden
+}
+
+// reasoninglessStreamCallback wraps cb so that streamed reasoning parts are
+// not delivered; chunks left empty are skipped.
+func reasoninglessStreamCallback(cb func(context.Context, *ai.ModelResponseChunk) error) func(context.Context, *ai.ModelResponseChunk) error {
+	return func(ctx context.Context, chunk *ai.ModelResponseChunk) error {
+		var content []*ai.Part
+		for _, part := range chunk.Content {
+			if part != nil && !part.IsReasoning() {
+				content = append(content, part)
+			}
+		}
+		if len(content) == 0 && len(chunk.Content) > 0 {
+			return nil
+		}
+		filtered := *chunk
+		filtered.Content = content
+		return cb(ctx, &filtered)
+	}
+}
+
+// hiddenReasoning returns the reasoning parts stored in metadata, also
+// accepting their JSON-decoded form from a serialized history.
+func hiddenReasoning(metadata map[string]any) []*ai.Part {
+	switch v := metadata[hiddenReasoningMetadataKey].(type) {
+	case []*ai.Part:
+		return v
+	case []any:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil
+		}
+		var parts []*ai.Part
+		if err := json.Unmarshal(data, &parts); err != nil {
+			return nil
+		}
+		return parts
+	}
+	return nil
+}
+
+// hiddenReasoningBlocks returns the Converse blocks of the reasoning hidden
+// in msg's metadata, to lead its assistant turn.
+func hiddenReasoningBlocks(msg *ai.Message) []types.ContentBlock {
+	if msg.Role != ai.RoleModel {
+		return nil
+	}
+	var blocks []types.ContentBlock
+	for _, part := range hiddenReasoning(msg.Metadata) {
+		if part != nil {
+			blocks = append(blocks, reasoningPartToContentBlocks(part)...)
+		}
+	}
+	return blocks
+}
+
+// Reasoning returns the reasoning parts of resp, whether they are in its
+// content or were excluded with [Config.IncludeReasoning].
+func Reasoning(resp *ai.ModelResponse) []*ai.Part {
+	if resp == nil || resp.Message == nil {
+		return nil
+	}
+	if hidden := hiddenReasoning(resp.Message.Metadata); len(hidden) > 0 {
+		return hidden
+	}
+	var parts []*ai.Part
+	for _, part := range resp.Message.Content {
+		if part != nil && part.IsReasoning() {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
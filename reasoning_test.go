@@ -18,7 +18,12 @@
 package bedrock
 
 import (
+	"context"
 	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -202,6 +207,85 @@ func TestConvertResponse_TextSkipsReasoning(t *testing.T) {
 	}
 }
 
+func TestConvertResponse_ReasoningAndAnswerStayOrdered(t *testing.T) {
+	reasoning := func(text string) types.ContentBlock {
+		return &types.ContentBlockMemberReasoningContent{
+			Value: &types.ReasoningContentBlockMemberReasoningText{
+				Value: types.ReasoningTextBlock{Text: aws.String(text), Signature: aws.String("sig")},
+			},
+		}
+	}
+	resp := &bedrockruntime.ConverseOutput{
+		Output: &types.ConverseOutputMemberMessage{
+			Value: types.Message{Content: []types.ContentBlock{
+				reasoning("First, check the units."),
+				&types.ContentBlockMemberText{Value: "It is 5 km. "},
+				reasoning("Now convert."),
+				&types.ContentBlockMemberText{Value: "That is 3.1 miles."},
+			}},
+		},
+		StopReason: types.StopReasonEndTurn,
+	}
+
+	got, err := (&Bedrock{}).convertResponse(resp, &ai.ModelRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := got.Message.Content
+	want := []struct {
+		reasoning bool
+		text      string
+	}{
+		{true, "First, check the units."},
+		{false, "It is 5 km. "},
+		{true, "Now convert."},
+		{false, "That is 3.1 miles."},
+	}
+	if len(parts) != len(want) {
+		t.Fatalf("len(parts) = %d, want %d", len(parts), len(want))
+	}
+	for i, w := range want {
+		p := parts[i]
+		if p.IsReasoning() != w.reasoning || p.Text != w.text {
+			t.Errorf("parts[%d] = %v %q, want reasoning=%v %q", i, p.Kind, p.Text, w.reasoning, w.text)
+		}
+		if flagged := p.Metadata[thinkingMetadataKey] == true; flagged != w.reasoning {
+			t.Errorf("parts[%d] thinking flag = %v, want %v", i, flagged, w.reasoning)
+		}
+	}
+	if text := got.Text(); text != "It is 5 km. That is 3.1 miles." {
+		t.Errorf("Text() = %q, want only the answer", text)
+	}
+}
+
+func TestConvertResponse_ReasoningOnlyHasEmptyAnswer(t *testing.T) {
+	// A response cut off while thinking has no answer; its reasoning must not
+	// read as one.
+	resp := &bedrockruntime.ConverseOutput{
+		Output: &types.ConverseOutputMemberMessage{
+			Value: types.Message{Content: []types.ContentBlock{
+				&types.ContentBlockMemberReasoningContent{
+					Value: &types.ReasoningContentBlockMemberReasoningText{
+						Value: types.ReasoningTextBlock{Text: aws.String("Still thinking about")},
+					},
+				},
+			}},
+		},
+		StopReason: types.StopReasonMaxTokens,
+	}
+	got, err := (&Bedrock{}).convertResponse(resp, &ai.ModelRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := got.Message.Content
+	if len(parts) != 2 || !parts[0].IsReasoning() || !parts[1].IsText() {
+		t.Fatalf("parts = %+v, want the reasoning then an empty answer", parts)
+	}
+	if text := got.Text(); text != "" {
+		t.Errorf("Text() = %q, want empty", text)
+	}
+}
+
 func TestReasoningBlockToPart_EmptyBlocksReturnNil(t *testing.T) {
 	part, err := reasoningBlockToPart(&types.ReasoningContentBlockMemberReasoningText{})
 	if err != nil {
@@ -304,6 +388,139 @@ func TestStreamFinalContent_ReasoningReassembly(t *testing.T) {
 	}
 }
 
+// --- Reasoning visibility ---------------------------------------------------
+
+const reasoningConverseResponse = `{"output":{"message":{"role":"assistant","content":[
+	{"reasoningContent":{"reasoningText":{"text":"Let me think.","signature":"sig"}}},
+	{"text":"42"}
+]}},"stopReason":"end_turn"}`
+
+func reasoningRequest(include *bool) *ai.ModelRequest {
+	return &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("meaning of life?")},
+		Config:   &Config{MaxTokens: 4000, ThinkingBudget: 2000, IncludeReasoning: include},
+	}
+}
+
+func TestGenerateText_IncludeReasoning(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, reasoningConverseResponse)
+	}))
+	defer server.Close()
+	on, off := true, false
+
+	for _, include := range []*bool{nil, &on} {
+		resp, err := newTestBedrock(server).generateText(context.Background(), "anthropic.claude-3-7-sonnet-20250219-v1:0", reasoningRequest(include), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Message.Content) != 2 || !resp.Message.Content[0].IsReasoning() {
+			t.Fatalf("content = %+v, want reasoning then answer", resp.Message.Content)
+		}
+		if got := Reasoning(resp); len(got) != 1 || got[0].Text != "Let me think." {
+			t.Errorf("Reasoning() = %+v", got)
+		}
+	}
+
+	resp, err := newTestBedrock(server).generateText(context.Background(), "anthropic.claude-3-7-sonnet-20250219-v1:0", reasoningRequest(&off), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, part := range resp.Message.Content {
+		if part.IsReasoning() {
+			t.Fatalf("content = %+v, want no reasoning parts", resp.Message.Content)
+		}
+	}
+	if resp.Text() != "42" {
+		t.Errorf("Text() = %q, want 42", resp.Text())
+	}
+	if got := Reasoning(resp); len(got) != 1 || got[0].Text != "Let me think." {
+		t.Errorf("Reasoning() = %+v, want the excluded reasoning", got)
+	}
+}
+
+func TestGenerateText_ExcludedReasoningNotStreamed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/vnd.amazon.eventstream")
+		writeStreamEvent(t, w, "messageStart", `{"role":"assistant"}`)
+		writeStreamEvent(t, w, "contentBlockDelta", `{"contentBlockIndex":0,"delta":{"reasoningContent":{"text":"Let me think."}}}`)
+		writeStreamEvent(t, w, "contentBlockDelta", `{"contentBlockIndex":0,"delta":{"reasoningContent":{"signature":"sig"}}}`)
+		writeStreamEvent(t, w, "contentBlockStop", `{"contentBlockIndex":0}`)
+		writeStreamEvent(t, w, "contentBlockDelta", `{"contentBlockIndex":1,"delta":{"text":"42"}}`)
+		writeStreamEvent(t, w, "contentBlockStop", `{"contentBlockIndex":1}`)
+		writeStreamEvent(t, w, "messageStop", `{"stopReason":"end_turn"}`)
+	}))
+	defer server.Close()
+	off := false
+
+	var streamed []*ai.Part
+	resp, err := newTestBedrock(server).generateText(context.Background(), "anthropic.claude-3-7-sonnet-20250219-v1:0", reasoningRequest(&off), func(ctx context.Context, chunk *ai.ModelResponseChunk) error {
+		streamed = append(streamed, chunk.Content...)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(streamed) != 1 || streamed[0].Text != "42" {
+		t.Errorf("streamed = %+v, want only the answer", streamed)
+	}
+	if resp.Text() != "42" || len(Reasoning(resp)) != 1 {
+		t.Errorf("resp = %+v, want the answer with hidden reasoning", resp.Message)
+	}
+}
+
+func TestBuildConverseInput_ReplaysHiddenReasoning(t *testing.T) {
+	off := false
+	resp := &ai.ModelResponse{Message: &ai.Message{Role: ai.RoleModel, Content: []*ai.Part{
+		newBedrockReasoningPart("Let me think.", "sig", nil),
+		ai.NewToolRequestPart(&ai.ToolRequest{Name: "lookup", Ref: "call-1", Input: map[string]any{}}),
+	}}}
+	hideReasoning(resp)
+
+	// The history may have been serialized between turns.
+	data, err := json.Marshal(resp.Message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded ai.Message
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, msg := range map[string]*ai.Message{"in memory": resp.Message, "decoded": &decoded} {
+		t.Run(name, func(t *testing.T) {
+			out, err := (&Bedrock{}).buildConverseInput("anthropic.claude-3-7-sonnet-20250219-v1:0", &ai.ModelRequest{
+				Messages: []*ai.Message{
+					ai.NewUserTextMessage("look it up"),
+					msg,
+					{Role: ai.RoleTool, Content: []*ai.Part{ai.NewToolResponsePart(&ai.ToolResponse{Name: "lookup", Ref: "call-1", Output: "found"})}},
+				},
+				Tools:  []*ai.ToolDefinition{{Name: "lookup", InputSchema: map[string]any{"type": "object"}}},
+				Config: &Config{IncludeReasoning: &off},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			assistant := out.Messages[1].Content
+			if len(assistant) != 2 {
+				t.Fatalf("assistant turn = %d blocks, want reasoning and tool use", len(assistant))
+			}
+			reasoning, ok := assistant[0].(*types.ContentBlockMemberReasoningContent)
+			if !ok {
+				t.Fatalf("assistant block 0 = %T, want reasoning first", assistant[0])
+			}
+			text := reasoning.Value.(*types.ReasoningContentBlockMemberReasoningText)
+			if aws.ToString(text.Value.Text) != "Let me think." || aws.ToString(text.Value.Signature) != "sig" {
+				t.Errorf("reasoning = %+v", text.Value)
+			}
+			if _, ok := assistant[1].(*types.ContentBlockMemberToolUse); !ok {
+				t.Errorf("assistant block 1 = %T, want tool use", assistant[1])
+			}
+		})
+	}
+}
+
 // --- Config decode ----------------------------------------------------------
 
 func TestConfigFromRequest_TypedAndAdditionalFields(t *testing.T) {
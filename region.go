@@ -0,0 +1,79 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"os"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+// regionLayer is one source of the AWS region a call is sent to.
+type regionLayer struct {
+	source string // Where the region comes from, e.g. "Bedrock.Region"
+	region string // "" when the source does not set one
+}
+
+// resolveRegion returns the region of the first layer that sets one, and
+// that layer's source. Layers are given in precedence order:
+//
+//  1. the request's Config.Region
+//  2. Bedrock.Region
+//  3. Bedrock.AWSConfig's Region
+//  4. the AWS_REGION environment variable
+//  5. the AWS_DEFAULT_REGION environment variable
+//  6. the shared AWS config file (~/.aws/config), as loaded by the AWS SDK
+//
+// Init resolves layers 2 to 6 once into the plugin's region, and each call
+// puts its own override in front (see [Bedrock.requestRegion]).
+func resolveRegion(layers ...regionLayer) (region, source string) {
+	for _, l := range layers {
+		if l.region != "" {
+			return l.region, l.source
+		}
+	}
+	return "", ""
+}
+
+// pluginRegionLayers returns the plugin-level region layers. shared is the
+// region the AWS SDK loaded from its shared config, when Init loaded one.
+func (b *Bedrock) pluginRegionLayers(shared string) []regionLayer {
+	layers := []regionLayer{{"Bedrock.Region", b.Region}}
+	if b.AWSConfig != nil {
+		layers = append(layers, regionLayer{"Bedrock.AWSConfig", b.AWSConfig.Region})
+	}
+	return append(layers,
+		regionLayer{"AWS_REGION", os.Getenv("AWS_REGION")},
+		regionLayer{"AWS_DEFAULT_REGION", os.Getenv("AWS_DEFAULT_REGION")},
+		regionLayer{"shared config", shared},
+	)
+}
+
+// requestRegion returns the region a request is sent to: its Config.Region
+// override or the plugin's region.
+func (b *Bedrock) requestRegion(input *ai.ModelRequest) string {
+	var override string
+	if cfg, err := configFromRequest(input); err == nil && cfg != nil {
+		override = cfg.Region
+	}
+	region, _ := resolveRegion(
+		regionLayer{"Config.Region", override},
+		regionLayer{"plugin", b.awsConfig.Region},
+	)
+	return region
+}
@@ -0,0 +1,112 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/firebase/genkit/go/ai"
+)
+
+func TestResolveRegion_Precedence(t *testing.T) {
+	all := []regionLayer{
+		{"Config.Region", "ap-south-1"},
+		{"Bedrock.Region", "us-west-2"},
+		{"Bedrock.AWSConfig", "eu-west-1"},
+		{"AWS_REGION", "eu-central-1"},
+		{"AWS_DEFAULT_REGION", "ca-central-1"},
+		{"shared config", "sa-east-1"},
+	}
+	// Each layer wins over every layer after it.
+	for i, want := range all {
+		region, source := resolveRegion(all[i:]...)
+		if region != want.region || source != want.source {
+			t.Errorf("layers %d..: got %s from %s, want %s from %s", i, region, source, want.region, want.source)
+		}
+	}
+	// Unset layers are skipped.
+	layers := append([]regionLayer(nil), all...)
+	for i := range 3 {
+		layers[i].region = ""
+	}
+	if region, source := resolveRegion(layers...); region != "eu-central-1" || source != "AWS_REGION" {
+		t.Errorf("with the first layers unset: got %s from %s, want eu-central-1 from AWS_REGION", region, source)
+	}
+	if region, source := resolveRegion(); region != "" || source != "" {
+		t.Errorf("no layers: got %q from %q", region, source)
+	}
+}
+
+func TestInitRegionPrecedence(t *testing.T) {
+	writeSharedRegion := func(t *testing.T, region string) {
+		t.Helper()
+		if err := os.WriteFile(os.Getenv("AWS_CONFIG_FILE"), []byte("[default]\nregion = "+region+"\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	awsConfig := func(region string) *aws.Config {
+		cfg := testInitializedBedrock().AWSConfig
+		cfg.Region = region
+		return cfg
+	}
+
+	tests := []struct {
+		name         string
+		plugin       *Bedrock
+		env, defEnv  string
+		shared, want string
+	}{
+		{"plugin region over AWSConfig", &Bedrock{Region: "us-west-2", AWSConfig: awsConfig("eu-west-1")}, "eu-central-1", "", "", "us-west-2"},
+		{"AWSConfig over environment", &Bedrock{AWSConfig: awsConfig("eu-west-1")}, "eu-central-1", "ca-central-1", "", "eu-west-1"},
+		{"AWS_REGION over AWS_DEFAULT_REGION", &Bedrock{}, "eu-central-1", "ca-central-1", "sa-east-1", "eu-central-1"},
+		{"AWS_DEFAULT_REGION over shared config", &Bedrock{}, "", "ca-central-1", "sa-east-1", "ca-central-1"},
+		{"shared config", &Bedrock{}, "", "", "sa-east-1", "sa-east-1"},
+		{"AWSConfig without region falls back to environment", &Bedrock{AWSConfig: awsConfig("")}, "", "ca-central-1", "", "ca-central-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isolateAWSConfig(t)
+			t.Setenv("AWS_REGION", tt.env)
+			t.Setenv("AWS_DEFAULT_REGION", tt.defEnv)
+			if tt.shared != "" {
+				writeSharedRegion(t, tt.shared)
+			}
+			tt.plugin.Init(context.Background())
+			if got := tt.plugin.awsConfig.Region; got != tt.want {
+				t.Errorf("region = %q, want %q", got, tt.want)
+			}
+			if got := tt.plugin.client.Options().Region; got != tt.want {
+				t.Errorf("runtime client region = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestRegion_OverrideWins(t *testing.T) {
+	b := &Bedrock{awsConfig: aws.Config{Region: "us-east-1"}}
+	req := &ai.ModelRequest{Config: &Config{Region: "eu-west-1"}}
+	if got := b.requestRegion(req); got != "eu-west-1" {
+		t.Errorf("requestRegion with override = %q, want eu-west-1", got)
+	}
+	if got := b.requestRegion(&ai.ModelRequest{}); got != "us-east-1" {
+		t.Errorf("requestRegion without override = %q, want the plugin region", got)
+	}
+}
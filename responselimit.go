@@ -0,0 +1,127 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// defaultMaxResponseBytes is the default for [Bedrock.MaxResponseBytes].
+const defaultMaxResponseBytes = 64 << 20
+
+// streamingOperations are the Bedrock Runtime operations whose responses
+// are event streams, read incrementally rather than held in memory.
+var streamingOperations = map[string]bool{
+	"ConverseStream":                     true,
+	"InvokeModelWithResponseStream":      true,
+	"InvokeModelWithBidirectionalStream": true,
+}
+
+// ResponseTooLargeError is returned when a non-streaming response body is
+// larger than [Bedrock.MaxResponseBytes].
+type ResponseTooLargeError struct {
+	Operation string // The Bedrock Runtime operation, e.g. "Converse"
+	Limit     int64  // The configured MaxResponseBytes
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("bedrock: %s response exceeds MaxResponseBytes (%d bytes)", e.Operation, e.Limit)
+}
+
+// maxResponseBytes returns the response size cap, or 0 for no cap.
+func (b *Bedrock) maxResponseBytes() int64 {
+	switch {
+	case b.MaxResponseBytes < 0:
+		return 0
+	case b.MaxResponseBytes == 0:
+		return defaultMaxResponseBytes
+	}
+	return b.MaxResponseBytes
+}
+
+// limitResponses is a Bedrock Runtime client option that enforces
+// MaxResponseBytes on non-streaming responses.
+func (b *Bedrock) limitResponses(o *bedrockruntime.Options) {
+	limit := b.maxResponseBytes()
+	if limit == 0 {
+		return
+	}
+	o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
+		// After the operation deserializer, so it sees the raw response
+		// before the body is read.
+		return stack.Deserialize.Add(responseLimit{limit: limit}, middleware.After)
+	})
+}
+
+// responseLimit fails responses over limit bytes: at once when the
+// Content-Length says so, and otherwise while the body is read.
+type responseLimit struct {
+	limit int64
+}
+
+func (responseLimit) ID() string { return "bedrock.MaxResponseBytes" }
+
+func (m responseLimit) HandleDeserialize(ctx context.Context, in middleware.DeserializeInput, next middleware.DeserializeHandler) (middleware.DeserializeOutput, middleware.Metadata, error) {
+	out, metadata, err := next.HandleDeserialize(ctx, in)
+	if err != nil {
+		return out, metadata, err
+	}
+	op := middleware.GetOperationName(ctx)
+	resp, ok := out.RawResponse.(*smithyhttp.Response)
+	if !ok || resp.Body == nil || streamingOperations[op] {
+		return out, metadata, nil
+	}
+	tooLarge := &ResponseTooLargeError{Operation: op, Limit: m.limit}
+	if resp.ContentLength > m.limit {
+		resp.Body.Close()
+		return out, metadata, tooLarge
+	}
+	resp.Body = &limitedBody{ReadCloser: resp.Body, remaining: m.limit, err: tooLarge}
+	return out, metadata, nil
+}
+
+// limitedBody reads at most remaining bytes from a response body and then
+// fails with err if the body has more.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	err       error
+}
+
+func (r *limitedBody) Read(p []byte) (int, error) {
+	if r.remaining <= 0 {
+		var probe [1]byte
+		n, err := r.ReadCloser.Read(probe[:])
+		if n > 0 {
+			return 0, r.err
+		}
+		return 0, err
+	}
+	if int64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+	n, err := r.ReadCloser.Read(p)
+	r.remaining -= int64(n)
+	return n, err
+}
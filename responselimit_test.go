@@ -0,0 +1,98 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+)
+
+// newLimitedTestClient returns a client for srv that enforces b's
+// MaxResponseBytes, as Init builds it.
+func newLimitedTestClient(b *Bedrock, srv *httptest.Server) *bedrockruntime.Client {
+	return bedrockruntime.NewFromConfig(aws.Config{
+		Region:       "us-east-1",
+		Credentials:  credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+		HTTPClient:   srv.Client(),
+		BaseEndpoint: aws.String(srv.URL),
+	}, b.limitResponses)
+}
+
+func TestMaxResponseBytes(t *testing.T) {
+	body := `{"images":["` + strings.Repeat("A", 1000) + `"]}`
+	tests := []struct {
+		name    string
+		limit   int64
+		chunked bool
+		wantErr bool
+	}{
+		{"under limit", 2000, false, false},
+		{"no limit", -1, false, false},
+		{"content length over limit", 100, false, true},
+		{"chunked body over limit", 100, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				if tt.chunked {
+					// Flushing before the body is written drops Content-Length.
+					w.(http.Flusher).Flush()
+				}
+				_, _ = w.Write([]byte(body))
+			}))
+			defer srv.Close()
+
+			b := &Bedrock{MaxResponseBytes: tt.limit}
+			out, err := newLimitedTestClient(b, srv).InvokeModel(context.Background(), &bedrockruntime.InvokeModelInput{
+				ModelId: aws.String("amazon.titan-image-generator-v2:0"),
+				Body:    []byte(`{}`),
+			})
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("InvokeModel: %v", err)
+				}
+				if string(out.Body) != body {
+					t.Fatalf("body has %d bytes, want %d", len(out.Body), len(body))
+				}
+				return
+			}
+			var tooLarge *ResponseTooLargeError
+			if !errors.As(err, &tooLarge) {
+				t.Fatalf("err = %v, want *ResponseTooLargeError", err)
+			}
+			if tooLarge.Operation != "InvokeModel" || tooLarge.Limit != tt.limit {
+				t.Errorf("err = %+v, want InvokeModel with limit %d", tooLarge, tt.limit)
+			}
+		})
+	}
+}
+
+func TestMaxResponseBytesDefault(t *testing.T) {
+	if got := (&Bedrock{}).maxResponseBytes(); got != defaultMaxResponseBytes {
+		t.Errorf("maxResponseBytes() = %d, want %d", got, defaultMaxResponseBytes)
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/firebase/genkit/go/ai"
+)
+
+// S3Output is an S3 destination for generated images; see
+// [Bedrock.ImageOutputS3].
+type S3Output struct {
+	Bucket string // Bucket name or S3 access point ARN, in any region (required)
+	Prefix string // Key prefix, e.g. "images/"; keys are Prefix + a random name
+}
+
+// objectUploader writes objects to S3. The plugin creates one at Init from
+// its AWS config.
+type objectUploader interface {
+	PutObject(ctx context.Context, bucket, key, contentType string, body []byte) error
+}
+
+// supportsS3ImageOutput reports whether images from modelName are written
+// to S3 when ImageOutputS3 is set. Modern Stability models return the
+// format the request's output_format asks for, so their images stay inline.
+func supportsS3ImageOutput(modelName string) bool {
+	return !isModernStabilityImageModel(modelName)
+}
+
+// imageS3Response uploads base64 images to the ImageOutputS3 destination
+// and returns a response with their s3:// URIs as media parts.
+func (b *Bedrock) imageS3Response(ctx context.Context, input *ai.ModelRequest, images []string) (*ai.ModelResponse, error) {
+	out := b.ImageOutputS3
+	prefix := out.Prefix
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	ctx, cancel := b.withRequestTimeout(ctx)
+	defer cancel()
+
+	var parts []*ai.Part
+	for _, image := range images {
+		if image == "" {
+			continue
+		}
+		data, err := base64.StdEncoding.DecodeString(image)
+		if err != nil {
+			return nil, fmt.Errorf("bedrock: decode generated image: %w", err)
+		}
+		key := prefix + rand.Text() + ".png"
+		if err := b.objects.PutObject(ctx, out.Bucket, key, "image/png", data); err != nil {
+			return nil, fmt.Errorf("bedrock: write generated image to s3://%s/%s: %w", out.Bucket, key, err)
+		}
+		parts = append(parts, ai.NewMediaPart("image/png", "s3://"+out.Bucket+"/"+key))
+	}
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("no images generated")
+	}
+	return &ai.ModelResponse{
+		Request:      input,
+		Message:      &ai.Message{Role: ai.RoleModel, Content: parts},
+		FinishReason: ai.FinishReasonStop,
+		Usage:        &ai.GenerationUsage{OutputImages: len(parts)},
+	}, nil
+}
+
+// s3Client writes objects with the SDK's S3 upload manager. Each bucket's
+// region is looked up once with [manager.GetBucketRegion] so buckets outside
+// the plugin's region are addressed on their own endpoint; access point ARNs
+// carry their region. With an endpoint override, buckets are addressed
+// path-style, as S3-compatible stores expect.
+type s3Client struct {
+	client   *s3.Client
+	uploader *manager.Uploader
+
+	mu      sync.Mutex
+	regions map[string]string // Bucket regions looked up so far; "" keeps the config region
+}
+
+func newS3Client(cfg aws.Config) *s3Client {
+	_, override := serviceEndpointOverride(context.Background(), cfg, "S3")
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		o.UsePathStyle = override
+		o.UseARNRegion = true
+	})
+	return &s3Client{
+		client:   client,
+		uploader: manager.NewUploader(client),
+		regions:  map[string]string{},
+	}
+}
+
+// PutObject implements [objectUploader].
+func (c *s3Client) PutObject(ctx context.Context, bucket, key, contentType string, body []byte) error {
+	if c.client.Options().Credentials == nil {
+		return errors.New("no AWS credentials configured")
+	}
+	region := c.bucketRegion(ctx, bucket)
+	_, err := c.uploader.Upload(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		ContentType: aws.String(contentType),
+		Body:        bytes.NewReader(body),
+	}, func(u *manager.Uploader) {
+		if region != "" {
+			u.ClientOptions = append(u.ClientOptions, func(o *s3.Options) { o.Region = region })
+		}
+	})
+	return err
+}
+
+// bucketRegion returns the region of bucket, or "" to use the configured
+// region. Failed lookups are not cached, so the next upload tries again.
+func (c *s3Client) bucketRegion(ctx context.Context, bucket string) string {
+	if strings.HasPrefix(bucket, "arn:") {
+		return ""
+	}
+	c.mu.Lock()
+	region, ok := c.regions[bucket]
+	c.mu.Unlock()
+	if ok {
+		return region
+	}
+	region, err := manager.GetBucketRegion(ctx, c.client, bucket)
+	if err != nil {
+		slog.Debug("bedrock: look up S3 bucket region", "bucket", bucket, "error", err)
+		return ""
+	}
+	c.mu.Lock()
+	c.regions[bucket] = region
+	c.mu.Unlock()
+	return region
+}
@@ -0,0 +1,183 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+// newS3TestBedrock returns a plugin whose runtime and S3 calls both go to
+// srv, writing images to bucket "images" under prefix "generated".
+func newS3TestBedrock(srv *httptest.Server) *Bedrock {
+	b := newTestBedrock(srv)
+	b.objects = newS3Client(aws.Config{
+		Region:       "us-east-1",
+		Credentials:  credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+		HTTPClient:   srv.Client(),
+		BaseEndpoint: aws.String(srv.URL),
+	})
+	b.ImageOutputS3 = &S3Output{Bucket: "images", Prefix: "generated"}
+	return b
+}
+
+func TestGenerateImage_ImageOutputS3(t *testing.T) {
+	var putPath, putBody, putType, putAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			body, _ := io.ReadAll(r.Body)
+			putPath, putBody, putType, putAuth = r.URL.Path, string(body), r.Header.Get("Content-Type"), r.Header.Get("Authorization")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"images":["aGVsbG8="]}`))
+	}))
+	defer srv.Close()
+
+	req := imagePromptRequest("a lighthouse")
+	resp, err := newS3TestBedrock(srv).generateImage(context.Background(), "amazon.titan-image-generator-v2:0", req, nil)
+	if err != nil {
+		t.Fatalf("generateImage: %v", err)
+	}
+	if !strings.HasPrefix(putPath, "/images/generated/") || !strings.HasSuffix(putPath, ".png") {
+		t.Fatalf("PUT path = %q, want /images/generated/*.png", putPath)
+	}
+	if putBody != "hello" || putType != "image/png" {
+		t.Errorf("PUT body = %q (%s), want decoded image/png bytes", putBody, putType)
+	}
+	if !strings.Contains(putAuth, "/us-east-1/s3/aws4_request") {
+		t.Errorf("Authorization = %q, want an S3 SigV4 signature", putAuth)
+	}
+	if len(resp.Message.Content) != 1 {
+		t.Fatalf("content = %+v, want one part", resp.Message.Content)
+	}
+	part := resp.Message.Content[0]
+	if !part.IsMedia() || part.ContentType != "image/png" || part.Text != "s3:/"+putPath {
+		t.Errorf("part = %+v, want image/png media at s3:/%s", part, putPath)
+	}
+	if resp.Usage == nil || resp.Usage.OutputImages != 1 {
+		t.Errorf("usage = %+v, want 1 output image", resp.Usage)
+	}
+}
+
+func TestGenerateImage_ImageOutputS3InlineFallback(t *testing.T) {
+	var puts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			puts.Add(1)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"images":["aGVsbG8="]}`))
+	}))
+	defer srv.Close()
+
+	req := imagePromptRequest("a lighthouse")
+	resp, err := newS3TestBedrock(srv).generateImage(context.Background(), "stability.stable-image-core-v1:1", req, nil)
+	if err != nil {
+		t.Fatalf("generateImage: %v", err)
+	}
+	assertImageResponse(t, resp, req, "aGVsbG8=")
+	if puts.Load() != 0 {
+		t.Errorf("made %d S3 uploads, want none", puts.Load())
+	}
+}
+
+func TestGenerateImage_ImageOutputS3UploadError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"images":["aGVsbG8="]}`))
+	}))
+	defer srv.Close()
+
+	_, err := newS3TestBedrock(srv).generateImage(context.Background(), "amazon.nova-canvas-v1:0", imagePromptRequest("a lighthouse"), nil)
+	if err == nil || !strings.Contains(err.Error(), "AccessDenied: Access Denied") || !strings.Contains(err.Error(), "s3://images/generated/") {
+		t.Fatalf("err = %v, want the S3 AccessDenied error with the object URI", err)
+	}
+}
+
+func TestInitPanicsOnImageOutputS3WithoutBucket(t *testing.T) {
+	b := &Bedrock{Region: "us-east-1", ImageOutputS3: &S3Output{Prefix: "generated/"}}
+	assertPanicsWith(t, "bedrock: ImageOutputS3 requires a Bucket", func() {
+		b.Init(context.Background())
+	})
+}
+
+// s3HTTPClient answers requests with the function's responses instead of
+// the network.
+type s3HTTPClient func(*http.Request) *http.Response
+
+func (f s3HTTPClient) Do(req *http.Request) (*http.Response, error) {
+	resp := f(req)
+	resp.Request = req
+	if resp.Body == nil {
+		resp.Body = io.NopCloser(strings.NewReader(""))
+	}
+	if resp.Header == nil {
+		resp.Header = http.Header{}
+	}
+	return resp, nil
+}
+
+func TestS3Client_AddressesBucketsInTheirRegion(t *testing.T) {
+	var heads int
+	var puts []string
+	client := newS3Client(aws.Config{
+		Region:      "us-east-1",
+		Credentials: credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+		HTTPClient: s3HTTPClient(func(r *http.Request) *http.Response {
+			if r.Method == http.MethodHead {
+				heads++
+				return &http.Response{StatusCode: http.StatusMovedPermanently, Header: http.Header{"X-Amz-Bucket-Region": {"eu-west-1"}}}
+			}
+			_, scope, _ := strings.Cut(r.Header.Get("Authorization"), "Credential=AKID/")
+			puts = append(puts, r.URL.Host+" "+strings.Split(scope, "/")[1])
+			return &http.Response{StatusCode: http.StatusOK}
+		}),
+	})
+	for _, bucket := range []string{"images", "images", "arn:aws:s3:us-west-2:123456789012:accesspoint/my-ap"} {
+		if err := client.PutObject(context.Background(), bucket, "a.png", "image/png", []byte("x")); err != nil {
+			t.Fatalf("PutObject(%s): %v", bucket, err)
+		}
+	}
+	if heads != 1 {
+		t.Errorf("made %d region lookups, want 1", heads)
+	}
+	want := []string{
+		"images.s3.eu-west-1.amazonaws.com eu-west-1",
+		"images.s3.eu-west-1.amazonaws.com eu-west-1",
+		"my-ap-123456789012.s3-accesspoint.us-west-2.amazonaws.com us-west-2",
+	}
+	if !slices.Equal(puts, want) {
+		t.Errorf("PUTs (host, signed region) = %q, want %q", puts, want)
+	}
+}
@@ -23,33 +23,29 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"reflect"
 	"sort"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/document"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
 	"github.com/firebase/genkit/go/ai"
 )
 
 var errStreamBlockRequired = errors.New("bedrock: stream block is nil")
 
-func (b *Bedrock) generateTextStream(ctx context.Context, input *bedrockruntime.ConverseInput, originalInput *ai.ModelRequest, cb func(context.Context, *ai.ModelResponseChunk) error) (*ai.ModelResponse, error) {
+func (b *Bedrock) generateTextStream(ctx context.Context, client BedrockClient, input *bedrockruntime.ConverseInput, originalInput *ai.ModelRequest, cb func(context.Context, *ai.ModelResponseChunk) error) (*ai.ModelResponse, error) {
 	ctx, cancel := b.withRequestTimeout(ctx)
 	defer cancel()
 
-	streamInput := &bedrockruntime.ConverseStreamInput{
-		ModelId:                      input.ModelId,
-		Messages:                     input.Messages,
-		System:                       input.System,
-		InferenceConfig:              input.InferenceConfig,
-		ToolConfig:                   input.ToolConfig,
-		AdditionalModelRequestFields: input.AdditionalModelRequestFields,
-	}
+	streamInput := converseStreamInput(input, originalInput)
 
-	streamOutput, err := b.client.ConverseStream(ctx, streamInput)
+	streamOutput, err := client.ConverseStream(ctx, streamInput)
 	if err != nil {
-		return nil, fmt.Errorf("bedrock converse stream failed: %w", err)
+		return nil, modelCallError("bedrock converse stream failed", aws.ToString(input.ModelId), b.requestRegion(originalInput), err)
 	}
 	stream := streamOutput.GetStream()
 	if stream == nil {
@@ -71,6 +67,37 @@ func (b *Bedrock) generateTextStream(ctx context.Context, input *bedrockruntime.
 	return finalResponse, nil
 }
 
+// converseStreamInput mirrors a built Converse input for ConverseStream,
+// adding the guardrail stream processing mode (sync unless the request's
+// [GuardrailConfig] asks for async). The mode was validated when input was
+// built.
+func converseStreamInput(input *bedrockruntime.ConverseInput, originalInput *ai.ModelRequest) *bedrockruntime.ConverseStreamInput {
+	streamInput := &bedrockruntime.ConverseStreamInput{
+		ModelId:                           input.ModelId,
+		Messages:                          input.Messages,
+		System:                            input.System,
+		InferenceConfig:                   input.InferenceConfig,
+		ToolConfig:                        input.ToolConfig,
+		AdditionalModelRequestFields:      input.AdditionalModelRequestFields,
+		AdditionalModelResponseFieldPaths: input.AdditionalModelResponseFieldPaths,
+	}
+	if g := input.GuardrailConfig; g != nil {
+		mode := types.GuardrailStreamProcessingModeSync
+		if cfg, _ := configFromRequest(originalInput); cfg != nil && cfg.Guardrail != nil {
+			if m, err := guardrailStreamProcessingMode(cfg.Guardrail.StreamProcessingMode); err == nil {
+				mode = m
+			}
+		}
+		streamInput.GuardrailConfig = &types.GuardrailStreamConfiguration{
+			GuardrailIdentifier:  g.GuardrailIdentifier,
+			GuardrailVersion:     g.GuardrailVersion,
+			Trace:                g.Trace,
+			StreamProcessingMode: mode,
+		}
+	}
+	return streamInput
+}
+
 // streamBlock accumulates the state of a single content block across delta
 // events keyed by ContentBlockIndex.
 type streamBlock struct {
@@ -81,15 +108,72 @@ type streamBlock struct {
 	toolID             string
 	toolName           string
 	toolInput          strings.Builder
+	partialToolInput   any // Last input sent with StreamPartialToolInput
 	isTool             bool
+	citations          []Citation
+
+	// Text and reasoning deltas may split a multibyte character; the
+	// incomplete tail is held back until the next delta completes it.
+	pendingText      utf8Tail
+	pendingReasoning utf8Tail
+}
+
+// utf8Tail holds the trailing bytes of a streamed string that end in an
+// incomplete UTF-8 sequence.
+type utf8Tail []byte
+
+// complete returns the held bytes plus s, up to the last complete rune, and
+// holds back the rest. Bytes that can never form a rune become U+FFFD, so
+// the result is always valid UTF-8.
+func (t *utf8Tail) complete(s string) string {
+	data := append(*t, s...)
+	cut := len(data)
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				cut = i
+			}
+			break
+		}
+	}
+	*t = append(utf8Tail(nil), data[cut:]...)
+	return strings.ToValidUTF8(string(data[:cut]), string(utf8.RuneError))
+}
+
+// flush returns whatever is still held, which at the end of a block is an
+// incomplete sequence and so becomes U+FFFD.
+func (t *utf8Tail) flush() string {
+	if len(*t) == 0 {
+		return ""
+	}
+	*t = nil
+	return string(utf8.RuneError)
 }
 
 func (b *Bedrock) consumeStreamEvents(ctx context.Context, events <-chan types.ConverseStreamOutput, originalInput *ai.ModelRequest, cb func(context.Context, *ai.ModelResponseChunk) error) (*ai.ModelResponse, error) {
 	blocks := map[int32]*streamBlock{}
 	var stopReason types.StopReason
 	var usage *types.TokenUsage
+	var latency *int64
+	var additional document.Interface
 
-	for event := range events {
+	for {
+		// Check ctx before each receive: select picks randomly among ready
+		// cases, so buffered events could otherwise still reach cb after
+		// cancellation.
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("bedrock converse stream canceled: %w", err)
+		}
+		var event types.ConverseStreamOutput
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("bedrock converse stream canceled: %w", ctx.Err())
+		case e, ok := <-events:
+			if !ok {
+				return b.finishStream(blocks, stopReason, usage, latency, additional, originalInput)
+			}
+			event = e
+		}
 		switch e := event.(type) {
 		case *types.ConverseStreamOutputMemberMessageStart:
 			// The outbound role is always assistant/model.
@@ -110,33 +194,67 @@ func (b *Bedrock) consumeStreamEvents(ctx context.Context, events <-chan types.C
 			if err := appendContentBlockDelta(ctx, block, e.Value.Delta, cb); err != nil {
 				return nil, err
 			}
+			if _, ok := e.Value.Delta.(*types.ContentBlockDeltaMemberToolUse); ok {
+				if err := b.emitPartialToolInput(ctx, idx, block, originalInput, cb); err != nil {
+					return nil, err
+				}
+			}
 		case *types.ConverseStreamOutputMemberContentBlockStop:
 			idx := indexOf(e.Value.ContentBlockIndex)
+			if err := flushStreamBlock(ctx, blocks[idx], cb); err != nil {
+				return nil, err
+			}
 			if err := b.emitToolBlockStop(ctx, idx, blocks[idx], originalInput, cb); err != nil {
 				return nil, err
 			}
 		case *types.ConverseStreamOutputMemberMessageStop:
 			stopReason = e.Value.StopReason
+			additional = e.Value.AdditionalModelResponseFields
 		case *types.ConverseStreamOutputMemberMetadata:
-			usage = e.Value.Usage
+			if e.Value.Usage != nil {
+				usage = e.Value.Usage
+				if err := b.emitUsage(ctx, usage, cb); err != nil {
+					return nil, err
+				}
+			}
+			if e.Value.Metrics != nil {
+				latency = e.Value.Metrics.LatencyMs
+			}
 		default:
 			// Unknown top-level events are ignored so new Bedrock event types don't break streaming.
 		}
 	}
+}
+
+// emitUsage sends cb a chunk carrying usage when StreamUsage is set.
+func (b *Bedrock) emitUsage(ctx context.Context, usage *types.TokenUsage, cb func(context.Context, *ai.ModelResponseChunk) error) error {
+	if !b.StreamUsage || cb == nil {
+		return nil
+	}
+	chunk := &ai.ModelResponseChunk{Index: 0, Custom: map[string]any{usageChunkKey: usageFromTokens(usage)}}
+	if err := cb(ctx, chunk); err != nil {
+		return fmt.Errorf("callback error: %w", err)
+	}
+	return nil
+}
 
+// finishStream assembles the final response once the event stream has ended.
+func (b *Bedrock) finishStream(blocks map[int32]*streamBlock, stopReason types.StopReason, usage *types.TokenUsage, latency *int64, additional document.Interface, originalInput *ai.ModelRequest) (*ai.ModelResponse, error) {
+	// Flush blocks the stream ended without a ContentBlockStop event.
+	for _, block := range blocks {
+		_ = flushStreamBlock(context.Background(), block, nil)
+	}
 	parts, err := b.blocksToParts(blocks, originalInput)
 	if err != nil {
 		return nil, err
 	}
-	if len(parts) == 0 {
-		parts = append(parts, ai.NewTextPart(""))
-	}
+	parts = withAnswerPart(parts)
 	finishReason := convertStopReasonToGenkit(stopReason)
 	if stopReason == "" {
 		finishReason = ai.FinishReasonStop
 	}
 	return &ai.ModelResponse{
-		Message:      &ai.Message{Role: ai.RoleModel, Content: parts},
+		Message:      &ai.Message{Role: ai.RoleModel, Content: parts, Metadata: responseMetadata(latency, additional, stopReason)},
 		FinishReason: finishReason,
 		Usage:        usageFromTokens(usage),
 		Request:      originalInput,
@@ -169,7 +287,11 @@ func (b *Bedrock) blocksToParts(blocks map[int32]*streamBlock, originalInput *ai
 			parts = append(parts, newBedrockReasoningPart(block.reasoning.String(), block.reasoningSignature, block.redactedReasoning))
 		}
 		if block.text.Len() > 0 {
-			parts = append(parts, ai.NewTextPart(block.text.String()))
+			part := ai.NewTextPart(block.text.String())
+			if len(block.citations) > 0 {
+				part.Metadata = map[string]any{citationsMetadataKey: block.citations}
+			}
+			parts = append(parts, part)
 		}
 	}
 	return parts, nil
@@ -181,9 +303,13 @@ func appendContentBlockDelta(ctx context.Context, block *streamBlock, delta type
 	}
 	switch d := delta.(type) {
 	case *types.ContentBlockDeltaMemberText:
-		block.text.WriteString(d.Value)
+		text := block.pendingText.complete(d.Value)
+		if text == "" {
+			break
+		}
+		block.text.WriteString(text)
 		if cb != nil {
-			if err := cb(ctx, &ai.ModelResponseChunk{Index: 0, Content: []*ai.Part{ai.NewTextPart(d.Value)}}); err != nil {
+			if err := cb(ctx, &ai.ModelResponseChunk{Index: 0, Content: []*ai.Part{ai.NewTextPart(text)}}); err != nil {
 				return fmt.Errorf("callback error: %w", err)
 			}
 		}
@@ -200,6 +326,8 @@ func appendContentBlockDelta(ctx context.Context, block *streamBlock, delta type
 				return fmt.Errorf("callback error: %w", err)
 			}
 		}
+	case *types.ContentBlockDeltaMemberCitation:
+		block.citations = append(block.citations, citationFromDelta(d.Value))
 	default:
 		return fmt.Errorf("bedrock: unhandled stream content delta variant %T", delta)
 	}
@@ -212,8 +340,7 @@ func appendReasoningDelta(block *streamBlock, delta types.ReasoningContentBlockD
 	}
 	switch d := delta.(type) {
 	case *types.ReasoningContentBlockDeltaMemberText:
-		block.reasoning.WriteString(d.Value)
-		return newBedrockReasoningPart(d.Value, "", nil), nil
+		return reasoningDeltaPart(block, block.pendingReasoning.complete(d.Value)), nil
 	case *types.ReasoningContentBlockDeltaMemberSignature:
 		block.reasoningSignature = d.Value
 	case *types.ReasoningContentBlockDeltaMemberRedactedContent:
@@ -238,6 +365,34 @@ func (b *Bedrock) emitToolBlockStop(ctx context.Context, idx int32, block *strea
 	return nil
 }
 
+// emitPartialToolInput sends cb the tool input received so far, completed
+// into a valid value (see completePartialJSON), as a partial tool request
+// when StreamPartialToolInput is set. Prefixes that cannot be completed, or
+// that add nothing to the last value sent, are skipped.
+func (b *Bedrock) emitPartialToolInput(ctx context.Context, idx int32, block *streamBlock, originalInput *ai.ModelRequest, cb func(context.Context, *ai.ModelResponseChunk) error) error {
+	if !b.StreamPartialToolInput || cb == nil || !block.isTool {
+		return nil
+	}
+	input, ok := completePartialJSON(block.toolInput.String())
+	if !ok || input == nil || reflect.DeepEqual(input, block.partialToolInput) {
+		return nil
+	}
+	block.partialToolInput = input
+	if inputMap, ok := input.(map[string]any); ok && originalInput != nil {
+		input = convertToolInputTypes(inputMap, block.toolName, originalInput.Tools)
+	}
+	part := toolUsePart(int(idx), &ai.ToolRequest{
+		Ref:     block.toolID,
+		Name:    block.toolName,
+		Input:   input,
+		Partial: true,
+	})
+	if err := cb(ctx, &ai.ModelResponseChunk{Index: 0, Content: []*ai.Part{part}}); err != nil {
+		return fmt.Errorf("callback error: %w", err)
+	}
+	return nil
+}
+
 func (b *Bedrock) toolBlockToPart(idx int32, block *streamBlock, originalInput *ai.ModelRequest) (*ai.Part, error) {
 	input, err := decodeToolInput(block.toolInput.String())
 	if err != nil {
@@ -248,9 +403,9 @@ func (b *Bedrock) toolBlockToPart(idx int32, block *streamBlock, originalInput *
 		if originalInput != nil {
 			tools = originalInput.Tools
 		}
-		input = b.convertToolInputTypes(inputMap, block.toolName, tools)
+		input = convertToolInputTypes(inputMap, block.toolName, tools)
 	}
-	return ai.NewToolRequestPart(&ai.ToolRequest{
+	return toolUsePart(int(idx), &ai.ToolRequest{
 		Ref:   block.toolID,
 		Name:  block.toolName,
 		Input: input,
@@ -276,6 +431,38 @@ func decodeToolInput(s string) (any, error) {
 	return v, nil
 }
 
+// reasoningDeltaPart appends text to the block's reasoning and returns it as
+// a streamed thinking part, or nil when text is empty.
+func reasoningDeltaPart(block *streamBlock, text string) *ai.Part {
+	if text == "" {
+		return nil
+	}
+	block.reasoning.WriteString(text)
+	return newBedrockReasoningPart(text, "", nil)
+}
+
+// flushStreamBlock emits the bytes a block still holds back when it ends.
+func flushStreamBlock(ctx context.Context, block *streamBlock, cb func(context.Context, *ai.ModelResponseChunk) error) error {
+	if block == nil {
+		return nil
+	}
+	var content []*ai.Part
+	if part := reasoningDeltaPart(block, block.pendingReasoning.flush()); part != nil {
+		content = append(content, part)
+	}
+	if text := block.pendingText.flush(); text != "" {
+		block.text.WriteString(text)
+		content = append(content, ai.NewTextPart(text))
+	}
+	if len(content) == 0 || cb == nil {
+		return nil
+	}
+	if err := cb(ctx, &ai.ModelResponseChunk{Index: 0, Content: content}); err != nil {
+		return fmt.Errorf("callback error: %w", err)
+	}
+	return nil
+}
+
 func getOrInit(blocks map[int32]*streamBlock, idx int32) *streamBlock {
 	if block, ok := blocks[idx]; ok {
 		return block
@@ -19,11 +19,20 @@ package bedrock
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/document"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
 	"github.com/firebase/genkit/go/ai"
 )
@@ -38,6 +47,7 @@ func TestConsumeStreamEvents_TextOnly(t *testing.T) {
 				OutputTokens: aws.Int32(2),
 				TotalTokens:  aws.Int32(5),
 			},
+			Metrics: &types.ConverseStreamMetrics{LatencyMs: aws.Int64(87)},
 		}},
 		&types.ConverseStreamOutputMemberMessageStop{Value: types.MessageStopEvent{StopReason: types.StopReasonEndTurn}},
 	)
@@ -69,6 +79,215 @@ func TestConsumeStreamEvents_TextOnly(t *testing.T) {
 	if resp.Request != req {
 		t.Fatalf("Request = %p, want %p", resp.Request, req)
 	}
+	if latency, ok := ServerLatency(resp); !ok || latency != 87*time.Millisecond {
+		t.Fatalf("ServerLatency() = %v, %v; want 87ms from the metadata event", latency, ok)
+	}
+}
+
+func TestConsumeStreamEvents_StreamUsage(t *testing.T) {
+	usageEvent := func(in, out int32) types.ConverseStreamOutput {
+		return &types.ConverseStreamOutputMemberMetadata{Value: types.ConverseStreamMetadataEvent{
+			Usage: &types.TokenUsage{InputTokens: aws.Int32(in), OutputTokens: aws.Int32(out), TotalTokens: aws.Int32(in + out)},
+		}}
+	}
+	events := streamEvents(
+		textDelta(0, "Hello"),
+		usageEvent(10, 1),
+		textDelta(0, " world"),
+		usageEvent(10, 2),
+		&types.ConverseStreamOutputMemberMessageStop{Value: types.MessageStopEvent{StopReason: types.StopReasonEndTurn}},
+		usageEvent(10, 3),
+	)
+
+	var text strings.Builder
+	var running []int
+	resp, err := (&Bedrock{StreamUsage: true}).consumeStreamEvents(context.Background(), events, nil, func(_ context.Context, chunk *ai.ModelResponseChunk) error {
+		if usage, ok := ChunkUsage(chunk); ok {
+			if len(chunk.Content) != 0 {
+				t.Errorf("usage chunk content = %+v, want none", chunk.Content)
+			}
+			running = append(running, usage.OutputTokens)
+			return nil
+		}
+		text.WriteString(chunk.Text())
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, 2, 3}; !reflect.DeepEqual(running, want) {
+		t.Errorf("running output tokens = %v, want %v", running, want)
+	}
+	if text.String() != "Hello world" {
+		t.Errorf("streamed text = %q", text.String())
+	}
+	if resp.Usage == nil || resp.Usage.OutputTokens != 3 || resp.Usage.TotalTokens != 13 {
+		t.Errorf("final usage = %+v, want the last reported totals", resp.Usage)
+	}
+
+	// Usage chunks survive a JSON round trip, as on a remote stream.
+	raw, err := json.Marshal(&ai.ModelResponseChunk{Custom: map[string]any{usageChunkKey: resp.Usage}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded ai.ModelResponseChunk
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if usage, ok := ChunkUsage(&decoded); !ok || usage.TotalTokens != 13 {
+		t.Errorf("ChunkUsage after round trip = %+v, %v", usage, ok)
+	}
+	if _, ok := ChunkUsage(&ai.ModelResponseChunk{Content: []*ai.Part{ai.NewTextPart("x")}}); ok {
+		t.Error("ChunkUsage(text chunk) ok = true, want false")
+	}
+}
+
+func TestConsumeStreamEvents_AdditionalModelResponseFields(t *testing.T) {
+	events := streamEvents(
+		textDelta(0, "ok"),
+		&types.ConverseStreamOutputMemberMessageStop{Value: types.MessageStopEvent{
+			StopReason:                    types.StopReasonEndTurn,
+			AdditionalModelResponseFields: document.NewLazyDocument(map[string]any{"trace": "abc"}),
+		}},
+	)
+	resp, err := (&Bedrock{}).consumeStreamEvents(context.Background(), events, &ai.ModelRequest{}, func(context.Context, *ai.ModelResponseChunk) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := AdditionalResponseFields(resp); got["trace"] != "abc" {
+		t.Errorf("AdditionalResponseFields() = %v, want trace=abc", got)
+	}
+}
+
+func TestConsumeStreamEvents_ReasoningThenText(t *testing.T) {
+	events := streamEvents(
+		reasoningDelta(0, &types.ReasoningContentBlockDeltaMemberText{Value: "Let me "}),
+		reasoningDelta(0, &types.ReasoningContentBlockDeltaMemberText{Value: "think."}),
+		reasoningDelta(0, &types.ReasoningContentBlockDeltaMemberSignature{Value: "sig"}),
+		reasoningDelta(1, &types.ReasoningContentBlockDeltaMemberRedactedContent{Value: []byte("encrypted")}),
+		textDelta(2, "The answer "),
+		textDelta(2, "is 4."),
+		&types.ConverseStreamOutputMemberMessageStop{Value: types.MessageStopEvent{StopReason: types.StopReasonEndTurn}},
+	)
+
+	var thinking, answer []string
+	resp, err := (&Bedrock{}).consumeStreamEvents(context.Background(), events, nil, func(ctx context.Context, chunk *ai.ModelResponseChunk) error {
+		for _, part := range chunk.Content {
+			if part.Metadata[thinkingMetadataKey] == true {
+				if !part.IsReasoning() {
+					t.Errorf("thinking chunk part kind = %v, want reasoning", part.Kind)
+				}
+				thinking = append(thinking, part.Text)
+				continue
+			}
+			if part.IsReasoning() {
+				t.Errorf("reasoning chunk %q missing thinking flag", part.Text)
+			}
+			answer = append(answer, part.Text)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(thinking, ""); got != "Let me think." {
+		t.Errorf("streamed thinking = %q, want %q", got, "Let me think.")
+	}
+	if got := strings.Join(answer, ""); got != "The answer is 4." {
+		t.Errorf("streamed answer = %q, want %q", got, "The answer is 4.")
+	}
+
+	content := resp.Message.Content
+	if len(content) != 3 {
+		t.Fatalf("len(final content) = %d, want reasoning, redacted reasoning, text", len(content))
+	}
+	if !content[0].IsReasoning() || content[0].Text != "Let me think." {
+		t.Errorf("content[0] = %+v, want assembled reasoning", content[0])
+	}
+	for i, part := range content {
+		if flagged := part.Metadata[thinkingMetadataKey] == true; flagged != part.IsReasoning() {
+			t.Errorf("content[%d] thinking flag = %v, want %v", i, flagged, part.IsReasoning())
+		}
+	}
+	if sig := metadataBytes(content[0].Metadata, reasoningSignatureMetadataKey); string(sig) != "sig" {
+		t.Errorf("signature = %q, want sig", sig)
+	}
+	if !content[1].IsReasoning() || string(metadataBytes(content[1].Metadata, redactedReasoningMetadataKey)) != "encrypted" {
+		t.Errorf("content[1] = %+v, want redacted reasoning", content[1])
+	}
+	if resp.Text() != "The answer is 4." {
+		t.Errorf("final text = %q, want %q", resp.Text(), "The answer is 4.")
+	}
+}
+
+func TestConverseStreamInput_GuardrailProcessingMode(t *testing.T) {
+	tests := []struct {
+		mode string
+		want types.GuardrailStreamProcessingMode
+	}{
+		{mode: "", want: types.GuardrailStreamProcessingModeSync},
+		{mode: "sync", want: types.GuardrailStreamProcessingModeSync},
+		{mode: "async", want: types.GuardrailStreamProcessingModeAsync},
+	}
+	for _, tt := range tests {
+		t.Run("mode="+tt.mode, func(t *testing.T) {
+			req := &ai.ModelRequest{
+				Messages: []*ai.Message{ai.NewUserTextMessage("hi")},
+				Config: &Config{Guardrail: &GuardrailConfig{
+					Identifier:           "gr-123",
+					Version:              "2",
+					Trace:                true,
+					StreamProcessingMode: tt.mode,
+				}},
+			}
+			input, err := (&Bedrock{}).buildConverseInput("amazon.nova-lite-v1:0", req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := converseStreamInput(input, req).GuardrailConfig
+			if got == nil {
+				t.Fatal("stream GuardrailConfig is nil")
+			}
+			if got.StreamProcessingMode != tt.want {
+				t.Errorf("StreamProcessingMode = %q, want %q", got.StreamProcessingMode, tt.want)
+			}
+			if aws.ToString(got.GuardrailIdentifier) != "gr-123" || aws.ToString(got.GuardrailVersion) != "2" || got.Trace != types.GuardrailTraceEnabled {
+				t.Errorf("stream GuardrailConfig = %+v", got)
+			}
+		})
+	}
+
+	// No guardrail configured: nothing is sent.
+	req := &ai.ModelRequest{Messages: []*ai.Message{ai.NewUserTextMessage("hi")}}
+	input, err := (&Bedrock{}).buildConverseInput("amazon.nova-lite-v1:0", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := converseStreamInput(input, req).GuardrailConfig; got != nil {
+		t.Errorf("GuardrailConfig = %+v, want nil", got)
+	}
+}
+
+func TestBuildConverseInput_GuardrailValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		g       *GuardrailConfig
+		wantErr string
+	}{
+		{name: "missing version", g: &GuardrailConfig{Identifier: "gr-123"}, wantErr: "identifier and version"},
+		{name: "bad mode", g: &GuardrailConfig{Identifier: "gr-123", Version: "1", StreamProcessingMode: "eventually"}, wantErr: "invalid guardrail streamProcessingMode"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := (&Bedrock{}).buildConverseInput("amazon.nova-lite-v1:0", &ai.ModelRequest{
+				Messages: []*ai.Message{ai.NewUserTextMessage("hi")},
+				Config:   &Config{Guardrail: tt.g},
+			})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
 }
 
 func TestBlocksToParts_StreamReassembly(t *testing.T) {
@@ -139,6 +358,9 @@ func TestConsumeStreamEvents_ToolUseFragmentsAndSchemaConversion(t *testing.T) {
 	if resp.FinishReason != ai.FinishReasonStop {
 		t.Fatalf("FinishReason = %q, want stop", resp.FinishReason)
 	}
+	if !StoppedForToolUse(resp) || StopReason(resp) != "tool_use" {
+		t.Errorf("StopReason = %q, want tool_use", StopReason(resp))
+	}
 	if len(resp.Message.Content) != 1 || !resp.Message.Content[0].IsToolRequest() {
 		t.Fatalf("final content = %+v, want one tool request", resp.Message.Content)
 	}
@@ -148,6 +370,165 @@ func TestConsumeStreamEvents_ToolUseFragmentsAndSchemaConversion(t *testing.T) {
 	}
 }
 
+func TestConsumeStreamEvents_StreamPartialToolInput(t *testing.T) {
+	req := &ai.ModelRequest{Tools: []*ai.ToolDefinition{{Name: "report"}}}
+	events := streamEvents(
+		toolStart(0, "call_1", "report"),
+		toolDelta(0, `{"city":"Par`),
+		toolDelta(0, `is","tags":["sun`),
+		toolDelta(0, `ny","warm"],"n`),
+		toolDelta(0, `otes":{"high`),
+		toolDelta(0, `":"25C"}}`),
+		toolStop(0),
+		&types.ConverseStreamOutputMemberMessageStop{Value: types.MessageStopEvent{StopReason: types.StopReasonToolUse}},
+	)
+
+	var partials []any
+	var complete int
+	b := &Bedrock{StreamPartialToolInput: true}
+	resp, err := b.consumeStreamEvents(context.Background(), events, req, func(ctx context.Context, chunk *ai.ModelResponseChunk) error {
+		tr := chunk.Content[0].ToolRequest
+		if tr.Partial {
+			partials = append(partials, tr.Input)
+		} else {
+			complete++
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantPartials := []any{
+		map[string]any{"city": "Par"},
+		map[string]any{"city": "Paris", "tags": []any{"sun"}},
+		map[string]any{"city": "Paris", "tags": []any{"sunny", "warm"}},
+		map[string]any{"city": "Paris", "tags": []any{"sunny", "warm"}, "notes": map[string]any{}},
+		map[string]any{"city": "Paris", "tags": []any{"sunny", "warm"}, "notes": map[string]any{"high": "25C"}},
+	}
+	if !reflect.DeepEqual(partials, wantPartials) {
+		t.Errorf("partial inputs = %#v\nwant %#v", partials, wantPartials)
+	}
+	if complete != 1 {
+		t.Errorf("complete tool request chunks = %d, want 1", complete)
+	}
+	final := resp.Message.Content[0].ToolRequest
+	if final.Partial || !reflect.DeepEqual(final.Input, wantPartials[len(wantPartials)-1]) {
+		t.Errorf("final tool request = %+v, want the complete input", final)
+	}
+}
+
+func TestConsumeStreamEvents_ToolUseBlockMetadata(t *testing.T) {
+	events := streamEvents(
+		toolStart(1, "call_a", "lookup"),
+		toolDelta(1, `{}`),
+		toolStop(1),
+		toolStart(2, "call_b", "lookup"),
+		toolDelta(2, `{}`),
+		toolStop(2),
+		&types.ConverseStreamOutputMemberMessageStop{Value: types.MessageStopEvent{StopReason: types.StopReasonToolUse}},
+	)
+	resp, err := (&Bedrock{}).consumeStreamEvents(context.Background(), events, &ai.ModelRequest{}, func(context.Context, *ai.ModelResponseChunk) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	tools := resp.ToolRequests()
+	if len(tools) != 2 {
+		t.Fatalf("tool requests = %d, want 2", len(tools))
+	}
+	for i, want := range []struct {
+		index int
+		id    string
+	}{{1, "call_a"}, {2, "call_b"}} {
+		index, id, ok := ToolUseBlock(tools[i])
+		if !ok || index != want.index || id != want.id {
+			t.Errorf("ToolUseBlock(tools[%d]) = %d, %q, %v; want %d, %q", i, index, id, ok, want.index, want.id)
+		}
+	}
+}
+
+func TestConsumeStreamEvents_CancelStopsCallbacks(t *testing.T) {
+	events := make(chan types.ConverseStreamOutput, 4)
+	events <- textDelta(0, "one")
+	events <- textDelta(0, "two")
+	events <- textDelta(0, "three")
+	// Left open, as a live stream would be.
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var chunks []string
+	_, err := (&Bedrock{}).consumeStreamEvents(ctx, events, &ai.ModelRequest{}, func(ctx context.Context, chunk *ai.ModelResponseChunk) error {
+		chunks = append(chunks, chunk.Text())
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("error = %v, want context.Canceled", err)
+	}
+	if len(chunks) != 1 || chunks[0] != "one" {
+		t.Errorf("chunks = %q, want only the chunk before cancellation", chunks)
+	}
+}
+
+// TestGenerateTextStream_CancelClosesStream cancels mid-stream against a
+// server that keeps the event stream open, and checks that the connection is
+// closed and no further chunks arrive.
+func TestGenerateTextStream_CancelClosesStream(t *testing.T) {
+	disconnected := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/vnd.amazon.eventstream")
+		w.WriteHeader(http.StatusOK)
+		writeStreamEvent(t, w, "messageStart", `{"role":"assistant"}`)
+		writeStreamEvent(t, w, "contentBlockDelta", `{"contentBlockIndex":0,"delta":{"text":"first"}}`)
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+			close(disconnected)
+			return
+		case <-release:
+		}
+		writeStreamEvent(t, w, "contentBlockDelta", `{"contentBlockIndex":0,"delta":{"text":"late"}}`)
+	}))
+	defer server.Close()
+	b := newTestBedrock(server)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var chunks []string
+	_, err := b.generateText(ctx, "amazon.nova-lite-v1:0", &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("hi")},
+	}, func(ctx context.Context, chunk *ai.ModelResponseChunk) error {
+		chunks = append(chunks, chunk.Text())
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("error = %v, want context.Canceled", err)
+	}
+	select {
+	case <-disconnected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("event stream connection was not closed after cancellation")
+	}
+	if len(chunks) != 1 || chunks[0] != "first" {
+		t.Errorf("chunks = %q, want only the chunk before cancellation", chunks)
+	}
+}
+
+// writeStreamEvent writes one Converse stream event in the AWS event stream
+// encoding.
+func writeStreamEvent(t *testing.T, w io.Writer, eventType, payload string) {
+	t.Helper()
+	var headers eventstream.Headers
+	headers.Set(":message-type", eventstream.StringValue("event"))
+	headers.Set(":event-type", eventstream.StringValue(eventType))
+	headers.Set(":content-type", eventstream.StringValue("application/json"))
+	if err := eventstream.NewEncoder().Encode(w, eventstream.Message{Headers: headers, Payload: []byte(payload)}); err != nil {
+		t.Errorf("encode %s event: %v", eventType, err)
+	}
+}
+
 func TestConsumeStreamEvents_CallbackErrors(t *testing.T) {
 	callbackErr := errors.New("callback failed")
 	tests := []struct {
@@ -211,7 +592,7 @@ func TestConsumeStreamEvents_UnsupportedDeltaErrors(t *testing.T) {
 	_, err := (&Bedrock{}).consumeStreamEvents(context.Background(), streamEvents(
 		&types.ConverseStreamOutputMemberContentBlockDelta{Value: types.ContentBlockDeltaEvent{
 			ContentBlockIndex: aws.Int32(0),
-			Delta:             &types.ContentBlockDeltaMemberCitation{},
+			Delta:             &types.UnknownUnionMember{Tag: "future_delta"},
 		}},
 	), nil, nil)
 	if err == nil || !strings.Contains(err.Error(), "unhandled stream content delta") {
@@ -266,6 +647,51 @@ func TestConsumeStreamEvents_EmptyContentReturnsPlaceholder(t *testing.T) {
 	}
 }
 
+func TestConsumeStreamEvents_NoContentBlocks(t *testing.T) {
+	tests := []struct {
+		stop types.StopReason
+		want ai.FinishReason
+	}{
+		{types.StopReasonEndTurn, ai.FinishReasonStop},
+		{types.StopReasonContentFiltered, ai.FinishReasonBlocked},
+		{types.StopReasonMaxTokens, ai.FinishReasonLength},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.stop), func(t *testing.T) {
+			events := streamEvents(
+				&types.ConverseStreamOutputMemberMessageStart{Value: types.MessageStartEvent{Role: types.ConversationRoleAssistant}},
+				&types.ConverseStreamOutputMemberMessageStop{Value: types.MessageStopEvent{StopReason: tt.stop}},
+				&types.ConverseStreamOutputMemberMetadata{Value: types.ConverseStreamMetadataEvent{
+					Usage: &types.TokenUsage{InputTokens: aws.Int32(4), OutputTokens: aws.Int32(0), TotalTokens: aws.Int32(4)},
+				}},
+			)
+			calls := 0
+			resp, err := (&Bedrock{}).consumeStreamEvents(context.Background(), events, &ai.ModelRequest{}, func(context.Context, *ai.ModelResponseChunk) error {
+				calls++
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if calls != 0 {
+				t.Errorf("callback called %d times, want 0", calls)
+			}
+			if resp == nil || resp.Message == nil || resp.Message.Role != ai.RoleModel {
+				t.Fatalf("resp = %+v, want a model message", resp)
+			}
+			if resp.Text() != "" || len(resp.Message.Content) != 1 {
+				t.Errorf("content = %+v, want one empty text part", resp.Message.Content)
+			}
+			if resp.FinishReason != tt.want || StopReason(resp) != string(tt.stop) {
+				t.Errorf("finish = %q (%q), want %q (%q)", resp.FinishReason, StopReason(resp), tt.want, tt.stop)
+			}
+			if resp.Usage == nil || resp.Usage.InputTokens != 4 || resp.Usage.OutputTokens != 0 {
+				t.Errorf("Usage = %+v, want 4 input and 0 output tokens", resp.Usage)
+			}
+		})
+	}
+}
+
 func TestDecodeToolInput_EmptyAndMalformed(t *testing.T) {
 	v, err := decodeToolInput(" \n\t")
 	if err != nil || v != nil {
@@ -283,6 +709,62 @@ func TestDecodeToolInput_TrailingJSONErrors(t *testing.T) {
 	}
 }
 
+func TestConsumeStreamEvents_SplitMultibyteRunes(t *testing.T) {
+	emoji := "\U0001F600" // 4 bytes in UTF-8
+	blockStop := &types.ConverseStreamOutputMemberContentBlockStop{Value: types.ContentBlockStopEvent{ContentBlockIndex: aws.Int32(1)}}
+	events := streamEvents(
+		reasoningDelta(0, &types.ReasoningContentBlockDeltaMemberText{Value: "é"[:1]}),
+		reasoningDelta(0, &types.ReasoningContentBlockDeltaMemberText{Value: "é"[1:] + "!"}),
+		textDelta(1, "hi "+emoji[:2]),
+		textDelta(1, emoji[2:3]),
+		textDelta(1, emoji[3:]+" ok"+emoji[:1]),
+		blockStop,
+		&types.ConverseStreamOutputMemberMessageStop{Value: types.MessageStopEvent{StopReason: types.StopReasonEndTurn}},
+	)
+
+	var text, reasoning strings.Builder
+	resp, err := (&Bedrock{}).consumeStreamEvents(context.Background(), events, nil, func(_ context.Context, chunk *ai.ModelResponseChunk) error {
+		for _, part := range chunk.Content {
+			if !utf8.ValidString(part.Text) {
+				t.Errorf("chunk part %q is not valid UTF-8", part.Text)
+			}
+			if part.IsReasoning() {
+				reasoning.WriteString(part.Text)
+			} else {
+				text.WriteString(part.Text)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The block ends on an incomplete sequence, which becomes U+FFFD.
+	want := "hi " + emoji + " ok\uFFFD"
+	if text.String() != want || resp.Text() != want {
+		t.Errorf("streamed %q, final %q; want %q", text.String(), resp.Text(), want)
+	}
+	if reasoning.String() != "é!" || resp.Reasoning() != "é!" {
+		t.Errorf("streamed reasoning %q, final %q; want %q", reasoning.String(), resp.Reasoning(), "é!")
+	}
+}
+
+func TestUTF8Tail(t *testing.T) {
+	var tail utf8Tail
+	if got := tail.complete("a\xe2\x82"); got != "a" {
+		t.Errorf("complete = %q, want %q", got, "a")
+	}
+	if got := tail.complete("\xac b"); got != "€ b" {
+		t.Errorf("complete = %q, want %q", got, "€ b")
+	}
+	if got := tail.complete("x\xffy"); got != "x\uFFFDy" {
+		t.Errorf("complete(invalid byte) = %q, want replacement", got)
+	}
+	if got := tail.flush(); got != "" {
+		t.Errorf("flush = %q, want empty", got)
+	}
+}
+
 func TestIndexOf(t *testing.T) {
 	if got := indexOf(nil); got != 0 {
 		t.Fatalf("indexOf(nil) = %d, want 0", got)
@@ -309,6 +791,13 @@ func textDelta(idx int32, text string) types.ConverseStreamOutput {
 	}}
 }
 
+func reasoningDelta(idx int32, delta types.ReasoningContentBlockDelta) types.ConverseStreamOutput {
+	return &types.ConverseStreamOutputMemberContentBlockDelta{Value: types.ContentBlockDeltaEvent{
+		ContentBlockIndex: aws.Int32(idx),
+		Delta:             &types.ContentBlockDeltaMemberReasoningContent{Value: delta},
+	}}
+}
+
 func toolStart(idx int32, id, name string) types.ConverseStreamOutput {
 	return &types.ConverseStreamOutputMemberContentBlockStart{Value: types.ContentBlockStartEvent{
 		ContentBlockIndex: aws.Int32(idx),
@@ -331,3 +820,20 @@ func toolStop(idx int32) types.ConverseStreamOutput {
 		ContentBlockIndex: aws.Int32(idx),
 	}}
 }
+
+func TestConsumeStreamEvents_UnknownStopReasonPreserved(t *testing.T) {
+	events := streamEvents(
+		textDelta(0, "hi"),
+		&types.ConverseStreamOutputMemberMessageStop{Value: types.MessageStopEvent{StopReason: "quota_paused"}},
+	)
+	resp, err := (&Bedrock{}).consumeStreamEvents(context.Background(), events, &ai.ModelRequest{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.FinishReason != ai.FinishReasonOther {
+		t.Errorf("FinishReason = %q, want other", resp.FinishReason)
+	}
+	if got, ok := RawStopReason(resp); !ok || got != "quota_paused" {
+		t.Errorf("RawStopReason() = %q, %v; want quota_paused", got, ok)
+	}
+}
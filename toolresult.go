@@ -0,0 +1,215 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+// toolResultLimit returns the byte limit for tool results in a request:
+// [Config.MaxToolResultBytes] when set, else [Bedrock.MaxToolResultBytes].
+// 0 means no limit.
+func (b *Bedrock) toolResultLimit(cfg *Config) int {
+	if cfg != nil && cfg.MaxToolResultBytes > 0 {
+		return cfg.MaxToolResultBytes
+	}
+	if b == nil {
+		return 0
+	}
+	return b.MaxToolResultBytes
+}
+
+// limitToolResults truncates the tool responses in input that exceed the
+// request's tool result limit. Each response's Output and text parts share
+// the limit, in order; media parts are kept. Messages with nothing to
+// truncate are shared with input, which is never modified.
+func (b *Bedrock) limitToolResults(input *ai.ModelRequest, cfg *Config) (*ai.ModelRequest, error) {
+	limit := b.toolResultLimit(cfg)
+	if limit <= 0 {
+		return input, nil
+	}
+	return mapToolResponses(input, func(resp *ai.ToolResponse) (*ai.ToolResponse, error) {
+		return truncateToolResponse(resp, limit)
+	})
+}
+
+// mapToolResponses returns input with each tool response replaced by
+// fn(resp). Messages whose responses fn returns unchanged are shared with
+// input, which is never modified.
+func mapToolResponses(input *ai.ModelRequest, fn func(*ai.ToolResponse) (*ai.ToolResponse, error)) (*ai.ModelRequest, error) {
+	var out *ai.ModelRequest
+	for i, msg := range input.Messages {
+		if msg == nil {
+			continue
+		}
+		var content []*ai.Part
+		for j, part := range msg.Content {
+			if !part.IsToolResponse() || part.ToolResponse == nil {
+				continue
+			}
+			resp, err := fn(part.ToolResponse)
+			if err != nil {
+				return nil, err
+			}
+			if resp == part.ToolResponse {
+				continue
+			}
+			if content == nil {
+				content = append([]*ai.Part(nil), msg.Content...)
+			}
+			replaced := *part
+			replaced.ToolResponse = resp
+			content[j] = &replaced
+		}
+		if content == nil {
+			continue
+		}
+		if out == nil {
+			copied := *input
+			copied.Messages = append([]*ai.Message(nil), input.Messages...)
+			out = &copied
+		}
+		copied := *msg
+		copied.Content = content
+		out.Messages[i] = &copied
+	}
+	if out == nil {
+		return input, nil
+	}
+	return out, nil
+}
+
+// toolResultImageRule lists the image types that models whose ID contains
+// Models accept in tool results.
+type toolResultImageRule struct {
+	Models string // Model ID substring, e.g. "amazon.nova-"
+	MIMEs  []string
+}
+
+// toolResultImageFormats holds the model families with tool result image
+// constraints.
+var toolResultImageFormats = []toolResultImageRule{
+	{Models: "amazon.nova-", MIMEs: []string{"image/png", "image/jpeg", "image/gif", "image/webp"}},
+}
+
+// prepareToolResultImages fits the tool result images in input to the
+// formats and size limits of models listed in toolResultImageFormats:
+// images in other formats are converted to PNG and oversized ones scaled
+// down (see [preprocessImage]). Images that cannot be converted fail the
+// request naming the tool. Other models get input unchanged.
+func prepareToolResultImages(modelName string, input *ai.ModelRequest) (*ai.ModelRequest, error) {
+	name := strings.ToLower(modelName)
+	i := slices.IndexFunc(toolResultImageFormats, func(rule toolResultImageRule) bool {
+		return strings.Contains(name, rule.Models)
+	})
+	if i < 0 {
+		return input, nil
+	}
+	accepted := toolResultImageFormats[i].MIMEs
+	return mapToolResponses(input, func(resp *ai.ToolResponse) (*ai.ToolResponse, error) {
+		var out *ai.ToolResponse
+		for j, part := range resp.Content {
+			if part == nil || !part.IsMedia() || !strings.HasPrefix(mediaMIME(part), "image/") {
+				continue
+			}
+			prepared, err := preprocessImagePart(part)
+			if err != nil {
+				return nil, fmt.Errorf("bedrock: tool response %q: %w", resp.Name, err)
+			}
+			if !slices.Contains(accepted, mediaMIME(prepared)) {
+				return nil, fmt.Errorf("bedrock: tool response %q: model %q does not accept %s images in tool results; use %s", resp.Name, modelName, mediaMIME(part), strings.Join(accepted, ", "))
+			}
+			if prepared == part {
+				continue
+			}
+			if out == nil {
+				copied := *resp
+				copied.Content = append([]*ai.Part(nil), resp.Content...)
+				out = &copied
+			}
+			out.Content[j] = prepared
+		}
+		if out == nil {
+			return resp, nil
+		}
+		return out, nil
+	})
+}
+
+// truncateToolResponse returns resp with its Output and text parts cut to
+// limit bytes in total, or resp itself when it fits. A JSON Output that does
+// not fit is sent as truncated JSON text.
+func truncateToolResponse(resp *ai.ToolResponse, limit int) (*ai.ToolResponse, error) {
+	budget := limit
+	var out *ai.ToolResponse
+	copiedContent := false
+	if resp.Output != nil {
+		text, err := toolResponseText(resp.Output)
+		if err != nil {
+			return nil, err
+		}
+		if kept, ok := truncateText(text, budget); ok {
+			copied := *resp
+			copied.Output = kept
+			out = &copied
+		}
+		budget = max(budget-len(text), 0)
+	}
+	for i, part := range resp.Content {
+		if !part.IsText() {
+			continue
+		}
+		kept, ok := truncateText(part.Text, budget)
+		budget = max(budget-len(part.Text), 0)
+		if !ok {
+			continue
+		}
+		if out == nil {
+			copied := *resp
+			out = &copied
+		}
+		if !copiedContent {
+			out.Content = append([]*ai.Part(nil), resp.Content...)
+			copiedContent = true
+		}
+		// Truncated JSON no longer parses, so it is sent as text.
+		out.Content[i] = ai.NewTextPart(kept)
+	}
+	if out == nil {
+		return resp, nil
+	}
+	return out, nil
+}
+
+// truncateText cuts s to at most limit bytes, on a rune boundary, and marks
+// the cut. ok is false when s fits.
+func truncateText(s string, limit int) (kept string, ok bool) {
+	if len(s) <= limit {
+		return s, false
+	}
+	n := limit
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + fmt.Sprintf("... [truncated %d bytes]", len(s)-n), true
+}
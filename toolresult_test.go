@@ -0,0 +1,196 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/firebase/genkit/go/ai"
+)
+
+func toolResultRequest(resp *ai.ToolResponse, cfg *Config) *ai.ModelRequest {
+	req := &ai.ModelRequest{Messages: []*ai.Message{
+		ai.NewUserTextMessage("look it up"),
+		{Role: ai.RoleModel, Content: []*ai.Part{ai.NewToolRequestPart(&ai.ToolRequest{Name: "lookup", Ref: "call-1"})}},
+		{Role: ai.RoleTool, Content: []*ai.Part{ai.NewToolResponsePart(resp)}},
+	}}
+	if cfg != nil {
+		req.Config = cfg
+	}
+	return req
+}
+
+func toolResultTexts(t *testing.T, in *types.Message) []string {
+	t.Helper()
+	result := in.Content[0].(*types.ContentBlockMemberToolResult).Value
+	var texts []string
+	for _, c := range result.Content {
+		texts = append(texts, c.(*types.ToolResultContentBlockMemberText).Value)
+	}
+	return texts
+}
+
+func TestBuildConverseInput_TruncatesToolResults(t *testing.T) {
+	big := strings.Repeat("x", 100)
+	b := &Bedrock{MaxToolResultBytes: 40}
+
+	out, err := b.buildConverseInput("model-id", toolResultRequest(&ai.ToolResponse{Name: "lookup", Ref: "call-1", Output: big}, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Repeat("x", 40) + "... [truncated 60 bytes]"
+	if got := toolResultTexts(t, &out.Messages[2]); len(got) != 1 || got[0] != want {
+		t.Errorf("truncated output = %q, want %q", got, want)
+	}
+
+	// The per-request limit wins, and the Output and text parts share it.
+	req := toolResultRequest(&ai.ToolResponse{
+		Name:    "lookup",
+		Ref:     "call-1",
+		Output:  "0123456789",
+		Content: []*ai.Part{ai.NewTextPart("abcdefghij"), ai.NewJSONPart(`{"k":"vvvvvvvv"}`)},
+	}, &Config{MaxToolResultBytes: 15})
+	out, err = b.buildConverseInput("model-id", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantTexts := []string{"0123456789", "abcde... [truncated 5 bytes]", "... [truncated 16 bytes]"}
+	if got := toolResultTexts(t, &out.Messages[2]); strings.Join(got, "|") != strings.Join(wantTexts, "|") {
+		t.Errorf("shared-limit texts = %q, want %q", got, wantTexts)
+	}
+	if got := req.Messages[2].Content[0].ToolResponse.Output; got != "0123456789" || req.Messages[2].Content[0].ToolResponse.Content[0].Text != "abcdefghij" {
+		t.Errorf("request modified: output %v", got)
+	}
+}
+
+func TestBuildConverseInput_SmallToolResultsUntouched(t *testing.T) {
+	b := &Bedrock{MaxToolResultBytes: 40}
+	resp := &ai.ToolResponse{Name: "lookup", Ref: "call-1", Output: map[string]any{"temp": 21}}
+	req := toolResultRequest(resp, nil)
+	limited, err := b.limitToolResults(req, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limited != req {
+		t.Error("request with small tool results was copied")
+	}
+	out, err := b.buildConverseInput("model-id", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := toolResultTexts(t, &out.Messages[2]); len(got) != 1 || got[0] != `{"temp":21}` {
+		t.Errorf("tool result = %q, want it unchanged", got)
+	}
+}
+
+func TestTruncateText(t *testing.T) {
+	if got, ok := truncateText("héllo", 2); !ok || got != "h... [truncated 5 bytes]" {
+		t.Errorf("truncateText mid-rune = %q, %v; want the cut before é", got, ok)
+	}
+	if got, ok := truncateText("hello", 5); ok || got != "hello" {
+		t.Errorf("truncateText at limit = %q, %v; want it untouched", got, ok)
+	}
+}
+
+func TestConfigValidate_MaxToolResultBytes(t *testing.T) {
+	if err := (&Config{MaxToolResultBytes: -1}).Validate(); err == nil || !strings.Contains(err.Error(), "maxToolResultBytes") {
+		t.Errorf("Validate() = %v, want maxToolResultBytes error", err)
+	}
+}
+
+func toolResultImage(t *testing.T, in *types.Message) types.ImageBlock {
+	t.Helper()
+	result := in.Content[0].(*types.ContentBlockMemberToolResult).Value
+	for _, c := range result.Content {
+		if img, ok := c.(*types.ToolResultContentBlockMemberImage); ok {
+			return img.Value
+		}
+	}
+	t.Fatalf("tool result content = %+v, want an image", result.Content)
+	return types.ImageBlock{}
+}
+
+func TestBuildConverseInput_NovaToolResultImages(t *testing.T) {
+	const nova = "us.amazon.nova-pro-v1:0"
+	imageResult := func(mime string, data []byte) *ai.ModelRequest {
+		return toolResultRequest(&ai.ToolResponse{
+			Name:    "screenshot",
+			Ref:     "call-1",
+			Content: []*ai.Part{ai.NewMediaPart(mime, dataURL(mime, data))},
+		}, nil)
+	}
+
+	t.Run("converts BMP to PNG", func(t *testing.T) {
+		req := imageResult("image/bmp", encodeTestBMP(t, testImage(4, 3), 24))
+		out, err := (&Bedrock{}).buildConverseInput(nova, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block := toolResultImage(t, &out.Messages[2])
+		if block.Format != types.ImageFormatPng {
+			t.Errorf("format = %q, want png", block.Format)
+		}
+		if cfg, err := png.DecodeConfig(bytes.NewReader(imageBytes(t, block))); err != nil || cfg.Width != 4 || cfg.Height != 3 {
+			t.Errorf("converted image = %+v, %v; want a 4x3 PNG", cfg, err)
+		}
+		if req.Messages[2].Content[0].ToolResponse.Content[0].ContentType != "image/bmp" {
+			t.Error("request modified")
+		}
+	})
+
+	t.Run("scales down oversized images", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, testImage(maxImageDimension+10, 2)); err != nil {
+			t.Fatal(err)
+		}
+		out, err := (&Bedrock{}).buildConverseInput(nova, imageResult("image/png", buf.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := png.DecodeConfig(bytes.NewReader(imageBytes(t, toolResultImage(t, &out.Messages[2]))))
+		if err != nil || cfg.Width > maxImageDimension {
+			t.Errorf("image = %+v, %v; want at most %d pixels wide", cfg, err, maxImageDimension)
+		}
+	})
+
+	t.Run("keeps accepted images", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, testImage(2, 2)); err != nil {
+			t.Fatal(err)
+		}
+		req := imageResult("image/png", buf.Bytes())
+		prepared, err := prepareToolResultImages(nova, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if prepared != req {
+			t.Error("request with accepted tool result images was copied")
+		}
+	})
+
+	t.Run("rejects unconvertible images", func(t *testing.T) {
+		_, err := (&Bedrock{}).buildConverseInput(nova, imageResult("image/tiff", []byte("not an image")))
+		if err == nil || !strings.Contains(err.Error(), `tool response "screenshot"`) || !strings.Contains(err.Error(), "image/tiff") {
+			t.Fatalf("err = %v, want an error naming the tool and format", err)
+		}
+	})
+}
@@ -19,6 +19,8 @@ package bedrock
 
 import (
 	"encoding/base64"
+	"fmt"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
 	"github.com/firebase/genkit/go/ai"
@@ -35,8 +37,21 @@ type (
 
 // ModelCapability represents the capabilities of a model
 type ModelCapability struct {
-	Multimodal bool // Supports image/media inputs
-	Tools      bool // Supports function calling
+	Multimodal      bool // Supports image inputs
+	Documents       bool // Supports document inputs (PDF, CSV, DOCX, ...)
+	Video           bool // Supports video inputs
+	Tools           bool // Supports function calling
+	MaxOutputTokens int  // Maximum maxTokens the model accepts (0: unknown, not validated)
+	ToolCaching     bool // Accepts a prompt cache point after the tool definitions
+	SystemPrompt    bool // Accepts a system prompt (false: folded into the first user message)
+	MaxImages       int  // Maximum image blocks per request (0: unknown, not validated)
+	Profile         bool // Offered through cross-region inference profiles (us., eu., ...)
+	// MaxStopSequences is the most stop sequences the model accepts (0: unknown, not validated)
+	MaxStopSequences int
+	// ParallelTools reports that the model may request several tools in one
+	// turn. Responses are not changed either way; orchestrators can use it to
+	// decide whether to expect, or ask for, one tool call at a time.
+	ParallelTools bool
 }
 
 // Constants
@@ -67,8 +82,23 @@ const (
 	FinishReasonUnknown FinishReason = "unknown"
 )
 
+// APIMode names the Bedrock API a chat model is called through (see
+// [Bedrock.APIModes]).
+type APIMode string
+
+// API mode constants
+const (
+	APIModeConverse APIMode = "converse" // The Converse and ConverseStream APIs
+	APIModeInvoke   APIMode = "invoke"   // InvokeModel with a registered ProviderCodec
+)
+
 const bedrockCachePointTypeKey = "bedrockCachePointType"
 
+// cacheWriteInputTokensUsageKey is the [ai.GenerationUsage.Custom] key under
+// which Bedrock's cacheWriteInputTokens count is reported. Genkit's usage type
+// only has a field for cache reads.
+const cacheWriteInputTokensUsageKey = "cacheWriteInputTokens"
+
 // Metadata keys used to round-trip Bedrock reasoning ("thinking") content back
 // into a follow-up request. Bedrock returns signed and sometimes redacted
 // reasoning that must be replayed verbatim on the next turn or the model
@@ -81,14 +111,89 @@ const (
 	redactedReasoningMetadataKey  = "bedrockRedactedContent"
 )
 
-// Config is the per-call configuration for Bedrock Converse models. Pass it
-// via [ai.WithConfig].
+// thinkingMetadataKey flags reasoning parts, streamed or final
+// (Metadata["thinking"] = true), so a UI can route them to a thinking panel
+// without inspecting the part kind. Answer text is always in separate text
+// parts, in the order the model produced them.
+const thinkingMetadataKey = "thinking"
+
+// serverLatencyMetadataKey holds Bedrock's server-side latency
+// (metrics.latencyMs) on the response message metadata. Genkit's own
+// [ai.ModelResponse.LatencyMs] is measured client-side and includes network
+// time.
+const serverLatencyMetadataKey = "bedrockLatencyMs"
+
+// additionalFieldsMetadataKey holds the model's additionalModelResponseFields
+// (provider-specific outputs outside the Converse schema) as a JSON object on
+// the response message metadata.
+const additionalFieldsMetadataKey = "bedrockAdditionalFields"
+
+// stopReasonMetadataKey holds Bedrock's stop reason, such as "tool_use", on
+// the response message metadata (see [StopReason]).
+const stopReasonMetadataKey = "bedrockStopReason"
+
+// rawStopReasonMetadataKey holds a Converse stopReason this plugin does not
+// know, verbatim, on the response message metadata; the finish reason for it
+// is "other".
+const rawStopReasonMetadataKey = "rawStopReason"
+
+// Tool request parts from Converse carry the position of their toolUse block
+// in the response content and its toolUseId, so agents can keep several tool
+// calls in order and correlate them with their results.
+const (
+	toolUseIndexMetadataKey = "bedrockContentBlockIndex"
+	toolUseIDMetadataKey    = "bedrockToolUseId"
+)
+
+// With IncludeRoutingMetadata, responses record the region that served the
+// call and, for inference profile model IDs, the profile.
+const (
+	regionMetadataKey           = "bedrockRegion"
+	inferenceProfileMetadataKey = "bedrockInferenceProfile"
+)
+
+// toolErrorMetadataKey flags (Metadata["toolError"] = true) a tool response
+// part reporting a failed tool call; it is sent to Converse as a tool result
+// with status "error". See [NewToolErrorPart].
+const toolErrorMetadataKey = "toolError"
+
+// logprobsMetadataKey holds the generated tokens' log probabilities, as
+// []TokenLogprob, when [Config.Logprobs] is set.
+const logprobsMetadataKey = "bedrockLogprobs"
+
+// usageChunkKey holds the running *ai.GenerationUsage in the Custom map of
+// stream chunks when [Bedrock.StreamUsage] is set.
+const usageChunkKey = "bedrockUsage"
+
+// agentTraceMetadataKey holds an [InvokeAgent] response's trace, as
+// []AgentTraceStep.
+const agentTraceMetadataKey = "bedrockAgentTrace"
+
+// TokenLogprob is a generated token and its log probability.
+type TokenLogprob struct {
+	Token   string  `json:"token"`
+	Logprob float64 `json:"logprob"`
+}
+
+// truncatedMetadataKey flags (Metadata["truncated"] = true) a response cut
+// off at maxTokens, alongside its FinishReasonLength.
+const truncatedMetadataKey = "truncated"
+
+// cacheHitMetadataKey flags (Metadata["cacheHit"] = true) a response whose
+// input was partly read from the prompt cache, that is one with
+// cacheReadInputTokens > 0. It is false when usage shows no cache read and
+// absent when the response has no usage.
+const cacheHitMetadataKey = "cacheHit"
+
+// GenerationConfig is the per-call configuration for Bedrock Converse models.
+// Pass it via [ai.WithConfig].
 //
 // It is fully optional and additive: callers may still pass configuration as a
 // map[string]any (the historical shape) or as *ai.GenerationCommonConfig; see
-// configFromRequest. The typed form exists mainly so model-specific knobs like
-// Claude extended thinking can be enabled through AdditionalModelRequestFields.
-type Config struct {
+// configFromRequest. Whatever the shape, the decoded config is checked by
+// [GenerationConfig.Validate] before any call. Zero values leave a setting to
+// the model's default.
+type GenerationConfig struct {
 	// MaxTokens is the upper bound on the generated response length. When 0, the
 	// plugin leaves the field unset except for Claude models, where Bedrock
 	// requires a value.
@@ -100,6 +205,12 @@ type Config struct {
 	// TopP is the nucleus-sampling cutoff. nil leaves it to the model default.
 	TopP *float32 `json:"topP,omitempty"`
 
+	// TopK limits sampling to the K most likely tokens. Converse has no common
+	// topK field, so it is sent as the model family's own request field
+	// (Claude and Mistral top_k, Nova inferenceConfig.topK, Cohere k). nil
+	// leaves it to the model default.
+	TopK *int `json:"topK,omitempty"`
+
 	// StopSequences are strings that, when generated, halt generation.
 	StopSequences []string `json:"stopSequences,omitempty"`
 
@@ -120,6 +231,186 @@ type Config struct {
 	//		},
 	//	}
 	AdditionalModelRequestFields map[string]any `json:"additionalModelRequestFields,omitempty"`
+
+	// AdditionalResponseFieldPaths selects model-specific response fields by
+	// JSON Pointer, e.g. "/stop_sequence" for Claude. Bedrock returns only the
+	// selected fields, which are available through [AdditionalResponseFields].
+	// At most 10 paths.
+	AdditionalResponseFieldPaths []string `json:"additionalResponseFieldPaths,omitempty"`
+
+	// Choices constrains the answer to exactly one of the listed values, for
+	// classification-style prompts. The plugin forces the model to call a
+	// tool whose argument is an enum of the choices and returns the chosen
+	// value as the response text. The list must not be empty, and Choices
+	// cannot be combined with tools or ToolChoice.
+	Choices []string `json:"choices,omitempty"`
+
+	// Citations asks models that support it (Anthropic Claude) to cite the
+	// request's documents. Cited answer text comes back as text parts whose
+	// source spans are available via [Citations].
+	Citations bool `json:"citations,omitempty"`
+
+	// Region sends this call to another AWS region, e.g. for a model that is
+	// not offered in the plugin's region. Clients are cached per region.
+	Region string `json:"region,omitempty"`
+
+	// Guardrail applies a Bedrock guardrail to the call. nil sends none.
+	Guardrail *GuardrailConfig `json:"guardrail,omitempty"`
+
+	// Llama holds Meta Llama generation options. It is ignored for other
+	// models.
+	Llama *LlamaConfig `json:"llama,omitempty"`
+
+	// ThinkingBudget enables Claude extended thinking with this many tokens
+	// of reasoning: at least 1024, and less than MaxTokens when MaxTokens is
+	// set. It is sent as the "thinking" request field unless
+	// AdditionalModelRequestFields sets one; other models ignore it. 0
+	// leaves thinking off.
+	ThinkingBudget int `json:"thinkingBudget,omitempty"`
+
+	// AnthropicBeta opts Claude models into Anthropic beta features, such as
+	// "token-efficient-tools-2025-02-19", sent as the "anthropic_beta"
+	// request field unless AdditionalModelRequestFields sets one. Requests to
+	// other models with betas fail.
+	AnthropicBeta []string `json:"anthropicBeta,omitempty"`
+
+	// NoSystemPrompt drops the request's system messages, so exactly the
+	// conversation turns are sent, e.g. for reproducible evaluations.
+	NoSystemPrompt bool `json:"noSystemPrompt,omitempty"`
+
+	// MaxToolResultBytes truncates each tool response in the request to this
+	// many bytes of text, overriding [Bedrock.MaxToolResultBytes]. 0 uses
+	// the plugin's limit.
+	MaxToolResultBytes int `json:"maxToolResultBytes,omitempty"`
+
+	// Logprobs asks for the log probability of each generated token, returned
+	// in the response metadata (see [Logprobs]). Cohere Command text models
+	// support it; on other built-in models the request fails.
+	Logprobs bool `json:"logprobs,omitempty"`
+
+	// Verbosity asks for a concise ("low"), balanced ("medium") or thorough
+	// ("high") answer, independently of MaxTokens. It is mapped to the
+	// model's own control where there is one (the effort setting of Claude
+	// Opus 4.5) and ignored for other models.
+	Verbosity string `json:"verbosity,omitempty"`
+
+	// IncludeReasoning controls whether the reasoning of a thinking model
+	// appears in the response content and stream. false keeps it out: the
+	// reasoning is logged at debug level and kept in the message metadata,
+	// where [Reasoning] reads it and later turns send it back to the model.
+	// nil includes it.
+	IncludeReasoning *bool `json:"includeReasoning,omitempty"`
+}
+
+// Config is the original name of [GenerationConfig]; both name the same
+// type.
+type Config = GenerationConfig
+
+// minThinkingBudget is the smallest Claude extended thinking budget.
+const minThinkingBudget = 1024
+
+// Validate checks the settings that do not depend on the model: value
+// ranges, stop sequences, Anthropic betas, response field paths, the
+// guardrail, Llama options, Choices, Region and Verbosity. Every Generate
+// call validates its config; limits that depend on the model, such as
+// maxTokens, the temperature ceiling (1 for most models, 2 for AI21 Jamba, 5
+// for Cohere Command) or the number of stop sequences, are left to Bedrock
+// or checked once the model is known.
+func (c *GenerationConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.MaxTokens < 0 {
+		return fmt.Errorf("bedrock: maxTokens %d must not be negative", c.MaxTokens)
+	}
+	if t := c.Temperature; t != nil && *t < 0 {
+		return fmt.Errorf("bedrock: temperature %v must not be negative", *t)
+	}
+	if p := c.TopP; p != nil && (*p < 0 || *p > 1) {
+		return fmt.Errorf("bedrock: topP %v out of range [0, 1]", *p)
+	}
+	if c.MaxToolResultBytes < 0 {
+		return fmt.Errorf("bedrock: maxToolResultBytes %d must not be negative", c.MaxToolResultBytes)
+	}
+	if k := c.TopK; k != nil && *k < 1 {
+		return fmt.Errorf("bedrock: topK %d must be at least 1", *k)
+	}
+	for i, seq := range c.StopSequences {
+		if seq == "" {
+			return fmt.Errorf("bedrock: stopSequences[%d] is empty", i)
+		}
+	}
+	switch {
+	case c.ThinkingBudget < 0:
+		return fmt.Errorf("bedrock: thinkingBudget %d must not be negative", c.ThinkingBudget)
+	case c.ThinkingBudget > 0 && c.ThinkingBudget < minThinkingBudget:
+		return fmt.Errorf("bedrock: thinkingBudget %d is below the minimum of %d", c.ThinkingBudget, minThinkingBudget)
+	case c.ThinkingBudget > 0 && c.MaxTokens > 0 && c.ThinkingBudget >= c.MaxTokens:
+		return fmt.Errorf("bedrock: thinkingBudget %d must be less than maxTokens %d", c.ThinkingBudget, c.MaxTokens)
+	}
+	for i, beta := range c.AnthropicBeta {
+		if strings.TrimSpace(beta) == "" {
+			return fmt.Errorf("bedrock: anthropicBeta[%d] is blank", i)
+		}
+	}
+	if _, err := additionalResponseFieldPaths(c.AdditionalResponseFieldPaths); err != nil {
+		return err
+	}
+	if _, err := buildGuardrailConfig(c.Guardrail); err != nil {
+		return err
+	}
+	if err := c.Llama.validate(); err != nil {
+		return err
+	}
+	if c.Choices != nil {
+		if err := checkChoiceList(c.Choices); err != nil {
+			return err
+		}
+	}
+	if c.Region != "" && !regionPattern.MatchString(c.Region) {
+		return fmt.Errorf("bedrock: invalid region %q in request config", c.Region)
+	}
+	switch c.Verbosity {
+	case "", "low", "medium", "high":
+	default:
+		return fmt.Errorf("bedrock: invalid verbosity %q (want low, medium or high)", c.Verbosity)
+	}
+	return nil
+}
+
+// LlamaConfig holds Meta Llama options that Converse has no common field for.
+// They are sent as additionalModelRequestFields.
+type LlamaConfig struct {
+	// RepetitionPenalty discourages repeated tokens: 1 disables it and higher
+	// values penalize repetition more. Must be in (0, 2]. nil leaves it to the
+	// model default.
+	RepetitionPenalty *float32 `json:"repetitionPenalty,omitempty"`
+}
+
+func (l *LlamaConfig) validate() error {
+	if l == nil {
+		return nil
+	}
+	if p := l.RepetitionPenalty; p != nil && (*p <= 0 || *p > 2) {
+		return fmt.Errorf("bedrock: Llama repetitionPenalty %v out of range (0, 2]", *p)
+	}
+	return nil
+}
+
+// GuardrailConfig selects a Bedrock guardrail for a Converse call.
+type GuardrailConfig struct {
+	// Identifier is the guardrail ID or ARN (required).
+	Identifier string `json:"identifier"`
+	// Version is the guardrail version, e.g. "1" or "DRAFT" (required).
+	Version string `json:"version"`
+	// Trace enables the guardrail trace in the response.
+	Trace bool `json:"trace,omitempty"`
+	// StreamProcessingMode applies to streaming calls only. "sync" (the
+	// default) buffers output until the guardrail has checked it: safer, but
+	// chunks arrive later. "async" streams immediately and checks in the
+	// background, so unsafe text may reach the client before the guardrail
+	// intervenes.
+	StreamProcessingMode string `json:"streamProcessingMode,omitempty"`
 }
 
 // configSchema returns the JSON schema for [Config], used as the per-call
@@ -134,16 +425,17 @@ func configSchema() map[string]any { return core.InferSchemaMap(Config{}) }
 // through genkit's request validation.
 func imageConfigSchema() map[string]any { return map[string]any{"type": "object"} }
 
-// newBedrockReasoningPart builds an ai reasoning part carrying the Bedrock
-// signature and/or redacted bytes needed to replay it on the next turn. The
-// signature is also stored under the generic "signature" key (via
-// ai.NewReasoningPart) so framework-level consumers see it too.
+// newBedrockReasoningPart builds an ai reasoning part, flagged as thinking,
+// carrying the Bedrock signature and/or redacted bytes needed to replay it on
+// the next turn. The signature is also stored under the generic "signature"
+// key (via ai.NewReasoningPart) so framework-level consumers see it too.
 func newBedrockReasoningPart(text, signature string, redacted []byte) *ai.Part {
 	var sig []byte
 	if signature != "" {
 		sig = []byte(signature)
 	}
 	p := ai.NewReasoningPart(text, sig)
+	p.Metadata[thinkingMetadataKey] = true
 	if len(sig) > 0 {
 		p.Metadata[reasoningSignatureMetadataKey] = sig
 	}
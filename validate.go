@@ -0,0 +1,120 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/firebase/genkit/go/ai"
+)
+
+// ValidateRequest checks req against what the plugin knows of modelID
+// without calling Bedrock, and returns every problem it finds joined with
+// [errors.Join], or nil. It checks the config, that there is a user turn,
+// tools against the model's tool support and Converse's name rules, media
+// against the kinds and image count the model accepts, stop sequences,
+// maxTokens against the output limit, and config fields the model cannot
+// combine or its API path does not support. System prompts are not
+// problems: models without system prompt support get them folded into the
+// first user message. Plugin settings such as StripUnsupportedTools and
+// ClampMaxTokens apply as they do when generating, and models outside the
+// capability map are only checked for what does not depend on the model.
+func (b *Bedrock) ValidateRequest(modelID string, req *ai.ModelRequest) error {
+	if req == nil {
+		return errors.New("bedrock: model request is nil")
+	}
+	var errs []error
+	add := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	cfg, err := decodeConfig(req)
+	if err != nil {
+		return err
+	}
+	if cfg != nil {
+		add(cfg.Validate())
+	}
+
+	_, err = b.checkUserMessage(dropSystemMessages(req, cfg))
+	add(err)
+
+	_, err = b.checkToolSupport(modelID, req)
+	add(err)
+	for _, tool := range req.Tools {
+		if tool == nil {
+			add(errors.New("bedrock: tool definition required"))
+			continue
+		}
+		add(checkToolDefinition(tool))
+	}
+
+	for _, msg := range req.Messages {
+		add(b.checkMediaTypes(modelID, []*ai.Message{msg}))
+	}
+	add(b.checkImagePartCount(modelID, req.Messages))
+
+	if cfg != nil {
+		add(b.checkStopSequences(modelID, cfg))
+		if cfg.MaxTokens > 0 {
+			add(b.checkMaxTokens(modelID, &types.InferenceConfiguration{MaxTokens: aws.Int32(int32(cfg.MaxTokens))}))
+		}
+		_, err = applyFieldRules(modelID, cfg, incompatibleFieldRules)
+		add(err)
+	}
+
+	_, invoke, err := b.routeInvoke(modelID)
+	add(err)
+	switch {
+	case cfg == nil:
+	case invoke:
+		add(checkInvokeConfig(modelID, cfg))
+	default:
+		if cfg.Logprobs {
+			add(errLogprobsUnsupported(modelID))
+		}
+		if cfg.Choices != nil {
+			add(b.checkChoices(modelID, cfg, req.Tools))
+		} else if cfg.ToolChoice != "" && cfg.ToolChoice != ToolChoiceNone && len(req.Tools) > 0 {
+			_, err = convertToolChoice(cfg.ToolChoice, req.Tools)
+			add(err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkImagePartCount is [checkImageCount] for the image parts of msgs.
+func (b *Bedrock) checkImagePartCount(modelName string, msgs []*ai.Message) error {
+	images := 0
+	for _, msg := range msgs {
+		if msg == nil {
+			continue
+		}
+		for _, part := range msg.Content {
+			if part != nil && part.IsMedia() && strings.HasPrefix(mediaMIME(part), "image/") {
+				images++
+			}
+		}
+	}
+	return b.checkImageLimit(modelName, images)
+}
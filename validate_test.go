@@ -0,0 +1,105 @@
+// Copyright 2025 Xavier Portilla Edo
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/firebase/genkit/go/ai"
+)
+
+func TestValidateRequest_ReportsAllProblems(t *testing.T) {
+	req := &ai.ModelRequest{
+		Messages: []*ai.Message{
+			ai.NewUserMessage(ai.NewTextPart("What is this?"), ai.NewMediaPart("image/png", "data:image/png;base64,iVBORw0KGgo=")),
+		},
+		Tools: []*ai.ToolDefinition{{Name: "get weather"}},
+		Config: &Config{
+			MaxTokens:     5000,
+			StopSequences: []string{"a", "b", "c", "d", "e"},
+			Citations:     true,
+		},
+	}
+	err := (&Bedrock{}).ValidateRequest("cohere.command-text-v14", req)
+	if err == nil {
+		t.Fatal("ValidateRequest = nil, want errors")
+	}
+	for _, want := range []string{
+		"does not support tool use",
+		`tool "get weather": name must be`,
+		"does not accept image input (image/png)",
+		"5 stop sequences but model",
+		"maxTokens 5000 exceeds the 4000-token output limit",
+		"served through InvokeModel, which does not support citations",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not report %q:\n%v", want, err)
+		}
+	}
+	var joined interface{ Unwrap() []error }
+	if !errors.As(err, &joined) || len(joined.Unwrap()) != 6 {
+		t.Errorf("error = %v, want 6 joined errors", err)
+	}
+}
+
+func TestValidateRequest_ConverseConfigConflicts(t *testing.T) {
+	req := &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewSystemTextMessage("Be brief.")},
+		Tools:    []*ai.ToolDefinition{{Name: "lookup"}},
+		Config: &Config{
+			MaxTokens:      4096,
+			ThinkingBudget: 2048,
+			Temperature:    aws.Float32(0.3),
+			Logprobs:       true,
+			ToolChoice:     "search",
+		},
+	}
+	err := (&Bedrock{}).ValidateRequest("us.anthropic.claude-3-7-sonnet-20250219-v1:0", req)
+	if err == nil {
+		t.Fatal("ValidateRequest = nil, want errors")
+	}
+	for _, want := range []string{
+		"at least one user message",
+		"temperature cannot be combined with thinking",
+		"does not return logprobs",
+		`ToolChoice "search" does not match any declared tool`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not report %q:\n%v", want, err)
+		}
+	}
+}
+
+func TestValidateRequest_Valid(t *testing.T) {
+	req := &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewSystemTextMessage("Be brief."), ai.NewUserTextMessage("hi")},
+		Tools:    []*ai.ToolDefinition{{Name: "lookup", Description: "Look things up."}},
+		Config:   &Config{MaxTokens: 1000, ToolChoice: "lookup"},
+	}
+	if err := (&Bedrock{}).ValidateRequest("anthropic.claude-3-5-haiku-20241022-v1:0", req); err != nil {
+		t.Errorf("ValidateRequest = %v, want nil", err)
+	}
+	// Titan folds the system prompt into the user turn, and StripUnsupportedTools drops tools.
+	req.Config = nil
+	if err := (&Bedrock{StripUnsupportedTools: true}).ValidateRequest("amazon.titan-text-express-v1", req); err != nil {
+		t.Errorf("ValidateRequest(titan) = %v, want nil", err)
+	}
+}